@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+var (
+	adminAddr      = flag.String("admin-addr", "", "address of the admin API (empty to disable); requires -admin-token-file or admin mTLS")
+	adminTokenFile = flag.String("admin-token-file", "", "file holding the bearer token admin API requests must present")
+	adminCertFile  = flag.String("admin-tls-cert", "", "PEM certificate the admin API presents (enables admin mTLS)")
+	adminKeyFile   = flag.String("admin-tls-key", "", "PEM private key matching -admin-tls-cert")
+	adminCAFile    = flag.String("admin-client-ca", "", "PEM bundle of CAs allowed to sign admin client certificates")
+
+	adminToken string        // Bearer token, if -admin-token-file is set
+	adminTLS   *certReloader // nil unless admin mTLS is configured
+
+	resyncing atomic.Bool // A resync started through the admin API is running
+)
+
+// Load the admin API's credentials. An admin API needs a token, client
+// certificates or both; with both, requests must present both.
+func setupAdmin() error {
+	if *adminAddr == "" {
+		return nil
+	}
+	if *adminTokenFile != "" {
+		data, err := os.ReadFile(*adminTokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read admin token: %v", err)
+		}
+		if adminToken = strings.TrimSpace(string(data)); adminToken == "" {
+			return errors.New("admin token file is empty")
+		}
+	}
+	if *adminCertFile != "" || *adminKeyFile != "" || *adminCAFile != "" {
+		if *adminCertFile == "" || *adminKeyFile == "" || *adminCAFile == "" {
+			return errors.New("admin mTLS requires -admin-tls-cert, -admin-tls-key and -admin-client-ca together")
+		}
+		r := &certReloader{certFile: *adminCertFile, keyFile: *adminKeyFile, caFile: *adminCAFile}
+		if _, _, err := r.current(); err != nil {
+			return err
+		}
+		adminTLS = r
+	}
+	if adminToken == "" && adminTLS == nil {
+		return errors.New("the admin API requires -admin-token-file or -admin-tls-cert, -admin-tls-key and -admin-client-ca")
+	}
+	return nil
+}
+
+// Admin API Thread
+func serveAdmin(ctx context.Context) {
+	server := &http.Server{
+		Addr:        *adminAddr,
+		Handler:     adminHandler(ctx),
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	context.AfterFunc(ctx, func() { server.Shutdown(context.Background()) })
+
+	apiLog.Info("Serving admin API", "addr", *adminAddr, "token", adminToken != "", "mtls", adminTLS != nil)
+	var err error
+	if adminTLS != nil {
+		server.TLSConfig = adminTLS.config()
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
+		apiLog.Error("Error serving admin API", "err", err)
+	}
+}
+
+// Routes of the admin API behind its authentication. ctx bounds the work
+// requests start in the background.
+func adminHandler(ctx context.Context) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin/peers", handleAdminPeers)
+	mux.HandleFunc("/admin/mining", handleMining)
+	mux.HandleFunc("/admin/resync", func(w http.ResponseWriter, r *http.Request) { handleResync(ctx, w, r) })
+	mux.HandleFunc("/admin/log-level", handleLogLevel)
+	mux.HandleFunc("/admin/mempool/flush", handleFlushMempool)
+	mux.HandleFunc("/admin/bans", handleBans)
+	return requireAdmin(mux)
+}
+
+// Refuse requests without the admin token, if one is configured. Client
+// certificates are checked by the TLS handshake before a request is read.
+func requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if adminToken != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+				apiLog.Warn("Refused admin request", "remote", r.RemoteAddr, "path", r.URL.Path)
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, http.StatusUnauthorized, "missing or wrong admin token")
+				return
+			}
+		}
+		if r.Method != http.MethodGet {
+			apiLog.Info("Admin request", "remote", r.RemoteAddr, "method", r.Method, "path", r.URL.Path)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GET /admin/peers: known peers. POST /admin/peers {"addr": IP}: add a
+// peer. DELETE /admin/peers?addr=IP: drop a peer.
+func handleAdminPeers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, peerManager.Peers())
+	case http.MethodPost:
+		var request struct {
+			Addr string `json:"addr"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&request); err != nil || net.ParseIP(request.Addr) == nil {
+			writeError(w, http.StatusBadRequest, `expected {"addr": IP}`)
+			return
+		}
+		if !peerManager.Add(request.Addr) {
+			writeError(w, http.StatusConflict, "peer is known, local or over -max-peers")
+			return
+		}
+		p2pLog.Info("Added peer through the admin API", "peer", request.Addr)
+		writeJSON(w, http.StatusOK, peerManager.Peers())
+	case http.MethodDelete:
+		addr := r.URL.Query().Get("addr")
+		if addr == "" {
+			writeError(w, http.StatusBadRequest, "addr is required")
+			return
+		}
+		peerManager.Remove(addr)
+		p2pLog.Info("Removed peer through the admin API", "peer", addr)
+		writeJSON(w, http.StatusOK, peerManager.Peers())
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "use GET, POST or DELETE")
+	}
+}
+
+// POST /admin/resync: catch up with the best peer in the background, as at
+// startup. 409 while a resync is already running.
+func handleResync(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+	if !resyncing.CompareAndSwap(false, true) {
+		writeError(w, http.StatusConflict, "a resync is already running")
+		return
+	}
+	go func() {
+		defer resyncing.Store(false)
+		syncChain(ctx)
+	}()
+	writeJSON(w, http.StatusAccepted, map[string]any{"height": chainStore.Height()})
+}
+
+// GET /admin/log-level: the minimum log level. POST /admin/log-level
+// {"level": L}: change it to debug, info, warn or error.
+func handleLogLevel(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		var request struct {
+			Level string `json:"level"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, `expected {"level": L}`)
+			return
+		}
+		if err := setLogLevel(request.Level); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		nodeLog.Info("Log level changed through the admin API", "level", logLevelVar.Level())
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"level": strings.ToLower(logLevelVar.Level().String())})
+}
+
+// POST /admin/mempool/flush: drop every pending transaction.
+func handleFlushMempool(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+	flushed := mempool.Flush()
+	txprocLog.Warn("Flushed mempool through the admin API", "transactions", flushed)
+	writeJSON(w, http.StatusOK, map[string]int{"flushed": flushed})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdminAPI(t *testing.T) {
+	setupTestMiner(t)
+	savedToken, savedPeers, savedLevel := adminToken, peerManager, logLevelVar.Level()
+	t.Cleanup(func() { adminToken, peerManager = savedToken, savedPeers; logLevelVar.Set(savedLevel) })
+	adminToken = "secret"
+	peerManager = NewPeerManager(nil)
+	handler := adminHandler(context.Background())
+
+	request := func(method, path, token, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, r)
+		return recorder
+	}
+
+	for _, token := range []string{"", "wrong"} {
+		if got := request(http.MethodPost, "/admin/mempool/flush", token, ""); got.Code != http.StatusUnauthorized {
+			t.Errorf("request with token %q: %d", token, got.Code)
+		}
+	}
+
+	if got := request(http.MethodPost, "/admin/peers", "secret", `{"addr": "198.51.100.7"}`); got.Code != http.StatusOK || peerManager.Count() != 1 {
+		t.Errorf("adding a peer: %d %s", got.Code, got.Body)
+	}
+	if got := request(http.MethodDelete, "/admin/peers?addr=198.51.100.7", "secret", ""); got.Code != http.StatusOK || peerManager.Count() != 0 {
+		t.Errorf("removing a peer: %d %s", got.Code, got.Body)
+	}
+
+	if got := request(http.MethodPost, "/admin/log-level", "secret", `{"level": "debug"}`); got.Code != http.StatusOK || logLevelVar.Level() != slog.LevelDebug {
+		t.Errorf("changing the log level: %d %s", got.Code, got.Body)
+	}
+	if got := request(http.MethodPost, "/admin/log-level", "secret", `{"level": "loud"}`); got.Code != http.StatusBadRequest {
+		t.Errorf("invalid log level: %d", got.Code)
+	}
+
+	if err := mempool.Add(signedTestTransaction(t, "pending"), priorityNormal); err != nil {
+		t.Fatal(err)
+	}
+	got := request(http.MethodPost, "/admin/mempool/flush", "secret", "")
+	var flushed map[string]int
+	json.Unmarshal(got.Body.Bytes(), &flushed)
+	if got.Code != http.StatusOK || flushed["flushed"] != 1 || mempool.Len() != 0 {
+		t.Errorf("flushing the mempool: %d %s", got.Code, got.Body)
+	}
+
+	if got := request(http.MethodPost, "/admin/mining", "secret", `{"enabled": false}`); got.Code != http.StatusOK || miningEnabled.Load() {
+		t.Errorf("stopping mining: %d %s", got.Code, got.Body)
+	}
+}
+
+func TestPublicAPICannotSwitchMining(t *testing.T) {
+	setupTestMiner(t)
+	recorder := httptest.NewRecorder()
+	apiHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/mining", strings.NewReader(`{"enabled": false}`)))
+	if recorder.Code != http.StatusMethodNotAllowed || !miningEnabled.Load() {
+		t.Errorf("public mining toggle: %d %s", recorder.Code, recorder.Body)
+	}
+}
+
+func TestAdminRequiresCredentials(t *testing.T) {
+	saved := *adminAddr
+	t.Cleanup(func() { *adminAddr = saved })
+	*adminAddr = "localhost:0"
+	if err := setupAdmin(); err == nil {
+		t.Error("admin API configured without a token or client certificates")
+	}
+}
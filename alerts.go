@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"expvar"
+	"flag"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Alert names.
+const (
+	alertNoBlock            = "no_block"
+	alertNoPeers            = "no_peers"
+	alertIPFSDown           = "ipfs_down"
+	alertValidationFailures = "validation_failures"
+	alertHashrateCollapse   = "hashrate_collapse"
+)
+
+var (
+	alertInterval     = flag.Duration("alert-interval", 30*time.Second, "how often alert conditions are checked")
+	alertNoBlockAfter = flag.Duration("alert-no-block", 10*time.Minute, "alert when no block is seen for this long")
+	alertFailureLimit = flag.Int("alert-validation-failures", 5, "alert when one peer sends this many invalid blocks in an alert interval")
+	metricsAddr       = flag.String("metrics-addr", "", "address serving expvar metrics at /debug/vars (default: disabled)")
+
+	hashCount    atomic.Uint64 // Hashes computed by the miner
+	miningActive atomic.Bool   // Whether the miner is searching for a nonce
+
+	alertMu            sync.Mutex
+	activeAlerts       = make(map[string]bool)
+	validationFailures = make(map[string]int) // Invalid blocks per peer in the current interval
+
+	alertCounts     = expvar.NewMap("alerts_fired")
+	alertActive     = expvar.NewMap("alerts_active")
+	hashrateMetric  = expvar.NewFloat("hashrate")
+	peerCountMetric = expvar.NewInt("peer_count")
+)
+
+// Record an invalid block received from a peer.
+func recordValidationFailure(peer string) {
+	alertMu.Lock()
+	defer alertMu.Unlock()
+	validationFailures[peer]++
+}
+
+// Raise or clear an alert. Raising logs it, counts it in the metrics and
+// sends a webhook; an alert that is already active is not raised again.
+func setAlert(name string, firing bool, message string) {
+	alertMu.Lock()
+	wasFiring := activeAlerts[name]
+	activeAlerts[name] = firing
+	alertMu.Unlock()
+
+	if firing {
+		alertActive.Set(name, expvarInt(1))
+	} else {
+		alertActive.Set(name, expvarInt(0))
+	}
+
+	switch {
+	case firing && !wasFiring:
+		alertCounts.Add(name, 1)
+		alertLog.Warn("Alert firing", "alert", name, "message", message)
+		fireWebhook("alert", map[string]string{"alert": name, "message": message})
+	case !firing && wasFiring:
+		alertLog.Info("Alert resolved", "alert", name)
+		fireWebhook("alert.resolved", map[string]string{"alert": name})
+	}
+}
+
+func expvarInt(v int64) *expvar.Int {
+	i := new(expvar.Int)
+	i.Set(v)
+	return i
+}
+
+// Alert Monitoring Thread
+func monitorAlerts(ctx context.Context) {
+	if *metricsAddr != "" {
+		server := &http.Server{Addr: *metricsAddr}
+		context.AfterFunc(ctx, func() { server.Close() })
+		go func() {
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				alertLog.Error("Error serving metrics", "err", err)
+			}
+		}()
+	}
+
+	started := time.Now()
+	lastHashes := hashCount.Load()
+	wasMining := miningActive.Load()
+	avgHashrate := 0.0
+
+	ticker := time.NewTicker(*alertInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		// No block seen for too long
+		last := chainHeadTime()
+		if last.IsZero() {
+			last = started
+		}
+		stale := time.Since(last)
+		setAlert(alertNoBlock, stale > *alertNoBlockAfter, fmt.Sprintf("no block for %s", stale.Round(time.Second)))
+
+		// Lost all peers
+		peers := peerManager.Count()
+		peerCountMetric.Set(int64(peers))
+		setAlert(alertNoPeers, peers == 0, "no connected miners")
+
+		// IPFS daemon unreachable
+		setAlert(alertIPFSDown, !ipfsShell.IsUp(), "IPFS daemon is not responding")
+
+		// Peers repeatedly sending invalid blocks
+		alertMu.Lock()
+		var offenders []string
+		for peer, count := range validationFailures {
+			if count >= *alertFailureLimit {
+				offenders = append(offenders, peer)
+			}
+		}
+		validationFailures = make(map[string]int)
+		alertMu.Unlock()
+		setAlert(alertValidationFailures, len(offenders) > 0, fmt.Sprintf("repeated invalid blocks from %v", offenders))
+
+		// Hashrate collapse while mining, measured against a moving average
+		hashes := hashCount.Load()
+		rate := float64(hashes-lastHashes) / alertInterval.Seconds()
+		lastHashes = hashes
+		isMining := miningActive.Load()
+		if wasMining && isMining {
+			hashrateMetric.Set(rate)
+			setAlert(alertHashrateCollapse, avgHashrate > 0 && rate < avgHashrate/10,
+				fmt.Sprintf("hashrate fell to %.0f H/s from an average of %.0f H/s", rate, avgHashrate))
+			if avgHashrate == 0 {
+				avgHashrate = rate
+			} else {
+				avgHashrate = 0.8*avgHashrate + 0.2*rate
+			}
+		}
+		wasMining = isMining
+	}
+}
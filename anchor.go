@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	anchorDriverName = flag.String("anchor-driver", "", "external chain to anchor the head hash to: http or bitcoin (default: disabled)")
+	anchorURL        = flag.String("anchor-url", "", "endpoint of the anchor driver (HTTP service or bitcoind JSON-RPC URL with credentials)")
+	anchorInterval   = flag.Duration("anchor-interval", time.Hour, "how often to anchor the current chain head")
+
+	// A hung anchor endpoint must not hold up shutdown
+	anchorClient = &http.Client{Timeout: 30 * time.Second}
+
+	anchor anchorDriver // nil unless -anchor-driver
+
+	anchorDrivers = map[string]func(url string) anchorDriver{
+		"http":    func(url string) anchorDriver { return httpAnchor{url: url} },
+		"bitcoin": func(url string) anchorDriver { return bitcoinAnchor{url: url} },
+	}
+)
+
+// AnchorRecord is the commitment published to the external chain.
+type AnchorRecord struct {
+	BlockNumber int    `json:"blockNumber"`
+	BlockHash   string `json:"blockHash"`
+	Time        int64  `json:"time"`
+}
+
+// Responses from anchor endpoints are read up to this size.
+const maxAnchorResponse = 64 << 10
+
+// anchorDriver commits a head hash to an external chain and returns a
+// driver-specific receipt such as a transaction ID.
+type anchorDriver interface {
+	Anchor(ctx context.Context, record AnchorRecord) (string, error)
+}
+
+// Set up the driver named by -anchor-driver, if any.
+func setupAnchor() error {
+	if *anchorDriverName == "" {
+		return nil
+	}
+	newDriver, ok := anchorDrivers[*anchorDriverName]
+	if !ok {
+		return fmt.Errorf("unknown anchor driver %q", *anchorDriverName)
+	}
+	if *anchorURL == "" {
+		return fmt.Errorf("anchor driver %q needs -anchor-url", *anchorDriverName)
+	}
+	if *anchorInterval <= 0 {
+		return errors.New("-anchor-interval must be positive")
+	}
+	anchor = newDriver(*anchorURL)
+	return nil
+}
+
+// Anchoring Thread
+func anchorChainHead(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	lastAnchored := ""
+	ticker := time.NewTicker(*anchorInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		hash, number := chainHead()
+		if hash == "" || hash == lastAnchored {
+			continue
+		}
+
+		record := AnchorRecord{BlockNumber: number, BlockHash: hash, Time: time.Now().Unix()}
+		receipt, err := anchor.Anchor(ctx, record)
+		if err != nil {
+			chainLog.Error("Error anchoring chain head", "err", err)
+			continue
+		}
+		lastAnchored = hash
+		chainLog.Info("Anchored chain head", "height", number, "hash", hash, "receipt", receipt)
+	}
+}
+
+// httpAnchor posts the record as JSON to an anchoring service, for example
+// a relayer that writes it to an Ethereum contract. The response body is
+// used as the receipt.
+type httpAnchor struct {
+	url string
+}
+
+func (a httpAnchor) Anchor(ctx context.Context, record AnchorRecord) (string, error) {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := anchorClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach anchor service: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var receipt bytes.Buffer
+	receipt.ReadFrom(io.LimitReader(resp.Body, maxAnchorResponse))
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("anchor service returned %s: %s", resp.Status, receipt.String())
+	}
+	return receipt.String(), nil
+}
+
+// bitcoinAnchor commits the block hash in an OP_RETURN output through a
+// bitcoind wallet over JSON-RPC and returns the transaction ID.
+type bitcoinAnchor struct {
+	url string
+}
+
+func (a bitcoinAnchor) Anchor(ctx context.Context, record AnchorRecord) (string, error) {
+	hash, err := hex.DecodeString(record.BlockHash)
+	if err != nil {
+		return "", fmt.Errorf("invalid block hash: %v", err)
+	}
+	data := hex.EncodeToString(append([]byte("BCA1"), hash...))
+
+	var raw string
+	if err := a.call(ctx, "createrawtransaction", []any{[]any{}, []any{map[string]string{"data": data}}}, &raw); err != nil {
+		return "", err
+	}
+	var funded struct{ Hex string }
+	if err := a.call(ctx, "fundrawtransaction", []any{raw}, &funded); err != nil {
+		return "", err
+	}
+	var signed struct {
+		Hex      string
+		Complete bool
+	}
+	if err := a.call(ctx, "signrawtransactionwithwallet", []any{funded.Hex}, &signed); err != nil {
+		return "", err
+	}
+	if !signed.Complete {
+		return "", errors.New("wallet could not fully sign the anchor transaction")
+	}
+	var txid string
+	if err := a.call(ctx, "sendrawtransaction", []any{signed.Hex}, &txid); err != nil {
+		return "", err
+	}
+	return txid, nil
+}
+
+// Make a bitcoind JSON-RPC call.
+func (a bitcoinAnchor) call(ctx context.Context, method string, params []any, result any) error {
+	body, err := json.Marshal(map[string]any{"jsonrpc": "1.0", "id": "anchor", "method": method, "params": params})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	resp, err := anchorClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach bitcoind: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var reply struct {
+		Result json.RawMessage
+		Error  *struct{ Message string }
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxAnchorResponse)).Decode(&reply); err != nil {
+		return fmt.Errorf("failed to decode %s response: %v", method, err)
+	}
+	if reply.Error != nil {
+		return fmt.Errorf("%s failed: %s", method, reply.Error.Message)
+	}
+	return json.Unmarshal(reply.Result, result)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSetupAnchorRejectsUnknownDriver(t *testing.T) {
+	defer func(name, url string, d anchorDriver) { *anchorDriverName, *anchorURL, anchor = name, url, d }(*anchorDriverName, *anchorURL, anchor)
+	*anchorDriverName, *anchorURL = "ethereum", "http://127.0.0.1:8545"
+	if err := setupAnchor(); err == nil {
+		t.Error("unknown anchor driver accepted")
+	}
+	*anchorDriverName = "http"
+	if err := setupAnchor(); err != nil || anchor == nil {
+		t.Errorf("http anchor driver: %v", err)
+	}
+}
+
+func TestHTTPAnchorLimitsReceipt(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("r", 2*maxAnchorResponse)))
+	}))
+	defer server.Close()
+
+	receipt, err := httpAnchor{url: server.URL}.Anchor(context.Background(), AnchorRecord{BlockHash: "00ab"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(receipt) != maxAnchorResponse {
+		t.Errorf("receipt is %d bytes, want at most %d", len(receipt), maxAnchorResponse)
+	}
+}
@@ -0,0 +1,456 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// Most blocks GET /blocks returns at once.
+const maxRecentBlocks = 100
+
+var apiAddr = flag.String("api-addr", "localhost:8082", "address of the HTTP API (empty to disable)")
+
+// HTTP API Thread
+func serveAPI(ctx context.Context) {
+	server := &http.Server{
+		Addr:        *apiAddr,
+		Handler:     apiHandler(),
+		BaseContext: func(net.Listener) context.Context { return ctx }, // Ends live updates on shutdown
+	}
+	context.AfterFunc(ctx, func() { server.Shutdown(context.Background()) })
+
+	apiLog.Info("Serving HTTP API", "addr", *apiAddr, "explorer", "/explorer/")
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		apiLog.Error("Error serving HTTP API", "err", err)
+	}
+}
+
+func apiHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/height", handleHeight)
+	mux.HandleFunc("/block", handleBlock)
+	mux.HandleFunc("/blocks", handleRecentBlocks)
+	mux.HandleFunc("/mempool", handleMempool)
+	mux.HandleFunc("/peers", handlePeers)
+	mux.HandleFunc("/transactions", handleSubmitTransaction)
+	mux.HandleFunc("/transaction", handleTransaction)
+	mux.HandleFunc("/proof", handleProof)
+	mux.HandleFunc("/result", handleResult)
+	mux.HandleFunc("/mining", handleMiningStatus)
+	mux.HandleFunc("/balance", handleBalance)
+	mux.HandleFunc("/jobs", handleJob)
+	mux.HandleFunc("/stale", handleStale)
+	mux.HandleFunc("/miners", handleMiners)
+	mux.HandleFunc("/checkpoint", handleCheckpoint)
+	mux.HandleFunc("/ipfs", handleIPFS)
+	mux.HandleFunc("/health", handleHealth)
+	mux.HandleFunc("/status", handleStatus)
+	mux.Handle("/mining/live", websocket.Handler(handleMiningLive))
+	mux.Handle("/events", websocket.Handler(handleEvents))
+	mux.Handle("/explorer/", explorerHandler())
+	return mux
+}
+
+// GET /height: chain height and tip hash.
+func handleHeight(w http.ResponseWriter, r *http.Request) {
+	reply := map[string]any{"height": chainStore.Height()}
+	if tip, ok := chainStore.Tip(); ok {
+		reply["tip"] = tip.Hash
+	}
+	writeJSON(w, http.StatusOK, reply)
+}
+
+// GET /block?number=N or /block?hash=H: a block on the main chain.
+func handleBlock(w http.ResponseWriter, r *http.Request) {
+	var block Block
+	var err error
+	query := r.URL.Query()
+	switch {
+	case query.Has("hash"):
+		block, err = chainStore.GetBlockByHash(query.Get("hash"))
+	case query.Has("number"):
+		number, convErr := strconv.Atoi(query.Get("number"))
+		if convErr != nil {
+			writeError(w, http.StatusBadRequest, "invalid block number")
+			return
+		}
+		block, err = chainStore.GetBlockByNumber(number)
+	default:
+		writeError(w, http.StatusBadRequest, "number or hash is required")
+		return
+	}
+
+	if errors.Is(err, errBlockNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, block)
+}
+
+// GET /blocks?count=N: the last N main chain blocks, newest first.
+// GET /blocks?from=A&to=B: main chain blocks A to B with their
+// transactions, oldest first; at most maxRecentBlocks of them, so fetch
+// longer ranges in pages.
+func handleRecentBlocks(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Has("from") {
+		handleBlockRange(w, r)
+		return
+	}
+	count := 10
+	if value := r.URL.Query().Get("count"); value != "" {
+		var err error
+		if count, err = strconv.Atoi(value); err != nil || count < 1 || count > maxRecentBlocks {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("count must be between 1 and %d", maxRecentBlocks))
+			return
+		}
+	}
+
+	blocks := []Block{}
+	for n := chainStore.Height() - 1; n >= chainStore.Base() && len(blocks) < count; n-- {
+		block, err := chainStore.GetBlockByNumber(n)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		blocks = append(blocks, block)
+	}
+	writeJSON(w, http.StatusOK, blocks)
+}
+
+func handleBlockRange(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	from, err := strconv.Atoi(query.Get("from"))
+	if err != nil || from < 0 {
+		writeError(w, http.StatusBadRequest, "invalid from height")
+		return
+	}
+	to := from + maxRecentBlocks - 1
+	if query.Has("to") {
+		if to, err = strconv.Atoi(query.Get("to")); err != nil || to < from {
+			writeError(w, http.StatusBadRequest, "to must be a height no lower than from")
+			return
+		}
+	}
+	if from < chainStore.Base() {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("blocks below %d were pruned by fast sync", chainStore.Base()))
+		return
+	}
+
+	blocks, err := blockRange(from, to, maxRecentBlocks)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if blocks == nil {
+		blocks = []Block{}
+	}
+	writeJSON(w, http.StatusOK, blocks)
+}
+
+// GET /stale?count=N: stale block statistics and the last N stale blocks.
+func handleStale(w http.ResponseWriter, r *http.Request) {
+	count := 10
+	if value := r.URL.Query().Get("count"); value != "" {
+		var err error
+		if count, err = strconv.Atoi(value); err != nil || count < 0 || count > staleRecent {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("count must be between 0 and %d", staleRecent))
+			return
+		}
+	}
+	chainMu.Lock()
+	stats := staleBlocks.Stats(chainStore.Height())
+	stats.SideBlocks = len(sideBlocks)
+	chainMu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{"stats": stats, "blocks": staleBlocks.Recent(count)})
+}
+
+// GET /checkpoint: the last checkpoint this node published and its CID.
+func handleCheckpoint(w http.ResponseWriter, r *http.Request) {
+	latestMu.Lock()
+	cid, checkpoint := latestCID, latestCheckpoint
+	latestMu.Unlock()
+	if cid == "" {
+		writeError(w, http.StatusNotFound, "no checkpoint published yet")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"cid": cid, "checkpoint": checkpoint})
+}
+
+// GET /mempool: pending transactions in mining order.
+func handleMempool(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, mempool.Pending())
+}
+
+// GET /peers: known miners.
+func handlePeers(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, peerManager.Peers())
+}
+
+// POST /transactions: add a transaction to the mempool.
+func handleSubmitTransaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+	var tx Transaction
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, int64(*maxMessageSize))).Decode(&tx); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("malformed transaction: %v", err))
+		return
+	}
+	if err := checkTransaction(tx); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := mempool.Add(tx, priorityNormal); err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	relayTransactions([]Transaction{tx})
+	writeJSON(w, http.StatusAccepted, map[string]string{"id": tx.ID})
+}
+
+// GET /transaction?id=T: a mined transaction with the block holding it,
+// or a pending one from the mempool. Light nodes answer with the block a
+// peer proved the transaction is in.
+func handleTransaction(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+	if *lightMode {
+		handleLightTransaction(w, id)
+		return
+	}
+	tx, location, err := chainStore.GetTransaction(id)
+	if errors.Is(err, errTransactionNotFound) {
+		if pending, ok := mempool.Get(id); ok {
+			writeJSON(w, http.StatusOK, map[string]any{"transaction": pending, "pending": true})
+			return
+		}
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"transaction": tx,
+		"blockHash":   location.BlockHash,
+		"blockNumber": location.BlockNumber,
+		"index":       location.Index,
+	})
+}
+
+// Answer /transaction on a light node from a Merkle proof fetched from the
+// peers and checked against our headers.
+func handleLightTransaction(w http.ResponseWriter, id string) {
+	proof, err := fetchTransactionProof(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"txId":        proof.TxID,
+		"blockHash":   proof.BlockHash,
+		"blockNumber": proof.Header.BlockNumber,
+		"index":       proof.Index,
+		"proof":       proof,
+	})
+}
+
+// GET /proof?tx=T: the Merkle proof that a mined transaction is in its
+// block, with the block header to check it against; see merkle.VerifyProof.
+// Light nodes serve a proof from their peers, checked against their own
+// headers.
+func handleProof(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("tx")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "tx is required")
+		return
+	}
+	var proof merkleProof
+	var err error
+	if *lightMode {
+		proof, err = fetchTransactionProof(id)
+	} else {
+		proof, err = chainStore.TransactionProof(id)
+	}
+	if errors.Is(err, errTransactionNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, proof)
+}
+
+// GET /jobs?id=J or /jobs?tx=T: the receipt of a job run by this node.
+// POST /jobs [{"scriptCid": C, "dataCid": D, ...}, ...]: see handleSubmitJobs.
+func handleJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		handleSubmitJobs(w, r)
+		return
+	}
+	var receipt Receipt
+	var ok bool
+	query := r.URL.Query()
+	switch {
+	case query.Has("id"):
+		receipt, ok = receipts.Get(query.Get("id"))
+	case query.Has("tx"):
+		receipt, ok = receipts.ByTransaction(query.Get("tx"))
+	default:
+		writeError(w, http.StatusBadRequest, "id or tx is required")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown job")
+		return
+	}
+	writeJSON(w, http.StatusOK, receipt)
+}
+
+// POST /jobs: queue a batch of jobs and return a receipt acknowledging each,
+// in order. Jobs refused on their own get failed receipts; poll the others
+// with GET /jobs?id=J.
+func handleSubmitJobs(w http.ResponseWriter, r *http.Request) {
+	if *lightMode {
+		writeError(w, http.StatusServiceUnavailable, "light nodes do not run jobs")
+		return
+	}
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(*maxMessageSize)))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+	specs, err := parseJobBatch(payload)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// The jobs outlive the request; shutdown fails those still queued
+	writeJSON(w, http.StatusOK, submitJobBatch(context.WithoutCancel(r.Context()), addr, specs))
+}
+
+// GET /mining: miner status. Switching mining is left to the admin API.
+func handleMiningStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET; switch mining through /admin/mining")
+		return
+	}
+	writeJSON(w, http.StatusOK, miningStatus())
+}
+
+// GET /admin/mining: miner status.
+// POST /admin/mining {"enabled": bool}: switch mining on or off.
+func handleMining(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		var request struct {
+			Enabled *bool `json:"enabled"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&request); err != nil || request.Enabled == nil {
+			writeError(w, http.StatusBadRequest, `expected {"enabled": true|false}`)
+			return
+		}
+		setMiningEnabled(*request.Enabled)
+	}
+	writeJSON(w, http.StatusOK, miningStatus())
+}
+
+// GET /ipfs: whether the IPFS daemon is up, whether downloads are degraded
+// to the fallback gateways, and the gateways' health.
+func handleIPFS(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"daemonUp": ipfsShell.IsUp(),
+		"degraded": ipfsDegraded.Load(),
+		"gateways": gatewayHealth(),
+	})
+}
+
+func miningStatus() map[string]any {
+	return map[string]any{
+		"enabled":     miningEnabled.Load(),
+		"active":      miningActive.Load(),
+		"hashes":      hashCount.Load(),
+		"hashrate":    hashrateMetric.Value(),
+		"minedBlocks": nodeState.MinedBlocks(),
+		"target":      fmt.Sprintf("%x", currentTarget()),
+	}
+}
+
+// GET /mining/live: a websocket sending the miner status and chain height
+// every second until the client goes away.
+func handleMiningLive(ws *websocket.Conn) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	// The client sends nothing; a failed read means it went away
+	gone := make(chan struct{})
+	go func() {
+		var discard []byte
+		for websocket.Message.Receive(ws, &discard) == nil {
+		}
+		close(gone)
+	}()
+
+	for {
+		status := miningStatus()
+		status["height"] = chainStore.Height()
+		if tip, ok := chainStore.Tip(); ok {
+			status["tip"] = tip.Hash
+		}
+		if err := websocket.JSON.Send(ws, status); err != nil {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-gone:
+			return
+		case <-ws.Request().Context().Done():
+			return
+		}
+	}
+}
+
+// GET /balance?address=A: an address's balance on the main chain.
+func handleBalance(w http.ResponseWriter, r *http.Request) {
+	address := r.URL.Query().Get("address")
+	if address == "" {
+		writeError(w, http.StatusBadRequest, "address is required")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"address": address, "balance": ledger.Balance(address)})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
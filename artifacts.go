@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// Environment variable naming the directory a job script may leave
+	// output files in
+	outputDirVar = "JOB_OUTPUT_DIR"
+
+	// Most output files a transaction may list
+	maxOutputFiles = 32
+)
+
+var (
+	inlineResultSize = flag.Int("inline-result-size", 4096, "largest result, in bytes, carried in its transaction; larger ones are only stored in IPFS")
+	maxOutputSize    = flag.Int64("max-output-size", 64<<20, "largest result or output file, in bytes, a job script may produce")
+
+	outputNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]{0,127}$`)
+	sha256HexPattern  = regexp.MustCompile(`^[0-9a-f]{64}$`)
+)
+
+// OutputFile is a file a job script left in its output directory, stored in
+// IPFS next to the result.
+type OutputFile struct {
+	Name string
+	CID  string // Of the file as stored: sealed to the transaction's recipient, if it has one
+	Hash string // Hex SHA-256 of the plaintext file, checked by re-execution
+}
+
+// Check that the file is named and stored as a transaction may list it.
+func (f OutputFile) Validate() error {
+	if !outputNamePattern.MatchString(f.Name) {
+		return fmt.Errorf("invalid output file name %q", f.Name)
+	}
+	if err := validateCID(f.CID); err != nil {
+		return fmt.Errorf("output file %s: %v", f.Name, err)
+	}
+	if !sha256HexPattern.MatchString(f.Hash) {
+		return fmt.Errorf("output file %s has an invalid hash", f.Name)
+	}
+	return nil
+}
+
+// Check the result CID and output files of a transaction.
+func validateArtifacts(tx Transaction) error {
+	if tx.ResultCID != "" {
+		if err := validateCID(tx.ResultCID); err != nil {
+			return fmt.Errorf("invalid result CID: %v", err)
+		}
+	}
+	if len(tx.Outputs) > maxOutputFiles {
+		return fmt.Errorf("%d output files exceed the limit of %d", len(tx.Outputs), maxOutputFiles)
+	}
+	for i, file := range tx.Outputs {
+		if err := file.Validate(); err != nil {
+			return err
+		}
+		if i > 0 && tx.Outputs[i-1].Name >= file.Name {
+			return errors.New("output files are not sorted by unique name")
+		}
+	}
+	if len(tx.Outputs) > 0 && tx.Proof != nil {
+		return errors.New("execution proofs do not cover output files")
+	}
+	return nil
+}
+
+// What a transaction's ID is the hash of: its data, or the CID of a result
+// too large to be carried.
+func transactionIDSource(tx Transaction) string {
+	if tx.Data == "" && tx.ResultCID != "" {
+		return tx.ResultCID
+	}
+	return tx.Data
+}
+
+// Environment of a script given an output directory; none without one.
+func outputEnv(dir string) []string {
+	if dir == "" {
+		return nil
+	}
+	return []string{outputDirVar + "=" + dir}
+}
+
+// Hash the files a script left in dir, sorted by name as ReadDir returns
+// them. Only regular files at the top of dir count, up to maxOutputFiles of
+// at most -max-output-size bytes each.
+func collectOutputs(dir string) ([]OutputFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read output directory: %v", err)
+	}
+	var outputs []OutputFile
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		if !outputNamePattern.MatchString(entry.Name()) {
+			return nil, fmt.Errorf("invalid output file name %q", entry.Name())
+		}
+		if len(outputs) == maxOutputFiles {
+			return nil, fmt.Errorf("script left more than %d output files", maxOutputFiles)
+		}
+		data, err := readOutputFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(data)
+		outputs = append(outputs, OutputFile{Name: entry.Name(), Hash: hex.EncodeToString(sum[:])})
+	}
+	return outputs, nil
+}
+
+func readOutputFile(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read output file: %v", err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, *maxOutputSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read output file: %v", err)
+	}
+	if int64(len(data)) > *maxOutputSize {
+		return nil, &jobError{codeTooLarge, fmt.Errorf("output file %s is larger than the limit of %d bytes", filepath.Base(path), *maxOutputSize)}
+	}
+	return data, nil
+}
+
+// Store a job's result, as the transaction carries it, and its output files
+// in IPFS, sealing the files to the transaction's recipient if it has one.
+// Results over -inline-result-size leave the transaction's data, which is
+// then identified by the result's CID.
+func storeResult(tx *Transaction, result, outputDir string) error {
+	cid, err := ipfsShell.Add(strings.NewReader(result))
+	if err != nil {
+		return fmt.Errorf("failed to store result in IPFS: %v", err)
+	}
+	tx.ResultCID = cid
+	if len(result) > *inlineResultSize {
+		tx.Data = ""
+	}
+
+	for i := range tx.Outputs {
+		file := &tx.Outputs[i]
+		data, err := readOutputFile(filepath.Join(outputDir, file.Name))
+		if err != nil {
+			return err
+		}
+		if tx.Recipient != "" {
+			recipient, _ := parsePayloadPublicKey(tx.Recipient)
+			if data, err = sealPayload(recipient, data); err != nil {
+				return fmt.Errorf("failed to encrypt output file %s: %v", file.Name, err)
+			}
+		}
+		if file.CID, err = ipfsShell.Add(bytes.NewReader(data)); err != nil {
+			return fmt.Errorf("failed to store output file %s in IPFS: %v", file.Name, err)
+		}
+	}
+	tx.ID = generateTransactionID(transactionIDSource(*tx))
+	return nil
+}
+
+// Check the output files a re-execution left against the ones the
+// transaction lists.
+func compareOutputs(claimed, got []OutputFile) error {
+	if len(claimed) != len(got) {
+		return fmt.Errorf("re-execution left %d output files, transaction lists %d", len(got), len(claimed))
+	}
+	for i := range claimed {
+		if claimed[i].Name != got[i].Name || claimed[i].Hash != got[i].Hash {
+			return fmt.Errorf("re-execution left output file %s with hash %s, transaction lists %s with %s",
+				got[i].Name, got[i].Hash, claimed[i].Name, claimed[i].Hash)
+		}
+	}
+	return nil
+}
+
+// Hash of the names and hashes of a transaction's output files, identifying
+// them in re-execution statements.
+func outputsHash(outputs []OutputFile) string {
+	var listing strings.Builder
+	for _, file := range outputs {
+		fmt.Fprintf(&listing, "%s %s\n", file.Name, file.Hash)
+	}
+	return resultHash(listing.String())
+}
+
+// GET /result?tx=T: the result of a transaction, from the transaction or
+// from IPFS. GET /result?tx=T&file=NAME: one of its output files. Plaintext
+// content is checked against the transaction's hashes before it is served;
+// sealed content is served as stored, for its recipient to open.
+func handleResult(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	id := query.Get("tx")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "tx is required")
+		return
+	}
+	if *lightMode {
+		writeError(w, http.StatusServiceUnavailable, "light nodes do not store transactions")
+		return
+	}
+	tx, _, err := chainStore.GetTransaction(id)
+	if errors.Is(err, errTransactionNotFound) {
+		var ok bool
+		if tx, ok = mempool.Get(id); ok {
+			err = nil
+		}
+	}
+	if errors.Is(err, errTransactionNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	cid, hash, content := tx.ResultCID, tx.ResultHash, tx.Data
+	if query.Has("file") {
+		name := query.Get("file")
+		i := sort.Search(len(tx.Outputs), func(i int) bool { return tx.Outputs[i].Name >= name })
+		if i == len(tx.Outputs) || tx.Outputs[i].Name != name {
+			writeError(w, http.StatusNotFound, "unknown output file")
+			return
+		}
+		cid, hash, content = tx.Outputs[i].CID, tx.Outputs[i].Hash, ""
+	}
+	if content == "" && cid != "" {
+		data, err := fetchArtifact(cid)
+		if err != nil {
+			writeError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		content = string(data)
+	}
+	if tx.Recipient == "" && hash != "" && resultHash(content) != hash {
+		writeError(w, http.StatusBadGateway, fmt.Sprintf("content of %s does not match its hash", cid))
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("X-Content-Hash", hash)
+	io.WriteString(w, content)
+}
+
+// Fetch a result or output file from IPFS, up to -max-output-size bytes.
+func fetchArtifact(cid string) ([]byte, error) {
+	reader, err := ipfsShell.Cat(cid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s from IPFS: %v", cid, err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(io.LimitReader(reader, *maxOutputSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s from IPFS: %v", cid, err)
+	}
+	if int64(len(data)) > *maxOutputSize {
+		return nil, fmt.Errorf("%s is larger than the limit of %d bytes", cid, *maxOutputSize)
+	}
+	return data, nil
+}
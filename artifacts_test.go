@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJobResultStoredInIPFS(t *testing.T) {
+	setupTestMiner(t)
+	setupReexecution(t)
+	defer func(size int) { *inlineResultSize = size }(*inlineResultSize)
+	*inlineResultSize = 8
+
+	scriptCID, _ := ipfsShell.Add(strings.NewReader(`tr a-z A-Z < "$1"; printf side > "$JOB_OUTPUT_DIR/side.txt"`))
+	dataCID, _ := ipfsShell.Add(strings.NewReader("hello world"))
+	job := jobRequest{ScriptCID: scriptCID, DataCID: dataCID}
+	job.ID = receipts.Open(job)
+	tx, err := runJob(context.Background(), job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Data != "" || tx.ResultCID == "" || tx.ID != generateTransactionID(tx.ResultCID) {
+		t.Errorf("result over the inline size carried as %q, CID %q, ID %s", tx.Data, tx.ResultCID, tx.ID)
+	}
+	if len(tx.Outputs) != 1 || tx.Outputs[0].Name != "side.txt" || tx.Outputs[0].Hash != resultHash("side") {
+		t.Errorf("output files %+v", tx.Outputs)
+	}
+	if err := checkTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(apiHandler())
+	defer server.Close()
+	for query, want := range map[string]string{
+		"tx=" + tx.ID:                    "HELLO WORLD",
+		"tx=" + tx.ID + "&file=side.txt": "side",
+	} {
+		response, err := http.Get(server.URL + "/result?" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(response.Body)
+		response.Body.Close()
+		if response.StatusCode != http.StatusOK || string(body) != want {
+			t.Errorf("GET /result?%s: status %d, body %q", query, response.StatusCode, body)
+		}
+	}
+	response, err := http.Get(server.URL + "/result?tx=" + tx.ID + "&file=other.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown output file: status %d", response.StatusCode)
+	}
+
+	// Validators re-run the script and check the output files too
+	block := mineTestBlock(t, tx)
+	data, _ := json.Marshal(block)
+	if !validateBlock(string(data), genesisBlock.Hash, currentTarget()) {
+		t.Error("block with the job's transaction is invalid")
+	}
+	tx.Outputs[0].Hash = resultHash("forged")
+	if err := signTransaction(&tx); err != nil {
+		t.Fatal(err)
+	}
+	block = mineTestBlock(t, tx)
+	data, _ = json.Marshal(block)
+	if validateBlock(string(data), genesisBlock.Hash, currentTarget()) {
+		t.Error("block claiming a forged output file is valid")
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	bansPath   string     // File the bans are kept in, set by loadBans; empty to keep them in memory only
+	bansSaveMu sync.Mutex // Orders writes of bansPath
+)
+
+// peerBan is a ban as GET /admin/bans lists it and <datadir>/bans.json
+// keeps it.
+type peerBan struct {
+	Peer  string    `json:"peer"` // IP, certificate name or node ID
+	Until time.Time `json:"until"`
+}
+
+// Load the bans saved by the last run from <datadir>/bans.json, dropping
+// those that have expired, and keep saving them there.
+func loadBans() error {
+	bansPath = filepath.Join(*dataDir, "bans.json")
+	data, err := os.ReadFile(bansPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read bans: %v", err)
+	}
+	var bans []peerBan
+	if err := json.Unmarshal(data, &bans); err != nil {
+		return fmt.Errorf("failed to decode bans: %v", err)
+	}
+	now := time.Now()
+	peerLimitsMu.Lock()
+	for _, ban := range bans {
+		if ban.Until.After(now) {
+			peerBans[ban.Peer] = ban.Until
+		}
+	}
+	peerLimitsMu.Unlock()
+	if len(bans) > 0 {
+		p2pLog.Info("Restored peer bans", "count", len(bans))
+	}
+	return nil
+}
+
+// Write the current bans to bansPath, if set.
+func saveBans() {
+	if bansPath == "" {
+		return
+	}
+	bansSaveMu.Lock()
+	defer bansSaveMu.Unlock()
+	data, _ := json.MarshalIndent(currentBans(), "", "  ")
+	if err := writeFileAtomic(bansPath, data, 0o600); err != nil {
+		p2pLog.Error("Error saving peer bans", "err", err)
+	}
+}
+
+// Bans in force, soonest to expire first.
+func currentBans() []peerBan {
+	now := time.Now()
+	peerLimitsMu.Lock()
+	bans := make([]peerBan, 0, len(peerBans))
+	for peer, until := range peerBans {
+		if until.After(now) {
+			bans = append(bans, peerBan{Peer: peer, Until: until})
+		}
+	}
+	peerLimitsMu.Unlock()
+	sort.Slice(bans, func(i, j int) bool {
+		if !bans[i].Until.Equal(bans[j].Until) {
+			return bans[i].Until.Before(bans[j].Until)
+		}
+		return bans[i].Peer < bans[j].Peer
+	})
+	return bans
+}
+
+// Ban a peer for duration, dropping it from the peer list.
+func banPeer(peer string, duration time.Duration) {
+	peerLimitsMu.Lock()
+	peerBans[peer] = time.Now().Add(duration)
+	delete(peerScores, peer)
+	peerLimitsMu.Unlock()
+	if peerManager != nil {
+		peerManager.Remove(peer)
+	}
+	saveBans()
+}
+
+// Lift a peer's ban and clear its score. Reports whether it was banned.
+func unbanPeer(peer string) bool {
+	peerLimitsMu.Lock()
+	_, banned := peerBans[peer]
+	delete(peerBans, peer)
+	delete(peerScores, peer)
+	peerLimitsMu.Unlock()
+	if banned {
+		saveBans()
+	}
+	return banned
+}
+
+// Report whether the peer behind a connection, by address or node ID, is
+// banned, so its connection should be dropped.
+func connBanned(addr net.Addr, id string) bool {
+	return peerBanned(peerHost(addr)) || (id != "" && peerBanned(id))
+}
+
+// GET /admin/bans: banned peers and the misbehaviour scores of the others.
+// POST /admin/bans {"peer": P, "duration": "1h"}: ban a peer, for
+// -ban-duration if no duration is given. DELETE /admin/bans?peer=P: lift a
+// ban.
+func handleBans(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		peerLimitsMu.Lock()
+		scores := make(map[string]int, len(peerScores))
+		for peer, score := range peerScores {
+			scores[peer] = score
+		}
+		peerLimitsMu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]any{"bans": currentBans(), "scores": scores, "limit": peerScoreLimit})
+	case http.MethodPost:
+		var request struct {
+			Peer     string `json:"peer"`
+			Duration string `json:"duration"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&request); err != nil || request.Peer == "" {
+			writeError(w, http.StatusBadRequest, `expected {"peer": P, "duration": D}`)
+			return
+		}
+		duration := *banDuration
+		if request.Duration != "" {
+			var err error
+			if duration, err = time.ParseDuration(request.Duration); err != nil || duration <= 0 {
+				writeError(w, http.StatusBadRequest, "invalid duration")
+				return
+			}
+		}
+		banPeer(request.Peer, duration)
+		p2pLog.Warn("Banned peer through the admin API", "peer", request.Peer, "duration", duration)
+		writeJSON(w, http.StatusOK, map[string]any{"peer": request.Peer, "until": time.Now().Add(duration)})
+	case http.MethodDelete:
+		peer := r.URL.Query().Get("peer")
+		if peer == "" {
+			writeError(w, http.StatusBadRequest, "peer is required")
+			return
+		}
+		if !unbanPeer(peer) {
+			writeError(w, http.StatusNotFound, "peer is not banned")
+			return
+		}
+		p2pLog.Info("Lifted peer ban through the admin API", "peer", peer)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "use GET, POST or DELETE")
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBansPersistAndAreManagedThroughAPI(t *testing.T) {
+	peer := "203.0.113.9"
+	savedDir, savedPath := *dataDir, bansPath
+	t.Cleanup(func() {
+		*dataDir, bansPath = savedDir, savedPath
+		unbanPeer(peer)
+	})
+	*dataDir = t.TempDir()
+	if err := loadBans(); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+	handleBans(recorder, httptest.NewRequest(http.MethodPost, "/admin/bans", bytes.NewReader([]byte(`{"peer": "`+peer+`", "duration": "1h"}`))))
+	if recorder.Code != http.StatusOK || !peerBanned(peer) {
+		t.Fatalf("banning through the API: %d %s", recorder.Code, recorder.Body)
+	}
+
+	// A restart restores the ban from the data directory
+	peerLimitsMu.Lock()
+	delete(peerBans, peer)
+	peerLimitsMu.Unlock()
+	if err := loadBans(); err != nil {
+		t.Fatal(err)
+	}
+	bans := currentBans()
+	if len(bans) != 1 || bans[0].Peer != peer || time.Until(bans[0].Until) < 59*time.Minute {
+		t.Fatalf("restored bans %v", bans)
+	}
+
+	recorder = httptest.NewRecorder()
+	handleBans(recorder, httptest.NewRequest(http.MethodDelete, "/admin/bans?peer="+peer, nil))
+	if recorder.Code != http.StatusNoContent || peerBanned(peer) {
+		t.Fatalf("lifting the ban through the API: %d %s", recorder.Code, recorder.Body)
+	}
+	recorder = httptest.NewRecorder()
+	handleBans(recorder, httptest.NewRequest(http.MethodDelete, "/admin/bans?peer="+peer, nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("lifting a ban twice: %d", recorder.Code)
+	}
+	if err := loadBans(); err != nil || len(currentBans()) != 0 {
+		t.Errorf("lifted ban was restored: %v %v", currentBans(), err)
+	}
+}
+
+func TestInvalidProofOfWorkIsPenalized(t *testing.T) {
+	setupTestMiner(t)
+	addr := &net.TCPAddr{IP: net.ParseIP("203.0.113.10"), Port: 8081}
+	t.Cleanup(func() { unbanPeer("203.0.113.10") })
+
+	block := mineTestBlock(t)
+	block.Hash = strings.Repeat("0", 64) // Claims a hash it does not have
+	data, _ := json.Marshal(block)
+	for i := 0; i < peerScoreLimit/scoreInvalidProofOfWork-1; i++ {
+		if !handleBlockAnnouncement(blockAnnouncement{Block: data}, "", "", addr) {
+			t.Fatal("connection dropped before the peer was banned")
+		}
+	}
+	if handleBlockAnnouncement(blockAnnouncement{Block: data}, "", "", addr) {
+		t.Error("connection kept after the peer was banned")
+	}
+	if !peerBanned("203.0.113.10") {
+		t.Error("peer relaying bad proofs of work was not banned")
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// Transactions in the block the benchmarks hash, serialize and verify.
+const benchBlockTxs = 100
+
+// Genesis of the benchmark chain; its target keeps the fixture quick to mine.
+var benchGenesis = Genesis{ChainID: "bench", TargetBits: 250}
+
+// Benchmarks run by the bench subcommand and, through bench_test.go, by
+// `go test -bench`, so a node's hardware and CI are measured alike.
+var benchmarks = []struct {
+	name string
+	fn   func(b *testing.B)
+}{
+	{"BlockHash", benchBlockHash},
+	{"NonceSearch", benchNonceSearch},
+	{"BlockSerialize", benchBlockSerialize},
+	{"BlockVerify", benchBlockVerify},
+}
+
+// Run the bench subcommand:
+//
+//	bench [-run regexp]
+//
+// It runs the benchmarks and prints one result line each.
+func runBench(args []string) int {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	filter := fs.String("run", "", "regular expression selecting the benchmarks to run")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	selected, err := regexp.Compile(*filter)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: invalid -run:", err)
+		return 2
+	}
+	if _, err := benchBlock(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error preparing benchmarks:", err)
+		return 1
+	}
+
+	fmt.Printf("%s/%s, %d CPUs, block of %d transactions\n", runtime.GOOS, runtime.GOARCH, runtime.NumCPU(), benchBlockTxs)
+	for _, bm := range benchmarks {
+		if !selected.MatchString(bm.name) {
+			continue
+		}
+		result := testing.Benchmark(bm.fn)
+		fmt.Printf("%-16s %s\t%s\n", bm.name, result, result.MemString())
+	}
+	return 0
+}
+
+// A mined block of benchBlockTxs signed transactions on the benchmark
+// genesis, built once under fresh keys.
+var benchBlock = sync.OnceValues(func() (Block, error) {
+	if err := useGenesis(benchGenesis); err != nil {
+		return Block{}, err
+	}
+	consensus = powConsensus{}
+	miningEnabled.Store(true)
+	wallet, err := generateSigningKey(sigAlgECDSAP256)
+	if err != nil {
+		return Block{}, err
+	}
+	identity, err := generateSigningKey(sigAlgEd25519)
+	if err != nil {
+		return Block{}, err
+	}
+	walletSigner, nodeSigner = cryptoSigner{key: wallet}, cryptoSigner{key: identity}
+	if walletAddress, err = nodeIDFromPublicKey(wallet.Public()); err != nil {
+		return Block{}, err
+	}
+	if nodeID, err = nodeIDFromPublicKey(identity.Public()); err != nil {
+		return Block{}, err
+	}
+
+	transactions := []Transaction{coinbaseTransaction(1, walletAddress, 0)}
+	for i := 1; i <= benchBlockTxs; i++ {
+		data := fmt.Sprintf("bench result %d", i)
+		tx := Transaction{ID: generateTransactionID(data), Data: data, NetworkID: networkID}
+		if err := signTransaction(&tx); err != nil {
+			return Block{}, err
+		}
+		transactions = append(transactions, tx)
+	}
+	// Built by hand rather than by blockTemplate, which needs a chain store
+	template := Block{
+		PrevHash:     genesisBlock.Hash,
+		MerkleRoot:   computeMerkleRoot(transactions),
+		Transactions: transactions,
+		BlockNumber:  1,
+		Timestamp:    genesisBlock.Timestamp + 1,
+		NetworkID:    networkID,
+		Target:       formatTarget(initialTarget),
+		Version:      headerHashVersion,
+	}
+	if err := signBlockHeader(&template); err != nil {
+		return Block{}, err
+	}
+	block, found := mineBlock(context.Background(), nil, template)
+	if !found {
+		return Block{}, errors.New("mining was interrupted")
+	}
+	return block, nil
+})
+
+func benchFixture(b *testing.B) Block {
+	block, err := benchBlock()
+	if err != nil {
+		b.Fatal(err)
+	}
+	return block
+}
+
+// SHA-256 throughput over the bytes a block hash covers.
+func benchBlockHash(b *testing.B) {
+	block := benchFixture(b)
+	b.SetBytes(int64(len(block.SerializeForHash())))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		block.ComputeHash()
+	}
+}
+
+// Nonces one core tries per second, as a mining worker tries them.
+func benchNonceSearch(b *testing.B) {
+	prefix := benchFixture(b).hashPrefix()
+	var unreachable [sha256.Size]byte
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tryNonce(prefix, i, &unreachable)
+	}
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "nonces/s")
+}
+
+// Encoding a block for IPFS and decoding it back, hash included.
+func benchBlockSerialize(b *testing.B) {
+	block := benchFixture(b)
+	b.SetBytes(int64(len(block.Serialize())))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := parseBlockSerialization(block.Serialize()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Full validation of a received block, signatures included.
+func benchBlockVerify(b *testing.B) {
+	block := benchFixture(b)
+	blockData, err := json.Marshal(block)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !validateBlock(string(blockData), genesisBlock.Hash, initialTarget) {
+			b.Fatal("benchmark block failed validation")
+		}
+	}
+}
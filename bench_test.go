@@ -0,0 +1,10 @@
+package main
+
+import "testing"
+
+// The benchmarks of bench.go, for `go test -bench .`.
+
+func BenchmarkBlockHash(b *testing.B)      { benchBlockHash(b) }
+func BenchmarkNonceSearch(b *testing.B)    { benchNonceSearch(b) }
+func BenchmarkBlockSerialize(b *testing.B) { benchBlockSerialize(b) }
+func BenchmarkBlockVerify(b *testing.B)    { benchBlockVerify(b) }
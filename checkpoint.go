@@ -0,0 +1,224 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hamayuna47/BlockChain-For-Algorithms-With-POW/merkle"
+)
+
+// Largest checkpoint fast sync downloads.
+const maxCheckpointSize = 64 << 20
+
+var (
+	checkpointInterval = flag.Int("checkpoint-interval", 1000, "blocks between the signed checkpoints this node publishes to IPFS (0 to disable)")
+	checkpointCID      = flag.String("checkpoint", "", "IPFS CID of a trusted checkpoint to fast-sync an empty chain from")
+	checkpointSigners  = flag.String("checkpoint-signers", "", "comma-separated node IDs whose checkpoints fast sync accepts (default any valid signature)")
+
+	latestMu         sync.Mutex
+	latestCID        string     // CID of the last checkpoint this node published
+	latestCheckpoint Checkpoint // The last checkpoint this node published
+)
+
+// Checkpoint is a signed snapshot of the chain state at a block: enough for
+// a new node to start from the block instead of replaying the chain.
+type Checkpoint struct {
+	ChainID    string           `json:"chainId"`
+	Height     int              `json:"height"` // Number of the tip block
+	TipHash    string           `json:"tipHash"`
+	LedgerRoot string           `json:"ledgerRoot"`
+	Work       string           `json:"work"`   // Hex cumulative work up to and including the tip
+	Target     string           `json:"target"` // Hex mining target for the blocks after the tip
+	Tip        Block            `json:"tip"`
+	Balances   map[string]int64 `json:"balances"`
+	Signature  *Signature       `json:"signature,omitempty"` // Over Digest, by the publishing node's key
+}
+
+// State digest the signature covers. The tip and balances are bound to it
+// through TipHash and LedgerRoot.
+func (c Checkpoint) Digest() []byte {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		c.ChainID, fmt.Sprint(c.Height), c.TipHash, c.LedgerRoot, c.Work, c.Target,
+	}, "\n")))
+	return sum[:]
+}
+
+// Merkle root of the balances, as "address=balance" leaves sorted by address.
+func ledgerRoot(balances map[string]int64) string {
+	addresses := make([]string, 0, len(balances))
+	for address, balance := range balances {
+		if balance != 0 {
+			addresses = append(addresses, address)
+		}
+	}
+	if len(addresses) == 0 {
+		empty := sha256.Sum256(nil)
+		return hex.EncodeToString(empty[:])
+	}
+	sort.Strings(addresses)
+	level := make([][]byte, len(addresses))
+	for i, address := range addresses {
+		level[i] = merkle.Leaf(fmt.Sprintf("%s=%d", address, balances[address]))
+	}
+	for len(level) > 1 {
+		level = merkleLevel(level)
+	}
+	return hex.EncodeToString(level[0])
+}
+
+// Snapshot the state at the current tip. The caller holds chainMu.
+func takeCheckpoint(tip Block) Checkpoint {
+	balances := ledger.Snapshot()
+	return Checkpoint{
+		ChainID:    networkID,
+		Height:     tip.BlockNumber,
+		TipHash:    tip.Hash,
+		LedgerRoot: ledgerRoot(balances),
+		Work:       chainStore.TotalWork().Text(16),
+		Target:     currentTarget().Text(16),
+		Tip:        tip,
+		Balances:   balances,
+	}
+}
+
+// Take a checkpoint if the new tip is at a checkpoint height, then sign
+// and publish it in the background. The caller holds chainMu.
+func maybeCheckpoint(tip Block) {
+	if *checkpointInterval <= 0 || tip.BlockNumber == 0 || tip.BlockNumber%*checkpointInterval != 0 {
+		return
+	}
+	checkpoint := takeCheckpoint(tip)
+	go func() {
+		if err := publishCheckpoint(checkpoint); err != nil {
+			chainLog.Warn("Error publishing checkpoint", "height", checkpoint.Height, "err", err)
+		}
+	}()
+}
+
+// Sign a checkpoint with the node key and add it to IPFS.
+func publishCheckpoint(checkpoint Checkpoint) error {
+	signature, err := signPayload(nodeSigner, sigDomainCheckpoint, checkpoint.Digest())
+	if err != nil {
+		return err
+	}
+	checkpoint.Signature = &signature
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %v", err)
+	}
+	cid, err := ipfsShell.Add(strings.NewReader(string(data)))
+	if err != nil {
+		return fmt.Errorf("failed to add checkpoint to IPFS: %v", err)
+	}
+
+	latestMu.Lock()
+	if checkpoint.Height >= latestCheckpoint.Height {
+		latestCID, latestCheckpoint = cid, checkpoint
+	}
+	latestMu.Unlock()
+	chainLog.Info("Published checkpoint", "height", checkpoint.Height, "tip", checkpoint.TipHash, "cid", cid)
+	return nil
+}
+
+// Check a checkpoint's signature and that its tip, balances, work and
+// target are consistent with what it signs. With a non-empty trusted set,
+// the signer's node ID must be in it. It returns the signer's node ID.
+func (c Checkpoint) Verify(trusted map[string]bool) (string, error) {
+	if c.Signature == nil {
+		return "", errors.New("checkpoint is not signed")
+	}
+	if err := c.Signature.Verify(sigDomainCheckpoint, c.Digest()); err != nil {
+		return "", fmt.Errorf("invalid checkpoint signature: %v", err)
+	}
+	pub, err := c.Signature.Public()
+	if err != nil {
+		return "", err
+	}
+	signer, err := nodeIDFromPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	if len(trusted) > 0 && !trusted[signer] {
+		return "", fmt.Errorf("checkpoint is signed by untrusted node %s", signer)
+	}
+
+	if c.ChainID != networkID {
+		return "", fmt.Errorf("checkpoint is for chain %q, not %q", c.ChainID, networkID)
+	}
+	if c.Height < 1 || c.Tip.BlockNumber != c.Height || c.Tip.Hash != c.TipHash || c.Tip.ComputeHash() != c.TipHash {
+		return "", errors.New("checkpoint tip does not match its hash and height")
+	}
+	if ledgerRoot(c.Balances) != c.LedgerRoot {
+		return "", errors.New("checkpoint balances do not match its ledger root")
+	}
+	for _, value := range []string{c.Work, c.Target} {
+		if n, ok := new(big.Int).SetString(value, 16); !ok || n.Sign() <= 0 {
+			return "", fmt.Errorf("invalid checkpoint number %q", value)
+		}
+	}
+	return signer, nil
+}
+
+// Start an empty chain from the checkpoint named by -checkpoint instead of
+// replaying it from the genesis block.
+func fastSync() error {
+	if *checkpointCID == "" || chainStore.Height() > 0 {
+		return nil
+	}
+	reader, err := ipfsShell.Cat(*checkpointCID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch checkpoint: %v", err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(io.LimitReader(reader, maxCheckpointSize))
+	if err != nil {
+		return fmt.Errorf("failed to fetch checkpoint: %v", err)
+	}
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return fmt.Errorf("failed to parse checkpoint: %v", err)
+	}
+
+	return startFromCheckpoint(checkpoint)
+}
+
+// Start the empty chain store from a checkpoint signed by one of
+// -checkpoint-signers.
+func startFromCheckpoint(checkpoint Checkpoint) error {
+	trusted := make(map[string]bool)
+	for _, id := range strings.Split(*checkpointSigners, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			trusted[id] = true
+		}
+	}
+	signer, err := checkpoint.Verify(trusted)
+	if err != nil {
+		return err
+	}
+	if err := chainStore.StartFromCheckpoint(checkpoint); err != nil {
+		return err
+	}
+	chainLog.Info("Fast-synced from checkpoint", "height", checkpoint.Height, "tip", checkpoint.TipHash, "signer", signer)
+	return nil
+}
+
+// Resume mining at the target of the checkpoint the chain starts from.
+func useCheckpointTarget() {
+	checkpoint := chainStore.Checkpoint()
+	if checkpoint == nil {
+		return
+	}
+	checkpointTarget, _ := new(big.Int).SetString(checkpoint.Target, 16)
+	difficultyMu.Lock()
+	defer difficultyMu.Unlock()
+	target.Set(checkpointTarget)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"testing"
+)
+
+// Build a chain of n blocks on the test genesis without mining, since
+// acceptBlock leaves proof of work to validation, and return its tip.
+func buildTestChain(t *testing.T, n int) Block {
+	t.Helper()
+	tip := genesisBlock
+	for i := 0; i < n; i++ {
+		tip = signedTestTemplate(t, tip.BlockNumber+1, tip, nil)
+		tip.Hash = tip.ComputeHash()
+		if err := acceptBlock(tip); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tip
+}
+
+// Use a fresh node key and a fake IPFS for the duration of a test.
+func setupCheckpointSigner(t *testing.T) string {
+	t.Helper()
+	savedSigner, savedIPFS := nodeSigner, ipfsShell
+	t.Cleanup(func() { nodeSigner, ipfsShell = savedSigner, savedIPFS })
+	key, err := generateSigningKey(sigAlgEd25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodeSigner, ipfsShell = cryptoSigner{key: key}, newFakeIPFS()
+	id, err := nodeIDFromPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func signedTestCheckpoint(t *testing.T, tip Block) Checkpoint {
+	t.Helper()
+	chainMu.Lock()
+	checkpoint := takeCheckpoint(tip)
+	chainMu.Unlock()
+	signature, err := signPayload(nodeSigner, sigDomainCheckpoint, checkpoint.Digest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkpoint.Signature = &signature
+	return checkpoint
+}
+
+func TestCheckpointVerify(t *testing.T) {
+	setupTestMiner(t)
+	signer := setupCheckpointSigner(t)
+	valid := signedTestCheckpoint(t, buildTestChain(t, 3))
+
+	if got, err := valid.Verify(map[string]bool{signer: true}); err != nil || got != signer {
+		t.Fatalf("Verify = %s, %v", got, err)
+	}
+	if _, err := valid.Verify(map[string]bool{"someone else": true}); err == nil {
+		t.Error("checkpoint by an untrusted signer verified")
+	}
+
+	for name, change := range map[string]func(*Checkpoint){
+		"unsigned":         func(c *Checkpoint) { c.Signature = nil },
+		"changed height":   func(c *Checkpoint) { c.Height++ },
+		"changed balances": func(c *Checkpoint) { c.Balances = map[string]int64{"thief": 1000} },
+		"other tip":        func(c *Checkpoint) { c.Tip = genesisBlock },
+		"other chain":      func(c *Checkpoint) { c.ChainID = "other" },
+	} {
+		checkpoint := valid
+		change(&checkpoint)
+		if _, err := checkpoint.Verify(nil); err == nil {
+			t.Errorf("%s: checkpoint verified", name)
+		}
+	}
+}
+
+func TestFastSyncFromCheckpoint(t *testing.T) {
+	setupTestMiner(t)
+	setupCheckpointSigner(t)
+	tip := buildTestChain(t, 5)
+	if err := publishCheckpoint(signedTestCheckpoint(t, tip)); err != nil {
+		t.Fatal(err)
+	}
+	balances := ledger.Snapshot()
+	work := chainStore.TotalWork()
+
+	// A new node starts from the published checkpoint
+	savedCID := *checkpointCID
+	t.Cleanup(func() { *checkpointCID = savedCID })
+	*checkpointCID = latestCID
+	dir := t.TempDir()
+	store, err := OpenChainStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chainStore, ledger, sideBlocks = store, NewLedger(), make(map[string]sideBlock)
+	if err := fastSync(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ledger.Load(store); err != nil {
+		t.Fatal(err)
+	}
+	if err := initGenesis(); err != nil {
+		t.Fatal(err)
+	}
+	if store.Height() != 6 || store.Base() != 5 || store.TotalWork().Cmp(work) != 0 {
+		t.Fatalf("height %d, base %d, work %v after fast sync", store.Height(), store.Base(), store.TotalWork())
+	}
+	for address, balance := range balances {
+		if ledger.Balance(address) != balance {
+			t.Errorf("balance of %s is %d, want %d", address, ledger.Balance(address), balance)
+		}
+	}
+
+	// The chain continues from the checkpoint and cannot be rolled back past it
+	next := blockTemplate(6, tip.Hash, tip.PrevCID, nil)
+	next.Hash = next.ComputeHash()
+	if err := acceptBlock(next); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Truncate(5); err == nil {
+		t.Error("chain truncated below its checkpoint")
+	}
+	if _, err := store.GetBlockByNumber(3); err != errBlockNotFound {
+		t.Errorf("block below the checkpoint: %v", err)
+	}
+	store.Close()
+
+	if store, err = OpenChainStore(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if store.Height() != 7 || store.Base() != 5 || store.Checkpoint() == nil {
+		t.Errorf("reopened store has height %d and base %d", store.Height(), store.Base())
+	}
+}
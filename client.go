@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	shell "github.com/ipfs/go-ipfs-api"
+)
+
+// Options of the client subcommand.
+type clientOptions struct {
+	node        string        // Job address of the node
+	recipient   string        // Optional key the result is sealed to
+	lockHeight  int           // Optional, see jobRequest
+	lockTime    int64         // Optional, see jobRequest
+	fee         int64         // Optional, see jobRequest
+	runtime     string        // Optional, see jobRequest
+	image       string        // Optional pinned image to run the script in
+	interpreter string        // Optional command in the image that runs the script
+	libs        string        // Optional comma-separated name==version list
+	args        []string      // Optional arguments of the script
+	vars        []string      // Optional NAME=VALUE environment variables of the script
+	wait        bool          // Wait for the result to be mined
+	poll        time.Duration // How often the receipt is requested while waiting
+	timeout     time.Duration // How long to wait in total
+}
+
+// Run the client subcommand:
+//
+//	client [flags] <script_file> <data_file>
+//
+// It adds the files to IPFS, submits the job to a node and reports the
+// job's receipts until the result is mined, the job fails or it times out.
+func runClient(args []string) int {
+	fs := flag.NewFlagSet("client", flag.ContinueOnError)
+	ipfsAddr := fs.String("ipfs-api", "localhost:5001", "address of the IPFS HTTP API to add the files through")
+	var opts clientOptions
+	fs.StringVar(&opts.node, "node", "localhost:8080", "job address of the node to submit to")
+	fs.StringVar(&opts.recipient, "recipient", "", "hex X25519 key to seal the result to")
+	fs.IntVar(&opts.lockHeight, "lockheight", 0, "block height before which the result may not be mined")
+	fs.Int64Var(&opts.lockTime, "locktime", 0, "Unix time before which the result may not be mined")
+	fs.Int64Var(&opts.fee, "fee", 0, "fee the node pays to have the result mined sooner")
+	fs.StringVar(&opts.runtime, "runtime", "", "runtime the script is written for (default from the script's extension)")
+	fs.StringVar(&opts.image, "image", "", "container image pinned by digest (name@sha256:...) to run the script in")
+	fs.StringVar(&opts.interpreter, "interpreter", "", "command in the image that runs the script (default python)")
+	fs.StringVar(&opts.libs, "libs", "", "comma-separated name==version libraries the image provides")
+	fs.Func("arg", "argument passed to the script after the data path; repeat for more", func(arg string) error {
+		opts.args = append(opts.args, arg)
+		return nil
+	})
+	fs.Func("var", "NAME=VALUE environment variable of the script, if the node allows NAME; repeat for more", func(v string) error {
+		if !strings.Contains(v, "=") {
+			return errors.New("expected NAME=VALUE")
+		}
+		opts.vars = append(opts.vars, v)
+		return nil
+	})
+	fs.BoolVar(&opts.wait, "wait", true, "wait until the result is mined")
+	fs.DurationVar(&opts.poll, "poll", 2*time.Second, "how often to ask for the job's status while waiting")
+	fs.DurationVar(&opts.timeout, "timeout", 10*time.Minute, "how long to wait for the result")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: client [flags] <script_file> <data_file>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	// Options of a job message are separated by whitespace
+	for _, param := range append(slices.Clone(opts.args), opts.vars...) {
+		if strings.ContainsFunc(param, unicode.IsSpace) {
+			fmt.Fprintf(os.Stderr, "Error: script argument or variable %q contains whitespace\n", param)
+			return 2
+		}
+	}
+	if opts.runtime == "" {
+		opts.runtime = runtimeForFile(fs.Arg(0))
+	}
+	if err := submitJob(shell.NewShell(*ipfsAddr), fs.Arg(0), fs.Arg(1), opts, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+	return 0
+}
+
+// Add a job's files to IPFS, submit the job and print its receipts.
+func submitJob(ipfs ipfsClient, scriptFile, dataFile string, opts clientOptions, out io.Writer) error {
+	scriptCID, err := addFileToIPFS(ipfs, scriptFile)
+	if err != nil {
+		return err
+	}
+	dataCID, err := addFileToIPFS(ipfs, dataFile)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "script %s\ndata   %s\n", scriptCID, dataCID)
+
+	conn, err := net.DialTimeout("tcp", opts.node, 10*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to connect to node: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(opts.timeout))
+	reader := bufio.NewReader(conn)
+
+	if err := writeFrame(conn, frameJob, []byte(jobMessage(scriptCID, dataCID, opts))); err != nil {
+		return fmt.Errorf("failed to submit job: %v", err)
+	}
+
+	// The node reports the job ID, then the outcome of running it
+	last := ""
+	for {
+		receipt, err := readReceipt(reader)
+		if err != nil {
+			return err
+		}
+		if receipt.Status != last {
+			printReceipt(out, receipt)
+			last = receipt.Status
+		}
+		switch {
+		case receipt.Status == jobFailed:
+			return errors.New("job failed")
+		case receipt.Status == jobMined, receipt.Status == jobPending && !opts.wait:
+			return nil
+		case receipt.Status == jobPending:
+			time.Sleep(opts.poll)
+			if err := writeFrame(conn, frameStatus, []byte(receipt.JobID)); err != nil {
+				return fmt.Errorf("failed to request job status: %v", err)
+			}
+		}
+	}
+}
+
+func addFileToIPFS(ipfs ipfsClient, path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	cid, err := ipfs.Add(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to add %s to IPFS: %v", path, err)
+	}
+	return cid, nil
+}
+
+// Format a job message as parseJobMessage reads it.
+func jobMessage(scriptCID, dataCID string, opts clientOptions) string {
+	parts := []string{scriptCID, dataCID}
+	if opts.recipient != "" {
+		parts = append(parts, opts.recipient)
+	}
+	if opts.lockHeight != 0 {
+		parts = append(parts, fmt.Sprintf("lockheight=%d", opts.lockHeight))
+	}
+	if opts.lockTime != 0 {
+		parts = append(parts, fmt.Sprintf("locktime=%d", opts.lockTime))
+	}
+	if opts.fee != 0 {
+		parts = append(parts, fmt.Sprintf("fee=%d", opts.fee))
+	}
+	if opts.runtime != "" {
+		parts = append(parts, "runtime="+opts.runtime)
+	}
+	if opts.image != "" {
+		parts = append(parts, "image="+opts.image)
+	}
+	if opts.interpreter != "" {
+		parts = append(parts, "interpreter="+opts.interpreter)
+	}
+	if opts.libs != "" {
+		parts = append(parts, "libs="+opts.libs)
+	}
+	for _, arg := range opts.args {
+		parts = append(parts, "arg="+arg)
+	}
+	for _, v := range opts.vars {
+		parts = append(parts, "var="+v)
+	}
+	return strings.Join(parts, " ")
+}
+
+func readReceipt(reader *bufio.Reader) (Receipt, error) {
+	payload, err := readFrameOfType(reader, frameReceipt)
+	if err != nil {
+		return Receipt{}, fmt.Errorf("failed to read receipt: %v", err)
+	}
+	var receipt Receipt
+	if err := json.Unmarshal(payload, &receipt); err != nil {
+		return Receipt{}, fmt.Errorf("malformed receipt: %v", err)
+	}
+	return receipt, nil
+}
+
+func printReceipt(out io.Writer, receipt Receipt) {
+	switch receipt.Status {
+	case jobPending:
+		fmt.Fprintf(out, "job %s: pending as transaction %s\n", receipt.JobID, receipt.TxID)
+	case jobMined:
+		fmt.Fprintf(out, "job %s: mined in block %d (%s)\n", receipt.JobID, receipt.Block, receipt.BlockHash)
+	case jobFailed:
+		fmt.Fprintf(out, "job %s: failed (%s): %s\n", receipt.JobID, receipt.Code, receipt.Error)
+	default:
+		fmt.Fprintf(out, "job %s: %s\n", receipt.JobID, receipt.Status)
+	}
+}
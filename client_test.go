@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJobMessageParses(t *testing.T) {
+	opts := clientOptions{lockHeight: 7, lockTime: 1700000000, fee: 3}
+	job, err := parseJobMessage(jobMessage(testScriptCID, testDataCID, opts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.ScriptCID != testScriptCID || job.DataCID != testDataCID || job.LockHeight != 7 || job.LockTime != 1700000000 || job.Fee != 3 {
+		t.Errorf("parsed %+v", job)
+	}
+}
+
+// A node that answers one job with a job ID, a pending receipt, and a mined
+// receipt once asked for the job's status.
+func fakeJobNode(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	messages := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		message, err := readFrameOfType(reader, frameJob)
+		if err != nil {
+			return
+		}
+		messages <- string(message)
+
+		writeReceipt(conn, Receipt{JobID: "job1", Status: jobDownloading})
+		writeReceipt(conn, Receipt{JobID: "job1", Status: jobPending, TxID: "tx1"})
+		if id, err := readFrameOfType(reader, frameStatus); err != nil || string(id) != "job1" {
+			return
+		}
+		writeReceipt(conn, Receipt{JobID: "job1", Status: jobMined, TxID: "tx1", Block: 4, BlockHash: "00ab"})
+	}()
+	return ln.Addr().String(), messages
+}
+
+func TestSubmitJob(t *testing.T) {
+	dir := t.TempDir()
+	script, data := filepath.Join(dir, "script.py"), filepath.Join(dir, "data.txt")
+	os.WriteFile(script, []byte("print(1)"), 0o644)
+	os.WriteFile(data, []byte("input"), 0o644)
+
+	addr, messages := fakeJobNode(t)
+	ipfs := newFakeIPFS()
+	var out bytes.Buffer
+	opts := clientOptions{node: addr, fee: 2, wait: true, poll: time.Millisecond, timeout: 10 * time.Second}
+	if err := submitJob(ipfs, script, data, opts, &out); err != nil {
+		t.Fatalf("%v\n%s", err, out.String())
+	}
+
+	fields := strings.Fields(<-messages)
+	if len(fields) != 3 || fields[2] != "fee=2" {
+		t.Fatalf("node received job %q", fields)
+	}
+	for _, cid := range fields[:2] {
+		if _, err := ipfs.Cat(cid); err != nil {
+			t.Errorf("job file %s was not added to IPFS", cid)
+		}
+	}
+	if !strings.Contains(out.String(), "mined in block 4") {
+		t.Errorf("client did not report the mined block:\n%s", out.String())
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Frame types. Every message on the transaction and block ports is sent as
+// a frame: a 4-byte big-endian payload length, a 1-byte type and the
+// payload.
+const (
+	frameJob   byte = 1 // Job request line, on the transaction port
+	frameBlock byte = 2 // blockAnnouncement JSON, on the block port
+	framePeer  byte = 3 // peerMessage JSON, on the block port
+	frameHello byte = 4 // helloMessage JSON, first on every block port connection
+
+	frameReceipt byte = 5 // Receipt JSON, sent back on the transaction port
+	frameStatus  byte = 6 // Job ID whose receipt is requested, on the transaction port
+
+	frameTransactions byte = 7 // JSON list of relayed transactions, on the block port
+
+	frameBatch    byte = 8 // JSON array of jobSpecs, on the transaction port
+	frameReceipts byte = 9 // JSON array of Receipts acknowledging a batch, in its order
+
+	frameIdentity byte = 10 // Signature JSON proving the dialer's node ID, after the hellos
+)
+
+const frameHeaderSize = 5
+
+var errFrameTooLarge = errors.New("frame exceeds the maximum message size")
+
+// Write one frame in a single write, so concurrent writers never interleave
+// partial frames.
+func writeFrame(w io.Writer, frameType byte, payload []byte) error {
+	if len(payload) > *maxMessageSize {
+		return errFrameTooLarge
+	}
+	frame := make([]byte, frameHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(frame, uint32(len(payload)))
+	frame[4] = frameType
+	copy(frame[frameHeaderSize:], payload)
+	_, err := w.Write(frame)
+	return err
+}
+
+// Read one frame. The length is checked against the message size limit
+// before the payload is read. A connection closed between frames returns
+// io.EOF; one closed inside a frame returns io.ErrUnexpectedEOF.
+func readFrame(r *bufio.Reader) (byte, []byte, error) {
+	var header [frameHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if uint64(length) > uint64(*maxMessageSize) {
+		return 0, nil, errFrameTooLarge
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, err
+	}
+	return header[4], payload, nil
+}
+
+// Read one frame and check its type.
+func readFrameOfType(r *bufio.Reader, frameType byte) ([]byte, error) {
+	gotType, payload, err := readFrame(r)
+	if err != nil {
+		return nil, err
+	}
+	if gotType != frameType {
+		return nil, fmt.Errorf("expected frame type %d, got %d", frameType, gotType)
+	}
+	return payload, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Environment variables named envPrefix followed by a flag name in upper
+// case with dashes as underscores override the config file, e.g.
+// BCNODE_MAX_BLOCK_TXS for -max-block-txs.
+const envPrefix = "BCNODE_"
+
+var (
+	configFile = flag.String("config", "", "TOML file of settings keyed by flag name; environment variables and flags override it")
+
+	jobAddr   = flag.String("job-addr", ":8080", "address to listen on for compute jobs")
+	blockAddr = flag.String("block-addr", ":8081", "address to listen on for other miners; its port is also used to dial them")
+	ipfsAPI   = flag.String("ipfs-api", "localhost:5001", "address of the IPFS HTTP API")
+)
+
+// Fill in every flag not given on the command line, first from the config
+// file and then from the environment. Each setting goes through the flag's
+// own parser, so the file and environment accept exactly what flags do.
+func loadConfig() error {
+	onCommandLine := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { onCommandLine[f.Name] = true })
+
+	if *configFile != "" {
+		var settings map[string]any
+		if _, err := toml.DecodeFile(*configFile, &settings); err != nil {
+			return fmt.Errorf("failed to read config file: %v", err)
+		}
+		// Apply in a fixed order so errors are reproducible
+		names := make([]string, 0, len(settings))
+		for name := range settings {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if flag.Lookup(name) == nil {
+				return fmt.Errorf("unknown setting %q in config file", name)
+			}
+			if onCommandLine[name] {
+				continue
+			}
+			if err := flag.Set(name, configValue(settings[name])); err != nil {
+				return fmt.Errorf("invalid %s in config file: %v", name, err)
+			}
+		}
+	}
+
+	var err error
+	flag.VisitAll(func(f *flag.Flag) {
+		if err != nil || onCommandLine[f.Name] {
+			return
+		}
+		name := envPrefix + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		if value, ok := os.LookupEnv(name); ok {
+			if setErr := flag.Set(f.Name, value); setErr != nil {
+				err = fmt.Errorf("invalid %s: %v", name, setErr)
+			}
+		}
+	})
+	return err
+}
+
+// Render a TOML value as flag text. Arrays become the comma-separated lists
+// that list flags such as -bootstrap take.
+func configValue(value any) string {
+	if list, ok := value.([]any); ok {
+		items := make([]string, len(list))
+		for i, item := range list {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(value)
+}
+
+// Address of another miner's block listener, given its IP. Peers are dialed
+// on the block port they announced in their hello, others on ours.
+func minerAddr(ip string) string {
+	if peerManager != nil {
+		if port := peerManager.blockPort(ip); port != 0 {
+			return net.JoinHostPort(ip, strconv.Itoa(port))
+		}
+	}
+	_, port, err := net.SplitHostPort(*blockAddr)
+	if err != nil || port == "" {
+		port = "8081"
+	}
+	return net.JoinHostPort(ip, port)
+}
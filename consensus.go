@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+)
+
+var (
+	consensusName = flag.String("consensus", "pow", "consensus engine: pow, useful to credit verified WASM computation (research), or instant to seal blocks without work on test networks")
+
+	consensus Consensus = powConsensus{}
+)
+
+// Consensus decides what a block must declare and prove to extend the
+// chain. The miner and the validators only talk to it through this
+// interface, so test networks can swap proof of work for a cheaper engine.
+// Every node of a network must run the same engine.
+type Consensus interface {
+	// Fill in the consensus fields of a block template.
+	PrepareBlock(block *Block)
+	// Seal a prepared block so it can be published. Reports false if ctx
+	// is cancelled, abort is signalled or mining is switched off first.
+	Seal(ctx context.Context, abort <-chan struct{}, block Block) (Block, bool)
+	// Check the seal of a block whose hash matches its contents. The block
+	// may declare no easier target than limit.
+	VerifySeal(block Block, limit *big.Int) error
+	// The target the child of parent must declare. find looks up blocks
+	// on the main chain and side branches by hash.
+	CalcDifficulty(parent Block, find func(hash string) (Block, bool)) (*big.Int, error)
+	// The work a block adds to its chain; the chain with the most
+	// cumulative work is the main chain.
+	Work(block Block) *big.Int
+}
+
+func setupConsensus() error {
+	switch *consensusName {
+	case "pow":
+		consensus = powConsensus{}
+	case "useful":
+		consensus = usefulWork{}
+		nodeLog.Warn("Crediting verified computation as block work; this consensus mode is experimental")
+	case "instant":
+		consensus = instantSeal{}
+		nodeLog.Warn("Sealing blocks without proof of work; use only on test networks")
+	default:
+		return fmt.Errorf("unknown consensus engine %q", *consensusName)
+	}
+	return nil
+}
+
+// Check the target a block declares is set and no easier than limit.
+func checkDeclaredTarget(block Block, limit *big.Int) (*big.Int, error) {
+	declared, ok := new(big.Int).SetString(block.Target, 16)
+	if !ok || declared.Sign() <= 0 || declared.Cmp(limit) > 0 {
+		return nil, fmt.Errorf("missing or too easy target %q", block.Target)
+	}
+	return declared, nil
+}
+
+// powConsensus is proof of work: the block hash must fall below the target
+// the header declares, which retargets every epoch.
+type powConsensus struct{}
+
+func (powConsensus) PrepareBlock(block *Block) {
+	block.Target = formatTarget(templateTarget(block.PrevHash))
+}
+
+func (powConsensus) Seal(ctx context.Context, abort <-chan struct{}, block Block) (Block, bool) {
+	return mineBlock(ctx, abort, block)
+}
+
+func (powConsensus) VerifySeal(block Block, limit *big.Int) error {
+	declared, err := checkDeclaredTarget(block, limit)
+	if err != nil {
+		return err
+	}
+	hash, ok := new(big.Int).SetString(block.Hash, 16)
+	if !ok || hash.Cmp(declared) != -1 {
+		return errors.New("insufficient proof of work")
+	}
+	return nil
+}
+
+func (powConsensus) CalcDifficulty(parent Block, find func(hash string) (Block, bool)) (*big.Int, error) {
+	return expectedTarget(parent, find)
+}
+
+func (powConsensus) Work(block Block) *big.Int {
+	return hashWork(blockTarget(block))
+}
+
+// instantSeal seals blocks as soon as they are built, for CI and local test
+// networks. Every block declares the easiest target, so the longest chain
+// still has the most work.
+type instantSeal struct{}
+
+func (instantSeal) PrepareBlock(block *Block) {
+	block.Target = formatTarget(maxTarget)
+}
+
+func (instantSeal) Seal(ctx context.Context, abort <-chan struct{}, block Block) (Block, bool) {
+	if ctx.Err() != nil || !miningEnabled.Load() {
+		return Block{}, false
+	}
+	select {
+	case <-abort:
+		return Block{}, false
+	default:
+	}
+	block.Hash = block.ComputeHash()
+	return block, true
+}
+
+func (instantSeal) VerifySeal(block Block, limit *big.Int) error {
+	_, err := checkDeclaredTarget(block, limit)
+	return err
+}
+
+func (instantSeal) CalcDifficulty(parent Block, find func(hash string) (Block, bool)) (*big.Int, error) {
+	return new(big.Int).Set(maxTarget), nil
+}
+
+func (instantSeal) Work(block Block) *big.Int {
+	return hashWork(blockTarget(block))
+}
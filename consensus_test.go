@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestInstantSeal(t *testing.T) {
+	setupTestMiner(t)
+	defer func(c Consensus) { consensus = c }(consensus)
+	consensus = instantSeal{}
+
+	block, found := consensus.Seal(context.Background(), nil, signedTestTemplate(t, 1, genesisBlock, nil))
+	if !found {
+		t.Fatal("instant seal did not seal")
+	}
+	data, err := json.Marshal(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !validateBlock(string(data), genesisBlock.Hash, maxTarget) {
+		t.Fatal("instantly sealed block is invalid")
+	}
+	if err := acceptBlock(block); err != nil {
+		t.Fatal(err)
+	}
+
+	// Proof-of-work nodes do not take blocks sealed without work
+	consensus = powConsensus{}
+	if validateBlock(string(data), genesisBlock.Hash, initialTarget) {
+		t.Error("proof-of-work node accepted an instantly sealed block")
+	}
+}
@@ -2,53 +2,102 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"math/big"
 	"net"
 	"os"
-	"os/exec"
+	"os/signal"
+	"path/filepath"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	shell "github.com/ipfs/go-ipfs-api"
 )
 
 type Transaction struct {
-	ID   string
-	Data string
+	ID         string
+	Data       string
+	Recipient  string          // Hex X25519 key Data is sealed to; empty for plaintext results
+	ScriptCID  string          // IPFS CID of the executed script
+	DataCID    string          // IPFS CID of the script's input data
+	ResultHash string          `json:",omitempty"` // Hex SHA-256 of the plaintext result, checked by re-execution
+	Runtime    string          `json:",omitempty"` // Runtime the script ran in; default python3
+	Env        *ExecutionEnv   `json:",omitempty"` // Pinned environment the script ran in; default the miner's sandbox
+	Params     *ScriptParams   `json:",omitempty"` // Arguments and environment variables the script ran with
+	ResultCID  string          `json:",omitempty"` // IPFS CID of the result as Data carries it; Data is empty for results over -inline-result-size
+	Outputs    []OutputFile    `json:",omitempty"` // Files the script left in its output directory, stored in IPFS
+	Units      int64           `json:",omitempty"` // Execution units the script metered, checked by re-execution; WASM only
+	Proof      *ExecutionProof `json:",omitempty"` // Optional proof of correct execution
+
+	LockHeight   int    `json:",omitempty"` // Not minable before this block height
+	ExpiryHeight int    `json:",omitempty"` // Not minable at or after this block height; 0 never expires
+	LockTime     int64  `json:",omitempty"` // Not minable before this Unix time
+	Fee          int64  `json:",omitempty"` // Paid by the sender to the block's miner
+	NetworkID    string `json:",omitempty"` // Chain ID of the only network the transaction is valid on
+
+	Signature *Signature `json:",omitempty"` // Sender's signature over the other fields
+	Coinbase  *Coinbase  `json:",omitempty"` // Miner reward; only on a block's first transaction
 }
 
 type Block struct {
 	PrevHash     string
+	MerkleRoot   string
 	Transactions []Transaction
 	Nonce        int
 	Hash         string
 	PrevCID      string
 	BlockNumber  int
+	Timestamp    int64  `json:",omitempty"` // Unix time the block was mined
+	NetworkID    string `json:",omitempty"` // Chain ID of the network the block was mined on
+	Target       string `json:",omitempty"` // Hex proof-of-work target the block was mined against
+	MinerID      string     `json:",omitempty"` // Node ID of the miner that built the block
+	MinerSig     *Signature `json:",omitempty"` // The miner's identity signature over the header
+	Version      int        `json:",omitempty"` // Hash preimage layout (see serialize.go); 0 for blocks hashed with their transactions
+	Units        int64      `json:",omitempty"` // Execution units of the transactions credited as work (see useful.go)
 }
 
 var (
-	transactionBuffer = make(chan Transaction, 100) // Buffer for dynamically created transactions
-	newBlock          = make(chan Block)           // Channel to broadcast new blocks
-	stopMining        = make(chan struct{})        // Channel to stop the mining process
-	target            = big.NewInt(1).Lsh(big.NewInt(1), 245) // Approximate target for ~30 seconds
-	ipfsShell         = shell.NewShell("localhost:5001")      // IPFS shell instance
-	connectedMiners   = []string{}                           // List of connected miner IPs
-	minedBlocks       = 0                                    // Number of blocks mined by this node
-	blockValidations  = make(map[string]int)                // Track block validation votes (by block hash)
+	target            = new(big.Int)                          // Mining target, set from the genesis and retargeted every epoch (see difficulty.go)
+	ipfsShell         ipfsClient                             // IPFS shell instance, connected to -ipfs-api
 )
 
-// Download file from IPFS.
-func downloadFromIPFS(cid, outputPath string) error {
+// The IPFS API calls the node makes, as provided by *shell.Shell.
+type ipfsClient interface {
+	Add(r io.Reader, options ...shell.AddOpts) (string, error)
+	Cat(path string) (io.ReadCloser, error)
+	Pin(path string) error
+	Unpin(path string) error
+	Pins() (map[string]shell.PinInfo, error)
+	ObjectStat(key string) (*shell.ObjectStats, error)
+	IsUp() bool
+}
+
+// Download file from IPFS, refusing files larger than maxSize bytes.
+// Cancelling ctx aborts the transfer.
+func downloadFromIPFS(ctx context.Context, cid, outputPath string, maxSize int64) error {
+	// Refuse files the daemon already knows to be too large before
+	// fetching any of them; the copy below enforces the limit regardless.
+	// The cumulative size includes the UnixFS encoding, hence the slack.
+	if stat, err := ipfsShell.ObjectStat(cid); err == nil && int64(stat.CumulativeSize) > maxSize+maxSize/32+1024 {
+		return &jobError{codeTooLarge, fmt.Errorf("%s is %d bytes, more than the limit of %d", cid, stat.CumulativeSize, maxSize)}
+	}
+
 	reader, err := ipfsShell.Cat(cid)
 	if err != nil {
 		return fmt.Errorf("failed to fetch file from IPFS: %v", err)
 	}
 	defer reader.Close()
+	stop := context.AfterFunc(ctx, func() { reader.Close() })
+	defer stop()
 
 	file, err := os.Create(outputPath)
 	if err != nil {
@@ -56,235 +105,586 @@ func downloadFromIPFS(cid, outputPath string) error {
 	}
 	defer file.Close()
 
-	_, err = io.Copy(file, reader)
+	n, err := io.Copy(file, io.LimitReader(reader, maxSize+1))
 	if err != nil {
 		return fmt.Errorf("failed to write to output file: %v", err)
 	}
+	if n > maxSize {
+		return &jobError{codeTooLarge, fmt.Errorf("%s is larger than the limit of %d bytes", cid, maxSize)}
+	}
+	ipfsLog.Debug("Downloaded file", "cid", cid, "path", outputPath, "bytes", n)
 	return nil
 }
 
-// Execute Python script with input data.
-func executeScript(scriptPath, dataPath string) (string, error) {
-	cmd := exec.Command("python", scriptPath, dataPath)
-	output, err := cmd.CombinedOutput()
+// Execute a script with input data and optional parameters in its runtime
+// in the sandbox. The script may leave files in outputDir, if not empty.
+// Only in-process runtimes meter execution units; others report none.
+func executeScript(ctx context.Context, runtime scriptRuntime, env *ExecutionEnv, scriptPath, dataPath, outputDir string, params *ScriptParams) (string, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, *scriptTimeout)
+	defer cancel()
+
+	var output string
+	var units int64
+	var err error
+	if runtime.Run != nil {
+		// In-process runtimes isolate scripts themselves
+		output, units, err = runtime.Run(ctx, scriptPath, dataPath, outputDir, params)
+	} else {
+		cmd, cmdErr := sandboxFor(env).Command(ctx, runtime, scriptPath, dataPath, outputDir, params)
+		if cmdErr != nil {
+			return "", 0, fmt.Errorf("failed to prepare sandbox: %v", cmdErr)
+		}
+		if cmd.Dir != "" {
+			defer os.RemoveAll(cmd.Dir) // The sandbox's private working directory
+		}
+		var combined []byte
+		combined, err = cmd.CombinedOutput()
+		output = string(combined)
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", 0, fmt.Errorf("script killed after %s timeout", *scriptTimeout)
+	}
 	if err != nil {
-		return "", fmt.Errorf("script execution failed: %v, output: %s", err, string(output))
+		return "", 0, fmt.Errorf("script execution failed: %v, output: %s", err, output)
 	}
-	return string(output), nil
+	return output, units, nil
 }
 
 // Transaction Processing Thread
-func processTransactions(wg *sync.WaitGroup) {
+func processTransactions(ctx context.Context, wg *sync.WaitGroup) {
 	defer wg.Done()
 
-	ln, err := net.Listen("tcp", ":8080")
+	ln, err := listenLimited(*jobAddr)
 	if err != nil {
-		fmt.Println("Error starting transaction listener:", err)
+		txprocLog.Error("Error starting transaction listener", "err", err)
 		return
 	}
-	defer ln.Close()
+	context.AfterFunc(ctx, func() { ln.Close() })
 
+	// Connections being handled; shutdown waits for them
+	var handlers sync.WaitGroup
 	for {
 		conn, err := ln.Accept()
 		if err != nil {
-			fmt.Println("Error accepting connection:", err)
+			if ctx.Err() != nil {
+				handlers.Wait()
+				return
+			}
+			txprocLog.Error("Error accepting connection", "err", err)
 			continue
 		}
 
+		handlers.Add(1)
 		go func(conn net.Conn) {
+			defer handlers.Done()
 			defer conn.Close()
+			stop := context.AfterFunc(ctx, func() { conn.Close() })
+			defer stop()
 
-			scanner := bufio.NewScanner(conn)
-			for scanner.Scan() {
-				message := scanner.Text()
-				fmt.Println("Received hashes:", message)
-
-				parts := strings.Split(message, " ")
-				if len(parts) != 2 {
-					fmt.Println("Invalid message format. Expected '<data_hash> <script_hash>'")
+			reader := bufio.NewReader(conn)
+			for {
+				frameType, payload, err := readFrame(reader)
+				if err != nil {
+					penalizeFrameError(conn.RemoteAddr(), "", err)
+					return
+				}
+				if connBanned(conn.RemoteAddr(), "") {
+					txprocLog.Warn("Dropping connection from banned peer", "peer", conn.RemoteAddr())
+					return
+				}
+				if frameType == frameStatus {
+					receipt, ok := receipts.Get(string(payload))
+					if !ok {
+						receipt = Receipt{JobID: string(payload), Status: jobFailed, Error: "unknown job"}
+					}
+					writeReceipt(conn, receipt)
 					continue
 				}
-				dataHash, scriptHash := parts[1], parts[0]
-
-				// Download data and script from IPFS
-				dataPath := "data.txt"
-				scriptPath := "script.py"
-
-				if err := downloadFromIPFS(dataHash, dataPath); err != nil {
-					fmt.Println("Failed to download data:", err)
+				if frameType == frameBatch {
+					specs, err := parseJobBatch(payload)
+					if err != nil {
+						txprocLog.Warn("Invalid job batch", "peer", conn.RemoteAddr(), "err", err)
+						penalizePeer(conn.RemoteAddr(), scoreMalformedMessage, "malformed job batch")
+						writeReceipt(conn, Receipt{Status: jobFailed, Error: err.Error(), Code: codeMalformedJob})
+						continue
+					}
+					txprocLog.Info("Received job batch", "jobs", len(specs), "peer", conn.RemoteAddr())
+					writeReceipts(conn, submitJobBatch(ctx, conn.RemoteAddr(), specs))
 					continue
 				}
-
-				if err := downloadFromIPFS(scriptHash, scriptPath); err != nil {
-					fmt.Println("Failed to download script:", err)
+				if frameType != frameJob {
+					txprocLog.Warn("Unknown frame type", "peer", conn.RemoteAddr(), "type", frameType)
+					penalizePeer(conn.RemoteAddr(), scoreMalformedMessage, "unknown frame type")
 					continue
 				}
+				message := string(payload)
+				txprocLog.Info("Received job", "message", message, "peer", conn.RemoteAddr())
 
-				// Execute the script to produce the transaction
-				result, err := executeScript(scriptPath, dataPath)
+				job, err := parseJobMessage(message)
 				if err != nil {
-					fmt.Println("Error executing script:", err)
+					txprocLog.Warn("Invalid message format", "peer", conn.RemoteAddr(), "err", err)
+					penalizePeer(conn.RemoteAddr(), scoreMalformedMessage, "malformed job message")
+					writeReceipt(conn, Receipt{Status: jobFailed, Error: err.Error(), Code: jobErrorCode(err, codeMalformedJob)})
 					continue
 				}
 
-				// Create a transaction from the result
-				transaction := Transaction{
-					ID:   generateTransactionID(result),
-					Data: result,
+				if err := chargeJob(conn.RemoteAddr()); err != nil {
+					writeReceipt(conn, Receipt{Status: jobFailed, Error: err.Error(), Code: codeRateLimited})
+					continue
 				}
 
-				// Add the transaction to the buffer
-				transactionBuffer <- transaction
-				fmt.Println("Transaction created and added to buffer:", transaction)
+				// The client learns the job ID first and the outcome once
+				// a worker has run the job
+				job.ID = receipts.Open(job)
+				done, err := jobQueue.Submit(ctx, peerHost(conn.RemoteAddr()), job)
+				if err != nil {
+					receipts.Fail(job.ID, err)
+				}
+				receipt, _ := receipts.Get(job.ID)
+				writeReceipt(conn, receipt)
+				if err != nil {
+					continue
+				}
+				if outcome := <-done; outcome.err != nil {
+					txprocLog.Error("Job failed", "job", job.ID, "script", job.ScriptCID, "data", job.DataCID, "err", outcome.err)
+				}
+				receipt, _ = receipts.Get(job.ID)
+				writeReceipt(conn, receipt)
 			}
 		}(conn)
 	}
 }
 
+// Run a job: fetch its script and data from IPFS, execute the script and
+// add the signed result to the mempool as a transaction. Progress is
+// recorded on the job's receipt.
+func runJob(ctx context.Context, job jobRequest) (_ Transaction, err error) {
+	defer func() {
+		if err != nil {
+			receipts.Fail(job.ID, err)
+		}
+	}()
+	receipts.Downloading(job.ID)
+	dataHash, scriptHash := job.DataCID, job.ScriptCID
+	runtime, err := enabledRuntime(job.Runtime)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	dir, release, err := newJobDir(job.ID)
+	if err != nil {
+		return Transaction{}, err
+	}
+	defer release()
+
+	// Download data and script from IPFS
+	dataPath := filepath.Join(dir, "data.txt")
+	scriptPath := filepath.Join(dir, "script"+runtime.Extensions[0])
+	outputDir := filepath.Join(dir, "output")
+	if err := os.Mkdir(outputDir, 0o700); err != nil {
+		return Transaction{}, fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	if err := downloadFromIPFS(ctx, dataHash, dataPath, *maxDataSize); err != nil {
+		return Transaction{}, &jobError{jobErrorCode(err, codeDownloadFailed), fmt.Errorf("failed to download data: %v", err)}
+	}
+
+	if err := downloadFromIPFS(ctx, scriptHash, scriptPath, *maxScriptSize); err != nil {
+		return Transaction{}, &jobError{jobErrorCode(err, codeDownloadFailed), fmt.Errorf("failed to download script: %v", err)}
+	}
+
+	// Decrypt inputs sealed to this node's payload key. Other nodes cannot
+	// re-execute the script then, so its execution units are not claimed.
+	sealedInputs := isSealedFile(dataPath) || isSealedFile(scriptPath)
+	if err := openSealedFile(dataPath); err != nil {
+		return Transaction{}, fmt.Errorf("failed to decrypt data: %v", err)
+	}
+	if err := openSealedFile(scriptPath); err != nil {
+		return Transaction{}, fmt.Errorf("failed to decrypt script: %v", err)
+	}
+
+	// Execute the script to produce the transaction
+	receipts.Executing(job.ID)
+	result, units, err := executeScript(ctx, runtime, job.Env, scriptPath, dataPath, outputDir, job.Params)
+	if err != nil {
+		return Transaction{}, err
+	}
+	if sealedInputs {
+		units = 0
+	}
+	if int64(len(result)) > *maxOutputSize {
+		return Transaction{}, &jobError{codeTooLarge, fmt.Errorf("result is larger than the limit of %d bytes", *maxOutputSize)}
+	}
+	outputs, err := collectOutputs(outputDir)
+	if err != nil {
+		return Transaction{}, err
+	}
+	plaintextHash := resultHash(result)
+
+	if job.Recipient != "" {
+		recipient, _ := parsePayloadPublicKey(job.Recipient)
+		sealed, err := sealPayload(recipient, []byte(result))
+		if err != nil {
+			return Transaction{}, fmt.Errorf("failed to encrypt result: %v", err)
+		}
+		result = hex.EncodeToString(sealed)
+	}
+
+	// Create a transaction from the result
+	transaction := Transaction{
+		Data:         result,
+		Recipient:    job.Recipient,
+		ScriptCID:    scriptHash,
+		DataCID:      dataHash,
+		ResultHash:   plaintextHash,
+		Runtime:      job.Runtime,
+		Env:          job.Env,
+		Params:       job.Params,
+		Outputs:      outputs,
+		Units:        units,
+		LockHeight:   job.LockHeight,
+		ExpiryHeight: jobExpiryHeight(),
+		LockTime:     job.LockTime,
+		Fee:          job.Fee,
+		NetworkID:    networkID,
+	}
+	if err := storeResult(&transaction, result, outputDir); err != nil {
+		return Transaction{}, err
+	}
+
+	// Proofs attest to the plaintext result of the script on its data alone,
+	// so sealed results, scripts run with parameters and scripts leaving
+	// output files carry none
+	if job.Recipient == "" && job.Params == nil && len(outputs) == 0 {
+		if err := attachExecutionProof(&transaction, scriptPath, dataPath, result); err != nil {
+			return Transaction{}, err
+		}
+	}
+
+	// Sign the transaction as its sender
+	if err := signTransaction(&transaction); err != nil {
+		return Transaction{}, err
+	}
+
+	// Add the transaction to the mempool
+	receipts.Pending(job.ID, transaction.ID)
+	if err := mempool.Add(transaction, priorityNormal); err != nil {
+		return Transaction{}, fmt.Errorf("failed to add transaction %s: %v", transaction.ID, err)
+	}
+	txprocLog.Info("Transaction created and added to mempool", "tx", transaction.ID, "script", scriptHash, "data", dataHash)
+	fireWebhook(eventJobCompleted, transaction)
+	relayTransactions([]Transaction{transaction})
+	return transaction, nil
+}
+
 // Mining Thread
-func startMining(prevHash, prevCID string, wg *sync.WaitGroup) {
+func startMining(ctx context.Context, wg *sync.WaitGroup) {
 	defer wg.Done()
 
+	waitingSince := time.Now()
 	for {
 		select {
-		case <-stopMining:
-			fmt.Println("Stopping mining thread...")
+		case <-ctx.Done():
+			miningLog.Info("Stopping mining thread")
 			return
 		default:
-			// Wait for exactly 3 transactions
-			transactions := make([]Transaction, 0, 3)
-			for len(transactions) < 3 {
-				tx := <-transactionBuffer // This blocks until a transaction is available
-				transactions = append(transactions, tx)
-				fmt.Println("Added transaction to block:", tx)
+			// Validator mode: idle until mining is switched back on
+			if !miningEnabled.Load() {
+				select {
+				case <-miningToggled:
+				case <-ctx.Done():
+				}
+				waitingSince = time.Now()
+				continue
 			}
 
-			// Perform proof of work
-			nonce := 0
-			for {
+			// Forget tips seen before we read the tip below
+			select {
+			case <-newTip:
+			default:
+			}
+
+			// The block extends the stored chain, which always holds at
+			// least the genesis block. After upload a block's PrevCID field
+			// holds its own CID, which is the next block's PrevCID.
+			height := chainStore.Height()
+			tip, _ := chainStore.Tip()
+			prevHash, prevCID := tip.Hash, tip.PrevCID
+
+			// Wait until enough final transactions are pending or the block
+			// interval has passed; time-locked ones stay pending. Finality
+			// is judged by the timestamp the block will carry.
+			timestamp := nextBlockTimestamp(prevHash)
+			transactions, ok := mempool.selectForBlock(height, timestamp, waitingSince)
+			if !ok {
 				select {
-				case <-stopMining:
-					return
-				default:
-					blockData := fmt.Sprintf("%s:%v:%d", prevHash, transactions, nonce)
-					hash := sha256.Sum256([]byte(blockData))
-					hashInt := new(big.Int).SetBytes(hash[:])
-					if hashInt.Cmp(target) == -1 {
-						block := Block{
-							PrevHash:     prevHash,
-							Transactions: transactions,
-							Nonce:        nonce,
-							Hash:         hex.EncodeToString(hash[:]),
-							PrevCID:      prevCID,
-							BlockNumber:  minedBlocks,
-						}
-						fmt.Println("Mined a new block:", block.Hash)
-
-						// Update mined blocks count
-						minedBlocks++
-
-						// Upload block to IPFS and get its CID
-						blockCID, err := uploadBlockToIPFS(block)
-						if err != nil {
-							fmt.Println("Error uploading block to IPFS:", err)
-							continue
-						}
-						block.PrevCID = blockCID
-
-						// Broadcast the new block to connected miners
-						for _, miner := range connectedMiners {
-							sendBlockToMiner(miner, block)
-						}
-
-						// Add block to the newBlock channel
-						newBlock <- block
-						return
-					}
-					nonce++
+				case <-mempool.Ready():
+				case <-time.After(time.Second):
+				case <-ctx.Done():
+				}
+				continue
+			}
+
+			// Sign the block as its miner, then seal it, by default with
+			// proof of work over the canonical serialization
+			template := blockTemplate(height, prevHash, prevCID, transactions)
+			template.Timestamp = timestamp
+			if err := signBlockHeader(&template); err != nil {
+				miningLog.Error("Error signing block", "height", height, "err", err)
+				select {
+				case <-time.After(time.Second):
+				case <-ctx.Done():
+				}
+				continue
+			}
+			block, found := consensus.Seal(ctx, newTip, template)
+			if !found {
+				// Shutting down, mining switched off, or another block
+				// became the tip. Our transactions are still pending unless
+				// the new tip included them, so the next attempt picks them
+				// up again.
+				if ctx.Err() == nil && miningEnabled.Load() {
+					miningLog.Info("Chain tip changed, restarting mining", "height", height)
 				}
+				continue
 			}
+			miningLog.Info("Mined a new block", "hash", block.Hash, "height", block.BlockNumber, "nonce", block.Nonce)
+
+			// Update mined blocks count
+			nodeState.RecordMined()
+
+			if err := publishBlock(block); err != nil {
+				miningLog.Error("Error publishing mined block", "hash", block.Hash, "err", err)
+				continue
+			}
+			waitingSince = time.Now()
+		}
+	}
+}
+
+// Build the block to mine at the given height on top of prevHash, paying
+// ourselves the block reward and the fees of the transactions.
+func blockTemplate(height int, prevHash, prevCID string, transactions []Transaction) Block {
+	transactions = append([]Transaction{coinbaseTransaction(height, walletAddress, blockFees(transactions))}, transactions...)
+	block := Block{
+		PrevHash:     prevHash,
+		MerkleRoot:   computeMerkleRoot(transactions),
+		Transactions: transactions,
+		PrevCID:      prevCID,
+		BlockNumber:  height,
+		Timestamp:    nextBlockTimestamp(prevHash),
+		NetworkID:    networkID,
+		Version:      headerHashVersion,
+	}
+	consensus.PrepareBlock(&block)
+	return block
+}
+
+// Target a block on top of prevHash must declare.
+func templateTarget(prevHash string) *big.Int {
+	if head, _ := chainHead(); head == prevHash {
+		return currentTarget()
+	}
+	if parent, ok := lookupBlock(prevHash); ok {
+		if next, err := consensus.CalcDifficulty(parent, lookupBlock); err == nil {
+			return next
 		}
 	}
+	return currentTarget()
+}
+
+// Upload a mined block to IPFS, store it, and broadcast it to the miners.
+func publishBlock(block Block) error {
+	// Upload block to IPFS and get its CID
+	blockCID, err := uploadBlockToIPFS(block)
+	if err != nil {
+		return err
+	}
+	block.PrevCID = blockCID
+
+	// Persist the block before announcing it
+	if err := acceptBlock(block); err != nil {
+		return fmt.Errorf("failed to store block: %v", err)
+	}
+
+	// Broadcast the new block to connected miners
+	peerManager.Broadcast(block)
+	return nil
 }
 
 // Broadcast block to other miners
 func sendBlockToMiner(miner string, block Block) {
-	conn, err := net.Dial("tcp", miner+":8081")
+	// Wrap the block with this node's signed vote
+	vote, err := signVote(block)
 	if err != nil {
-		fmt.Println("Error connecting to miner:", err)
+		p2pLog.Error("Error signing block vote", "hash", block.Hash, "err", err)
 		return
 	}
-	defer conn.Close()
+	announcement := blockAnnouncement{Vote: vote}
 
-	// Serialize block to JSON
-	blockData, err := json.Marshal(block)
-	if err != nil {
-		fmt.Println("Error serializing block to JSON:", err)
+	// Announce the hash to miners that fetch what they miss, and send
+	// older miners the whole block
+	if peerManager.takesInventory(miner) {
+		inv := inventoryOf(block)
+		announcement.Inventory = &inv
+	} else if announcement.Block, err = json.Marshal(block); err != nil {
+		p2pLog.Error("Error serializing block to JSON", "hash", block.Hash, "err", err)
 		return
 	}
 
-	// Send serialized block data
-	_, err = conn.Write(blockData)
-	if err != nil {
-		fmt.Println("Error sending block to miner:", err)
+	// Send it over the configured transport
+	if err := transport.SendBlock(miner, announcement); err != nil {
+		p2pLog.Warn("Error sending block to miner", "peer", miner, "hash", block.Hash, "err", err)
 	}
 }
 
-
 // Upload block to IPFS and return its CID
 func uploadBlockToIPFS(block Block) (string, error) {
-	blockData := fmt.Sprintf("%v", block)
+	// Store the canonical serialization, from which the block hash is
+	// recomputed
+	blockData := block.Serialize()
 
-	// Add the block to IPFS
-	cid, err := ipfsShell.Add(strings.NewReader(blockData))
+	// Add and pin the block so IPFS garbage collection keeps it
+	cid, err := ipfsShell.Add(bytes.NewReader(blockData), shell.Pin(true))
 	if err != nil {
 		return "", fmt.Errorf("failed to upload block to IPFS: %v", err)
 	}
+	ipfsLog.Debug("Uploaded block", "hash", block.Hash, "cid", cid)
 
 	return cid, nil
 }
 
+// Download a block uploaded by uploadBlockToIPFS. As for stored blocks,
+// the returned block's PrevCID field holds its own CID. Cancelling ctx
+// aborts the transfer.
+func downloadBlockFromIPFS(ctx context.Context, cid string) (Block, error) {
+	reader, err := ipfsShell.Cat(cid)
+	if err != nil {
+		return Block{}, fmt.Errorf("failed to fetch block from IPFS: %v", err)
+	}
+	defer reader.Close()
+	stop := context.AfterFunc(ctx, func() { reader.Close() })
+	defer stop()
+
+	data, err := io.ReadAll(io.LimitReader(reader, int64(*maxMessageSize)+1))
+	if err != nil {
+		return Block{}, fmt.Errorf("failed to read block from IPFS: %v", err)
+	}
+	if len(data) > *maxMessageSize {
+		return Block{}, fmt.Errorf("block %s exceeds the maximum message size", cid)
+	}
+
+	block, err := parseBlockSerialization(data)
+	if err != nil {
+		return Block{}, err
+	}
+	block.PrevCID = cid
+	return block, nil
+}
+
 // Block Reception and Validation Thread
-func receiveAndValidateBlocks(wg *sync.WaitGroup) {
+func receiveAndValidateBlocks(ctx context.Context, wg *sync.WaitGroup) {
 	defer wg.Done()
 
-	ln, err := net.Listen("tcp", ":8081")
+	ln, err := listenMiners(*blockAddr)
 	if err != nil {
-		fmt.Println("Error starting block listener:", err)
+		p2pLog.Error("Error starting block listener", "err", err)
 		return
 	}
-	defer ln.Close()
+	context.AfterFunc(ctx, func() { ln.Close() })
 
+	// Connections being handled; shutdown waits for blocks in flight
+	var handlers sync.WaitGroup
 	for {
 		conn, err := ln.Accept()
 		if err != nil {
-			fmt.Println("Error accepting block connection:", err)
+			if ctx.Err() != nil {
+				handlers.Wait()
+				return
+			}
+			p2pLog.Error("Error accepting block connection", "err", err)
 			continue
 		}
 
+		handlers.Add(1)
 		go func(conn net.Conn) {
+			defer handlers.Done()
 			defer conn.Close()
+			stop := context.AfterFunc(ctx, func() { conn.Close() })
+			defer stop()
 
-			scanner := bufio.NewScanner(conn)
-			for scanner.Scan() {
-				blockData := scanner.Text()
-				fmt.Println("Received block:", blockData)
+			connIdentity, err := minerIdentity(conn)
+			if err != nil {
+				p2pLog.Warn("Error identifying miner", "peer", conn.RemoteAddr(), "err", err)
+				return
+			}
 
-				// Deserialize block data into Block struct
-				var block Block
-				err := json.Unmarshal([]byte(blockData), &block)
+			// Every connection opens with a hello from each side
+			reader := bufio.NewReader(conn)
+			hello, err := serverHandshake(conn, reader)
+			if err != nil {
+				p2pLog.Warn("Handshake failed", "peer", conn.RemoteAddr(), "node", hello.NodeID, "agent", hello.Agent, "err", err)
+				return
+			}
+
+			// A node ID proven in the hello identifies the miner across
+			// addresses. With -tls-identity it must be the TLS key's.
+			if hello.NodeID != "" {
+				if identityTLS != nil && hello.NodeID != connIdentity {
+					p2pLog.Warn("Hello node ID does not match TLS key", "peer", conn.RemoteAddr(), "node", hello.NodeID, "tlsNode", connIdentity)
+					return
+				}
+				connIdentity = hello.NodeID
+			}
+
+			for {
+				frameType, payload, err := readFrame(reader)
 				if err != nil {
-					fmt.Println("Error decoding block data:", err)
-					continue
+					penalizeFrameError(conn.RemoteAddr(), connIdentity, err)
+					return
+				}
+				if connBanned(conn.RemoteAddr(), connIdentity) {
+					p2pLog.Warn("Dropping connection from banned peer", "peer", conn.RemoteAddr(), "node", connIdentity)
+					return
 				}
 
-				// Validate the block
-				if validateBlock(blockData, "-1", target) {
-					blockHash := getBlockHash(blockData)
-					blockValidations[blockHash]++
-					if blockValidations[blockHash] > len(connectedMiners)/2 {
-						fmt.Println("Block validated and added to blockchain.")
+				// Peer control messages are answered on the same connection
+				if frameType == framePeer {
+					var control peerMessage
+					err := json.Unmarshal(payload, &control)
+					if err == nil {
+						err = handlePeerMessage(conn, hello, control)
+					}
+					if err != nil {
+						p2pLog.Warn("Error handling peer message", "peer", conn.RemoteAddr(), "node", connIdentity, "type", control.Type, "err", err)
+						penalizeNode(conn.RemoteAddr(), connIdentity, scoreMalformedMessage, "bad peer message")
+					}
+					continue
+				}
+				if frameType == frameTransactions {
+					var txs []Transaction
+					if err := json.Unmarshal(payload, &txs); err != nil {
+						p2pLog.Warn("Error decoding relayed transactions", "peer", conn.RemoteAddr(), "node", connIdentity, "err", err)
+						penalizeNode(conn.RemoteAddr(), connIdentity, scoreMalformedMessage, "malformed transactions")
+						continue
 					}
+					handleRelayedTransactions(txs, conn.RemoteAddr(), connIdentity)
+					continue
+				}
+				if frameType != frameBlock {
+					p2pLog.Warn("Unknown frame type", "peer", conn.RemoteAddr(), "node", connIdentity, "type", frameType)
+					penalizeNode(conn.RemoteAddr(), connIdentity, scoreMalformedMessage, "unknown frame type")
+					continue
+				}
+
+				// Unwrap the announcement. Bare blocks from older nodes count
+				// as a vote by the connection's identity.
+				var announcement blockAnnouncement
+				if err := json.Unmarshal(payload, &announcement); err != nil || (announcement.Block == nil && announcement.Inventory == nil) {
+					announcement = blockAnnouncement{Block: json.RawMessage(payload)}
+				}
+				if !handleBlockAnnouncement(announcement, connIdentity, hello.NodeID, conn.RemoteAddr()) {
+					return
 				}
 			}
 		}(conn)
@@ -292,62 +692,504 @@ func receiveAndValidateBlocks(wg *sync.WaitGroup) {
 }
 
 
+// Validate an announced block and count its vote. sender is the identity
+// of the connection it arrived on, nodeID the node ID the peer proved in its
+// hello (empty if it proved none) and addr its address. Reports false if the
+// connection should be dropped.
+func handleBlockAnnouncement(announcement blockAnnouncement, sender, nodeID string, addr net.Addr) bool {
+	// Fetch blocks announced by inventory unless we have them already
+	if announcement.Block == nil && announcement.Inventory != nil {
+		inv := *announcement.Inventory
+		if knownBlock(inv.Hash) {
+			p2pLog.Debug("Ignoring inventory of known block", "peer", addr, "hash", inv.Hash)
+			return true
+		}
+		block, err := fetchAnnouncedBlock(inv, addr)
+		if err != nil {
+			p2pLog.Warn("Could not fetch announced block", "peer", addr, "node", sender, "hash", inv.Hash, "height", inv.Height, "err", err)
+			return true
+		}
+		if announcement.Block, err = json.Marshal(block); err != nil {
+			return true
+		}
+	}
+
+	blockData := string(announcement.Block)
+	p2pLog.Debug("Received block", "peer", addr, "block", blockData)
+
+	// Deserialize block data into Block struct
+	var block Block
+	err := json.Unmarshal([]byte(blockData), &block)
+	if err != nil {
+		p2pLog.Warn("Error decoding block data", "peer", addr, "node", sender, "err", err)
+		penalizeNode(addr, sender, scoreMalformedMessage, "malformed block")
+		return true
+	}
+
+	voter := sender
+	if announcement.Vote != nil {
+		voter, err = verifyVote(*announcement.Vote, block)
+		if err != nil {
+			p2pLog.Warn("Invalid block vote", "peer", addr, "node", sender, "hash", block.Hash, "err", err)
+			penalizeNode(addr, sender, scoreMalformedMessage, "invalid vote signature")
+			return true
+		}
+
+		// A peer votes only as the node it proved to be; otherwise one peer
+		// could sign with fresh keys and make up a majority alone
+		switch {
+		case nodeID == "":
+			voter = sender
+		case voter != nodeID:
+			p2pLog.Warn("Block vote signed by another node", "peer", addr, "node", sender, "hash", block.Hash, "voter", voter)
+			penalizeNode(addr, sender, scoreMalformedMessage, "vote signed by another node")
+			return true
+		}
+		if peerBanned(voter) {
+			p2pLog.Warn("Dropping connection from banned node", "node", voter)
+			return false
+		}
+	}
+
+	// Copies of blocks we already have and repeated votes change nothing,
+	// so they are not validated again
+	blockHash := block.ComputeHash()
+	if knownBlock(blockHash) {
+		p2pLog.Debug("Ignoring known block", "peer", addr, "hash", blockHash)
+		return true
+	}
+	if nodeState.HasVote(blockHash, voter) {
+		p2pLog.Debug("Ignoring duplicate vote", "peer", addr, "hash", blockHash, "voter", voter)
+		return true
+	}
+
+	// Validate the block, holding it to the target its parent implies when
+	// the parent is known; acceptBlock checks the target exactly
+	limit := maxTarget
+	if parent, ok := lookupBlock(block.PrevHash); ok {
+		if expected, err := consensus.CalcDifficulty(parent, lookupBlock); err == nil {
+			limit = expected
+		}
+	}
+
+	// A seal costs nothing to check, so whoever relays a bad one is at
+	// fault, whatever miner it names
+	if block.Hash != blockHash || consensus.VerifySeal(block, limit) != nil {
+		p2pLog.Warn("Block fails its proof of work", "peer", addr, "node", sender, "hash", block.Hash)
+		penalizeNode(addr, sender, scoreInvalidProofOfWork, "invalid proof of work")
+		return !connBanned(addr, sender)
+	}
+	if !validateBlock(blockData, "-1", limit) {
+		recordValidationFailure(voter)
+		penalizeIdentity(voter, scoreInvalidBlock, "invalid block")
+		return true
+	}
+
+	// Only distinct validators count toward the majority
+	votes, isNew := nodeState.AddVote(blockHash, block.BlockNumber, voter)
+	if isNew && votes > peerManager.Count()/2 {
+		stored, err := acceptOrOrphan(block, addr)
+		if err != nil {
+			chainLog.Error("Error storing validated block", "hash", block.Hash, "err", err)
+			return true
+		}
+		if stored {
+			chainLog.Info("Block validated and added to blockchain", "hash", block.Hash, "height", block.BlockNumber, "votes", votes)
+		}
+		nodeState.ForgetVotes(blockHash)
+		nodeState.PruneVotes(chainStore.Height() - maxForkDepth)
+	}
+	return true
+}
+
 // Validate a block
-func validateBlock(blockData string, prevHash string, target *big.Int) bool {
+func validateBlock(blockData string, prevHash string, target *big.Int) (valid bool) {
 	var block Block
 	err := json.Unmarshal([]byte(blockData), &block)
 	if err != nil {
-		fmt.Println("Error decoding block data:", err)
+		chainLog.Warn("Invalid block: cannot decode", "err", err)
+		return false
+	}
+
+	// Only the genesis block, which is built locally, has no parent
+	if block.BlockNumber == 0 || block.PrevHash == "-1" {
+		chainLog.Warn("Invalid block: mined block claims to be a genesis block", "hash", block.Hash)
+		return false
+	}
+
+	// Blocks of other networks never mix with ours
+	if block.NetworkID != networkID {
+		chainLog.Warn("Invalid block: wrong network", "hash", block.Hash, "network", block.NetworkID, "expected", networkID)
+		return false
+	}
+
+	// Each layout has one Version value, so a block has one encoding
+	if block.Version != 0 && block.Version != headerHashVersion {
+		chainLog.Warn("Invalid block: unknown version", "hash", block.Hash, "version", block.Version)
 		return false
 	}
 
 	// Check previous hash
 	if prevHash != "-1" && block.PrevHash != prevHash {
-		fmt.Printf("Invalid block: Previous hash mismatch.\n")
+		chainLog.Warn("Invalid block: previous hash mismatch", "hash", block.Hash, "prevHash", block.PrevHash, "expected", prevHash)
+		return false
+	}
+
+	// Verify the seal: the hash must be the block's canonical hash, and
+	// the consensus engine must accept it under a target no easier than
+	// target
+	if block.Hash != block.ComputeHash() {
+		chainLog.Warn("Invalid block: hash does not match contents", "hash", block.Hash)
+		return false
+	}
+	if err := consensus.VerifySeal(block, target); err != nil {
+		chainLog.Warn("Invalid block: bad seal", "hash", block.Hash, "err", err)
+		return false
+	}
+
+	// The miner must have signed the header. From here on a rejected block
+	// is held against its miner.
+	miner, err := verifyBlockHeader(block)
+	if err != nil {
+		chainLog.Warn("Invalid block: bad miner signature", "hash", block.Hash, "err", err)
+		return false
+	}
+	defer func() {
+		if !valid {
+			nodeState.RecordInvalidBlock(miner)
+		}
+	}()
+
+	// Reject timestamps too far ahead of our clock
+	if time.Unix(block.Timestamp, 0).After(time.Now().Add(maxFutureBlockTime)) {
+		chainLog.Warn("Invalid block: timestamp too far in the future", "hash", block.Hash, "timestamp", block.Timestamp)
+		return false
+	}
+
+	// Light nodes check headers only and trust the transactions to the
+	// proof of work
+	if *lightMode {
+		return true
+	}
+
+	// Check the Merkle root commits to these transactions
+	if block.MerkleRoot != computeMerkleRoot(block.Transactions) {
+		chainLog.Warn("Invalid block: Merkle root mismatch", "hash", block.Hash)
 		return false
 	}
 
 	// Validate transactions
-	for _, tx := range block.Transactions {
-		if tx.ID == "" {
+	for i, tx := range block.Transactions {
+		if tx.Coinbase != nil {
+			if i != 0 {
+				chainLog.Warn("Invalid block: coinbase is not the first transaction", "hash", block.Hash, "tx", tx.ID)
+				return false
+			}
+			if err := checkCoinbase(tx, block.BlockNumber, blockFees(block.Transactions)); err != nil {
+				chainLog.Warn("Invalid block: bad coinbase", "hash", block.Hash, "err", err)
+				return false
+			}
+			continue
+		}
+		if err := checkTransaction(tx); err != nil {
+			chainLog.Warn("Invalid block: bad transaction", "hash", block.Hash, "err", err)
+			return false
+		}
+		if !tx.isFinal(block.BlockNumber, block.Timestamp) {
+			chainLog.Warn("Invalid block: transaction is still time-locked", "hash", block.Hash, "tx", tx.ID)
+			return false
+		}
+		if tx.expired(block.BlockNumber) {
+			chainLog.Warn("Invalid block: transaction has expired", "hash", block.Hash, "tx", tx.ID, "expiryHeight", tx.ExpiryHeight)
+			return false
+		}
+	}
+
+	// Computation credited as work must add up to what the header claims
+	if want := creditedUnits(block.Transactions); block.Units != want {
+		chainLog.Warn("Invalid block: wrong execution units", "hash", block.Hash, "units", block.Units, "expected", want)
+		return false
+	}
+
+	// Optionally check the computations themselves; credited computation
+	// is always checked
+	if *verifyExecution || creditsComputation() {
+		if err := verifyBlockExecution(block); err != nil {
+			chainLog.Warn("Invalid block: result does not match re-execution", "hash", block.Hash, "err", err)
 			return false
 		}
 	}
 	return true
 }
+
+// Check a transaction's ID, signature and execution proof. Coinbase
+// transactions are checked by the block that contains them.
+func checkTransaction(tx Transaction) error {
+	if tx.Coinbase != nil {
+		return fmt.Errorf("transaction %s is a coinbase", tx.ID)
+	}
+	if tx.ID == "" || tx.ID != generateTransactionID(transactionIDSource(tx)) {
+		return fmt.Errorf("transaction ID %q does not match its data", tx.ID)
+	}
+	if tx.Fee < 0 {
+		return fmt.Errorf("transaction %s has a negative fee", tx.ID)
+	}
+	if err := checkUnits(tx); err != nil {
+		return fmt.Errorf("transaction %s: %v", tx.ID, err)
+	}
+	if tx.NetworkID != networkID {
+		return fmt.Errorf("transaction %s is for network %q, not %q", tx.ID, tx.NetworkID, networkID)
+	}
+	// A result only stored in IPFS is checked by re-execution instead
+	if tx.Recipient == "" && tx.ResultHash != "" && transactionIDSource(tx) == tx.Data && tx.ResultHash != resultHash(tx.Data) {
+		return fmt.Errorf("transaction %s claims a result hash that does not match its data", tx.ID)
+	}
+	if err := validateArtifacts(tx); err != nil {
+		return fmt.Errorf("transaction %s: %v", tx.ID, err)
+	}
+	if _, err := lookupRuntime(tx.Runtime); err != nil {
+		return fmt.Errorf("transaction %s: %v", tx.ID, err)
+	}
+	if tx.Env != nil {
+		if err := tx.Env.Validate(); err != nil {
+			return fmt.Errorf("transaction %s: %v", tx.ID, err)
+		}
+	}
+	if tx.Params != nil {
+		if err := tx.Params.Validate(); err != nil {
+			return fmt.Errorf("transaction %s: %v", tx.ID, err)
+		}
+		if tx.Proof != nil {
+			return fmt.Errorf("transaction %s: execution proofs do not cover script parameters", tx.ID)
+		}
+	}
+	if _, err := verifyTransactionSignature(tx); err != nil {
+		return fmt.Errorf("transaction %s: %v", tx.ID, err)
+	}
+	if tx.Proof != nil {
+		if err := verifyExecutionProof(tx); err != nil {
+			return fmt.Errorf("transaction %s: %v", tx.ID, err)
+		}
+	}
+	return nil
+}
+
 func generateTransactionID(data string) string {
 	hash := sha256.Sum256([]byte(data))
 	return hex.EncodeToString(hash[:])
 }
 
-func getBlockHash(blockData string) string {
-	hash := sha256.Sum256([]byte(blockData))
-	return hex.EncodeToString(hash[:])
-}
 // Main function
 func main() {
+	// `client` submits a job to a node instead of running one
+	if len(os.Args) > 1 && os.Args[1] == "client" {
+		os.Exit(runClient(os.Args[2:]))
+	}
+	// `bench` measures hashing, mining and validation speed
+	if len(os.Args) > 1 && os.Args[1] == "bench" {
+		os.Exit(runBench(os.Args[2:]))
+	}
+	// `devnet` writes and starts a network of local nodes
+	if len(os.Args) > 1 && os.Args[1] == "devnet" {
+		os.Exit(runDevnet(os.Args[2:]))
+	}
+	// `export-chain` and `import-chain` back up and restore the local chain
+	if len(os.Args) > 1 && (os.Args[1] == "export-chain" || os.Args[1] == "import-chain") {
+		os.Exit(runChainFileCommand(os.Args[1], os.Args[2:]))
+	}
+
+	flag.Parse()
+	if err := loadConfig(); err != nil {
+		fmt.Println("Error loading configuration:", err)
+		os.Exit(1)
+	}
+
+	// Cancelled on SIGINT or SIGTERM to shut everything down
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := setupLogging(); err != nil {
+		fmt.Println("Error configuring logging:", err)
+		os.Exit(1)
+	}
+
+	if err := setupNodeIdentity(); err != nil {
+		nodeLog.Error("Error loading node identity", "err", err)
+		os.Exit(1)
+	}
+	if err := setupWallet(); err != nil {
+		nodeLog.Error("Error loading wallet", "err", err)
+		os.Exit(1)
+	}
+	if err := setupMinerTLS(); err != nil {
+		nodeLog.Error("Error configuring miner TLS", "err", err)
+		os.Exit(1)
+	}
+	if err := setupAdmin(); err != nil {
+		nodeLog.Error("Error configuring admin API", "err", err)
+		os.Exit(1)
+	}
+	if err := setupPayloadKey(); err != nil {
+		nodeLog.Error("Error loading payload key", "err", err)
+		os.Exit(1)
+	}
+	if err := setupJobDirs(); err != nil {
+		nodeLog.Error("Error preparing job directories", "err", err)
+		os.Exit(1)
+	}
+	if err := setupRuntimes(); err != nil {
+		nodeLog.Error("Error configuring script runtimes", "err", err)
+		os.Exit(1)
+	}
+	if err := setupSandbox(); err != nil {
+		nodeLog.Error("Error configuring script sandbox", "err", err)
+		os.Exit(1)
+	}
+	if err := setupWebhooks(); err != nil {
+		nodeLog.Error("Error configuring webhooks", "err", err)
+		os.Exit(1)
+	}
+	if err := setupAnchor(); err != nil {
+		nodeLog.Error("Error configuring anchoring", "err", err)
+		os.Exit(1)
+	}
+	if err := setupProofSystem(); err != nil {
+		nodeLog.Error("Error configuring proof system", "err", err)
+		os.Exit(1)
+	}
+	if err := setupTransport(); err != nil {
+		nodeLog.Error("Error configuring transport", "err", err)
+		os.Exit(1)
+	}
+	if err := setupConsensus(); err != nil {
+		nodeLog.Error("Error configuring consensus", "err", err)
+		os.Exit(1)
+	}
+	if err := setupGenesis(); err != nil {
+		nodeLog.Error("Error loading genesis", "err", err)
+		os.Exit(1)
+	}
+	ipfsShell = withGatewayFallback(newResilientIPFS(shell.NewShell(*ipfsAPI)))
+	miningEnabled.Store(!*noMine)
+
 	// WaitGroup for managing goroutines
 	var wg sync.WaitGroup
 
-	// Initialize variables for genesis block
-	prevHash := "-1" // Placeholder for genesis block
-	prevCID := "-1"  // Placeholder for genesis block CID
+	// Open the local chain
+	store, err := OpenChainStore(chainDataDir())
+	if err != nil {
+		nodeLog.Error("Error opening chain store", "err", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+	chainStore = store
+	if staleBlocks, err = OpenStaleLog(*dataDir); err != nil {
+		nodeLog.Error("Error opening stale block log", "err", err)
+		os.Exit(1)
+	}
+	defer staleBlocks.Close()
+	if err := fastSync(); err != nil {
+		nodeLog.Error("Error fast-syncing from checkpoint", "err", err)
+		os.Exit(1)
+	}
+	if err := ledger.Load(store); err != nil {
+		nodeLog.Error("Error loading ledger", "err", err)
+		os.Exit(1)
+	}
+	useCheckpointTarget()
+	if err := initGenesis(); err != nil {
+		nodeLog.Error("Error initializing chain", "err", err)
+		os.Exit(1)
+	}
+
+	if tip, ok := chainStore.Tip(); ok {
+		chainLog.Info("Loaded chain", "height", chainStore.Height(), "tip", tip.Hash)
+	}
+	if err := loadMempool(); err != nil {
+		nodeLog.Error("Error loading mempool", "err", err)
+		os.Exit(1)
+	}
+	if err := loadBans(); err != nil {
+		nodeLog.Error("Error loading peer bans", "err", err)
+		os.Exit(1)
+	}
+
+	// Start from the bootstrap miners
+	peerManager = NewPeerManager(strings.Split(*bootstrapPeers, ","))
+
+	// Add goroutines to process transactions; light nodes run no jobs
+	if !*lightMode {
+		wg.Add(1)
+		go runJobWorkers(ctx, &wg)
+		wg.Add(1)
+		go processTransactions(ctx, &wg)
+	}
 
-	// Add goroutines to process transactions
+	// Keep pending transactions across restarts
 	wg.Add(1)
-	go processTransactions(&wg)
+	go persistMempool(ctx, &wg)
 
 	// Add goroutines to receive and validate blocks
 	wg.Add(1)
-	go receiveAndValidateBlocks(&wg)
+	if *transportName == "grpc" {
+		go serveGRPC(ctx, &wg)
+	} else {
+		go receiveAndValidateBlocks(ctx, &wg)
+	}
 
-	// Simulate some connected miners (replace with real IPs in a network)
-	connectedMiners = append(connectedMiners, "127.0.0.1") // Example miner IP
+	// Bootstrap miners from DNS seeds, then keep re-resolving them
+	if *dnsSeeds != "" {
+		resolveDNSSeeds()
+		go refreshDNSSeeds(ctx)
+	}
 
-	// Start mining process
-	wg.Add(1)
-	go startMining(prevHash, prevCID, &wg)
+	// Deliver chain event notifications
+	if *webhookURLs != "" {
+		go deliverWebhooks(ctx)
+	}
+
+	// Ping peers, exchange peer lists and drop dead peers
+	go peerManager.Run(ctx)
+
+	// Forgive old misbehaviour and forget peers that have gone
+	go maintainPeerLimits(ctx)
+
+	// Watch for anomalies
+	go monitorAlerts(ctx)
+
+	// Fall back to IPFS gateways while the daemon is down
+	go monitorIPFS(ctx)
+
+	// Keep the chain pinned in IPFS
+	if *pinRepairInterval > 0 {
+		wg.Add(1)
+		go maintainPins(ctx, &wg)
+	}
+
+	// Periodically anchor the chain head to an external chain
+	if anchor != nil {
+		wg.Add(1)
+		go anchorChainHead(ctx, &wg)
+	}
+
+	if *apiAddr != "" {
+		go serveAPI(ctx)
+	}
+	if *adminAddr != "" {
+		go serveAdmin(ctx)
+	}
+
+	// Catch up with the network before mining on our own tip
+	syncChain(ctx)
+
+	// Start mining process; it idles while the node runs as a validator.
+	// Light nodes cannot build blocks without the full chain.
+	if !*lightMode {
+		wg.Add(1)
+		go startMining(ctx, &wg)
+	}
 
-	// Wait for all goroutines to finish
+	// Wait for all goroutines to finish, then close the store
 	wg.Wait()
+	nodeLog.Info("Shut down cleanly")
 }
\ No newline at end of file
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// Use a fresh wallet, an easy target and a chain holding only the test
+// genesis block for the duration of a test.
+func setupTestMiner(t *testing.T) {
+	t.Helper()
+	if err := useGenesis(testGenesis); err != nil {
+		t.Fatal(err)
+	}
+	miningEnabled.Store(true)
+
+	store, err := OpenChainStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	stale, err := OpenStaleLog(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { stale.Close() })
+	chainStore, ledger, mempool, sideBlocks, staleBlocks = store, NewLedger(), NewMempool(), make(map[string]sideBlock), stale
+	if err := initGenesis(); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := generateSigningKey(sigAlgECDSAP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	walletSigner = cryptoSigner{key: key}
+	if walletAddress, err = nodeIDFromPublicKey(key.Public()); err != nil {
+		t.Fatal(err)
+	}
+	identity, err := generateSigningKey(sigAlgEd25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodeSigner = cryptoSigner{key: identity}
+	if nodeID, err = nodeIDFromPublicKey(identity.Public()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// A block template signed by this node.
+func signedTestTemplate(t *testing.T, height int, parent Block, transactions []Transaction) Block {
+	t.Helper()
+	block := blockTemplate(height, parent.Hash, parent.PrevCID, transactions)
+	if err := signBlockHeader(&block); err != nil {
+		t.Fatal(err)
+	}
+	return block
+}
+
+func signedTestTransaction(t *testing.T, data string) Transaction {
+	t.Helper()
+	tx := Transaction{ID: generateTransactionID(data), Data: data, NetworkID: networkID}
+	if err := signTransaction(&tx); err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+// Mine block 1 on the test genesis holding the given transactions.
+func mineTestBlock(t *testing.T, transactions ...Transaction) Block {
+	t.Helper()
+	block, found := mineBlock(context.Background(), nil, signedTestTemplate(t, 1, genesisBlock, transactions))
+	if !found {
+		t.Fatal("mining was interrupted")
+	}
+	return block
+}
+
+// A different hex digit than c.
+func flipHex(c byte) string {
+	if c == '0' {
+		return "1"
+	}
+	return "0"
+}
+
+func TestMineBlockMeetsTarget(t *testing.T) {
+	setupTestMiner(t)
+	block := mineTestBlock(t)
+
+	if block.Hash != block.ComputeHash() {
+		t.Fatalf("block hash %s is not its computed hash %s", block.Hash, block.ComputeHash())
+	}
+	hash, _ := new(big.Int).SetString(block.Hash, 16)
+	if hash.Cmp(currentTarget()) >= 0 {
+		t.Fatalf("block hash %s does not meet the target", block.Hash)
+	}
+}
+
+func TestMineBlockStops(t *testing.T) {
+	setupTestMiner(t)
+	impossible := blockTemplate(1, genesisBlock.Hash, genesisBlock.PrevCID, nil)
+	impossible.Target = "0" // No hash is below zero
+
+	abort := make(chan struct{})
+	close(abort)
+	if _, found := mineBlock(context.Background(), abort, impossible); found {
+		t.Error("mining continued after abort")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, found := mineBlock(ctx, nil, impossible); found {
+		t.Error("mining continued after cancellation")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	miningEnabled.Store(false)
+	defer miningEnabled.Store(true)
+	if _, found := mineBlock(ctx, nil, impossible); found || ctx.Err() != nil {
+		t.Error("mining continued after it was disabled")
+	}
+}
+
+func TestValidateBlock(t *testing.T) {
+	setupTestMiner(t)
+	valid := mineTestBlock(t, signedTestTransaction(t, "result"))
+
+	// Re-sign and re-mine a modified copy of the valid block so only the
+	// change is wrong
+	remine := func(change func(*Block)) Block {
+		block := valid
+		block.Transactions = append([]Transaction{}, valid.Transactions...)
+		change(&block)
+		if err := signBlockHeader(&block); err != nil {
+			t.Fatal(err)
+		}
+		block, found := mineBlock(context.Background(), nil, block)
+		if !found {
+			t.Fatal("mining was interrupted")
+		}
+		return block
+	}
+	unsigned := Transaction{ID: generateTransactionID("forged"), Data: "forged"}
+	lockedUntil := func(lockTime, blockTime int64) func(*Block) {
+		return func(b *Block) {
+			locked := Transaction{ID: generateTransactionID("dated"), Data: "dated", NetworkID: networkID, LockTime: lockTime}
+			if err := signTransaction(&locked); err != nil {
+				t.Fatal(err)
+			}
+			b.Timestamp = blockTime
+			b.Transactions = append(b.Transactions, locked)
+			b.MerkleRoot = computeMerkleRoot(b.Transactions)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		block    Block
+		prevHash string
+		valid    bool
+	}{
+		{"valid", valid, genesisBlock.Hash, true},
+		{"parent unchecked", valid, "-1", true},
+		{"unexpected parent", valid, "00ab", false},
+		{"tampered hash", func() Block { b := valid; b.Hash = b.Hash[:63] + flipHex(b.Hash[63]); return b }(), genesisBlock.Hash, false},
+		{"tampered nonce", func() Block { b := valid; b.Nonce++; return b }(), genesisBlock.Hash, false},
+		{"claims to be genesis", remine(func(b *Block) { b.BlockNumber, b.PrevHash = 0, "-1" }), "-1", false},
+		{"other network", remine(func(b *Block) { b.NetworkID = "other" }), genesisBlock.Hash, false},
+		{"other network's transaction", remine(func(b *Block) {
+			foreign := Transaction{ID: generateTransactionID("foreign"), Data: "foreign", NetworkID: "other"}
+			if err := signTransaction(&foreign); err != nil {
+				t.Fatal(err)
+			}
+			b.Transactions = append(b.Transactions, foreign)
+			b.MerkleRoot = computeMerkleRoot(b.Transactions)
+		}), genesisBlock.Hash, false},
+		{"future timestamp", remine(func(b *Block) { b.Timestamp = time.Now().Add(3 * time.Hour).Unix() }), genesisBlock.Hash, false},
+		{"wrong Merkle root", remine(func(b *Block) { b.MerkleRoot = computeMerkleRoot(nil) }), genesisBlock.Hash, false},
+		{"unsigned transaction", remine(func(b *Block) {
+			b.Transactions = append(b.Transactions, unsigned)
+			b.MerkleRoot = computeMerkleRoot(b.Transactions)
+		}), genesisBlock.Hash, false},
+		{"coinbase not first", remine(func(b *Block) {
+			b.Transactions[0], b.Transactions[1] = b.Transactions[1], b.Transactions[0]
+			b.MerkleRoot = computeMerkleRoot(b.Transactions)
+		}), genesisBlock.Hash, false},
+		{"inflated coinbase", remine(func(b *Block) {
+			coinbase := *b.Transactions[0].Coinbase
+			coinbase.Amount++
+			b.Transactions[0].Coinbase = &coinbase
+			b.MerkleRoot = computeMerkleRoot(b.Transactions)
+		}), genesisBlock.Hash, false},
+		{"time-locked transaction", remine(func(b *Block) {
+			locked := Transaction{ID: generateTransactionID("later"), Data: "later", LockHeight: 5}
+			if err := signTransaction(&locked); err != nil {
+				t.Fatal(err)
+			}
+			b.Transactions = append(b.Transactions, locked)
+			b.MerkleRoot = computeMerkleRoot(b.Transactions)
+		}), genesisBlock.Hash, false},
+		// Time locks are judged by the block's timestamp, not the clock
+		{"time lock before block timestamp", remine(lockedUntil(time.Now().Add(time.Hour).Unix(), time.Now().Add(90*time.Minute).Unix())), genesisBlock.Hash, true},
+		{"time lock after block timestamp", remine(lockedUntil(time.Now().Unix(), valid.Timestamp-1)), genesisBlock.Hash, false},
+		{"expired transaction", remine(func(b *Block) {
+			expired := Transaction{ID: generateTransactionID("stale"), Data: "stale", ExpiryHeight: 1}
+			if err := signTransaction(&expired); err != nil {
+				t.Fatal(err)
+			}
+			b.Transactions = append(b.Transactions, expired)
+			b.MerkleRoot = computeMerkleRoot(b.Transactions)
+		}), genesisBlock.Hash, false},
+	}
+	for _, test := range tests {
+		data, err := json.Marshal(test.block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := validateBlock(string(data), test.prevHash, currentTarget()); got != test.valid {
+			t.Errorf("%s: validateBlock = %v, want %v", test.name, got, test.valid)
+		}
+	}
+
+	// A block that meets an easier target than the one required
+	data, _ := json.Marshal(valid)
+	if validateBlock(string(data), genesisBlock.Hash, big.NewInt(1)) {
+		t.Error("block with insufficient proof of work is valid")
+	}
+}
+
+func TestValidateBlockMinerSignature(t *testing.T) {
+	setupTestMiner(t)
+	defer func(s *NodeState) { nodeState = s }(nodeState)
+	nodeState = NewNodeState()
+	valid := mineTestBlock(t)
+	other, err := generateSigningKey(sigAlgEd25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherID, _ := nodeIDFromPublicKey(other.Public())
+
+	// Mine a modified copy of the valid block without re-signing it
+	remine := func(change func(*Block)) Block {
+		block := valid
+		change(&block)
+		block, found := mineBlock(context.Background(), nil, block)
+		if !found {
+			t.Fatal("mining was interrupted")
+		}
+		return block
+	}
+	isValid := func(block Block) bool {
+		data, _ := json.Marshal(block)
+		return validateBlock(string(data), genesisBlock.Hash, currentTarget())
+	}
+
+	if miner, err := verifyBlockHeader(valid); err != nil || miner != nodeID {
+		t.Fatalf("signed block has miner %q, %v; want %q", miner, err, nodeID)
+	}
+	for name, block := range map[string]Block{
+		"unsigned":        remine(func(b *Block) { b.MinerID, b.MinerSig = "", nil }),
+		"misattributed":   remine(func(b *Block) { b.MinerID = otherID }),
+		"tampered header": remine(func(b *Block) { b.Timestamp++ }),
+	} {
+		if isValid(block) {
+			t.Errorf("%s block is valid", name)
+		}
+	}
+	if invalid := nodeState.InvalidBlocks(); len(invalid) != 0 {
+		t.Errorf("blocks without a valid signature were held against %v", invalid)
+	}
+
+	// A properly signed but invalid block counts against its miner
+	bad := remine(func(b *Block) {
+		b.MerkleRoot = computeMerkleRoot(nil)
+		if err := signBlockHeader(b); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if isValid(bad) {
+		t.Fatal("block with a wrong Merkle root is valid")
+	}
+	if invalid := nodeState.InvalidBlocks(); invalid[nodeID] != 1 {
+		t.Errorf("invalid blocks by miner are %v, want one by %s", invalid, nodeID)
+	}
+}
+
+func TestNodeIdentityFromSignerBackend(t *testing.T) {
+	key, err := generateSigningKey(sigAlgECDSAP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := nodeIDFromPublicKey(key.Public())
+	defer func(spec string, s Signer, id string) { *identitySpec, nodeSigner, nodeID = spec, s, id }(*identitySpec, nodeSigner, nodeID)
+	signerBackends["test"] = func(spec string) (crypto.Signer, error) { return key, nil }
+	defer delete(signerBackends, "test")
+
+	*identitySpec = "test:slot-0"
+	if err := setupNodeIdentity(); err != nil {
+		t.Fatal(err)
+	}
+	if nodeID != want {
+		t.Errorf("node ID is %s, want %s from the backend key", nodeID, want)
+	}
+}
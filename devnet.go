@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Addresses of the devnet's Docker network. Peers are known by IP, so
+// every container gets a fixed one.
+const (
+	devnetSubnet  = "172.28.0.0/24"
+	devnetIPFSIP  = "172.28.0.2"
+	devnetFirstIP = 10 // Last octet of node 0
+)
+
+// Options of the devnet subcommand.
+type devnetOptions struct {
+	nodes      int    // Nodes in the network
+	dir        string // Directory the devnet is written to
+	ipfs       string // mock, kubo, or the address of an IPFS API the nodes can reach
+	targetBits uint   // Initial target of the devnet genesis, as a power of two
+	image      string // Image the nodes run
+	build      string // Source tree the image is built from; empty to use the image as is
+	apiPort    int    // Host port of node 0's HTTP API; node i uses apiPort+i
+	jobPort    int    // Host port of node 0's job listener; node i uses jobPort+i
+	reset      bool   // Replace an existing devnet in dir
+	up         bool   // Start the devnet once written
+}
+
+// Run the devnet subcommand:
+//
+//	devnet [flags]
+//	devnet ipfs [-addr :5001]
+//
+// The first form writes a Docker Compose project of -nodes nodes that peer
+// with each other and mine a low-difficulty genesis of their own, with one
+// data directory each, and starts it with -up. The second serves the
+// in-memory IPFS API the devnet uses with -ipfs mock.
+func runDevnet(args []string) int {
+	if len(args) > 0 && args[0] == "ipfs" {
+		return runMockIPFS(args[1:])
+	}
+
+	fs := flag.NewFlagSet("devnet", flag.ContinueOnError)
+	var opts devnetOptions
+	fs.IntVar(&opts.nodes, "nodes", 3, "number of nodes")
+	fs.StringVar(&opts.dir, "dir", "devnet", "directory to write the devnet to")
+	fs.StringVar(&opts.ipfs, "ipfs", "mock", "IPFS the nodes use: mock (in memory), kubo (an IPFS daemon container) or the address of an IPFS API")
+	fs.UintVar(&opts.targetBits, "target-bits", 248, "initial mining target as a power of two; higher is easier")
+	fs.StringVar(&opts.image, "image", "algochain-node:devnet", "container image the nodes run")
+	fs.StringVar(&opts.build, "build", ".", "source tree to build the image from (empty to use -image as is)")
+	fs.IntVar(&opts.apiPort, "api-port", 18082, "host port of the first node's HTTP API; the others follow")
+	fs.IntVar(&opts.jobPort, "job-port", 18180, "host port of the first node's job listener; the others follow")
+	fs.BoolVar(&opts.reset, "reset", false, "delete an existing devnet in -dir, chains included")
+	fs.BoolVar(&opts.up, "up", false, "start the devnet with docker compose")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: devnet [flags]\n       devnet ipfs [-addr :5001]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	if err := writeDevnet(opts); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+	composeFile := filepath.Join(opts.dir, "docker-compose.yml")
+	fmt.Printf("Wrote a devnet of %d nodes to %s\n", opts.nodes, opts.dir)
+	for i := 0; i < opts.nodes; i++ {
+		fmt.Printf("  node%d: API http://localhost:%d, jobs localhost:%d\n", i, opts.apiPort+i, opts.jobPort+i)
+	}
+	if !opts.up {
+		fmt.Printf("Start it with: docker compose -f %s up\n", composeFile)
+		return 0
+	}
+
+	compose := []string{"compose", "-f", composeFile, "up"}
+	if opts.build != "" {
+		compose = append(compose, "--build")
+	}
+	cmd := exec.Command("docker", compose...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		fmt.Fprintln(os.Stderr, "Error running docker compose:", err)
+		return 1
+	}
+	return 0
+}
+
+// A service of the devnet's Compose project.
+type devnetService struct {
+	Name    string
+	Image   string
+	Build   string   // Absolute source tree to build Image from, if any
+	Command []string // Arguments to the image's entrypoint
+	WorkDir string
+	Ports   []string // host:container
+	IP      string
+	IPFS    bool // Depends on the ipfs service
+}
+
+// Write the genesis, the node configurations and the Compose project of a
+// devnet to opts.dir.
+func writeDevnet(opts devnetOptions) error {
+	if opts.nodes < 1 || opts.nodes > 200 {
+		return fmt.Errorf("a devnet has 1 to 200 nodes, not %d", opts.nodes)
+	}
+	if opts.reset {
+		if err := os.RemoveAll(opts.dir); err != nil {
+			return fmt.Errorf("failed to remove old devnet: %v", err)
+		}
+	}
+	if entries, err := os.ReadDir(opts.dir); err == nil && len(entries) > 0 {
+		return fmt.Errorf("%s is not empty; pass -reset to replace the devnet in it", opts.dir)
+	}
+	build := ""
+	if opts.build != "" {
+		var err error
+		if build, err = filepath.Abs(opts.build); err != nil {
+			return err
+		}
+	}
+
+	// A chain of its own, with a target low enough to mine on a laptop
+	if opts.targetBits == 0 || opts.targetBits > 255 {
+		return fmt.Errorf("target bits must be between 1 and 255, got %d", opts.targetBits)
+	}
+	genesis := Genesis{ChainID: "devnet", TargetBits: opts.targetBits, Timestamp: time.Now().Unix()}
+	if err := writeDevnetFile(opts.dir, "genesis.json", mustIndentJSON(genesis)); err != nil {
+		return err
+	}
+	// Devnet keys guard nothing, so every node shares a known passphrase
+	if err := writeDevnetFile(opts.dir, "passphrase", "devnet\n"); err != nil {
+		return err
+	}
+
+	var services []devnetService
+	ipfsAPI := opts.ipfs
+	switch opts.ipfs {
+	case "mock":
+		services = append(services, devnetService{Name: "ipfs", Image: opts.image, Build: build, Command: []string{"devnet", "ipfs", "-addr", ":5001"}, IP: devnetIPFSIP})
+		ipfsAPI = "ipfs:5001"
+	case "kubo":
+		services = append(services, devnetService{Name: "ipfs", Image: "ipfs/kubo:release", IP: devnetIPFSIP})
+		ipfsAPI = "ipfs:5001"
+	}
+
+	ips := make([]string, opts.nodes)
+	for i := range ips {
+		ips[i] = fmt.Sprintf("172.28.0.%d", devnetFirstIP+i)
+	}
+	for i, ip := range ips {
+		name := fmt.Sprintf("node%d", i)
+		var peers []string
+		for _, peer := range ips {
+			if peer != ip {
+				peers = append(peers, fmt.Sprintf("%q", peer))
+			}
+		}
+		config := fmt.Sprintf(`# Node %d of the devnet. Paths are relative to this directory.
+job-addr = ":8080"
+block-addr = ":8081"
+api-addr = ":8082"
+ipfs-api = %q
+genesis = "../genesis.json"
+keystore-passphrase-file = "../passphrase"
+bootstrap = [%s]
+`, i, ipfsAPI, strings.Join(peers, ", "))
+		if err := writeDevnetFile(filepath.Join(opts.dir, name), "node.toml", config); err != nil {
+			return err
+		}
+		services = append(services, devnetService{
+			Name:    name,
+			Image:   opts.image,
+			Build:   build,
+			Command: []string{"-config", "node.toml"},
+			WorkDir: "/devnet/" + name,
+			Ports:   []string{fmt.Sprintf("%d:8082", opts.apiPort+i), fmt.Sprintf("%d:8080", opts.jobPort+i)},
+			IP:      ip,
+			IPFS:    opts.ipfs == "mock" || opts.ipfs == "kubo",
+		})
+	}
+
+	var compose strings.Builder
+	err := devnetComposeTemplate.Execute(&compose, map[string]any{"Nodes": opts.nodes, "Services": services, "Subnet": devnetSubnet})
+	if err != nil {
+		return err
+	}
+	return writeDevnetFile(opts.dir, "docker-compose.yml", compose.String())
+}
+
+func writeDevnetFile(dir, name, content string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", dir, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", name, err)
+	}
+	return nil
+}
+
+func mustIndentJSON(v any) string {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return string(data) + "\n"
+}
+
+var devnetComposeTemplate = template.Must(template.New("compose").Funcs(template.FuncMap{
+	// JSON strings and arrays are valid YAML flow scalars and sequences
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}).Parse(`# Devnet of {{.Nodes}} nodes, generated by the devnet subcommand. Node data
+# lives in the node directories next to this file.
+services:
+{{- range .Services}}
+  {{.Name}}:
+    image: {{.Image}}
+{{- if .Build}}
+    build: {{json .Build}}
+{{- end}}
+{{- if .Command}}
+    command: {{json .Command}}
+{{- end}}
+{{- if .WorkDir}}
+    working_dir: {{.WorkDir}}
+    volumes:
+      - ./:/devnet
+{{- end}}
+{{- if .Ports}}
+    ports:
+{{- range .Ports}}
+      - "{{.}}"
+{{- end}}
+{{- end}}
+{{- if .IPFS}}
+    depends_on:
+      - ipfs
+{{- end}}
+    networks:
+      devnet:
+        ipv4_address: {{.IP}}
+{{- end}}
+networks:
+  devnet:
+    ipam:
+      config:
+        - subnet: {{.Subnet}}
+`))
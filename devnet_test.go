@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+func TestWriteDevnet(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "devnet")
+	opts := devnetOptions{nodes: 3, dir: dir, ipfs: "mock", targetBits: 248, image: "node:test", apiPort: 18082, jobPort: 18180}
+	if err := writeDevnet(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	genesis, err := loadGenesis(filepath.Join(dir, "genesis.json"))
+	if err != nil || genesis.TargetBits != 248 {
+		t.Fatalf("devnet genesis is %+v, %v", genesis, err)
+	}
+	for i, ip := range []string{"172.28.0.10", "172.28.0.11", "172.28.0.12"} {
+		var settings map[string]any
+		path := filepath.Join(dir, fmt.Sprintf("node%d", i), "node.toml")
+		if _, err := toml.DecodeFile(path, &settings); err != nil {
+			t.Fatal(err)
+		}
+		for name := range settings {
+			if flag.Lookup(name) == nil {
+				t.Errorf("node %d: unknown setting %q", i, name)
+			}
+		}
+		if peers := settings["bootstrap"].([]any); len(peers) != 2 || peers[0] == ip || peers[1] == ip {
+			t.Errorf("node %d at %s bootstraps from %v", i, ip, peers)
+		}
+	}
+	compose, err := os.ReadFile(filepath.Join(dir, "docker-compose.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"  ipfs:", `command: ["devnet","ipfs","-addr",":5001"]`, "  node2:", `- "18084:8082"`, "ipv4_address: 172.28.0.12"} {
+		if !strings.Contains(string(compose), want) {
+			t.Errorf("compose file lacks %q:\n%s", want, compose)
+		}
+	}
+
+	if err := writeDevnet(opts); err == nil {
+		t.Error("devnet was written over an existing one")
+	}
+	opts.reset = true
+	if err := writeDevnet(opts); err != nil {
+		t.Errorf("devnet was not replaced with -reset: %v", err)
+	}
+}
+
+func TestMockIPFS(t *testing.T) {
+	server := httptest.NewServer(newMockIPFS().handler())
+	defer server.Close()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", "script.py")
+	part.Write([]byte("print(1)"))
+	form.Close()
+	response, err := http.Post(server.URL+"/api/v0/add?pin=true", form.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var added struct{ Hash string }
+	json.NewDecoder(response.Body).Decode(&added)
+	response.Body.Close()
+	if err := validateCID(added.Hash); err != nil {
+		t.Fatalf("added file got CID %q: %v", added.Hash, err)
+	}
+
+	response, err = http.Post(server.URL+"/api/v0/cat?arg="+added.Hash, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+	if string(data) != "print(1)" {
+		t.Errorf("cat returned %q", data)
+	}
+
+	response, err = http.Post(server.URL+"/api/v0/pin/ls", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pins struct{ Keys map[string]any }
+	json.NewDecoder(response.Body).Decode(&pins)
+	response.Body.Close()
+	if _, ok := pins.Keys[added.Hash]; !ok {
+		t.Errorf("added file is not pinned: %v", pins.Keys)
+	}
+
+	response, err = http.Post(server.URL+"/api/v0/cat?arg=unknown", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusInternalServerError {
+		t.Errorf("cat of an unknown CID answered with status %d", response.StatusCode)
+	}
+}
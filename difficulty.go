@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// Easiest allowed target; retargeting never goes above it.
+var maxTarget = new(big.Int).Lsh(big.NewInt(1), 255)
+
+// Target of the first blocks, before the first retarget.
+var initialTarget = new(big.Int)
+
+var (
+	targetBlockTime  = flag.Duration("block-time", 30*time.Second, "block interval the difficulty retargets towards")
+	retargetInterval = flag.Int("retarget-interval", 10, "number of blocks per difficulty epoch")
+
+	difficultyMu sync.Mutex // Guards target
+
+	errAncestorPruned = errors.New("epoch start is below the chain's checkpoint")
+)
+
+// Set the initial target to 2^bits. Each bit fewer doubles the difficulty.
+func setupDifficulty(bits uint) error {
+	if bits == 0 || bits > 255 {
+		return fmt.Errorf("target bits must be between 1 and 255, got %d", bits)
+	}
+	initialTarget.Lsh(big.NewInt(1), bits)
+	target.Set(initialTarget)
+	return nil
+}
+
+// Return a copy of the target the next block on the tip must declare.
+func currentTarget() *big.Int {
+	difficultyMu.Lock()
+	defer difficultyMu.Unlock()
+	return new(big.Int).Set(target)
+}
+
+// The target a block's header declares. Blocks without one, the genesis
+// block among them, count as mined at the initial target.
+func blockTarget(block Block) *big.Int {
+	if declared, ok := new(big.Int).SetString(block.Target, 16); ok {
+		return declared
+	}
+	return new(big.Int).Set(initialTarget)
+}
+
+// Hex encoding of a target as declared in block headers.
+func formatTarget(t *big.Int) string {
+	return t.Text(16)
+}
+
+// Return the target the child of parent must declare. Targets change only
+// at the start of an epoch, every -retarget-interval blocks, when the
+// parent's target is scaled by how long the epoch that just ended took
+// between block timestamps. Every node thus derives the same target from
+// the same chain, whichever branch it is on. find looks up blocks on the
+// main chain and side branches by hash.
+func expectedTarget(parent Block, find func(hash string) (Block, bool)) (*big.Int, error) {
+	current := blockTarget(parent)
+	height := parent.BlockNumber + 1
+	if height%*retargetInterval != 0 || height <= *retargetInterval {
+		return current, nil
+	}
+
+	// The last block of the previous epoch starts this one's timing
+	first, err := ancestorAt(parent, height-*retargetInterval-1, find)
+	if err != nil {
+		return nil, err
+	}
+	actual := time.Duration(parent.Timestamp-first.Timestamp) * time.Second
+	return retarget(current, actual, time.Duration(*retargetInterval)*(*targetBlockTime)), nil
+}
+
+// Walk back from block to its ancestor at the given height.
+func ancestorAt(block Block, height int, find func(hash string) (Block, bool)) (Block, error) {
+	for block.BlockNumber > height {
+		// Once on the main chain, the ancestor is a stored block
+		if main, err := chainStore.GetBlockByNumber(block.BlockNumber); err == nil && main.Hash == block.Hash {
+			if height < chainStore.Base() {
+				return Block{}, errAncestorPruned
+			}
+			return chainStore.GetBlockByNumber(height)
+		}
+		parent, ok := find(block.PrevHash)
+		if !ok {
+			return Block{}, fmt.Errorf("ancestor %s of block %d is unknown", block.PrevHash, block.BlockNumber)
+		}
+		block = parent
+	}
+	return block, nil
+}
+
+// Check that a block declares the target its parent implies. A chain
+// fast-synced from a checkpoint cannot time the first epoch after it, so
+// there the declared target is only held to the retarget limits. The
+// caller holds chainMu.
+func checkBlockTarget(block Block) error {
+	parent, ok := findBlock(block.PrevHash)
+	if !ok {
+		return errUnknownParent
+	}
+	expected, err := consensus.CalcDifficulty(parent, findBlock)
+	if errors.Is(err, errAncestorPruned) {
+		declared, current := blockTarget(block), blockTarget(parent)
+		if declared.Cmp(new(big.Int).Div(current, big.NewInt(4))) < 0 || declared.Cmp(new(big.Int).Mul(current, big.NewInt(4))) > 0 {
+			return fmt.Errorf("block %d target %s is outside the retarget limits", block.BlockNumber, block.Target)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if block.Target != formatTarget(expected) {
+		return fmt.Errorf("block %d declares target %s, expected %s", block.BlockNumber, block.Target, formatTarget(expected))
+	}
+	return nil
+}
+
+// Update the mining target for a new main chain tip.
+func updateTarget(tip Block) {
+	next, err := consensus.CalcDifficulty(tip, func(hash string) (Block, bool) {
+		block, err := chainStore.GetBlockByHash(hash)
+		return block, err == nil
+	})
+	if err != nil {
+		// Keep the tip's target until the epoch can be timed
+		next = blockTarget(tip)
+		if !errors.Is(err, errAncestorPruned) {
+			chainLog.Warn("Error computing target", "height", tip.BlockNumber+1, "err", err)
+		}
+	}
+
+	difficultyMu.Lock()
+	defer difficultyMu.Unlock()
+	if next.Cmp(target) != 0 && tip.BlockNumber > 0 {
+		chainLog.Info("Retargeted difficulty", "height", tip.BlockNumber+1, "target", formatTarget(next))
+	}
+	target.Set(next)
+}
+
+// Scale the target by actual/expected epoch time, limited to a factor of 4
+// either way so a single odd epoch cannot swing difficulty wildly.
+func retarget(old *big.Int, actual, expected time.Duration) *big.Int {
+	if actual < expected/4 {
+		actual = expected / 4
+	}
+	if actual > expected*4 {
+		actual = expected * 4
+	}
+
+	next := new(big.Int).Mul(old, big.NewInt(int64(actual)))
+	next.Div(next, big.NewInt(int64(expected)))
+	if next.Cmp(maxTarget) > 0 {
+		next.Set(maxTarget)
+	}
+	if next.Sign() <= 0 {
+		next.SetInt64(1)
+	}
+	return next
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestTargetRetargetsFromTimestamps(t *testing.T) {
+	setupTestMiner(t)
+	defer func(n int) { *retargetInterval = n }(*retargetInterval)
+	*retargetInterval = 2
+
+	// Blocks one second apart, far faster than -block-time, on a branch
+	// not in the chain store
+	blocks := map[string]Block{genesisBlock.Hash: genesisBlock}
+	find := func(hash string) (Block, bool) {
+		block, ok := blocks[hash]
+		return block, ok
+	}
+	tip := genesisBlock
+	for height := 1; height <= 3; height++ {
+		block := Block{BlockNumber: height, PrevHash: tip.Hash, Timestamp: genesisBlock.Timestamp + int64(height), Target: formatTarget(initialTarget)}
+		block.Hash = block.ComputeHash()
+		blocks[block.Hash] = block
+		tip = block
+	}
+
+	parent := blocks[tip.PrevHash]
+	if got, err := expectedTarget(parent, find); err != nil || got.Cmp(initialTarget) != 0 {
+		t.Errorf("target within an epoch = %v, %v; want it unchanged", got, err)
+	}
+	want := new(big.Int).Div(initialTarget, big.NewInt(4))
+	if got, err := expectedTarget(tip, find); err != nil || got.Cmp(want) != 0 {
+		t.Errorf("target at the epoch start = %v, %v; want the limit of a quarter", got, err)
+	}
+}
+
+func TestBlockWithWrongTargetRejected(t *testing.T) {
+	setupTestMiner(t)
+	block := blockTemplate(1, genesisBlock.Hash, genesisBlock.PrevCID, nil)
+	block.Target = formatTarget(maxTarget)
+	block.Hash = block.ComputeHash()
+	if err := acceptBlock(block); err == nil {
+		t.Fatal("accepted a block declaring an easier target than the chain implies")
+	}
+
+	block = blockTemplate(1, genesisBlock.Hash, genesisBlock.PrevCID, nil)
+	block.Hash = block.ComputeHash()
+	if err := acceptBlock(block); err != nil {
+		t.Fatal(err)
+	}
+}
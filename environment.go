@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ExecutionEnv pins the environment a job script runs in. Results depend on
+// the interpreter and libraries, so a job that names an environment is run
+// in that exact container image by every node that runs or re-runs it.
+type ExecutionEnv struct {
+	Image       string   // Container image pinned by digest, e.g. python@sha256:<hex>
+	Interpreter string   `json:",omitempty"` // Command in the image that runs the script; default the runtime's
+	Libraries   []string `json:",omitempty"` // Packages the image provides, as name==version
+}
+
+var (
+	pinnedImagePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._/:-]*@sha256:[0-9a-f]{64}$`)
+	interpreterPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+	libraryPattern     = regexp.MustCompile(`^[A-Za-z0-9._-]+==[A-Za-z0-9.+!_-]+$`)
+)
+
+// Check that the environment is fully pinned.
+func (e ExecutionEnv) Validate() error {
+	if !pinnedImagePattern.MatchString(e.Image) {
+		return fmt.Errorf("image %q is not pinned by sha256 digest", e.Image)
+	}
+	if e.Interpreter != "" && !interpreterPattern.MatchString(e.Interpreter) {
+		return fmt.Errorf("invalid interpreter %q", e.Interpreter)
+	}
+	for _, library := range e.Libraries {
+		if !libraryPattern.MatchString(library) {
+			return fmt.Errorf("library %q is not pinned as name==version", library)
+		}
+	}
+	return nil
+}
+
+// Apply an environment option of a job message to env, allocating it on
+// first use. It reports false for options that are not about the
+// environment.
+func parseEnvOption(env **ExecutionEnv, key, value string) (bool, error) {
+	switch key {
+	case "image", "interpreter", "libs":
+	default:
+		return false, nil
+	}
+	if *env == nil {
+		*env = &ExecutionEnv{}
+	}
+	switch key {
+	case "image":
+		(*env).Image = value
+	case "interpreter":
+		(*env).Interpreter = value
+	case "libs":
+		if value == "" {
+			return true, errors.New("empty library list")
+		}
+		(*env).Libraries = strings.Split(value, ",")
+	}
+	return true, nil
+}
+
+// Sandbox that runs scripts in env, or the node's configured sandbox if the
+// job names no environment.
+func sandboxFor(env *ExecutionEnv) sandbox {
+	if env == nil {
+		return scriptSandbox
+	}
+	return dockerSandbox{env: env}
+}
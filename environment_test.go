@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+var testImage = "python@sha256:" + strings.Repeat("ab", 32)
+
+func TestJobMessageEnvironment(t *testing.T) {
+	opts := clientOptions{image: testImage, interpreter: "python3", libs: "numpy==1.26.4,scipy==1.13.0"}
+	job, err := parseJobMessage(jobMessage(testScriptCID, testDataCID, opts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Env == nil || job.Env.Image != testImage || job.Env.Interpreter != "python3" ||
+		len(job.Env.Libraries) != 2 || job.Env.Libraries[1] != "scipy==1.13.0" {
+		t.Errorf("parsed environment %+v", job.Env)
+	}
+
+	job, err = parseJobMessage(testJobCIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Env != nil {
+		t.Errorf("job without environment options has environment %+v", job.Env)
+	}
+}
+
+func TestExecutionEnvMustBePinned(t *testing.T) {
+	for name, message := range map[string]string{
+		"tag":              testJobCIDs + " image=python:3-slim",
+		"short digest":     testJobCIDs + " image=python@sha256:abcd",
+		"no image":         testJobCIDs + " interpreter=python3",
+		"bad interpreter":  testJobCIDs + " image=" + testImage + " interpreter=sh;rm",
+		"unpinned library": testJobCIDs + " image=" + testImage + " libs=numpy",
+		"empty libraries":  testJobCIDs + " image=" + testImage + " libs=",
+	} {
+		if _, err := parseJobMessage(message); err == nil {
+			t.Errorf("%s: parsed without error", name)
+		}
+	}
+}
+
+func TestSandboxForEnvironment(t *testing.T) {
+	if sandboxFor(nil) != scriptSandbox {
+		t.Error("job without environment does not use the configured sandbox")
+	}
+	env := &ExecutionEnv{Image: testImage, Interpreter: "python3.12"}
+	s := sandboxFor(env)
+	runtime, _ := lookupRuntime("")
+	cmd, err := s.Command(context.Background(), runtime, "script.py", "data.txt", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := strings.Join(cmd.Args, " ")
+	if !strings.HasSuffix(args, " "+testImage+" python3.12 /job/script.py /job/data.txt") {
+		t.Errorf("sandbox for pinned environment runs %s", args)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// Event types published on the event bus.
+const (
+	eventNewBlock       = "NewBlock"       // A block joined the main chain; data is the block
+	eventNewTransaction = "NewTransaction" // A transaction entered the mempool; data is the transaction
+	eventReorgDetected  = "ReorgDetected"  // The main chain switched branches; data has forkHeight, removed and added
+	eventPeerConnected  = "PeerConnected"  // A peer completed its first handshake; data has addr, agent, version and height
+)
+
+// Events buffered per subscriber; a subscriber that falls further behind
+// misses events rather than stalling the node.
+const subscriberBuffer = 256
+
+var events = NewEventBus()
+
+// Event as delivered to subscribers.
+type Event struct {
+	Type string `json:"type"`
+	Time int64  `json:"time"`
+	Data any    `json:"data"`
+}
+
+// EventBus fans chain and network events out to subscribers.
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers map[*Subscription]struct{}
+}
+
+// Subscription receives the events of the types it asked for.
+type Subscription struct {
+	bus     *EventBus
+	types   map[string]bool // Empty for all types
+	ch      chan Event
+	dropped atomic.Uint64
+	once    sync.Once
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: make(map[*Subscription]struct{})}
+}
+
+// Subscribe to events of the given types, or of all types if none are given.
+func (b *EventBus) Subscribe(types ...string) *Subscription {
+	s := &Subscription{bus: b, types: make(map[string]bool), ch: make(chan Event, subscriberBuffer)}
+	for _, t := range types {
+		s.types[t] = true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[s] = struct{}{}
+	return s
+}
+
+// Publish an event to every interested subscriber without blocking.
+func (b *EventBus) Publish(eventType string, data any) {
+	event := Event{Type: eventType, Time: time.Now().Unix(), Data: data}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for s := range b.subscribers {
+		if len(s.types) > 0 && !s.types[eventType] {
+			continue
+		}
+		select {
+		case s.ch <- event:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// Events delivers the subscription's events until it is closed.
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Dropped reports how many events the subscriber was too slow to receive.
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subscribers, s)
+		s.bus.mu.Unlock()
+		close(s.ch)
+	})
+}
+
+// GET /events?types=T1,T2: a websocket streaming events of the given types,
+// or of all types, as JSON messages.
+func handleEvents(ws *websocket.Conn) {
+	var types []string
+	if value := ws.Request().URL.Query().Get("types"); value != "" {
+		types = strings.Split(value, ",")
+	}
+	sub := events.Subscribe(types...)
+	defer sub.Close()
+
+	// The client sends nothing; a failed read means it went away
+	gone := make(chan struct{})
+	go func() {
+		var discard []byte
+		for websocket.Message.Receive(ws, &discard) == nil {
+		}
+		close(gone)
+	}()
+
+	for {
+		select {
+		case event := <-sub.Events():
+			if err := websocket.JSON.Send(ws, event); err != nil {
+				return
+			}
+		case <-gone:
+			return
+		case <-ws.Request().Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+func TestEventBusFiltersByType(t *testing.T) {
+	bus := NewEventBus()
+	all, blocks := bus.Subscribe(), bus.Subscribe(eventNewBlock)
+	defer all.Close()
+	defer blocks.Close()
+
+	bus.Publish(eventNewTransaction, "tx")
+	bus.Publish(eventNewBlock, "block")
+
+	if got := (<-all.Events()).Type; got != eventNewTransaction {
+		t.Errorf("first event is %s", got)
+	}
+	if got := (<-all.Events()).Type; got != eventNewBlock {
+		t.Errorf("second event is %s", got)
+	}
+	if got := <-blocks.Events(); got.Type != eventNewBlock || got.Data != "block" {
+		t.Errorf("block subscriber got %+v", got)
+	}
+	if len(blocks.Events()) != 0 {
+		t.Error("block subscriber got a transaction event")
+	}
+}
+
+func TestEventBusDropsForSlowSubscribers(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe()
+	for i := 0; i < subscriberBuffer+3; i++ {
+		bus.Publish(eventNewBlock, i)
+	}
+	if sub.Dropped() != 3 {
+		t.Errorf("dropped %d events, want 3", sub.Dropped())
+	}
+
+	sub.Close()
+	sub.Close()
+	bus.Publish(eventNewBlock, "after close") // Must not panic
+}
+
+func TestEventsWebsocket(t *testing.T) {
+	setupTestMiner(t)
+	server := httptest.NewServer(apiHandler())
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/events?types=" + eventNewBlock + "," + eventNewTransaction
+	ws, err := websocket.Dial(url, "", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	// The subscription starts once the handler runs
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		events.mu.RLock()
+		subscribed := len(events.subscribers) > 0
+		events.mu.RUnlock()
+		if subscribed || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	tx := signedTestTransaction(t, "result")
+	if err := mempool.Add(tx, priorityNormal); err != nil {
+		t.Fatal(err)
+	}
+	block := mineTestBlock(t)
+	if err := acceptBlock(block); err != nil {
+		t.Fatal(err)
+	}
+
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for _, want := range []string{eventNewTransaction, eventNewBlock} {
+		var event struct {
+			Type string
+			Data struct{ ID, Hash string }
+		}
+		if err := websocket.JSON.Receive(ws, &event); err != nil {
+			t.Fatal(err)
+		}
+		if event.Type != want {
+			t.Fatalf("got %s event, want %s", event.Type, want)
+		}
+		if want == eventNewBlock && event.Data.Hash != block.Hash || want == eventNewTransaction && event.Data.ID != tx.ID {
+			t.Errorf("%s event carries %+v", want, event.Data)
+		}
+	}
+}
+
+func TestFirstHelloPublishesPeerConnected(t *testing.T) {
+	saved := peerManager
+	t.Cleanup(func() { peerManager = saved })
+	peerManager = NewPeerManager([]string{"198.51.100.7"})
+	sub := events.Subscribe(eventPeerConnected)
+	defer sub.Close()
+
+	hello := helloMessage{Version: protocolVersion, Agent: nodeAgent, Height: 3}
+	peerManager.recordHello("198.51.100.7", hello)
+	peerManager.recordHello("198.51.100.7", hello)
+	peerManager.recordHello("198.51.100.8", hello) // Not a known peer
+
+	if len(sub.Events()) != 1 {
+		t.Fatalf("published %d PeerConnected events, want 1", len(sub.Events()))
+	}
+	event := <-sub.Events()
+	if data := event.Data.(map[string]any); data["addr"] != "198.51.100.7" || data["height"] != 3 {
+		t.Errorf("PeerConnected carries %v", data)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"embed"
+	"flag"
+	"html/template"
+	"io/fs"
+	"net/http"
+)
+
+var (
+	ipfsGateway = flag.String("ipfs-gateway", "https://ipfs.io/ipfs/", "IPFS gateway URL prefix the block explorer links CIDs to")
+
+	//go:embed web
+	webFiles embed.FS
+
+	explorerPage = template.Must(template.ParseFS(webFiles, "web/index.html"))
+)
+
+// Serve the block explorer under /explorer/. The page polls the JSON API
+// for blocks and peers and follows the miner over /mining/live.
+func explorerHandler() http.Handler {
+	static, _ := fs.Sub(webFiles, "web")
+	files := http.StripPrefix("/explorer/", http.FileServerFS(static))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/explorer/" {
+			files.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		explorerPage.Execute(w, map[string]string{"Gateway": *ipfsGateway, "Network": networkID})
+	})
+}
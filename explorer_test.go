@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hamayuna47/BlockChain-For-Algorithms-With-POW/merkle"
+	"golang.org/x/net/websocket"
+)
+
+func TestExplorerPage(t *testing.T) {
+	setupTestMiner(t)
+	server := httptest.NewServer(apiHandler())
+	defer server.Close()
+
+	for path, want := range map[string]string{
+		"/explorer/":            `data-gateway="` + *ipfsGateway + `"`,
+		"/explorer/explorer.js": "/mining/live",
+	} {
+		response, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body strings.Builder
+		_, err = io.Copy(&body, response.Body)
+		response.Body.Close()
+		if err != nil || response.StatusCode != http.StatusOK || !strings.Contains(body.String(), want) {
+			t.Errorf("GET %s: status %d, body missing %q", path, response.StatusCode, want)
+		}
+	}
+}
+
+func TestRecentBlocks(t *testing.T) {
+	setupTestMiner(t)
+	block := mineTestBlock(t)
+	if err := chainStore.AppendBlock(block); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(apiHandler())
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/blocks?count=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	var blocks []Block
+	if err := json.NewDecoder(response.Body).Decode(&blocks); err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 2 || blocks[0].Hash != block.Hash || blocks[1].Hash != genesisBlock.Hash {
+		t.Errorf("got %d blocks, want block 1 then the genesis", len(blocks))
+	}
+
+	response, err = http.Get(server.URL + "/blocks?count=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("count 0 answered with status %d", response.StatusCode)
+	}
+}
+
+func TestBlockRange(t *testing.T) {
+	setupTestMiner(t)
+	tip := buildTestChain(t, 3)
+	server := httptest.NewServer(apiHandler())
+	defer server.Close()
+
+	get := func(query string) (int, []Block) {
+		t.Helper()
+		response, err := http.Get(server.URL + "/blocks?" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+		var blocks []Block
+		if response.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(response.Body).Decode(&blocks); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return response.StatusCode, blocks
+	}
+
+	if status, blocks := get("from=1&to=2"); status != http.StatusOK || len(blocks) != 2 || blocks[0].BlockNumber != 1 || blocks[1].BlockNumber != 2 {
+		t.Errorf("from=1&to=2: status %d, %d blocks", status, len(blocks))
+	}
+	// Open and overlong ranges stop at the tip
+	if status, blocks := get("from=2"); status != http.StatusOK || len(blocks) != 2 || blocks[1].Hash != tip.Hash || len(blocks[1].Transactions) == 0 {
+		t.Errorf("from=2: status %d, %d blocks", status, len(blocks))
+	}
+	if status, blocks := get("from=9&to=12"); status != http.StatusOK || len(blocks) != 0 {
+		t.Errorf("range past the tip: status %d, %d blocks", status, len(blocks))
+	}
+	if status, _ := get("from=2&to=1"); status != http.StatusBadRequest {
+		t.Errorf("reversed range answered with status %d", status)
+	}
+}
+
+func TestMinerStats(t *testing.T) {
+	setupTestMiner(t)
+	buildTestChain(t, 3)
+	defer func(s *NodeState) { nodeState = s }(nodeState)
+	nodeState = NewNodeState()
+	nodeState.RecordInvalidBlock("rogue")
+	server := httptest.NewServer(apiHandler())
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/miners?blocks=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	var stats struct {
+		Blocks int          `json:"blocks"`
+		Miners []minerStats `json:"miners"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	want := []minerStats{
+		{MinerID: nodeID, Blocks: 2, Share: 1, LastBlock: 3},
+		{MinerID: "rogue", Invalid: 1},
+	}
+	if stats.Blocks != 2 || len(stats.Miners) != 2 || stats.Miners[0] != want[0] || stats.Miners[1] != want[1] {
+		t.Errorf("miner stats are %+v, want %+v over 2 blocks", stats, want)
+	}
+}
+
+func TestTransactionLookup(t *testing.T) {
+	setupTestMiner(t)
+	tip := buildTestChain(t, 1)
+	pending := signedTestTransaction(t, "pending")
+	if err := mempool.Add(pending, priorityNormal); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(apiHandler())
+	defer server.Close()
+
+	get := func(id string) (int, map[string]any) {
+		t.Helper()
+		response, err := http.Get(server.URL + "/transaction?id=" + id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+		var body map[string]any
+		json.NewDecoder(response.Body).Decode(&body)
+		return response.StatusCode, body
+	}
+	if status, body := get(tip.Transactions[0].ID); status != http.StatusOK || body["blockHash"] != tip.Hash || body["blockNumber"] != float64(1) {
+		t.Errorf("mined transaction: status %d, %v", status, body)
+	}
+	if status, body := get(pending.ID); status != http.StatusOK || body["pending"] != true {
+		t.Errorf("pending transaction: status %d, %v", status, body)
+	}
+	if status, _ := get("unknown"); status != http.StatusNotFound {
+		t.Errorf("unknown transaction answered with status %d", status)
+	}
+}
+
+func TestTransactionProof(t *testing.T) {
+	setupTestMiner(t)
+	tip := buildTestChain(t, 2)
+	server := httptest.NewServer(apiHandler())
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/proof?tx=" + tip.Transactions[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	var proof merkle.Proof
+	if err := json.NewDecoder(response.Body).Decode(&proof); err != nil {
+		t.Fatal(err)
+	}
+	if proof.Header.Hash != tip.Hash || proof.Header.BlockNumber != tip.BlockNumber {
+		t.Fatalf("proof header is %+v, want block %s", proof.Header, tip.Hash)
+	}
+	if err := merkle.VerifyProof(proof); err != nil {
+		t.Fatalf("served proof does not verify: %v", err)
+	}
+
+	response, err = http.Get(server.URL + "/proof?tx=unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("proof of an unknown transaction answered with status %d", response.StatusCode)
+	}
+}
+
+func TestMiningLive(t *testing.T) {
+	setupTestMiner(t)
+	server := httptest.NewServer(apiHandler())
+	defer server.Close()
+
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/mining/live", "", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	var status map[string]any
+	if err := websocket.JSON.Receive(ws, &status); err != nil {
+		t.Fatal(err)
+	}
+	if status["height"] != float64(1) || status["tip"] != genesisBlock.Hash || status["enabled"] != true {
+		t.Errorf("live status %v", status)
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Largest line an import reads: one block with all its transactions.
+const maxExportLine = 64 << 20
+
+// chainExport is the first line of a chain export file. Each following
+// line is one main chain block in JSON, oldest first.
+type chainExport struct {
+	ChainID    string      `json:"chainId"`
+	Genesis    string      `json:"genesis"`              // Hash of the chain's genesis block
+	Height     int         `json:"height"`               // Number of blocks in the chain
+	Checkpoint *Checkpoint `json:"checkpoint,omitempty"` // Checkpoint the blocks start from, if the chain was fast-synced
+}
+
+// Run `export-chain <file>` or `import-chain <file>` against the chain store
+// in -datadir. Both take the node's flags and config file; "-" is standard
+// output or input. Stop the node before importing into its data directory.
+func runChainFileCommand(command string, args []string) int {
+	flag.CommandLine.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [node flags] <file>\n", command)
+		flag.PrintDefaults()
+	}
+	if err := flag.CommandLine.Parse(args); err != nil {
+		return 2
+	}
+	if flag.NArg() != 1 {
+		flag.Usage()
+		return 2
+	}
+	if err := loadConfig(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error loading configuration:", err)
+		return 1
+	}
+	if err := setupLogging(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error configuring logging:", err)
+		return 1
+	}
+	for _, setup := range []func() error{setupRuntimes, setupProofSystem, setupConsensus, setupGenesis} {
+		if err := setup(); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return 1
+		}
+	}
+
+	store, err := OpenChainStore(*dataDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+	defer store.Close()
+	chainStore = store
+
+	path := flag.Arg(0)
+	if command == "export-chain" {
+		err = exportChainFile(path)
+	} else {
+		err = importChainFile(path)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+	return 0
+}
+
+func exportChainFile(path string) error {
+	if path == "-" {
+		return exportChain(os.Stdout)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %v", err)
+	}
+	if err := exportChain(file); err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+	return file.Close()
+}
+
+func importChainFile(path string) error {
+	in := os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open import file: %v", err)
+		}
+		defer file.Close()
+		in = file
+	}
+	imported, err := importChain(in)
+	if err != nil {
+		return err
+	}
+	chainLog.Info("Imported chain", "blocks", imported, "height", chainStore.Height())
+	return nil
+}
+
+// Write the stored main chain to w.
+func exportChain(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	encoder := json.NewEncoder(out)
+	height := chainStore.Height()
+	header := chainExport{ChainID: networkID, Genesis: genesisBlock.Hash, Height: height, Checkpoint: chainStore.Checkpoint()}
+	if err := encoder.Encode(header); err != nil {
+		return fmt.Errorf("failed to write export header: %v", err)
+	}
+	for number := chainStore.Base(); number < height; number++ {
+		block, err := chainStore.GetBlockByNumber(number)
+		if err != nil {
+			return err
+		}
+		if err := encoder.Encode(block); err != nil {
+			return fmt.Errorf("failed to write block %d: %v", number, err)
+		}
+	}
+	if err := out.Flush(); err != nil {
+		return fmt.Errorf("failed to write export: %v", err)
+	}
+	chainLog.Info("Exported chain", "blocks", height-chainStore.Base(), "height", height)
+	return nil
+}
+
+// Read a chain export from r and append the blocks the local chain lacks,
+// validating each one as if it came from a peer. Blocks already stored are
+// skipped, so an interrupted import can be run again. It returns the
+// number of blocks appended.
+func importChain(r io.Reader) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxExportLine)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return 0, fmt.Errorf("failed to read export: %v", err)
+		}
+		return 0, errors.New("export file is empty")
+	}
+	var header chainExport
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return 0, fmt.Errorf("failed to decode export header: %v", err)
+	}
+	if header.ChainID != networkID || header.Genesis != genesisBlock.Hash {
+		return 0, fmt.Errorf("export is of chain %q with genesis %s, not %q with genesis %s", header.ChainID, header.Genesis, networkID, genesisBlock.Hash)
+	}
+
+	if header.Checkpoint != nil && chainStore.Height() == 0 {
+		if err := startFromCheckpoint(*header.Checkpoint); err != nil {
+			return 0, err
+		}
+	}
+	// Rebuild the balances the imported blocks' fees are checked against
+	ledger = NewLedger()
+	if err := ledger.Load(chainStore); err != nil {
+		return 0, err
+	}
+	if err := initGenesis(); err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	for scanner.Scan() {
+		var block Block
+		if err := json.Unmarshal(scanner.Bytes(), &block); err != nil {
+			return imported, fmt.Errorf("failed to decode block after %d imported: %v", imported, err)
+		}
+		if block.BlockNumber < chainStore.Height() {
+			stored, err := chainStore.GetBlockByNumber(block.BlockNumber)
+			if errors.Is(err, errBlockNotFound) {
+				continue // Below the checkpoint the local chain starts from
+			}
+			if err != nil {
+				return imported, err
+			}
+			if stored.Hash != block.Hash {
+				return imported, fmt.Errorf("block %d %s conflicts with stored block %s", block.BlockNumber, block.Hash, stored.Hash)
+			}
+			continue
+		}
+		if err := appendValidBlock(block); err != nil {
+			return imported, fmt.Errorf("block %d: %v", block.BlockNumber, err)
+		}
+		imported++
+		if imported%1000 == 0 {
+			chainLog.Info("Importing chain", "height", block.BlockNumber, "of", header.Height)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return imported, fmt.Errorf("failed to read export: %v", err)
+	}
+	if tip, ok := chainStore.Tip(); ok {
+		setChainHead(tip)
+	}
+	if chainStore.Height() < header.Height {
+		return imported, fmt.Errorf("export ends at height %d but declares %d", chainStore.Height(), header.Height)
+	}
+	return imported, nil
+}
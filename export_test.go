@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExportImportChain(t *testing.T) {
+	setupTestMiner(t)
+	defer func(c Consensus) { consensus = c }(consensus)
+	consensus = instantSeal{} // Blocks built without mining still validate
+	tip := buildTestChain(t, 3)
+	miner := walletAddress
+
+	var export bytes.Buffer
+	if err := exportChain(&export); err != nil {
+		t.Fatal(err)
+	}
+
+	// Import into a fresh node, then again to show it is idempotent
+	setupTestMiner(t)
+	for _, want := range []int{3, 0} {
+		imported, err := importChain(bytes.NewReader(export.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored, _ := chainStore.Tip(); imported != want || stored.Hash != tip.Hash {
+			t.Fatalf("imported %d blocks up to %s, want %d up to %s", imported, stored.Hash, want, tip.Hash)
+		}
+	}
+	if balance := ledger.Balance(miner); balance != 3*blockReward {
+		t.Errorf("miner balance after import is %d, want %d", balance, 3*blockReward)
+	}
+
+	// A tampered block is rejected
+	setupTestMiner(t)
+	lines := strings.Split(export.String(), "\n")
+	lines[2] = strings.Replace(lines[2], `"BlockNumber":1`, `"BlockNumber":1,"Nonce":7`, 1)
+	if _, err := importChain(strings.NewReader(strings.Join(lines, "\n"))); err == nil {
+		t.Error("imported a tampered block")
+	}
+}
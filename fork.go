@@ -0,0 +1,265 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// Side-branch blocks further than this below the tip are forgotten.
+const maxForkDepth = 100
+
+var (
+	chainMu    sync.Mutex               // Serializes changes to the main chain
+	sideBlocks = map[string]sideBlock{} // Valid blocks off the main chain, by hash
+
+	errUnknownParent = errors.New("parent block is unknown")
+	errWrongGenesis  = errors.New("block 0 is not this chain's genesis block")
+)
+
+// A block on a side branch with the cumulative work of its branch.
+type sideBlock struct {
+	block   Block
+	work    *big.Int
+	seen    time.Time // When the block arrived; zero for blocks reorged off the main chain
+	reorged bool
+}
+
+// Work represented by a block, as the consensus engine weighs it.
+func blockWork(block Block) *big.Int {
+	return consensus.Work(block)
+}
+
+// The expected number of hashes needed to meet a target, 2^256 / (target + 1).
+func hashWork(target *big.Int) *big.Int {
+	denominator := new(big.Int).Add(target, big.NewInt(1))
+	return new(big.Int).Div(new(big.Int).Lsh(big.NewInt(1), 256), denominator)
+}
+
+// Add a validated block to the chain. A block extending the tip is appended;
+// any other block with a known parent is kept on a side branch, and if that
+// branch now has more cumulative work than the main chain it becomes the
+// main chain.
+func acceptBlock(block Block) error {
+	block = storedForm(block)
+	chainMu.Lock()
+	defer chainMu.Unlock()
+
+	if _, err := chainStore.GetBlockByHash(block.Hash); err == nil {
+		return nil
+	}
+	if _, ok := sideBlocks[block.Hash]; ok {
+		return nil
+	}
+	if block.BlockNumber == 0 {
+		return errWrongGenesis
+	}
+	if err := checkMedianTime(block); err != nil {
+		return err
+	}
+	if err := checkBlockTarget(block); err != nil {
+		return err
+	}
+	if err := checkReplays(block); err != nil {
+		return err
+	}
+
+	if tip, ok := chainStore.Tip(); ok && block.PrevHash == tip.Hash {
+		if err := connectBlock(block); err != nil {
+			return err
+		}
+		setChainHead(block)
+		fireWebhook(eventBlockAccepted, block)
+		events.Publish(eventNewBlock, block)
+		pruneSideBlocks()
+		maybeCheckpoint(block)
+		return nil
+	}
+
+	// Find the cumulative work up to the parent on either branch
+	var parentWork *big.Int
+	if parent, err := chainStore.GetBlockByHash(block.PrevHash); err == nil {
+		if parent.BlockNumber != block.BlockNumber-1 {
+			return fmt.Errorf("block number %d does not follow parent %d", block.BlockNumber, parent.BlockNumber)
+		}
+		if parentWork, err = chainStore.WorkAt(parent.BlockNumber); err != nil {
+			return err
+		}
+	} else if parent, ok := sideBlocks[block.PrevHash]; ok {
+		if parent.block.BlockNumber != block.BlockNumber-1 {
+			return fmt.Errorf("block number %d does not follow parent %d", block.BlockNumber, parent.block.BlockNumber)
+		}
+		parentWork = parent.work
+	} else {
+		return errUnknownParent
+	}
+
+	work := new(big.Int).Add(parentWork, blockWork(block))
+	sideBlocks[block.Hash] = sideBlock{block: block, work: work, seen: time.Now()}
+	chainLog.Info("Block is on a side branch", "hash", block.Hash, "height", block.BlockNumber)
+	pruneSideBlocks()
+
+	if work.Cmp(chainStore.TotalWork()) > 0 {
+		return reorganize(block)
+	}
+	return nil
+}
+
+// Switch the main chain to the side branch ending at tip. The ledger and
+// receipts move across first, so a branch whose fees do not add up is
+// refused before the store changes. Blocks removed from the main chain move
+// to the side branches and their transactions go back to the mempool.
+func reorganize(tip Block) error {
+	// Walk back to the fork point on the main chain
+	var branch []Block
+	for hash := tip.Hash; ; {
+		side, ok := sideBlocks[hash]
+		if !ok {
+			break
+		}
+		branch = append([]Block{side.block}, branch...)
+		hash = side.block.PrevHash
+	}
+	forkHeight := branch[0].BlockNumber
+	removed, err := chainStore.BlocksFrom(forkHeight)
+	if err != nil {
+		return err
+	}
+	reorg := chainReorg{ForkHeight: forkHeight, Removed: removed, Added: branch}
+	if err := notifyReorg(reorg); err != nil {
+		return fmt.Errorf("reorg to %s refused: %v", tip.Hash, err)
+	}
+	if err := replaceBlocks(forkHeight, branch); err != nil {
+		// Put the old branch back to match the ledger and receipts
+		if err := replaceBlocks(forkHeight, removed); err != nil {
+			chainLog.Error("Error restoring chain after failed reorg", "forkHeight", forkHeight, "err", err)
+		}
+		if err := notifyReorg(reorg.inverse()); err != nil {
+			chainLog.Error("Error undoing reorg", "forkHeight", forkHeight, "err", err)
+		}
+		return fmt.Errorf("reorg failed: %v", err)
+	}
+	for _, block := range branch {
+		mempool.Remove(block.Transactions)
+		delete(sideBlocks, block.Hash)
+	}
+
+	// Keep the old blocks in case their branch overtakes again
+	for _, block := range removed {
+		parentWork := new(big.Int)
+		if parent, ok := sideBlocks[block.PrevHash]; ok {
+			parentWork = parent.work
+		} else if block.BlockNumber > 0 {
+			if parentWork, err = chainStore.WorkAt(block.BlockNumber - 1); err != nil {
+				return err
+			}
+		}
+		sideBlocks[block.Hash] = sideBlock{block: block, work: new(big.Int).Add(parentWork, blockWork(block)), reorged: true}
+	}
+	setChainHead(tip)
+	chainLog.Warn("Reorganized chain", "forkHeight", forkHeight, "removed", len(removed), "added", len(branch), "tip", tip.Hash)
+
+	// Requeue transactions that did not make it into the new branch; the
+	// old branch's coinbases pay for blocks that no longer exist
+	included := make(map[string]bool)
+	for _, block := range branch {
+		for _, tx := range block.Transactions {
+			included[tx.ID] = true
+		}
+	}
+	for _, block := range removed {
+		for _, tx := range block.Transactions {
+			if tx.Coinbase != nil || included[tx.ID] {
+				continue
+			}
+			mempool.Add(tx, priorityRequeued)
+		}
+	}
+
+	fireWebhook(eventChainReorg, reorg)
+	events.Publish(eventReorgDetected, reorg)
+	for _, block := range branch {
+		events.Publish(eventNewBlock, block)
+	}
+	maybeCheckpoint(tip)
+	return nil
+}
+
+// Replace the stored blocks from the given height on with blocks.
+func replaceBlocks(height int, blocks []Block) error {
+	if _, err := chainStore.Truncate(height); err != nil {
+		return err
+	}
+	for _, block := range blocks {
+		if err := chainStore.AppendBlock(block); err != nil {
+			return fmt.Errorf("failed to append block %d: %v", block.BlockNumber, err)
+		}
+	}
+	return nil
+}
+
+// Report whether a block is on the main chain or a side branch.
+func knownBlock(hash string) bool {
+	chainMu.Lock()
+	defer chainMu.Unlock()
+	if _, ok := sideBlocks[hash]; ok {
+		return true
+	}
+	_, err := chainStore.GetBlockByHash(hash)
+	return err == nil
+}
+
+// Return a block on the main chain or a side branch by hash.
+func lookupBlock(hash string) (Block, bool) {
+	chainMu.Lock()
+	defer chainMu.Unlock()
+	return findBlock(hash)
+}
+
+// Like lookupBlock, for callers that hold chainMu.
+func findBlock(hash string) (Block, bool) {
+	if side, ok := sideBlocks[hash]; ok {
+		return side.block, true
+	}
+	block, err := chainStore.GetBlockByHash(hash)
+	return block, err == nil
+}
+
+// Append a block to the main chain and apply it to the mempool and ledger.
+// The caller holds chainMu.
+func connectBlock(block Block) error {
+	if err := ledger.CheckFees(block); err != nil {
+		return err
+	}
+	if err := chainStore.AppendBlock(block); err != nil {
+		return err
+	}
+	mempool.Remove(block.Transactions)
+	mempool.DropExpired(block.BlockNumber + 1)
+	ledger.ApplyBlock(block)
+	receipts.BlockConnected(block)
+	return nil
+}
+
+// Forget side-branch blocks too far below the tip to ever win, recording
+// them as stale.
+func pruneSideBlocks() {
+	height := chainStore.Height()
+	floor := height - maxForkDepth
+	for hash, side := range sideBlocks {
+		if side.block.BlockNumber >= floor {
+			continue
+		}
+		delete(sideBlocks, hash)
+		if staleBlocks != nil {
+			if err := staleBlocks.Record(side); err != nil {
+				chainLog.Warn("Error recording stale block", "hash", hash, "err", err)
+			}
+		}
+	}
+	if staleBlocks != nil {
+		staleRateMetric.Set(staleBlocks.Stats(height).Rate)
+	}
+}
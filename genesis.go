@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+const defaultGenesisFile = "genesis.json"
+
+var (
+	genesisFile = flag.String("genesis", defaultGenesisFile, "genesis file shared by every node of the network (the built-in genesis is used if the default file is missing)")
+
+	genesisBlock Block  // First block of the chain, built from the genesis file
+	networkID    string // Chain ID of the genesis; blocks, transactions and peers must carry it
+)
+
+// Genesis describes the first block of a chain. Every node of a network
+// must use the same genesis, since the genesis block is built from it
+// deterministically and nodes only accept chains that start with it.
+type Genesis struct {
+	ChainID    string           `json:"chainId"`
+	TargetBits uint             `json:"targetBits"`      // Initial target as a power of two
+	Timestamp  int64            `json:"timestamp"`       // Unix time of the genesis block
+	Alloc      map[string]int64 `json:"alloc,omitempty"` // Initial balances by wallet address
+}
+
+// Genesis used when no genesis file exists.
+var defaultGenesis = Genesis{ChainID: "algochain", TargetBits: 245, Timestamp: 1735689600}
+
+// Load the genesis file and join its network.
+func setupGenesis() error {
+	genesis, err := loadGenesis(*genesisFile)
+	if err != nil {
+		return err
+	}
+	if err := useGenesis(genesis); err != nil {
+		return err
+	}
+	nodeLog.Info("Loaded genesis", "chainId", genesis.ChainID, "hash", genesisBlock.Hash)
+	return nil
+}
+
+// Build the genesis block, set the initial target and take the chain ID as
+// the network ID.
+func useGenesis(genesis Genesis) error {
+	if err := setupDifficulty(genesis.TargetBits); err != nil {
+		return err
+	}
+	genesisBlock = genesis.Block()
+	networkID = genesis.ChainID
+	return nil
+}
+
+func loadGenesis(path string) (Genesis, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && path == defaultGenesisFile {
+		return defaultGenesis, nil
+	}
+	if err != nil {
+		return Genesis{}, fmt.Errorf("failed to read genesis file: %v", err)
+	}
+
+	var genesis Genesis
+	if err := json.Unmarshal(data, &genesis); err != nil {
+		return Genesis{}, fmt.Errorf("failed to parse genesis file: %v", err)
+	}
+	if genesis.ChainID == "" {
+		return Genesis{}, errors.New("genesis has no chain ID")
+	}
+	for address, amount := range genesis.Alloc {
+		if amount <= 0 {
+			return Genesis{}, fmt.Errorf("genesis allocates %d to %s", amount, address)
+		}
+	}
+	return genesis, nil
+}
+
+// Build the genesis block. Its first transaction commits to the chain ID;
+// each allocation is a coinbase-style transaction crediting an address. It
+// has no parent and carries no proof of work.
+func (g Genesis) Block() Block {
+	data := "genesis " + g.ChainID
+	transactions := []Transaction{{ID: generateTransactionID(data), Data: data}}
+
+	addresses := make([]string, 0, len(g.Alloc))
+	for address := range g.Alloc {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	for _, address := range addresses {
+		data := "genesis alloc " + address
+		transactions = append(transactions, Transaction{
+			ID:       generateTransactionID(data),
+			Data:     data,
+			Coinbase: &Coinbase{Address: address, Amount: g.Alloc[address]},
+		})
+	}
+
+	block := Block{
+		PrevHash:     "-1",
+		PrevCID:      "-1", // Never uploaded, so block 1 has no parent CID
+		MerkleRoot:   computeMerkleRoot(transactions),
+		Transactions: transactions,
+		BlockNumber:  0,
+		Timestamp:    g.Timestamp,
+		NetworkID:    g.ChainID,
+	}
+	block.Hash = block.ComputeHash()
+	return block
+}
+
+// Check the network ID a peer announced against ours.
+func checkNetworkID(peerNetwork string) error {
+	if peerNetwork != networkID {
+		return fmt.Errorf("peer is on network %q, not %q", peerNetwork, networkID)
+	}
+	return nil
+}
+
+// Store the genesis block in an empty chain, or check that the stored chain
+// starts with it or with a checkpoint of this network.
+func initGenesis() error {
+	if checkpoint := chainStore.Checkpoint(); checkpoint != nil {
+		if checkpoint.ChainID != networkID {
+			return fmt.Errorf("chain store starts from a checkpoint of chain %q, not %q", checkpoint.ChainID, networkID)
+		}
+		return nil
+	}
+	stored, err := chainStore.GetBlockByNumber(0)
+	if errors.Is(err, errBlockNotFound) {
+		chainMu.Lock()
+		err = connectBlock(genesisBlock)
+		chainMu.Unlock()
+		if err != nil {
+			return fmt.Errorf("failed to store genesis block: %v", err)
+		}
+		setChainHead(genesisBlock)
+		chainLog.Info("Initialized chain from genesis", "hash", genesisBlock.Hash)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if stored.Hash != genesisBlock.Hash {
+		return fmt.Errorf("chain store starts with block %s, not genesis %s", stored.Hash, genesisBlock.Hash)
+	}
+	return nil
+}
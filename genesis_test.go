@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenesisBlockIsDeterministic(t *testing.T) {
+	genesis := Genesis{ChainID: "test", TargetBits: testTargetBits, Timestamp: 1700000000, Alloc: map[string]int64{"b": 2, "a": 1, "c": 3}}
+	block := genesis.Block()
+	for i := 0; i < 10; i++ {
+		if again := genesis.Block(); again.Hash != block.Hash {
+			t.Fatalf("genesis hash changed from %s to %s", block.Hash, again.Hash)
+		}
+	}
+	if block.Hash != block.ComputeHash() || block.MerkleRoot != computeMerkleRoot(block.Transactions) {
+		t.Error("genesis block hash or Merkle root is not computed")
+	}
+
+	// Allocations are credited in address order after the chain ID
+	if len(block.Transactions) != 4 {
+		t.Fatalf("genesis has %d transactions, want 4", len(block.Transactions))
+	}
+	for i, address := range []string{"a", "b", "c"} {
+		coinbase := block.Transactions[i+1].Coinbase
+		if coinbase == nil || coinbase.Address != address || coinbase.Amount != genesis.Alloc[address] {
+			t.Errorf("transaction %d does not allocate to %s", i+1, address)
+		}
+	}
+
+	other := genesis
+	other.ChainID = "other"
+	if other.Block().Hash == block.Hash {
+		t.Error("genesis blocks of different chains share a hash")
+	}
+}
+
+func TestLoadGenesis(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	genesis, err := loadGenesis(write("genesis.json", `{"chainId": "net", "targetBits": 240, "timestamp": 5, "alloc": {"addr": 100}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if genesis.ChainID != "net" || genesis.TargetBits != 240 || genesis.Timestamp != 5 || genesis.Alloc["addr"] != 100 {
+		t.Errorf("loaded genesis %+v", genesis)
+	}
+
+	for name, content := range map[string]string{
+		"no-chain.json":  `{"targetBits": 240}`,
+		"negative.json":  `{"chainId": "net", "alloc": {"addr": -1}}`,
+		"malformed.json": `{"chainId": `,
+	} {
+		if _, err := loadGenesis(write(name, content)); err == nil {
+			t.Errorf("%s: loaded invalid genesis", name)
+		}
+	}
+	if _, err := loadGenesis(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("loaded a missing genesis file")
+	}
+}
+
+func TestGenesisAllocationIsCredited(t *testing.T) {
+	setupTestMiner(t)
+	genesisBlock = Genesis{ChainID: "test", Alloc: map[string]int64{walletAddress: 500}}.Block()
+	store, err := OpenChainStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	chainStore, ledger = store, NewLedger()
+
+	if err := initGenesis(); err != nil {
+		t.Fatal(err)
+	}
+	if got := ledger.Balance(walletAddress); got != 500 {
+		t.Errorf("allocated balance is %d, want 500", got)
+	}
+}
+
+func TestStoredChainMustMatchGenesis(t *testing.T) {
+	setupTestMiner(t)
+
+	// The stored chain was started from the test genesis; another genesis
+	// must be refused
+	genesisBlock = Genesis{ChainID: "other", TargetBits: testTargetBits}.Block()
+	if err := initGenesis(); err == nil {
+		t.Fatal("accepted a chain started from a different genesis")
+	}
+
+	genesisBlock = testGenesis.Block()
+	if err := initGenesis(); err != nil {
+		t.Fatalf("rejected the chain's own genesis: %v", err)
+	}
+	if chainStore.Height() != 1 {
+		t.Errorf("genesis was stored again: height %d", chainStore.Height())
+	}
+}
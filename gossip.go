@@ -0,0 +1,126 @@
+package main
+
+import (
+	"flag"
+	"net"
+	"sync"
+)
+
+// Most transactions sent to a peer in one relay message.
+const maxRelayBatch = 100
+
+var (
+	txSeenCacheSize = flag.Int("tx-seen-cache", 20000, "number of recent transactions remembered to filter repeated gossip")
+
+	txInventory = NewTxInventory() // Transactions seen recently and the peers known to have them
+)
+
+// TxInventory remembers recently seen transactions and which peers are known
+// to have each one, so gossip neither loops nor sends a peer a transaction
+// it already has. The oldest transactions are forgotten first.
+type TxInventory struct {
+	mu    sync.Mutex
+	known map[string]map[string]bool // Peers known to have a transaction, by transaction ID
+	order []string                   // Transaction IDs, oldest first
+}
+
+func NewTxInventory() *TxInventory {
+	return &TxInventory{known: make(map[string]map[string]bool)}
+}
+
+// Record that peer has the transaction, or that this node has it when peer
+// is empty. Reports whether the transaction had not been seen before.
+func (inv *TxInventory) Mark(id, peer string) bool {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	peers, seen := inv.known[id]
+	if !seen {
+		peers = make(map[string]bool)
+		inv.known[id] = peers
+		inv.order = append(inv.order, id)
+		for len(inv.order) > *txSeenCacheSize {
+			delete(inv.known, inv.order[0])
+			inv.order = inv.order[1:]
+		}
+	}
+	if peer != "" {
+		peers[peer] = true
+	}
+	return !seen
+}
+
+// Return the peers not known to have the transaction, and record that they
+// will have it once it is sent.
+func (inv *TxInventory) Targets(id string, peers []string) []string {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	known, ok := inv.known[id]
+	if !ok {
+		return peers
+	}
+	var targets []string
+	for _, peer := range peers {
+		if !known[peer] {
+			known[peer] = true
+			targets = append(targets, peer)
+		}
+	}
+	return targets
+}
+
+// Relay transactions to every peer not known to have them.
+func relayTransactions(txs []Transaction) {
+	if peerManager == nil {
+		return
+	}
+	peers := peerManager.Peers()
+	batches := make(map[string][]Transaction)
+	for _, tx := range txs {
+		txInventory.Mark(tx.ID, "")
+		for _, peer := range txInventory.Targets(tx.ID, peers) {
+			batches[peer] = append(batches[peer], tx)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for peer, batch := range batches {
+		for len(batch) > 0 {
+			n := min(len(batch), maxRelayBatch)
+			wg.Add(1)
+			go func(peer string, batch []Transaction) {
+				defer wg.Done()
+				if err := transport.SendTransactions(peer, batch); err != nil {
+					p2pLog.Warn("Error relaying transactions", "peer", peer, "count", len(batch), "err", err)
+				}
+			}(peer, batch[:n])
+			batch = batch[n:]
+		}
+	}
+	wg.Wait()
+}
+
+// Add transactions relayed by a peer to the mempool and pass the new valid
+// ones on. sender is the peer's node ID if known.
+func handleRelayedTransactions(txs []Transaction, addr net.Addr, sender string) {
+	host := peerHost(addr)
+	var fresh []Transaction
+	for _, tx := range txs {
+		if !txInventory.Mark(tx.ID, host) {
+			continue
+		}
+		if err := checkTransaction(tx); err != nil {
+			txprocLog.Warn("Invalid relayed transaction", "peer", addr, "node", sender, "tx", tx.ID, "err", err)
+			penalizeNode(addr, sender, scoreMalformedMessage, "invalid transaction")
+			continue
+		}
+		if err := mempool.Add(tx, priorityNormal); err != nil {
+			txprocLog.Debug("Relayed transaction not added", "peer", addr, "tx", tx.ID, "err", err)
+			continue
+		}
+		txprocLog.Info("Received transaction from peer", "peer", addr, "node", sender, "tx", tx.ID)
+		fresh = append(fresh, tx)
+	}
+	if len(fresh) > 0 {
+		relayTransactions(fresh)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestTxInventoryFiltersKnownPeers(t *testing.T) {
+	inv := NewTxInventory()
+	if !inv.Mark("tx1", "198.51.100.1") {
+		t.Fatal("first sighting reported as seen")
+	}
+	if inv.Mark("tx1", "198.51.100.2") {
+		t.Fatal("second sighting reported as new")
+	}
+
+	peers := []string{"198.51.100.1", "198.51.100.2", "198.51.100.3"}
+	if got := inv.Targets("tx1", peers); len(got) != 1 || got[0] != "198.51.100.3" {
+		t.Fatalf("targets = %v, want only the peer without the transaction", got)
+	}
+	if got := inv.Targets("tx1", peers); len(got) != 0 {
+		t.Fatalf("targets after relaying = %v, want none", got)
+	}
+}
+
+func TestTxInventoryForgetsOldest(t *testing.T) {
+	defer func(n int) { *txSeenCacheSize = n }(*txSeenCacheSize)
+	*txSeenCacheSize = 2
+
+	inv := NewTxInventory()
+	inv.Mark("tx1", "")
+	inv.Mark("tx2", "")
+	inv.Mark("tx3", "")
+	if !inv.Mark("tx1", "") {
+		t.Error("oldest transaction is still remembered")
+	}
+	if inv.Mark("tx3", "") {
+		t.Error("newest transaction was forgotten")
+	}
+}
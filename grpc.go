@@ -0,0 +1,685 @@
+package main
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative node.proto
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// nodeService serves the Node service of node.proto on the block port when
+// -transport=grpc. It shares the block, sync and job handling of the TCP
+// protocol.
+type nodeService struct {
+	UnimplementedNodeServer
+	ctx context.Context // Cancelled on shutdown; running jobs are cancelled with it
+}
+
+// gRPC Block Server Thread
+func serveGRPC(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	ln, err := listenLimited(*blockAddr)
+	if err != nil {
+		p2pLog.Error("Error starting gRPC listener", "err", err)
+		return
+	}
+
+	server := grpc.NewServer(grpc.Creds(grpcCredentials("")), grpc.MaxRecvMsgSize(*maxMessageSize))
+	RegisterNodeServer(server, &nodeService{ctx: ctx})
+
+	// Shutdown waits for calls in flight
+	stopped := make(chan struct{})
+	context.AfterFunc(ctx, func() {
+		server.GracefulStop()
+		close(stopped)
+	})
+	if err := server.Serve(ln); err != nil {
+		p2pLog.Error("gRPC server failed", "err", err)
+		return
+	}
+	<-stopped
+}
+
+// Miner TLS for calls to host (or for serving when host is empty) when
+// configured; otherwise plaintext.
+func grpcCredentials(host string) credentials.TransportCredentials {
+	config := minerTLSConfig(host)
+	if config == nil {
+		return insecure.NewCredentials()
+	}
+	return credentials.NewTLS(config)
+}
+
+// Identify the miner making a call, like minerIdentity does for TCP
+// connections, and return its address. Without TLS the miner is known by
+// the node ID it proved when we dialed it, if it did.
+func grpcCaller(ctx context.Context) (string, net.Addr, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", nil, status.Error(codes.Internal, "no peer in call context")
+	}
+	if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.PeerCertificates) > 0 {
+		return certIdentity(info.State.PeerCertificates[0]), p.Addr, nil
+	}
+	if id := peerManager.NodeID(peerHost(p.Addr)); id != "" {
+		return id, p.Addr, nil
+	}
+	return peerHost(p.Addr), p.Addr, nil
+}
+
+func (s *nodeService) SubmitTransaction(ctx context.Context, req *SubmitTransactionRequest) (*PBTransaction, error) {
+	if *lightMode {
+		return nil, status.Error(codes.Unavailable, "light nodes do not run jobs")
+	}
+	if err := validateCID(req.ScriptCid); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid script CID: %v", err)
+	}
+	if err := validateCID(req.DataCid); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid data CID: %v", err)
+	}
+	if req.Fee < 0 {
+		return nil, status.Error(codes.InvalidArgument, "fee must not be negative")
+	}
+	if req.Recipient != "" {
+		if _, err := parsePayloadPublicKey(req.Recipient); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid recipient key: %v", err)
+		}
+	}
+	job := jobRequest{
+		ScriptCID:  req.ScriptCid,
+		DataCID:    req.DataCid,
+		Recipient:  req.Recipient,
+		LockHeight: int(req.LockHeight),
+		LockTime:   req.LockTime,
+		Fee:        req.Fee,
+		Runtime:    req.Runtime,
+		Env:        envFromPB(req.Env),
+		Params:     paramsFromPB(req.Params),
+	}
+	if _, err := lookupRuntime(job.Runtime); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if job.Env != nil {
+		if err := job.Env.Validate(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid environment: %v", err)
+		}
+	}
+	if err := checkJobParams(job); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	submitter := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		if err := chargeJob(p.Addr); err != nil {
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		}
+		submitter = peerHost(p.Addr)
+	}
+	job.ID = receipts.Open(job)
+
+	// Stop the job if either the caller goes away or the node shuts down
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(s.ctx, cancel)
+	defer stop()
+
+	done, err := jobQueue.Submit(ctx, submitter, job)
+	if err != nil {
+		receipts.Fail(job.ID, err)
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	outcome := <-done
+	if err := outcome.err; err != nil {
+		txprocLog.Error("Job failed", "job", job.ID, "script", job.ScriptCID, "data", job.DataCID, "err", err)
+		switch jobErrorCode(err, codeJobFailed) {
+		case codeTooLarge:
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		case codeTimedOut:
+			return nil, status.Error(codes.DeadlineExceeded, err.Error())
+		}
+		return nil, status.Error(codes.Aborted, err.Error())
+	}
+	return transactionToPB(outcome.tx), nil
+}
+
+func (s *nodeService) AnnounceBlock(ctx context.Context, req *AnnounceBlockRequest) (*AnnounceBlockResponse, error) {
+	sender, addr, err := grpcCaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	announcement := blockAnnouncement{Vote: signatureFromPB(req.Vote)}
+	switch {
+	case req.Block != nil:
+		if announcement.Block, err = json.Marshal(blockFromPB(req.Block)); err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	case req.Inventory != nil:
+		announcement.Inventory = &blockInventory{Hash: req.Inventory.Hash, Height: int(req.Inventory.Height), CID: req.Inventory.Cid}
+	default:
+		penalizeNode(addr, sender, scoreMalformedMessage, "malformed block")
+		return nil, status.Error(codes.InvalidArgument, "missing block")
+	}
+	if !handleBlockAnnouncement(announcement, sender, peerManager.NodeID(peerHost(addr)), addr) {
+		return nil, status.Error(codes.PermissionDenied, "node is misbehaving")
+	}
+	return &AnnounceBlockResponse{}, nil
+}
+
+func (s *nodeService) GetBlocks(ctx context.Context, req *GetBlocksRequest) (*GetBlocksResponse, error) {
+	blocks, err := servedBlocks(int(req.From), int(req.To), req.HeadersOnly)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	reply := &GetBlocksResponse{Blocks: make([]*PBBlock, 0, len(blocks))}
+	for _, block := range blocks {
+		reply.Blocks = append(reply.Blocks, blockToPB(block))
+	}
+	return reply, nil
+}
+
+func (s *nodeService) GetBlock(ctx context.Context, req *GetBlockRequest) (*GetBlocksResponse, error) {
+	reply := &GetBlocksResponse{}
+	if block, ok := lookupBlock(req.Hash); ok && !*lightMode {
+		reply.Blocks = append(reply.Blocks, blockToPB(block))
+	}
+	return reply, nil
+}
+
+func (s *nodeService) GetTransactionProof(ctx context.Context, req *GetTransactionProofRequest) (*GetTransactionProofResponse, error) {
+	reply := &GetTransactionProofResponse{}
+	if proof, err := chainStore.TransactionProof(req.TxId); err == nil {
+		reply.Proof = proofToPB(proof)
+	}
+	return reply, nil
+}
+
+func (s *nodeService) GetPeers(ctx context.Context, req *GetPeersRequest) (*GetPeersResponse, error) {
+	return &GetPeersResponse{Peers: peerManager.Peers()}, nil
+}
+
+func (s *nodeService) Ping(ctx context.Context, req *PingRequest) (*PingResponse, error) {
+	if err := checkNetworkID(req.NetworkId); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	// Whoever pings us is listening on the block port too
+	if p, ok := peer.FromContext(ctx); ok && peerManager.Add(peerHost(p.Addr)) {
+		p2pLog.Info("Learned peer from ping", "peer", peerHost(p.Addr))
+	}
+	reply := &PingResponse{Height: int64(chainStore.Height()), NetworkId: networkID}
+	if tip, ok := chainStore.Tip(); ok {
+		reply.Tip = tip.Hash
+	}
+	return reply, nil
+}
+
+func (s *nodeService) RelayTransactions(ctx context.Context, req *RelayTransactionsRequest) (*RelayTransactionsResponse, error) {
+	sender, addr, err := grpcCaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	txs := make([]Transaction, 0, len(req.Transactions))
+	for _, tx := range req.Transactions {
+		if tx == nil {
+			penalizeNode(addr, sender, scoreMalformedMessage, "malformed transactions")
+			return nil, status.Error(codes.InvalidArgument, "empty transaction")
+		}
+		txs = append(txs, transactionFromPB(tx))
+	}
+	handleRelayedTransactions(txs, addr, sender)
+	return &RelayTransactionsResponse{}, nil
+}
+
+func (s *nodeService) Hello(ctx context.Context, req *PBHello) (*PBHello, error) {
+	hello := helloFromPB(req)
+	reply := localHello()
+	if _, err := checkHello(hello); err != nil {
+		reply.Reject = err.Error()
+		return helloToPB(reply), nil
+	}
+	proof, err := proveHello(hello.Nonce)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	reply.Proof = proof
+
+	// A single call leaves the caller no way to answer a challenge, so its
+	// node ID is not trusted; the one it proved when we dialed it stays
+	if p, ok := peer.FromContext(ctx); ok {
+		host := peerHost(p.Addr)
+		hello.NodeID = peerManager.NodeID(host)
+		peerManager.recordHello(host, hello)
+	}
+	return helloToPB(reply), nil
+}
+
+// grpcTransport calls the Node service of other miners over one cached
+// client connection per miner, opened with a Hello call.
+type grpcTransport struct {
+	mu    sync.Mutex
+	conns map[string]*grpc.ClientConn // By miner IP
+}
+
+func newGRPCTransport() *grpcTransport {
+	return &grpcTransport{conns: make(map[string]*grpc.ClientConn)}
+}
+
+func (t *grpcTransport) client(addr string) (NodeClient, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if conn, ok := t.conns[addr]; ok {
+		return NewNodeClient(conn), nil
+	}
+
+	conn, err := grpc.NewClient("passthrough:///"+minerAddr(addr),
+		grpc.WithTransportCredentials(grpcCredentials(addr)),
+		grpc.WithContextDialer(func(ctx context.Context, target string) (net.Conn, error) {
+			return dialLimited(target)
+		}),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(*maxMessageSize)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	client := NewNodeClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+	ours := localHello()
+	reply, err := client.Hello(ctx, helloToPB(ours))
+	if err == nil {
+		hello := helloFromPB(reply)
+		if _, err = checkHello(hello); err == nil {
+			if hello, err = checkHelloReply(ours, hello); err == nil {
+				peerManager.recordHello(addr, hello)
+			}
+		}
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("handshake with %s failed: %v", addr, err)
+	}
+	t.conns[addr] = conn
+	return client, nil
+}
+
+func (t *grpcTransport) SendBlock(addr string, announcement blockAnnouncement) error {
+	client, err := t.client(addr)
+	if err != nil {
+		return err
+	}
+	req := &AnnounceBlockRequest{Vote: signatureToPB(announcement.Vote)}
+	if inv := announcement.Inventory; inv != nil {
+		req.Inventory = &PBBlockInventory{Hash: inv.Hash, Height: int64(inv.Height), Cid: inv.CID}
+	}
+	if announcement.Block != nil {
+		var block Block
+		if err := json.Unmarshal(announcement.Block, &block); err != nil {
+			return err
+		}
+		req.Block = blockToPB(block)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err = client.AnnounceBlock(ctx, req)
+	return err
+}
+
+func (t *grpcTransport) Block(addr, hash string) (Block, error) {
+	client, err := t.client(addr)
+	if err != nil {
+		return Block{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	reply, err := client.GetBlock(ctx, &GetBlockRequest{Hash: hash})
+	if err != nil {
+		return Block{}, err
+	}
+	if len(reply.Blocks) == 0 || reply.Blocks[0] == nil {
+		return Block{}, errBlockNotFound
+	}
+	return blockFromPB(reply.Blocks[0]), nil
+}
+
+func (t *grpcTransport) Exchange(addr string) ([]string, time.Duration, error) {
+	client, err := t.client(addr)
+	if err != nil {
+		return nil, 0, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	pong, err := client.Ping(ctx, &PingRequest{NetworkId: networkID})
+	if err != nil {
+		return nil, 0, err
+	}
+	rtt := time.Since(start)
+	if err := checkNetworkID(pong.NetworkId); err != nil {
+		return nil, rtt, err
+	}
+
+	reply, err := client.GetPeers(ctx, &GetPeersRequest{})
+	if err != nil {
+		return nil, rtt, err
+	}
+	return reply.Peers, rtt, nil
+}
+
+func (t *grpcTransport) Height(addr string) (int, error) {
+	client, err := t.client(addr)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	reply, err := client.Ping(ctx, &PingRequest{NetworkId: networkID})
+	if err != nil {
+		return 0, err
+	}
+	if err := checkNetworkID(reply.NetworkId); err != nil {
+		return 0, err
+	}
+	return int(reply.Height), nil
+}
+
+func (t *grpcTransport) Blocks(addr string, from, to int) ([]Block, error) {
+	return t.blocks(addr, &GetBlocksRequest{From: int64(from), To: int64(to)})
+}
+
+func (t *grpcTransport) Headers(addr string, from, to int) ([]Block, error) {
+	return t.blocks(addr, &GetBlocksRequest{From: int64(from), To: int64(to), HeadersOnly: true})
+}
+
+func (t *grpcTransport) blocks(addr string, req *GetBlocksRequest) ([]Block, error) {
+	client, err := t.client(addr)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	reply, err := client.GetBlocks(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	blocks := make([]Block, 0, len(reply.Blocks))
+	for _, block := range reply.Blocks {
+		if block == nil {
+			return nil, errors.New("peer returned an empty block")
+		}
+		blocks = append(blocks, blockFromPB(block))
+	}
+	return blocks, nil
+}
+
+func (t *grpcTransport) SendTransactions(addr string, txs []Transaction) error {
+	client, err := t.client(addr)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	req := &RelayTransactionsRequest{Transactions: make([]*PBTransaction, 0, len(txs))}
+	for _, tx := range txs {
+		req.Transactions = append(req.Transactions, transactionToPB(tx))
+	}
+	_, err = client.RelayTransactions(ctx, req)
+	return err
+}
+
+func (t *grpcTransport) TransactionProof(addr, txID string) (merkleProof, error) {
+	client, err := t.client(addr)
+	if err != nil {
+		return merkleProof{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	reply, err := client.GetTransactionProof(ctx, &GetTransactionProofRequest{TxId: txID})
+	if err != nil {
+		return merkleProof{}, err
+	}
+	if reply.Proof == nil {
+		return merkleProof{}, errTransactionNotFound
+	}
+	return proofFromPB(reply.Proof), nil
+}
+
+// Conversions between chain types and their protobuf messages. They must be
+// lossless, since block hashes are computed over the chain types.
+
+func helloToPB(hello helloMessage) *PBHello {
+	return &PBHello{
+		Version:    int64(hello.Version),
+		MinVersion: int64(hello.MinVersion),
+		Agent:      hello.Agent,
+		NetworkId:  hello.Network,
+		Height:     int64(hello.Height),
+		JobPort:    int64(hello.JobPort),
+		BlockPort:  int64(hello.BlockPort),
+		Reject:     hello.Reject,
+		NodeId:     hello.NodeID,
+		Time:       hello.Time,
+		Identity:   signatureToPB(hello.Identity),
+		Nonce:      hello.Nonce,
+		Proof:      signatureToPB(hello.Proof),
+	}
+}
+
+func helloFromPB(pb *PBHello) helloMessage {
+	return helloMessage{
+		Version:    int(pb.Version),
+		MinVersion: int(pb.MinVersion),
+		Agent:      pb.Agent,
+		Network:    pb.NetworkId,
+		Height:     int(pb.Height),
+		JobPort:    int(pb.JobPort),
+		BlockPort:  int(pb.BlockPort),
+		Reject:     pb.Reject,
+		NodeID:     pb.NodeId,
+		Time:       pb.Time,
+		Identity:   signatureFromPB(pb.Identity),
+		Nonce:      pb.Nonce,
+		Proof:      signatureFromPB(pb.Proof),
+	}
+}
+
+func blockToPB(block Block) *PBBlock {
+	pb := &PBBlock{
+		PrevHash:    block.PrevHash,
+		MerkleRoot:  block.MerkleRoot,
+		Nonce:       int64(block.Nonce),
+		Hash:        block.Hash,
+		PrevCid:     block.PrevCID,
+		BlockNumber: int64(block.BlockNumber),
+		Timestamp:   block.Timestamp,
+		NetworkId:   block.NetworkID,
+		Target:      block.Target,
+		MinerId:     block.MinerID,
+		MinerSig:    signatureToPB(block.MinerSig),
+		Version:     int64(block.Version),
+		Units:       block.Units,
+	}
+	for _, tx := range block.Transactions {
+		pb.Transactions = append(pb.Transactions, transactionToPB(tx))
+	}
+	return pb
+}
+
+func blockFromPB(pb *PBBlock) Block {
+	block := Block{
+		PrevHash:    pb.PrevHash,
+		MerkleRoot:  pb.MerkleRoot,
+		Nonce:       int(pb.Nonce),
+		Hash:        pb.Hash,
+		PrevCID:     pb.PrevCid,
+		BlockNumber: int(pb.BlockNumber),
+		Timestamp:   pb.Timestamp,
+		NetworkID:   pb.NetworkId,
+		Target:      pb.Target,
+		MinerID:     pb.MinerId,
+		MinerSig:    signatureFromPB(pb.MinerSig),
+		Version:     int(pb.Version),
+		Units:       pb.Units,
+	}
+	for _, tx := range pb.Transactions {
+		if tx != nil {
+			block.Transactions = append(block.Transactions, transactionFromPB(tx))
+		}
+	}
+	return block
+}
+
+func transactionToPB(tx Transaction) *PBTransaction {
+	pb := &PBTransaction{
+		Id:           tx.ID,
+		Data:         tx.Data,
+		Recipient:    tx.Recipient,
+		ScriptCid:    tx.ScriptCID,
+		DataCid:      tx.DataCID,
+		ResultHash:   tx.ResultHash,
+		LockHeight:   int64(tx.LockHeight),
+		LockTime:     tx.LockTime,
+		Fee:          tx.Fee,
+		NetworkId:    tx.NetworkID,
+		Signature:    signatureToPB(tx.Signature),
+		Runtime:      tx.Runtime,
+		Env:          envToPB(tx.Env),
+		Params:       paramsToPB(tx.Params),
+		ResultCid:    tx.ResultCID,
+		Units:        tx.Units,
+		ExpiryHeight: int64(tx.ExpiryHeight),
+	}
+	for _, file := range tx.Outputs {
+		pb.Outputs = append(pb.Outputs, &PBOutputFile{Name: file.Name, Cid: file.CID, Hash: file.Hash})
+	}
+	if tx.Proof != nil {
+		pb.Proof = &PBExecutionProof{System: tx.Proof.System, Data: tx.Proof.Data}
+	}
+	if tx.Coinbase != nil {
+		pb.Coinbase = &PBCoinbase{Address: tx.Coinbase.Address, Amount: tx.Coinbase.Amount}
+	}
+	return pb
+}
+
+func transactionFromPB(pb *PBTransaction) Transaction {
+	tx := Transaction{
+		ID:           pb.Id,
+		Data:         pb.Data,
+		Recipient:    pb.Recipient,
+		ScriptCID:    pb.ScriptCid,
+		DataCID:      pb.DataCid,
+		ResultHash:   pb.ResultHash,
+		LockHeight:   int(pb.LockHeight),
+		LockTime:     pb.LockTime,
+		Fee:          pb.Fee,
+		NetworkID:    pb.NetworkId,
+		Signature:    signatureFromPB(pb.Signature),
+		Runtime:      pb.Runtime,
+		Env:          envFromPB(pb.Env),
+		Params:       paramsFromPB(pb.Params),
+		ResultCID:    pb.ResultCid,
+		Units:        pb.Units,
+		ExpiryHeight: int(pb.ExpiryHeight),
+	}
+	for _, file := range pb.Outputs {
+		tx.Outputs = append(tx.Outputs, OutputFile{Name: file.Name, CID: file.Cid, Hash: file.Hash})
+	}
+	if pb.Proof != nil {
+		tx.Proof = &ExecutionProof{System: pb.Proof.System, Data: pb.Proof.Data}
+	}
+	if pb.Coinbase != nil {
+		tx.Coinbase = &Coinbase{Address: pb.Coinbase.Address, Amount: pb.Coinbase.Amount}
+	}
+	return tx
+}
+
+func proofToPB(proof merkleProof) *PBMerkleProof {
+	return &PBMerkleProof{
+		TxId:      proof.TxID,
+		BlockHash: proof.BlockHash,
+		Index:     int64(proof.Index),
+		Count:     int64(proof.Count),
+		Branch:    proof.Branch,
+		Header:    headerToPB(proof.Header),
+	}
+}
+
+func proofFromPB(pb *PBMerkleProof) merkleProof {
+	return merkleProof{
+		TxID:      pb.TxId,
+		BlockHash: pb.BlockHash,
+		Index:     int(pb.Index),
+		Count:     int(pb.Count),
+		Branch:    pb.Branch,
+		Header:    headerFromPB(pb.Header),
+	}
+}
+
+func headerToPB(header *Block) *PBBlock {
+	if header == nil {
+		return nil
+	}
+	return blockToPB(*header)
+}
+
+func headerFromPB(pb *PBBlock) *Block {
+	if pb == nil {
+		return nil
+	}
+	header := blockFromPB(pb)
+	return &header
+}
+
+func envToPB(env *ExecutionEnv) *PBExecutionEnv {
+	if env == nil {
+		return nil
+	}
+	return &PBExecutionEnv{Image: env.Image, Interpreter: env.Interpreter, Libraries: env.Libraries}
+}
+
+func envFromPB(pb *PBExecutionEnv) *ExecutionEnv {
+	if pb == nil {
+		return nil
+	}
+	return &ExecutionEnv{Image: pb.Image, Interpreter: pb.Interpreter, Libraries: pb.Libraries}
+}
+
+func paramsToPB(params *ScriptParams) *PBScriptParams {
+	if params == nil {
+		return nil
+	}
+	return &PBScriptParams{Args: params.Args, Vars: params.Vars}
+}
+
+func paramsFromPB(pb *PBScriptParams) *ScriptParams {
+	if pb == nil {
+		return nil
+	}
+	return &ScriptParams{Args: pb.Args, Vars: pb.Vars}
+}
+
+func signatureToPB(sig *Signature) *PBSignature {
+	if sig == nil {
+		return nil
+	}
+	return &PBSignature{Algorithm: sig.Algorithm, PublicKey: sig.PublicKey, Value: sig.Value}
+}
+
+func signatureFromPB(pb *PBSignature) *Signature {
+	if pb == nil {
+		return nil
+	}
+	return &Signature{Algorithm: pb.Algorithm, PublicKey: pb.PublicKey, Value: pb.Value}
+}
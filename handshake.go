@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Versions of the miner-to-miner protocol this node speaks. Two nodes talk
+// using the highest version both speak and disconnect if there is none.
+const (
+	protocolVersion    = 4 // Version 2 opens every connection with a hello, 3 announces blocks by inventory, 4 proves node IDs over a challenge
+	minProtocolVersion = 2
+)
+
+// Software name and release, sent to peers for diagnostics only.
+const nodeAgent = "algochain/0.2"
+
+// Time allowed for both hellos to be exchanged.
+const handshakeTimeout = 10 * time.Second
+
+// How far a hello's signing time may be from ours.
+const maxHelloSkew = 5 * time.Minute
+
+// First message in each direction on every block-port connection.
+type helloMessage struct {
+	Version    int    `json:"version"`    // Highest protocol version spoken
+	MinVersion int    `json:"minVersion"` // Lowest protocol version spoken
+	Agent      string `json:"agent,omitempty"`
+	Network    string `json:"network"`
+	Height     int    `json:"height"`
+	JobPort    int    `json:"jobPort,omitempty"`   // Port the sender takes compute jobs on
+	BlockPort  int    `json:"blockPort,omitempty"` // Port the sender takes miner connections on
+	Reject     string `json:"reject,omitempty"`    // Why the sender is closing the connection
+
+	// The sender's node ID, signed by its identity key over the network,
+	// node ID and signing time for nodes older than version 4. Anyone who
+	// has seen the hello can replay that signature, so newer nodes only
+	// trust a node ID proven over the challenge they sent.
+	NodeID   string     `json:"nodeId,omitempty"`
+	Time     int64      `json:"time,omitempty"`
+	Identity *Signature `json:"identity,omitempty"`
+
+	// A fresh challenge for the other side, and the sender's proof of its
+	// node ID over the other side's challenge. The dialer has not seen
+	// the listener's challenge when it says hello, so it sends its proof
+	// in a frameIdentity after the hellos.
+	Nonce string     `json:"nonce,omitempty"`
+	Proof *Signature `json:"proof,omitempty"`
+}
+
+// This node's hello.
+func localHello() helloMessage {
+	hello := helloMessage{
+		Version:    protocolVersion,
+		MinVersion: minProtocolVersion,
+		Agent:      nodeAgent,
+		Network:    networkID,
+		Height:     chainStore.Height(),
+		JobPort:    listenPort(*jobAddr),
+		BlockPort:  listenPort(*blockAddr),
+		Nonce:      newHelloNonce(),
+	}
+	if nodeSigner == nil {
+		return hello
+	}
+	hello.NodeID, hello.Time = nodeID, time.Now().Unix()
+	identity, err := signPayload(nodeSigner, sigDomainHello, helloDigest(hello))
+	if err != nil {
+		p2pLog.Warn("Error signing hello", "err", err)
+		hello.NodeID, hello.Time = "", 0
+		return hello
+	}
+	hello.Identity = &identity
+	return hello
+}
+
+// Bytes covered by a hello's identity signature.
+func helloDigest(hello helloMessage) []byte {
+	return []byte(fmt.Sprintf("%s|%s|%d", hello.Network, hello.NodeID, hello.Time))
+}
+
+func newHelloNonce() string {
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	return hex.EncodeToString(nonce)
+}
+
+// Prove this node's ID over a peer's challenge, or return nil if the node
+// has no identity or the peer sent no challenge.
+func proveHello(nonce string) (*Signature, error) {
+	if nodeSigner == nil || nonce == "" {
+		return nil, nil
+	}
+	proof, err := signPayload(nodeSigner, sigDomainHelloProof, helloProofDigest(networkID, nodeID, nonce))
+	if err != nil {
+		return nil, err
+	}
+	return &proof, nil
+}
+
+// Bytes covered by a proof of node ID over a challenge.
+func helloProofDigest(network, nodeID, nonce string) []byte {
+	return []byte(fmt.Sprintf("%s|%s|%s", network, nodeID, nonce))
+}
+
+// Check that proof is the hello's node ID signed over our challenge nonce.
+func verifyHelloProof(hello helloMessage, proof *Signature, nonce string) error {
+	if proof == nil {
+		return errors.New("node ID is not proven")
+	}
+	if err := proof.Verify(sigDomainHelloProof, helloProofDigest(hello.Network, hello.NodeID, nonce)); err != nil {
+		return fmt.Errorf("invalid node ID proof: %v", err)
+	}
+	pub, err := proof.Public()
+	if err != nil {
+		return err
+	}
+	id, err := nodeIDFromPublicKey(pub)
+	if err != nil {
+		return err
+	}
+	if id != hello.NodeID {
+		return fmt.Errorf("node ID proof belongs to node %s, not %s", id, hello.NodeID)
+	}
+	return nil
+}
+
+// Check the hello a peer answered ours with. A node ID without a proof over
+// our challenge, as from older nodes, is dropped; a bad proof is an error.
+func checkHelloReply(ours, peer helloMessage) (helloMessage, error) {
+	if peer.Proof == nil {
+		peer.NodeID = ""
+		return peer, nil
+	}
+	if err := verifyHelloProof(peer, peer.Proof, ours.Nonce); err != nil {
+		return peer, err
+	}
+	return peer, nil
+}
+
+// Check that a hello's node ID is backed by a recent signature from the key
+// it is derived from. Hellos from older nodes carry no node ID.
+func verifyHelloIdentity(hello helloMessage) error {
+	if hello.NodeID == "" {
+		return nil
+	}
+	if hello.Identity == nil {
+		return errors.New("hello has a node ID but no identity signature")
+	}
+	if skew := time.Since(time.Unix(hello.Time, 0)); skew > maxHelloSkew || skew < -maxHelloSkew {
+		return fmt.Errorf("hello identity was signed %s away from our clock", skew.Abs().Round(time.Second))
+	}
+	if err := hello.Identity.Verify(sigDomainHello, helloDigest(hello)); err != nil {
+		return fmt.Errorf("invalid hello identity: %v", err)
+	}
+	pub, err := hello.Identity.Public()
+	if err != nil {
+		return err
+	}
+	id, err := nodeIDFromPublicKey(pub)
+	if err != nil {
+		return err
+	}
+	if id != hello.NodeID {
+		return fmt.Errorf("hello identity belongs to node %s, not %s", id, hello.NodeID)
+	}
+	return nil
+}
+
+// Port number of a listen address, or 0 if it has none.
+func listenPort(addr string) int {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(port)
+	return n
+}
+
+// Check a peer's hello and pick the protocol version to speak with it.
+func checkHello(peer helloMessage) (int, error) {
+	if peer.Reject != "" {
+		return 0, fmt.Errorf("peer refused the connection: %s", peer.Reject)
+	}
+	if err := checkNetworkID(peer.Network); err != nil {
+		return 0, err
+	}
+	if err := verifyHelloIdentity(peer); err != nil {
+		return 0, err
+	}
+	if peer.NodeID != "" && peerBanned(peer.NodeID) {
+		return 0, fmt.Errorf("node %s is banned", peer.NodeID)
+	}
+	version := min(protocolVersion, peer.Version)
+	if version < minProtocolVersion || version < peer.MinVersion {
+		return 0, fmt.Errorf("no common protocol version: we speak %d-%d, peer %q speaks %d-%d",
+			minProtocolVersion, protocolVersion, peer.Agent, peer.MinVersion, peer.Version)
+	}
+	return version, nil
+}
+
+// Dial a miner and exchange hellos. The returned reader must be used for
+// everything read from the connection.
+func dialPeer(addr string) (net.Conn, *bufio.Reader, error) {
+	conn, err := dialMiner(minerAddr(addr))
+	if err != nil {
+		return nil, nil, err
+	}
+	reader := bufio.NewReader(conn)
+	if err := clientHandshake(conn, reader, addr); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("handshake with %s failed: %v", addr, err)
+	}
+	return conn, reader, nil
+}
+
+// Send our hello, then read and check the miner's, remembering it if the
+// miner is a known peer.
+func clientHandshake(conn net.Conn, reader *bufio.Reader, addr string) error {
+	peer, err := sayHello(conn, reader, localHello())
+	if err != nil {
+		return err
+	}
+	peerManager.recordHello(addr, peer)
+	return nil
+}
+
+// Send ours as the hello, read and check the miner's and answer its
+// challenge. The returned hello has a node ID only if the miner proved it.
+func sayHello(conn net.Conn, reader *bufio.Reader, ours helloMessage) (helloMessage, error) {
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	if err := writeHello(conn, ours); err != nil {
+		return helloMessage{}, err
+	}
+	peer, err := readHello(reader)
+	if err != nil {
+		return helloMessage{}, err
+	}
+	if _, err := checkHello(peer); err != nil {
+		return peer, err
+	}
+	if peer, err = checkHelloReply(ours, peer); err != nil {
+		return peer, err
+	}
+	if ours.NodeID == "" || peer.Nonce == "" {
+		return peer, nil
+	}
+	proof, err := proveHello(peer.Nonce)
+	if err != nil {
+		return peer, err
+	}
+	data, err := json.Marshal(proof)
+	if err != nil {
+		return peer, err
+	}
+	return peer, writeFrame(conn, frameIdentity, data)
+}
+
+// Read and check a connecting miner's hello, then answer with ours. An
+// incompatible miner gets a hello saying why before the connection is
+// closed, so it can tell a refusal from a network failure. The returned
+// hello has a node ID only if the miner proved it over our challenge.
+func serverHandshake(conn net.Conn, reader *bufio.Reader) (helloMessage, error) {
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	peer, err := readHello(reader)
+	if err != nil {
+		return helloMessage{}, err
+	}
+	reply := localHello()
+	if _, err = checkHello(peer); err != nil {
+		reply.Reject = err.Error()
+	} else if reply.Proof, err = proveHello(peer.Nonce); err != nil {
+		reply.Reject = "internal error"
+	}
+	if writeErr := writeHello(conn, reply); err == nil {
+		err = writeErr
+	}
+	if err != nil || peer.NodeID == "" {
+		return peer, err
+	}
+
+	// Older miners cannot answer the challenge, so their node ID is not
+	// trusted
+	if peer.Nonce == "" {
+		peer.NodeID = ""
+		return peer, nil
+	}
+	payload, err := readFrameOfType(reader, frameIdentity)
+	if err != nil {
+		return peer, err
+	}
+	var proof Signature
+	if err := json.Unmarshal(payload, &proof); err != nil {
+		return peer, fmt.Errorf("malformed node ID proof: %v", err)
+	}
+	return peer, verifyHelloProof(peer, &proof, reply.Nonce)
+}
+
+func writeHello(conn net.Conn, hello helloMessage) error {
+	data, err := json.Marshal(hello)
+	if err != nil {
+		return err
+	}
+	return writeFrame(conn, frameHello, data)
+}
+
+func readHello(reader *bufio.Reader) (helloMessage, error) {
+	payload, err := readFrameOfType(reader, frameHello)
+	if err != nil {
+		return helloMessage{}, err
+	}
+	var hello helloMessage
+	if err := json.Unmarshal(payload, &hello); err != nil {
+		return helloMessage{}, fmt.Errorf("malformed hello: %v", err)
+	}
+	return hello, nil
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckHelloNegotiatesVersion(t *testing.T) {
+	setupTestMiner(t)
+	hello := func(minVersion, version int) helloMessage {
+		return helloMessage{Version: version, MinVersion: minVersion, Network: networkID}
+	}
+
+	tests := []struct {
+		name    string
+		peer    helloMessage
+		version int // 0 if the peer is incompatible
+	}{
+		{"same versions", hello(minProtocolVersion, protocolVersion), protocolVersion},
+		{"newer peer", hello(minProtocolVersion, protocolVersion+3), protocolVersion},
+		{"peer too old", hello(minProtocolVersion-1, minProtocolVersion-1), 0},
+		{"peer dropped our version", hello(protocolVersion+1, protocolVersion+2), 0},
+		{"other network", helloMessage{Version: protocolVersion, MinVersion: minProtocolVersion, Network: "other"}, 0},
+		{"refusal", helloMessage{Version: protocolVersion, MinVersion: minProtocolVersion, Network: networkID, Reject: "full"}, 0},
+	}
+	for _, test := range tests {
+		version, err := checkHello(test.peer)
+		if version != test.version || (err == nil) != (test.version != 0) {
+			t.Errorf("%s: checkHello = %d, %v; want version %d", test.name, version, err, test.version)
+		}
+	}
+}
+
+// Run a client and a server handshake over an in-memory connection,
+// returning the client's hello as the server trusts it.
+func pipeHandshake(t *testing.T, clientHello helloMessage) (clientErr, serverErr error, seen helloMessage) {
+	t.Helper()
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	clientDone := make(chan error, 1)
+	go func() {
+		_, err := sayHello(client, bufio.NewReader(client), clientHello)
+		clientDone <- err
+	}()
+	seen, serverErr = serverHandshake(server, bufio.NewReader(server))
+	server.Close() // Unblocks a proof the server does not wait for
+	return <-clientDone, serverErr, seen
+}
+
+func TestHandshake(t *testing.T) {
+	setupTestMiner(t)
+	peerManager = NewPeerManager(nil)
+
+	clientErr, serverErr, _ := pipeHandshake(t, localHello())
+	if clientErr != nil || serverErr != nil {
+		t.Fatalf("compatible handshake failed: client %v, server %v", clientErr, serverErr)
+	}
+
+	// An incompatible miner is told why before the connection is closed
+	foreign := localHello()
+	foreign.Network = "other"
+	clientErr, serverErr, _ = pipeHandshake(t, foreign)
+	if serverErr == nil {
+		t.Error("server accepted a miner of another network")
+	}
+	if clientErr == nil || !strings.Contains(clientErr.Error(), "refused") {
+		t.Errorf("client was not told it was refused: %v", clientErr)
+	}
+}
+
+func TestHelloSetsPeerBlockPort(t *testing.T) {
+	peerManager = NewPeerManager([]string{"198.51.100.7"})
+	defer func() { peerManager = nil }()
+
+	peerManager.recordHello("198.51.100.7", helloMessage{BlockPort: 9091})
+	if got := minerAddr("198.51.100.7"); got != "198.51.100.7:9091" {
+		t.Errorf("announced block port is not dialed: %s", got)
+	}
+	if got := minerAddr("198.51.100.8"); got != "198.51.100.8:8081" {
+		t.Errorf("unknown peer is not dialed on our block port: %s", got)
+	}
+}
+
+func TestHelloIdentity(t *testing.T) {
+	setupTestMiner(t)
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(s Signer, id string) { nodeSigner, nodeID = s, id }(nodeSigner, nodeID)
+	nodeSigner = cryptoSigner{key: key}
+	if nodeID, err = nodeIDFromPublicKey(key.Public()); err != nil {
+		t.Fatal(err)
+	}
+
+	hello := localHello()
+	if hello.NodeID != nodeID {
+		t.Fatalf("hello node ID = %q, want %q", hello.NodeID, nodeID)
+	}
+	if _, err := checkHello(hello); err != nil {
+		t.Fatalf("signed hello rejected: %v", err)
+	}
+
+	resign := func(h helloMessage) helloMessage {
+		sig, err := signPayload(nodeSigner, sigDomainHello, helloDigest(h))
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.Identity = &sig
+		return h
+	}
+	claimed := hello
+	claimed.NodeID = strings.Repeat("ab", 20)
+	unsigned := hello
+	unsigned.Identity = nil
+	old := hello
+	old.Time -= int64(time.Hour / time.Second)
+
+	tests := []struct {
+		name  string
+		hello helloMessage
+	}{
+		{"claimed node ID", claimed},
+		{"claimed node ID re-signed", resign(claimed)},
+		{"missing signature", unsigned},
+		{"old signature", resign(old)},
+	}
+	for _, test := range tests {
+		if _, err := checkHello(test.hello); err == nil {
+			t.Errorf("%s: hello accepted", test.name)
+		}
+	}
+
+	penalizeIdentity(nodeID, peerScoreLimit, "test")
+	defer func() {
+		peerLimitsMu.Lock()
+		delete(peerBans, nodeID)
+		peerLimitsMu.Unlock()
+	}()
+	if _, err := checkHello(hello); err == nil {
+		t.Error("hello from a banned node accepted")
+	}
+}
+
+func TestHelloProofIsBoundToConnection(t *testing.T) {
+	setupTestMiner(t)
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(s Signer, id string) { nodeSigner, nodeID = s, id }(nodeSigner, nodeID)
+	nodeSigner = cryptoSigner{key: key}
+	if nodeID, err = nodeIDFromPublicKey(key.Public()); err != nil {
+		t.Fatal(err)
+	}
+
+	clientErr, serverErr, seen := pipeHandshake(t, localHello())
+	if clientErr != nil || serverErr != nil || seen.NodeID != nodeID {
+		t.Fatalf("proven handshake: client %v, server %v, node ID %q", clientErr, serverErr, seen.NodeID)
+	}
+
+	// A hello from an older node has no challenge to answer, so its node ID
+	// is not trusted
+	older := localHello()
+	older.Nonce = ""
+	if _, serverErr, seen := pipeHandshake(t, older); serverErr != nil || seen.NodeID != "" {
+		t.Errorf("older hello: server %v, node ID %q", serverErr, seen.NodeID)
+	}
+
+	// A captured hello and proof do not answer another connection's challenge
+	captured, err := proveHello(newHelloNonce())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	done := make(chan error, 1)
+	go func() {
+		_, err := serverHandshake(server, bufio.NewReader(server))
+		done <- err
+	}()
+	go func() {
+		reader := bufio.NewReader(client)
+		writeHello(client, localHello())
+		readHello(reader)
+		data, _ := json.Marshal(captured)
+		writeFrame(client, frameIdentity, data)
+	}()
+	if err := <-done; err == nil {
+		t.Error("replayed node ID proof accepted")
+	}
+}
+
+func TestPeerKeepsNodeIDAcrossAddresses(t *testing.T) {
+	peerManager = NewPeerManager([]string{"198.51.100.7", "198.51.100.9"})
+	defer func() { peerManager = nil }()
+
+	id := strings.Repeat("cd", 20)
+	peerManager.recordHello("198.51.100.7", helloMessage{Version: protocolVersion, NodeID: id})
+	if got := peerManager.NodeID("198.51.100.7"); got != id {
+		t.Fatalf("NodeID = %q, want %q", got, id)
+	}
+
+	// The node reconnects from another address
+	peerManager.recordHello("198.51.100.9", helloMessage{Version: protocolVersion, NodeID: id})
+	if got := peerManager.NodeID("198.51.100.9"); got != id {
+		t.Errorf("NodeID at new address = %q, want %q", got, id)
+	}
+	if peers := peerManager.Peers(); len(peers) != 1 || peers[0] != "198.51.100.9" {
+		t.Errorf("peers = %v, want only the new address", peers)
+	}
+}
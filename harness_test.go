@@ -0,0 +1,426 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	shell "github.com/ipfs/go-ipfs-api"
+)
+
+// Target used by test networks: about one hash in 64 meets it.
+const testTargetBits = 250
+
+// Genesis shared by the nodes of test networks.
+var testGenesis = Genesis{ChainID: "test", TargetBits: testTargetBits}
+
+// CIDs test jobs name for their script and data.
+const (
+	testScriptCID = "QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG"
+	testDataCID   = "bafkreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52zy"
+	testJobCIDs   = testScriptCID + " " + testDataCID
+)
+
+// fakeIPFS is an in-memory IPFS node shared by every node of a test network.
+type fakeIPFS struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	pins    map[string]bool
+}
+
+func newFakeIPFS() *fakeIPFS {
+	return &fakeIPFS{objects: make(map[string][]byte), pins: make(map[string]bool)}
+}
+
+func (f *fakeIPFS) Add(r io.Reader, options ...shell.AddOpts) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	cid := mockCID(data) // A valid CID, as transactions record them
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.objects[cid] = data
+	f.pins[cid] = true
+	return cid, nil
+}
+
+func (f *fakeIPFS) Cat(path string) (io.ReadCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.objects[path]
+	if !ok {
+		return nil, fmt.Errorf("%s not found", path)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (f *fakeIPFS) Pin(path string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.objects[path]; !ok {
+		return fmt.Errorf("%s not found", path)
+	}
+	f.pins[path] = true
+	return nil
+}
+
+func (f *fakeIPFS) Unpin(path string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.pins, path)
+	return nil
+}
+
+func (f *fakeIPFS) Pins() (map[string]shell.PinInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	pins := make(map[string]shell.PinInfo)
+	for cid := range f.pins {
+		pins[cid] = shell.PinInfo{Type: "recursive"}
+	}
+	return pins, nil
+}
+
+func (f *fakeIPFS) ObjectStat(key string) (*shell.ObjectStats, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.objects[key]
+	if !ok {
+		return nil, fmt.Errorf("%s not found", key)
+	}
+	return &shell.ObjectStats{Hash: key, CumulativeSize: len(data), DataSize: len(data)}, nil
+}
+
+func (f *fakeIPFS) IsUp() bool { return true }
+
+// testNode holds the state of one node of a test network. The node code
+// keeps its state in package globals, so the network swaps a node's state
+// in while running code as that node.
+type testNode struct {
+	ip    string
+	store *ChainStore
+
+	mempool       *Mempool
+	ledger        *Ledger
+	peers         *PeerManager
+	wallet        Signer
+	walletAddress string
+	identity      Signer
+	id            string
+	sideBlocks    map[string]sideBlock
+	state         *NodeState
+	receipts      *ReceiptStore
+	orphans       *OrphanPool
+	stale         *StaleLog
+	txInventory   *TxInventory
+	newTip        chan struct{}
+	light         bool // Runs with -light
+
+	headHash   string
+	headNumber int
+	headTime   time.Time
+	target     *big.Int
+}
+
+// testNetwork runs nodes in one process, one at a time. Blocks announced
+// over its transport are queued until Deliver; sync requests are answered
+// straight from the serving node's store.
+type testNetwork struct {
+	t     *testing.T
+	mu    sync.Mutex // Held while a node runs
+	nodes []*testNode
+	byIP  map[string]*testNode
+	ipfs  *fakeIPFS
+
+	queueMu sync.Mutex // Announcements are sent from several goroutines
+	queue   []testDelivery
+}
+
+type testDelivery struct {
+	from, to     *testNode
+	announcement blockAnnouncement
+	txs          []Transaction // Relayed transactions instead of a block
+}
+
+// Start a network of n fully connected nodes with only the genesis block.
+func newTestNetwork(t *testing.T, n int) *testNetwork {
+	t.Helper()
+	if err := useGenesis(testGenesis); err != nil {
+		t.Fatal(err)
+	}
+	miningEnabled.Store(true)
+
+	network := &testNetwork{t: t, byIP: make(map[string]*testNode), ipfs: newFakeIPFS()}
+	for i := 0; i < n; i++ {
+		network.addNode(fmt.Sprintf("198.51.100.%d", i+1))
+	}
+	for _, node := range network.nodes {
+		for _, other := range network.nodes {
+			if other != node {
+				node.peers.Add(other.ip)
+			}
+		}
+	}
+	return network
+}
+
+func (tn *testNetwork) addNode(ip string) *testNode {
+	tn.t.Helper()
+	store, err := OpenChainStore(tn.t.TempDir())
+	if err != nil {
+		tn.t.Fatal(err)
+	}
+	tn.t.Cleanup(func() { store.Close() })
+	stale, err := OpenStaleLog(tn.t.TempDir())
+	if err != nil {
+		tn.t.Fatal(err)
+	}
+	tn.t.Cleanup(func() { stale.Close() })
+
+	wallet, err := generateSigningKey(sigAlgECDSAP256)
+	if err != nil {
+		tn.t.Fatal(err)
+	}
+	identity, err := generateSigningKey(sigAlgEd25519)
+	if err != nil {
+		tn.t.Fatal(err)
+	}
+	node := &testNode{
+		ip:          ip,
+		store:       store,
+		mempool:     NewMempool(),
+		ledger:      NewLedger(),
+		peers:       NewPeerManager(nil),
+		wallet:      cryptoSigner{key: wallet},
+		identity:    cryptoSigner{key: identity},
+		sideBlocks:  make(map[string]sideBlock),
+		state:       NewNodeState(),
+		receipts:    NewReceiptStore(),
+		orphans:     NewOrphanPool(),
+		stale:       stale,
+		txInventory: NewTxInventory(),
+		newTip:      make(chan struct{}, 1),
+		target:      new(big.Int).Set(initialTarget),
+	}
+	if node.walletAddress, err = nodeIDFromPublicKey(wallet.Public()); err != nil {
+		tn.t.Fatal(err)
+	}
+	if node.id, err = nodeIDFromPublicKey(identity.Public()); err != nil {
+		tn.t.Fatal(err)
+	}
+	tn.Run(node, func() { err = initGenesis() })
+	if err != nil {
+		tn.t.Fatal(err)
+	}
+	tn.nodes = append(tn.nodes, node)
+	tn.byIP[ip] = node
+	return node
+}
+
+// Run fn as the node, with the node's state in the package globals.
+func (tn *testNetwork) Run(node *testNode, fn func()) {
+	tn.mu.Lock()
+	defer tn.mu.Unlock()
+	defer func(light bool) { *lightMode = light }(*lightMode)
+	*lightMode = node.light
+
+	chainStore, mempool, ledger, peerManager = node.store, node.mempool, node.ledger, node.peers
+	walletSigner, walletAddress, nodeSigner, nodeID = node.wallet, node.walletAddress, node.identity, node.id
+	sideBlocks, nodeState, receipts, orphans, newTip = node.sideBlocks, node.state, node.receipts, node.orphans, node.newTip
+	staleBlocks, txInventory = node.stale, node.txInventory
+	headHash, headNumber, headTime = node.headHash, node.headNumber, node.headTime
+	target = node.target
+	transport = testTransport{network: tn, from: node}
+	ipfsShell = tn.ipfs
+
+	fn()
+
+	node.headHash, node.headNumber, node.headTime = headHash, headNumber, headTime
+	node.target = target
+}
+
+// Mine a block on the node's tip with its pending transactions and publish
+// it to the network.
+func (tn *testNetwork) Mine(node *testNode) Block {
+	tn.t.Helper()
+	var block Block
+	var err error
+	tn.Run(node, func() {
+		height := chainStore.Height()
+		tip, _ := chainStore.Tip()
+		template := blockTemplate(height, tip.Hash, tip.PrevCID, mempool.Select(*maxBlockTxs, height, nextBlockTimestamp(tip.Hash)))
+		if err = signBlockHeader(&template); err != nil {
+			return
+		}
+		var found bool
+		block, found = consensus.Seal(context.Background(), nil, template)
+		if !found {
+			err = errors.New("mining was interrupted")
+			return
+		}
+		err = publishBlock(block)
+	})
+	if err != nil {
+		tn.t.Fatal(err)
+	}
+	return block
+}
+
+// Deliver queued block announcements and relayed transactions until none
+// are left.
+func (tn *testNetwork) Deliver() {
+	for {
+		tn.queueMu.Lock()
+		if len(tn.queue) == 0 {
+			tn.queueMu.Unlock()
+			return
+		}
+		delivery := tn.queue[0]
+		tn.queue = tn.queue[1:]
+		tn.queueMu.Unlock()
+
+		addr := &net.TCPAddr{IP: net.ParseIP(delivery.from.ip), Port: 8081}
+		tn.Run(delivery.to, func() {
+			if delivery.txs != nil {
+				handleRelayedTransactions(delivery.txs, addr, delivery.from.id)
+				return
+			}
+			handleBlockAnnouncement(delivery.announcement, delivery.from.ip, delivery.from.id, addr)
+		})
+	}
+}
+
+// Deliver pending announcements, then let every node sync from its peers.
+func (tn *testNetwork) Settle() {
+	tn.Deliver()
+	for _, node := range tn.nodes {
+		tn.Run(node, func() { syncChain(context.Background()) })
+	}
+}
+
+// Fail the test unless every node has the same chain of the given height.
+func (tn *testNetwork) RequireConverged(height int) {
+	tn.t.Helper()
+	want, ok := tn.nodes[0].store.Tip()
+	for _, node := range tn.nodes {
+		tip, tipOK := node.store.Tip()
+		if node.store.Height() != height || tipOK != ok || tip.Hash != want.Hash {
+			tn.t.Fatalf("node %s has height %d and tip %q, want height %d and tip %q",
+				node.ip, node.store.Height(), tip.Hash, height, want.Hash)
+		}
+	}
+}
+
+// testTransport carries one node's requests to the other nodes of its
+// network.
+type testTransport struct {
+	network *testNetwork
+	from    *testNode
+}
+
+func (t testTransport) node(addr string) (*testNode, error) {
+	node, ok := t.network.byIP[addr]
+	if !ok {
+		return nil, fmt.Errorf("no node at %s", addr)
+	}
+	return node, nil
+}
+
+func (t testTransport) SendBlock(addr string, announcement blockAnnouncement) error {
+	to, err := t.node(addr)
+	if err != nil {
+		return err
+	}
+	t.network.queueMu.Lock()
+	defer t.network.queueMu.Unlock()
+	t.network.queue = append(t.network.queue, testDelivery{from: t.from, to: to, announcement: announcement})
+	return nil
+}
+
+func (t testTransport) Block(addr, hash string) (Block, error) {
+	node, err := t.node(addr)
+	if err != nil {
+		return Block{}, err
+	}
+	if side, ok := node.sideBlocks[hash]; ok {
+		return side.block, nil
+	}
+	return node.store.GetBlockByHash(hash)
+}
+
+func (t testTransport) SendTransactions(addr string, txs []Transaction) error {
+	to, err := t.node(addr)
+	if err != nil {
+		return err
+	}
+	t.network.queueMu.Lock()
+	defer t.network.queueMu.Unlock()
+	t.network.queue = append(t.network.queue, testDelivery{from: t.from, to: to, txs: txs})
+	return nil
+}
+
+func (t testTransport) Exchange(addr string) ([]string, time.Duration, error) {
+	to, err := t.node(addr)
+	if err != nil {
+		return nil, 0, err
+	}
+	return to.peers.Peers(), time.Millisecond, nil
+}
+
+func (t testTransport) Height(addr string) (int, error) {
+	to, err := t.node(addr)
+	if err != nil {
+		return 0, err
+	}
+	return to.store.Height(), nil
+}
+
+func (t testTransport) Blocks(addr string, from, to int) ([]Block, error) {
+	return t.blocks(addr, from, to, false)
+}
+
+func (t testTransport) Headers(addr string, from, to int) ([]Block, error) {
+	return t.blocks(addr, from, to, true)
+}
+
+func (t testTransport) blocks(addr string, from, to int, headers bool) ([]Block, error) {
+	node, err := t.node(addr)
+	if err != nil {
+		return nil, err
+	}
+	if node.light && !headers {
+		return nil, nil
+	}
+	var blocks []Block
+	for n := from; n <= to && n < from+*syncBatchSize; n++ {
+		block, err := node.store.GetBlockByNumber(n)
+		if errors.Is(err, errBlockNotFound) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if headers {
+			block = block.Header()
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+func (t testTransport) TransactionProof(addr, txID string) (merkleProof, error) {
+	node, err := t.node(addr)
+	if err != nil {
+		return merkleProof{}, err
+	}
+	return node.store.TransactionProof(txID)
+}
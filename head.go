@@ -0,0 +1,44 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	headMu     sync.Mutex
+	headHash   string    // Hash of the latest block this node mined or accepted
+	headNumber int       // Number of that block
+	headTime   time.Time // When this node saw that block
+
+	newTip = make(chan struct{}, 1) // Signalled when the head changes
+)
+
+// Record the latest block this node mined or accepted, set the target for
+// the block after it and notify the miner.
+func setChainHead(block Block) {
+	now := time.Now()
+	headMu.Lock()
+	headHash, headNumber, headTime = block.Hash, block.BlockNumber, now
+	headMu.Unlock()
+
+	updateTarget(block)
+
+	select {
+	case newTip <- struct{}{}:
+	default:
+	}
+}
+
+func chainHead() (string, int) {
+	headMu.Lock()
+	defer headMu.Unlock()
+	return headHash, headNumber
+}
+
+// Time the current head was seen, or zero if no block has been seen yet.
+func chainHeadTime() time.Time {
+	headMu.Lock()
+	defer headMu.Unlock()
+	return headTime
+}
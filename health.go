@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var healthMaxLag = flag.Int("health-max-lag", 3, "blocks a node may trail its best peer before GET /health reports it unhealthy")
+
+// The node's view of how far its chain is behind its peers.
+type syncStatus struct {
+	Height     int    `json:"height"`
+	PeerHeight int    `json:"peerHeight"` // Best height peers announced and backed with blocks; -1 if none did
+	Behind     int    `json:"behind"`     // Blocks to go to reach PeerHeight
+	State      string `json:"state"`      // "at tip" or "behind"
+}
+
+func currentSyncStatus() syncStatus {
+	status := syncStatus{Height: chainStore.Height(), PeerHeight: -1, State: "at tip"}
+	if peerManager != nil {
+		// Hellos are unchecked; a claim only counts as far as blocks this
+		// node has verified back it up
+		status.PeerHeight = min(peerManager.BestHeight(), verifiedHeight())
+	}
+	if status.PeerHeight > status.Height {
+		status.Behind = status.PeerHeight - status.Height
+		status.State = "behind"
+	}
+	return status
+}
+
+// Height the node has seen proof of work for: its own chain, a side
+// branch, or an orphan waiting for its parents that meets the chain's
+// current target. An orphan sealed against an easier target costs next to
+// nothing to make.
+func verifiedHeight() int {
+	height := chainStore.Height()
+	chainMu.Lock()
+	for _, side := range sideBlocks {
+		height = max(height, side.block.BlockNumber+1)
+	}
+	chainMu.Unlock()
+	return max(height, orphans.MaxBlockNumber(currentTarget())+1)
+}
+
+// Reasons the node should not serve traffic: IPFS is unreachable or the
+// chain trails the peers by more than -health-max-lag blocks.
+func healthProblems(ipfsUp bool, sync syncStatus) []string {
+	var problems []string
+	if !ipfsUp {
+		problems = append(problems, "IPFS daemon is unreachable")
+	}
+	if sync.Behind > *healthMaxLag {
+		problems = append(problems, fmt.Sprintf("chain is %d blocks behind its peers", sync.Behind))
+	}
+	return problems
+}
+
+// GET /health: 200 if the node is healthy, 503 with the problems if not,
+// for load balancers.
+func handleHealth(w http.ResponseWriter, r *http.Request) {
+	problems := healthProblems(ipfsShell.IsUp(), currentSyncStatus())
+	if len(problems) > 0 {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"status": "unhealthy", "problems": problems})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+}
+
+// GET /status: IPFS connectivity, peers, sync status, mempool depth, chain
+// store disk usage and the time of the last block, for monitoring. Always
+// 200; the healthy field says what GET /health would.
+func handleStatus(w http.ResponseWriter, r *http.Request) {
+	ipfsUp := ipfsShell.IsUp()
+	sync := currentSyncStatus()
+	problems := healthProblems(ipfsUp, sync)
+	status := map[string]any{
+		"healthy":  len(problems) == 0,
+		"problems": problems,
+		"ipfs":     map[string]any{"daemonUp": ipfsUp, "degraded": ipfsDegraded.Load()},
+		"sync":     sync,
+		"mempool":  mempool.Len(),
+		"light":    *lightMode,
+	}
+	if peerManager != nil {
+		status["peers"] = peerManager.Count()
+	}
+	if size, err := chainStore.DiskUsage(); err == nil {
+		status["chainStoreBytes"] = size
+	} else {
+		apiLog.Warn("Could not measure chain store", "err", err)
+	}
+	if tip, ok := chainStore.Tip(); ok {
+		status["tip"] = tip.Hash
+		if tip.Timestamp != 0 {
+			lastBlock := time.Unix(tip.Timestamp, 0)
+			status["lastBlockTime"] = lastBlock.UTC().Format(time.RFC3339)
+			status["lastBlockAge"] = int64(time.Since(lastBlock).Seconds())
+		}
+	}
+	writeJSON(w, http.StatusOK, status)
+}
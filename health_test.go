@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthAndStatus(t *testing.T) {
+	setupTestMiner(t)
+	saved, savedIPFS, savedOrphans := peerManager, ipfsShell, orphans
+	t.Cleanup(func() { peerManager, ipfsShell, orphans = saved, savedIPFS, savedOrphans })
+	ipfsShell = newFakeIPFS()
+	peerManager = NewPeerManager([]string{"198.51.100.7"})
+	orphans = NewOrphanPool()
+
+	get := func(handler http.HandlerFunc, path string) (int, map[string]any) {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		var body map[string]any
+		if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		return recorder.Code, body
+	}
+
+	// A peer within -health-max-lag blocks, which sent its tip
+	height := chainStore.Height()
+	orphans.Add(sealedTestOrphan(t, height+*healthMaxLag-1))
+	peerManager.recordHello("198.51.100.7", helloMessage{Version: protocolVersion, Height: height + *healthMaxLag})
+	if code, body := get(handleHealth, "/health"); code != http.StatusOK {
+		t.Errorf("node within the lag is unhealthy: %d %v", code, body)
+	}
+	code, status := get(handleStatus, "/status")
+	if code != http.StatusOK || status["healthy"] != true || status["peers"] != 1.0 || status["mempool"] != 0.0 {
+		t.Errorf("status of a healthy node: %d %v", code, status)
+	}
+	if sync, _ := status["sync"].(map[string]any); sync["state"] != "behind" || sync["behind"] != float64(*healthMaxLag) {
+		t.Errorf("sync status %v", status["sync"])
+	}
+	if size, _ := status["chainStoreBytes"].(float64); size <= 0 {
+		t.Errorf("chain store takes %v bytes", status["chainStoreBytes"])
+	}
+
+	// A peer further ahead
+	orphans.Add(sealedTestOrphan(t, height+*healthMaxLag))
+	peerManager.recordHello("198.51.100.7", helloMessage{Version: protocolVersion, Height: height + *healthMaxLag + 1})
+	if code, body := get(handleHealth, "/health"); code != http.StatusServiceUnavailable || body["status"] != "unhealthy" {
+		t.Errorf("node behind its peers is healthy: %d %v", code, body)
+	}
+	if _, status := get(handleStatus, "/status"); status["healthy"] != false {
+		t.Errorf("status of a lagging node: %v", status)
+	}
+}
+
+func TestHealthIgnoresUnbackedPeerHeight(t *testing.T) {
+	setupTestMiner(t)
+	saved, savedOrphans := peerManager, orphans
+	t.Cleanup(func() { peerManager, orphans = saved, savedOrphans })
+	peerManager = NewPeerManager([]string{"198.51.100.7", "198.51.100.8"})
+	orphans = NewOrphanPool()
+
+	// One peer claims a far-ahead chain but has sent no blocks of it
+	height := chainStore.Height()
+	peerManager.recordHello("198.51.100.7", helloMessage{Version: protocolVersion, Height: height + 1000})
+	peerManager.recordHello("198.51.100.8", helloMessage{Version: protocolVersion, Height: height})
+	if sync := currentSyncStatus(); sync.Behind != 0 || len(healthProblems(true, sync)) != 0 {
+		t.Errorf("unbacked claim made the node unhealthy: %+v", sync)
+	}
+
+	// Nor do orphans sealed against an easier target than the chain's
+	cheap := blockTemplate(height+999, "unknown", "", nil)
+	cheap.Target = fmt.Sprintf("%x", maxTarget)
+	cheap.Hash = cheap.ComputeHash()
+	orphans.Add(cheap)
+	if sync := currentSyncStatus(); sync.Behind != 0 {
+		t.Errorf("cheaply sealed orphan made the node %d behind", sync.Behind)
+	}
+
+	// Blocks the node has verified count up to the claim
+	orphans.Add(sealedTestOrphan(t, height+9))
+	if sync := currentSyncStatus(); sync.Behind != 10 {
+		t.Errorf("node with a verified orphan 10 blocks ahead is %d behind", sync.Behind)
+	}
+}
+
+// A block at the given height on an unknown parent, sealed at the chain's
+// current target.
+func sealedTestOrphan(t *testing.T, height int) Block {
+	t.Helper()
+	template := signedTestTemplate(t, height, Block{Hash: fmt.Sprintf("unknown-%d", height)}, nil)
+	block, found := mineBlock(context.Background(), nil, template)
+	if !found {
+		t.Fatal("mining was interrupted")
+	}
+	return block
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Lowest protocol version that takes block announcements by inventory.
+// Older miners are sent the full block.
+const inventoryProtocolVersion = 3
+
+// A block announced by hash. The receiver fetches the block only if it does
+// not have it yet: from the announcing miner, or else from IPFS.
+type blockInventory struct {
+	Hash   string `json:"hash"`
+	Height int    `json:"height"`
+	CID    string `json:"cid,omitempty"` // IPFS copy of the block
+}
+
+func inventoryOf(block Block) blockInventory {
+	// After upload a block's PrevCID field holds its own CID
+	return blockInventory{Hash: block.Hash, Height: block.BlockNumber, CID: block.PrevCID}
+}
+
+// Fetch an announced block from the miner that announced it, falling back to
+// the IPFS copy named in the inventory.
+func fetchAnnouncedBlock(inv blockInventory, from net.Addr) (Block, error) {
+	block, err := transport.Block(peerHost(from), inv.Hash)
+	if err == nil && block.Hash != inv.Hash {
+		err = fmt.Errorf("peer sent block %s instead", block.Hash)
+	}
+	if err == nil {
+		return block, nil
+	}
+	if inv.CID == "" {
+		return Block{}, err
+	}
+	p2pLog.Debug("Could not fetch announced block from peer, trying IPFS", "peer", from, "hash", inv.Hash, "err", err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	block, ipfsErr := downloadBlockFromIPFS(ctx, inv.CID)
+	if ipfsErr != nil {
+		return Block{}, fmt.Errorf("from peer: %v; from IPFS: %v", err, ipfsErr)
+	}
+	if block.Hash != inv.Hash {
+		return Block{}, fmt.Errorf("CID %s holds block %s, not %s", inv.CID, block.Hash, inv.Hash)
+	}
+	return block, nil
+}
+
+// Ask a miner for a block on its main chain or side branches by hash.
+func requestBlock(addr, hash string) (Block, error) {
+	reply, err := requestFromPeer(addr, peerMessage{Type: msgGetBlock, Hash: hash}, msgBlocks)
+	if err != nil {
+		return Block{}, err
+	}
+	if len(reply.Blocks) == 0 {
+		return Block{}, errBlockNotFound
+	}
+	return reply.Blocks[0], nil
+}
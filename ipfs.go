@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"expvar"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	shell "github.com/ipfs/go-ipfs-api"
+)
+
+// Empty identity CID. Gateways answer it without touching the network,
+// which makes it a cheap health probe.
+const gatewayProbeCID = "bafkqaaa"
+
+var (
+	ipfsFallbackGateways = flag.String("ipfs-fallback-gateways", "", "comma-separated IPFS HTTP gateway URL prefixes, like https://ipfs.io/ipfs/, to download from while the IPFS daemon is unreachable")
+	ipfsHealthInterval   = flag.Duration("ipfs-health-interval", 30*time.Second, "how often the IPFS daemon and fallback gateways are checked")
+	gatewayRetries       = flag.Int("ipfs-gateway-retries", 3, "attempts per download across the fallback gateways")
+
+	ipfsTimeout         = flag.Duration("ipfs-timeout", 30*time.Second, "how long an IPFS call may take before it is retried; for downloads, until the transfer starts")
+	ipfsRetries         = flag.Int("ipfs-retries", 3, "attempts per IPFS call when the daemon does not answer")
+	ipfsBreakerFailures = flag.Int("ipfs-breaker-failures", 5, "consecutive failed IPFS calls that stop further calls for -ipfs-breaker-cooldown (0 disables)")
+	ipfsBreakerCooldown = flag.Duration("ipfs-breaker-cooldown", 30*time.Second, "how long IPFS calls fail fast once the daemon keeps failing")
+
+	ipfsDegraded       atomic.Bool // The daemon is down, so downloads go to the gateways and uploads fail
+	ipfsDegradedMetric = expvar.NewInt("ipfs_degraded")
+
+	ipfsErrorsMetric      = expvar.NewMap("ipfs_errors") // Failed IPFS calls by operation
+	ipfsRetriesMetric     = expvar.NewInt("ipfs_retries")
+	ipfsCircuitOpenMetric = expvar.NewInt("ipfs_circuit_open")
+
+	errIPFSCircuitOpen = errors.New("IPFS daemon keeps failing; calls are paused")
+)
+
+// resilientIPFS bounds every call to the IPFS daemon by -ipfs-timeout and
+// retries calls the daemon did not answer with exponential backoff. After
+// -ipfs-breaker-failures failures in a row, calls fail fast until the
+// cooldown ends, then one call at a time probes the daemon again.
+type resilientIPFS struct {
+	daemon ipfsClient
+
+	mu        sync.Mutex
+	failures  int       // Consecutive calls the daemon did not answer
+	openUntil time.Time // Calls fail fast until then
+}
+
+func newResilientIPFS(daemon ipfsClient) *resilientIPFS {
+	return &resilientIPFS{daemon: daemon}
+}
+
+func (c *resilientIPFS) Add(r io.Reader, options ...shell.AddOpts) (string, error) {
+	// Attempts after the first need the data again
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return callIPFS(c, "add", func() (string, error) {
+		return c.daemon.Add(bytes.NewReader(data), options...)
+	})
+}
+
+func (c *resilientIPFS) Cat(path string) (io.ReadCloser, error) {
+	return callIPFS(c, "cat", func() (io.ReadCloser, error) { return c.daemon.Cat(path) })
+}
+
+func (c *resilientIPFS) Pin(path string) error {
+	_, err := callIPFS(c, "pin", func() (struct{}, error) { return struct{}{}, c.daemon.Pin(path) })
+	return err
+}
+
+func (c *resilientIPFS) Unpin(path string) error {
+	_, err := callIPFS(c, "unpin", func() (struct{}, error) { return struct{}{}, c.daemon.Unpin(path) })
+	return err
+}
+
+func (c *resilientIPFS) Pins() (map[string]shell.PinInfo, error) {
+	return callIPFS(c, "pins", c.daemon.Pins)
+}
+
+func (c *resilientIPFS) ObjectStat(key string) (*shell.ObjectStats, error) {
+	return callIPFS(c, "stat", func() (*shell.ObjectStats, error) { return c.daemon.ObjectStat(key) })
+}
+
+// Health probes bypass retries and the breaker, so they see the daemon
+// recover.
+func (c *resilientIPFS) IsUp() bool {
+	up, err := withIPFSTimeout(func() (bool, error) { return c.daemon.IsUp(), nil })
+	return err == nil && up
+}
+
+// Make an IPFS call under the retry and breaker policy. Errors the daemon
+// answered with, such as an unknown CID, are returned at once.
+func callIPFS[T any](c *resilientIPFS, op string, call func() (T, error)) (T, error) {
+	var zero T
+	backoff := 250 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		if !c.allow() {
+			ipfsErrorsMetric.Add(op, 1)
+			return zero, errIPFSCircuitOpen
+		}
+		result, err := withIPFSTimeout(call)
+		var answered *shell.Error
+		if err == nil || errors.As(err, &answered) {
+			c.succeeded()
+			return result, err
+		}
+
+		ipfsErrorsMetric.Add(op, 1)
+		if c.failed() || attempt >= *ipfsRetries {
+			return zero, err
+		}
+		ipfsRetriesMetric.Add(1)
+		ipfsLog.Debug("Retrying IPFS call", "op", op, "attempt", attempt, "err", err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// Run call, giving up after -ipfs-timeout. A reader returned after that is
+// closed so the transfer does not linger.
+func withIPFSTimeout[T any](call func() (T, error)) (T, error) {
+	type outcome struct {
+		value T
+		err   error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		value, err := call()
+		done <- outcome{value, err}
+	}()
+
+	timer := time.NewTimer(*ipfsTimeout)
+	defer timer.Stop()
+	select {
+	case result := <-done:
+		return result.value, result.err
+	case <-timer.C:
+		go func() {
+			if late := <-done; late.err == nil {
+				if closer, ok := any(late.value).(io.Closer); ok {
+					closer.Close()
+				}
+			}
+		}()
+		var zero T
+		return zero, fmt.Errorf("IPFS call timed out after %s", *ipfsTimeout)
+	}
+}
+
+func (c *resilientIPFS) allow() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !time.Now().Before(c.openUntil)
+}
+
+func (c *resilientIPFS) succeeded() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.failures >= *ipfsBreakerFailures && *ipfsBreakerFailures > 0 {
+		ipfsLog.Info("IPFS daemon answers again, resuming calls")
+		ipfsCircuitOpenMetric.Set(0)
+	}
+	c.failures = 0
+}
+
+// Count a failed call and report whether it opened the breaker.
+func (c *resilientIPFS) failed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failures++
+	if *ipfsBreakerFailures <= 0 || c.failures < *ipfsBreakerFailures {
+		return false
+	}
+	c.openUntil = time.Now().Add(*ipfsBreakerCooldown)
+	if c.failures == *ipfsBreakerFailures {
+		ipfsLog.Warn("IPFS daemon keeps failing, pausing calls", "failures", c.failures, "cooldown", *ipfsBreakerCooldown)
+	}
+	ipfsCircuitOpenMetric.Set(1)
+	return true
+}
+
+// gatewayFallback is an IPFS client that downloads from HTTP gateways when
+// the IPFS daemon is unreachable. Adding and pinning still need the daemon.
+type gatewayFallback struct {
+	ipfsClient // The daemon at -ipfs-api
+	gateways   []*fallbackGateway
+	http       *http.Client
+}
+
+type fallbackGateway struct {
+	url     string // Prefix the CID is appended to
+	healthy atomic.Bool
+}
+
+// Wrap the daemon client with the -ipfs-fallback-gateways, if any.
+func withGatewayFallback(daemon ipfsClient) ipfsClient {
+	var gateways []*fallbackGateway
+	for _, url := range strings.Split(*ipfsFallbackGateways, ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			if !strings.HasSuffix(url, "/") {
+				url += "/"
+			}
+			gateway := &fallbackGateway{url: url}
+			gateway.healthy.Store(true)
+			gateways = append(gateways, gateway)
+		}
+	}
+	if len(gateways) == 0 {
+		return daemon
+	}
+	return &gatewayFallback{
+		ipfsClient: daemon,
+		gateways:   gateways,
+		// Downloads can be large, so only waiting for a gateway to start
+		// answering is bounded
+		http: &http.Client{Transport: &http.Transport{ResponseHeaderTimeout: 30 * time.Second}},
+	}
+}
+
+// Read a file from the daemon, or from the gateways while it is down.
+func (f *gatewayFallback) Cat(path string) (io.ReadCloser, error) {
+	if !ipfsDegraded.Load() {
+		reader, err := f.ipfsClient.Cat(path)
+		if err == nil || f.ipfsClient.IsUp() {
+			return reader, err
+		}
+		setIPFSDegraded(true)
+	}
+	return f.catFromGateways(path)
+}
+
+// Try the healthy gateways first, then the others, backing off between
+// attempts.
+func (f *gatewayFallback) catFromGateways(path string) (io.ReadCloser, error) {
+	var order []*fallbackGateway
+	for _, healthy := range []bool{true, false} {
+		for _, gateway := range f.gateways {
+			if gateway.healthy.Load() == healthy {
+				order = append(order, gateway)
+			}
+		}
+	}
+
+	backoff := 250 * time.Millisecond
+	var err error
+	for attempt := 0; attempt < max(*gatewayRetries, 1); attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		gateway := order[attempt%len(order)]
+		var reader io.ReadCloser
+		if reader, err = f.get(gateway, path); err == nil {
+			ipfsLog.Debug("Downloaded from gateway", "gateway", gateway.url, "cid", path)
+			return reader, nil
+		}
+		ipfsLog.Warn("Error downloading from IPFS gateway", "gateway", gateway.url, "cid", path, "attempt", attempt+1, "err", err)
+	}
+	return nil, fmt.Errorf("IPFS daemon is down and gateways failed: %v", err)
+}
+
+func (f *gatewayFallback) get(gateway *fallbackGateway, path string) (io.ReadCloser, error) {
+	response, err := f.http.Get(gateway.url + strings.TrimPrefix(path, "/ipfs/"))
+	if err != nil {
+		gateway.healthy.Store(false)
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		if response.StatusCode >= 500 {
+			gateway.healthy.Store(false)
+		}
+		return nil, fmt.Errorf("gateway answered %s", response.Status)
+	}
+	return response.Body, nil
+}
+
+// Probe the daemon and every gateway.
+func (f *gatewayFallback) checkHealth(ctx context.Context) {
+	setIPFSDegraded(!f.ipfsClient.IsUp())
+	for _, gateway := range f.gateways {
+		err := probeGateway(ctx, f.http, gateway.url)
+		if was := gateway.healthy.Swap(err == nil); was != (err == nil) {
+			if err != nil {
+				ipfsLog.Warn("IPFS gateway is unhealthy", "gateway", gateway.url, "err", err)
+			} else {
+				ipfsLog.Info("IPFS gateway recovered", "gateway", gateway.url)
+			}
+		}
+	}
+}
+
+func probeGateway(ctx context.Context, client *http.Client, url string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url+gatewayProbeCID, nil)
+	if err != nil {
+		return err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return errors.New(response.Status)
+	}
+	return nil
+}
+
+func setIPFSDegraded(degraded bool) {
+	if ipfsDegraded.Swap(degraded) == degraded {
+		return
+	}
+	if degraded {
+		ipfsDegradedMetric.Set(1)
+		ipfsLog.Warn("IPFS daemon is unreachable, downloading from fallback gateways")
+	} else {
+		ipfsDegradedMetric.Set(0)
+		ipfsLog.Info("IPFS daemon is reachable again")
+	}
+}
+
+// IPFS Health Thread
+func monitorIPFS(ctx context.Context) {
+	fallback, ok := ipfsShell.(*gatewayFallback)
+	if !ok {
+		return
+	}
+	ticker := time.NewTicker(*ipfsHealthInterval)
+	defer ticker.Stop()
+	for {
+		fallback.checkHealth(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Health of the fallback gateways, by URL.
+func gatewayHealth() map[string]bool {
+	health := make(map[string]bool)
+	if fallback, ok := ipfsShell.(*gatewayFallback); ok {
+		for _, gateway := range fallback.gateways {
+			health[gateway.url] = gateway.healthy.Load()
+		}
+	}
+	return health
+}
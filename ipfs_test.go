@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	shell "github.com/ipfs/go-ipfs-api"
+)
+
+// An IPFS daemon that stopped answering.
+type downIPFS struct{ *fakeIPFS }
+
+func (downIPFS) Cat(path string) (io.ReadCloser, error) { return nil, errors.New("connection refused") }
+func (downIPFS) IsUp() bool                             { return false }
+
+func TestGatewayFallback(t *testing.T) {
+	defer setIPFSDegraded(false)
+	var requests []string
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		if r.URL.Path != "/ipfs/bafyblock" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, "block data")
+	}))
+	defer gateway.Close()
+	defer func(gateways string) { *ipfsFallbackGateways = gateways }(*ipfsFallbackGateways)
+	*ipfsFallbackGateways = "http://127.0.0.1:1/ipfs," + gateway.URL + "/ipfs/"
+
+	defer func(shell ipfsClient) { ipfsShell = shell }(ipfsShell)
+	ipfsShell = withGatewayFallback(downIPFS{newFakeIPFS()})
+	reader, err := ipfsShell.Cat("bafyblock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(data) != "block data" || !ipfsDegraded.Load() {
+		t.Errorf("got %q, degraded %v; want the block from the second gateway while degraded", data, ipfsDegraded.Load())
+	}
+
+	// The unreachable gateway is marked unhealthy and tried last
+	if health := gatewayHealth(); len(health) != 2 || health["http://127.0.0.1:1/ipfs/"] {
+		t.Error("unreachable gateway still counts as healthy")
+	}
+	defer func(n int) { *gatewayRetries = n }(*gatewayRetries)
+	*gatewayRetries = 1
+	requests = nil
+	if _, err := ipfsShell.Cat("bafymissing"); err == nil || !strings.Contains(err.Error(), "gateways failed") {
+		t.Errorf("missing file: err = %v", err)
+	}
+	if len(requests) == 0 {
+		t.Error("healthy gateway was not tried first")
+	}
+}
+
+// An IPFS daemon whose calls fail until it has failed down times, and that
+// takes delay to answer.
+type flakyIPFS struct {
+	*fakeIPFS
+	down  atomic.Int32
+	delay atomic.Int64
+	calls atomic.Int32
+}
+
+func (f *flakyIPFS) Pin(path string) error {
+	f.calls.Add(1)
+	time.Sleep(time.Duration(f.delay.Load()))
+	if f.down.Add(-1) >= 0 {
+		return errors.New("connection reset")
+	}
+	if path == "missing" {
+		return &shell.Error{Command: "pin/add", Message: "not found"}
+	}
+	return f.fakeIPFS.Pin(path)
+}
+
+func TestResilientIPFS(t *testing.T) {
+	defer func(timeout time.Duration, retries, failures int) {
+		*ipfsTimeout, *ipfsRetries, *ipfsBreakerFailures = timeout, retries, failures
+	}(*ipfsTimeout, *ipfsRetries, *ipfsBreakerFailures)
+	*ipfsTimeout, *ipfsRetries, *ipfsBreakerFailures = 50*time.Millisecond, 2, 3
+
+	daemon := &flakyIPFS{fakeIPFS: newFakeIPFS()}
+	daemon.down.Store(1)
+	client := newResilientIPFS(daemon)
+	cid, err := daemon.Add(strings.NewReader("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Pin(cid); err != nil || daemon.calls.Load() != 2 {
+		t.Fatalf("pin after one failure: err %v after %d calls, want success on the retry", err, daemon.calls.Load())
+	}
+
+	// Errors the daemon answered with are not retried
+	if err := client.Pin("missing"); err == nil || daemon.calls.Load() != 3 {
+		t.Errorf("pin of a missing CID: err %v after %d calls", err, daemon.calls.Load())
+	}
+
+	// Slow calls time out, and enough failures in a row open the breaker
+	daemon.calls.Store(0)
+	daemon.delay.Store(int64(200 * time.Millisecond))
+	if err := client.Pin(cid); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("slow pin: err = %v", err)
+	}
+	client.Pin(cid)
+	if err := client.Pin(cid); err != errIPFSCircuitOpen || daemon.calls.Load() != 3 {
+		t.Errorf("pin with the breaker open: err %v after %d calls", err, daemon.calls.Load())
+	}
+}
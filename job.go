@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Codes on failed receipts saying why a job was refused or failed, so
+// clients need not parse the error message.
+const (
+	codeMalformedJob   = "malformed_job"   // The job message does not parse
+	codeInvalidCID     = "invalid_cid"     // A script or data CID is not a CID
+	codeRateLimited    = "rate_limited"    // The submitter sent too many jobs
+	codeTooLarge       = "too_large"       // The script or data exceeds its size limit
+	codeDownloadFailed = "download_failed" // The script or data could not be fetched
+	codeQueueFull      = "queue_full"      // Too many jobs are waiting to run
+	codeTimedOut       = "timed_out"       // The job ran longer than -job-timeout
+	codeJobFailed      = "job_failed"      // Anything else, such as the script failing
+)
+
+// Most jobs one batch may hold.
+const maxBatchJobs = 100
+
+// Alphabets of the multibase encodings accepted in CIDs.
+const (
+	base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	base32Alphabet = "abcdefghijklmnopqrstuvwxyz234567"
+)
+
+var (
+	maxScriptSize = flag.Int64("max-script-size", 1<<20, "largest script, in bytes, a job may run")
+	maxDataSize   = flag.Int64("max-data-size", 64<<20, "largest data file, in bytes, a job may take")
+)
+
+// jobError is a job failure with the code reported to its submitter.
+type jobError struct {
+	code string
+	err  error
+}
+
+func (e *jobError) Error() string {
+	return e.err.Error()
+}
+
+// The code of a job error, or fallback for other errors.
+func jobErrorCode(err error, fallback string) string {
+	var failure *jobError
+	if errors.As(err, &failure) {
+		return failure.code
+	}
+	return fallback
+}
+
+// Check that s looks like a CID: a base58 CIDv0, or a CIDv1 in base32,
+// base58 or base16. The IPFS daemon still decides whether it exists.
+func validateCID(s string) error {
+	var alphabet string
+	switch {
+	case len(s) == 46 && strings.HasPrefix(s, "Qm"):
+		alphabet = base58Alphabet
+	case len(s) > 128:
+		return errors.New("too long")
+	case len(s) >= 8 && s[0] == 'b':
+		alphabet = base32Alphabet
+	case len(s) >= 8 && s[0] == 'B':
+		alphabet = strings.ToUpper(base32Alphabet)
+	case len(s) >= 8 && s[0] == 'z':
+		alphabet = base58Alphabet
+	case len(s) >= 8 && s[0] == 'f':
+		alphabet = "0123456789abcdef"
+	default:
+		return fmt.Errorf("%q is not a CID", s)
+	}
+	for _, c := range s[1:] {
+		if !strings.ContainsRune(alphabet, c) {
+			return fmt.Errorf("%q is not a CID", s)
+		}
+	}
+	return nil
+}
+
+// A compute job submitted on the transaction listener.
+type jobRequest struct {
+	ID         string // Receipt ID, assigned when the job is accepted
+	ScriptCID  string
+	DataCID    string
+	Recipient  string        // Optional hex X25519 key the result is sealed to
+	LockHeight int           // Optional height before which the result may not be mined
+	LockTime   int64         // Optional Unix time before which the result may not be mined
+	Fee        int64         // Optional fee this node pays to have the result mined sooner
+	Runtime    string        // Optional runtime the script is written for; default python3
+	Env        *ExecutionEnv // Optional pinned environment to run the script in
+	Params     *ScriptParams // Optional arguments and environment variables of the script
+}
+
+// Parse a job message of the form
+//
+//	<script_hash> <data_hash> [recipient_key] [lockheight=N] [locktime=UNIX] [fee=N] [runtime=NAME]
+//	    [image=NAME@sha256:DIGEST] [interpreter=CMD] [libs=NAME==VERSION,...] [arg=VALUE]... [var=NAME=VALUE]...
+//
+// Arguments are passed in order. Values cannot hold whitespace here; batches
+// of jobs can carry any.
+func parseJobMessage(message string) (jobRequest, error) {
+	parts := strings.Fields(message)
+	if len(parts) < 2 {
+		return jobRequest{}, errors.New("expected '<script_hash> <data_hash> [recipient_key] [option=VALUE]...'")
+	}
+	job := jobRequest{ScriptCID: parts[0], DataCID: parts[1]}
+	if err := validateCID(job.ScriptCID); err != nil {
+		return jobRequest{}, &jobError{codeInvalidCID, fmt.Errorf("invalid script CID: %v", err)}
+	}
+	if err := validateCID(job.DataCID); err != nil {
+		return jobRequest{}, &jobError{codeInvalidCID, fmt.Errorf("invalid data CID: %v", err)}
+	}
+
+	for _, part := range parts[2:] {
+		key, value, isOption := strings.Cut(part, "=")
+		if !isOption {
+			if job.Recipient != "" {
+				return jobRequest{}, errors.New("more than one recipient key")
+			}
+			if _, err := parsePayloadPublicKey(part); err != nil {
+				return jobRequest{}, fmt.Errorf("invalid recipient key: %v", err)
+			}
+			job.Recipient = part
+			continue
+		}
+
+		if isEnv, err := parseEnvOption(&job.Env, key, value); isEnv {
+			if err != nil {
+				return jobRequest{}, fmt.Errorf("invalid %s: %v", key, err)
+			}
+			continue
+		}
+		if isParam, err := parseParamOption(&job.Params, key, value); isParam {
+			if err != nil {
+				return jobRequest{}, fmt.Errorf("invalid %s: %v", key, err)
+			}
+			continue
+		}
+
+		var err error
+		switch key {
+		case "lockheight":
+			job.LockHeight, err = strconv.Atoi(value)
+		case "locktime":
+			job.LockTime, err = strconv.ParseInt(value, 10, 64)
+		case "fee":
+			job.Fee, err = strconv.ParseInt(value, 10, 64)
+			if err == nil && job.Fee < 0 {
+				err = errors.New("must not be negative")
+			}
+		case "runtime":
+			job.Runtime = value
+			_, err = lookupRuntime(value)
+		default:
+			return jobRequest{}, fmt.Errorf("unknown option %q", key)
+		}
+		if err != nil {
+			return jobRequest{}, fmt.Errorf("invalid %s: %v", key, err)
+		}
+	}
+	if err := checkJobEnv(job); err != nil {
+		return jobRequest{}, err
+	}
+	if err := checkJobParams(job); err != nil {
+		return jobRequest{}, err
+	}
+	return job, nil
+}
+
+// Check a job's pinned environment, if it has one, against its runtime.
+func checkJobEnv(job jobRequest) error {
+	if job.Env == nil {
+		return nil
+	}
+	if err := job.Env.Validate(); err != nil {
+		return fmt.Errorf("invalid environment: %v", err)
+	}
+	if runtime, _ := lookupRuntime(job.Runtime); runtime.Run != nil {
+		return fmt.Errorf("runtime %s runs in process and takes no environment", runtime.Name)
+	}
+	return nil
+}
+
+// jobSpec is one job of a batch: the fields of a job message, as JSON.
+type jobSpec struct {
+	ScriptCID  string            `json:"scriptCid"`
+	DataCID    string            `json:"dataCid"`
+	Recipient  string            `json:"recipient,omitempty"`
+	LockHeight int               `json:"lockHeight,omitempty"`
+	LockTime   int64             `json:"lockTime,omitempty"`
+	Fee        int64             `json:"fee,omitempty"`
+	Runtime    string            `json:"runtime,omitempty"`
+	Env        *ExecutionEnv     `json:"env,omitempty"`
+	Args       []string          `json:"args,omitempty"`
+	Vars       map[string]string `json:"vars,omitempty"`
+}
+
+// Parse a batch of jobs: a JSON array of at most maxBatchJobs jobSpecs.
+func parseJobBatch(payload []byte) ([]jobSpec, error) {
+	var specs []jobSpec
+	if err := json.Unmarshal(payload, &specs); err != nil {
+		return nil, fmt.Errorf("expected a JSON array of jobs: %v", err)
+	}
+	if len(specs) == 0 {
+		return nil, errors.New("empty batch")
+	}
+	if len(specs) > maxBatchJobs {
+		return nil, fmt.Errorf("batch of %d jobs exceeds the limit of %d", len(specs), maxBatchJobs)
+	}
+	return specs, nil
+}
+
+// The job a batch entry asks for, checked as parseJobMessage checks a job
+// message.
+func (s jobSpec) job() (jobRequest, error) {
+	if err := validateCID(s.ScriptCID); err != nil {
+		return jobRequest{}, &jobError{codeInvalidCID, fmt.Errorf("invalid script CID: %v", err)}
+	}
+	if err := validateCID(s.DataCID); err != nil {
+		return jobRequest{}, &jobError{codeInvalidCID, fmt.Errorf("invalid data CID: %v", err)}
+	}
+	if s.Recipient != "" {
+		if _, err := parsePayloadPublicKey(s.Recipient); err != nil {
+			return jobRequest{}, fmt.Errorf("invalid recipient key: %v", err)
+		}
+	}
+	if s.Fee < 0 {
+		return jobRequest{}, errors.New("invalid fee: must not be negative")
+	}
+	if _, err := lookupRuntime(s.Runtime); err != nil {
+		return jobRequest{}, fmt.Errorf("invalid runtime: %v", err)
+	}
+	job := jobRequest{
+		ScriptCID:  s.ScriptCID,
+		DataCID:    s.DataCID,
+		Recipient:  s.Recipient,
+		LockHeight: s.LockHeight,
+		LockTime:   s.LockTime,
+		Fee:        s.Fee,
+		Runtime:    s.Runtime,
+		Env:        s.Env,
+	}
+	if len(s.Args) > 0 || len(s.Vars) > 0 {
+		job.Params = &ScriptParams{Args: s.Args, Vars: s.Vars}
+	}
+	if err := checkJobEnv(job); err != nil {
+		return jobRequest{}, err
+	}
+	if err := checkJobParams(job); err != nil {
+		return jobRequest{}, err
+	}
+	return job, nil
+}
+
+// Queue the jobs of a batch sent by the submitter at addr and acknowledge
+// each with its receipt, in order: queued if accepted, failed with a code if
+// not. Every job is charged to the submitter's rate limit. Outcomes are
+// recorded on the receipts only, for the submitter to poll.
+func submitJobBatch(ctx context.Context, addr net.Addr, specs []jobSpec) []Receipt {
+	acks := make([]Receipt, len(specs))
+	for i, spec := range specs {
+		refused := Receipt{Status: jobFailed, ScriptCID: spec.ScriptCID, DataCID: spec.DataCID, Updated: time.Now()}
+		job, err := spec.job()
+		if err != nil {
+			refused.Error, refused.Code = err.Error(), jobErrorCode(err, codeMalformedJob)
+			acks[i] = refused
+			continue
+		}
+		if err := chargeJob(addr); err != nil {
+			refused.Error, refused.Code = err.Error(), codeRateLimited
+			acks[i] = refused
+			continue
+		}
+
+		job.ID = receipts.Open(job)
+		done, err := jobQueue.Submit(ctx, peerHost(addr), job)
+		if err != nil {
+			receipts.Fail(job.ID, err)
+		} else {
+			go func() {
+				if outcome := <-done; outcome.err != nil {
+					txprocLog.Error("Job failed", "job", job.ID, "script", job.ScriptCID, "data", job.DataCID, "err", outcome.err)
+				}
+			}()
+		}
+		acks[i], _ = receipts.Get(job.ID)
+	}
+	return acks
+}
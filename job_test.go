@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJobCIDsAreValidated(t *testing.T) {
+	for _, cid := range []string{testScriptCID, testDataCID, "zdj7WWeQ43G6JJvLWQWZpyHuAMq6uYWRjkBXFad11vE2LHhQ7", "f01551220b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"} {
+		if err := validateCID(cid); err != nil {
+			t.Errorf("%s: %v", cid, err)
+		}
+	}
+	for _, cid := range []string{"script", "Qm0000000000000000000000000000000000000000000000", "bafy/../etc", "QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbd"} {
+		if err := validateCID(cid); err == nil {
+			t.Errorf("%s validated", cid)
+		}
+	}
+
+	_, err := parseJobMessage("script " + testDataCID)
+	if code := jobErrorCode(err, codeMalformedJob); code != codeInvalidCID {
+		t.Errorf("job with a bad script CID rejected with %q", code)
+	}
+}
+
+func TestOversizedJobFilesAreRefused(t *testing.T) {
+	defer func(shell ipfsClient) { ipfsShell = shell }(ipfsShell)
+	ipfs := newFakeIPFS()
+	ipfsShell = ipfs
+	cid, err := ipfs.Add(strings.NewReader(strings.Repeat("x", 4096)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "data")
+	if err := downloadFromIPFS(context.Background(), cid, path, 4096); err != nil {
+		t.Fatal(err)
+	}
+	// Caught from the object size before the download, or during it when
+	// the size is within the encoding slack
+	for _, limit := range []int64{1024, 4000} {
+		err = downloadFromIPFS(context.Background(), cid, path, limit)
+		if code := jobErrorCode(err, codeDownloadFailed); code != codeTooLarge {
+			t.Errorf("download over a %d byte limit: err %v with code %q", limit, err, code)
+		}
+	}
+}
+
+func TestJobBatch(t *testing.T) {
+	defer func(q *JobQueue, store *ReceiptStore) { jobQueue, receipts = q, store }(jobQueue, receipts)
+	jobQueue, receipts = NewJobQueue(), NewReceiptStore()
+	defer jobQueue.close()
+	server := httptest.NewServer(apiHandler())
+	defer server.Close()
+
+	batch := `[
+		{"scriptCid": "` + testScriptCID + `", "dataCid": "` + testDataCID + `", "fee": 5, "runtime": "wasm"},
+		{"scriptCid": "script", "dataCid": "` + testDataCID + `"},
+		{"scriptCid": "` + testScriptCID + `", "dataCid": "` + testDataCID + `", "fee": -1}
+	]`
+	response, err := http.Post(server.URL+"/jobs", "application/json", strings.NewReader(batch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	var acks []Receipt
+	if err := json.NewDecoder(response.Body).Decode(&acks); err != nil {
+		t.Fatal(err)
+	}
+	if len(acks) != 3 {
+		t.Fatalf("got %d acknowledgements for 3 jobs", len(acks))
+	}
+	if acks[0].Status != jobQueued || acks[0].JobID == "" {
+		t.Errorf("valid job acknowledged with %+v", acks[0])
+	}
+	if acks[1].Status != jobFailed || acks[1].Code != codeInvalidCID || acks[1].JobID != "" {
+		t.Errorf("job with a bad CID acknowledged with %+v", acks[1])
+	}
+	if acks[2].Status != jobFailed || acks[2].Code != codeMalformedJob {
+		t.Errorf("job with a negative fee acknowledged with %+v", acks[2])
+	}
+	if jobQueue.waiting != 1 {
+		t.Errorf("%d jobs queued, want 1", jobQueue.waiting)
+	}
+
+	for _, malformed := range []string{`[]`, `{"scriptCid": "x"}`, "[" + strings.Repeat("{},", maxBatchJobs) + "{}]"} {
+		response, err := http.Post(server.URL+"/jobs", "application/json", strings.NewReader(malformed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("batch %.20s: status %d", malformed, response.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"flag"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	jobWorkers    = flag.Int("job-workers", 4, "number of jobs run at the same time")
+	jobQueueDepth = flag.Int("job-queue", 100, "number of jobs waiting for a worker before new ones are refused")
+	jobTimeout    = flag.Duration("job-timeout", 5*time.Minute, "how long a job may take from download to mempool")
+
+	jobQueue = NewJobQueue() // Jobs accepted from clients, waiting to run
+
+	jobsQueuedMetric  = expvar.NewInt("jobs_queued")
+	jobsRunningMetric = expvar.NewInt("jobs_running")
+
+	errJobQueueFull   = &jobError{codeQueueFull, errors.New("job queue is full")}
+	errJobQueueClosed = errors.New("node is shutting down")
+)
+
+// jobOutcome is what a worker reports back to the job's submitter.
+type jobOutcome struct {
+	tx  Transaction
+	err error
+}
+
+type queuedJob struct {
+	ctx  context.Context // The submitter's context; the job is dropped once it ends
+	job  jobRequest
+	done chan jobOutcome
+}
+
+// JobQueue holds jobs waiting for a worker. Each submitter has its own
+// FIFO queue and workers take from the submitters in turn, so a client
+// sending many jobs cannot starve the others.
+type JobQueue struct {
+	mu      sync.Mutex
+	ready   *sync.Cond
+	queues  map[string][]*queuedJob // Waiting jobs by submitter
+	turns   []string                // Submitters with waiting jobs, next served first
+	waiting int
+	closed  bool
+}
+
+func NewJobQueue() *JobQueue {
+	q := &JobQueue{queues: make(map[string][]*queuedJob)}
+	q.ready = sync.NewCond(&q.mu)
+	return q
+}
+
+// Queue a job for a worker. The returned channel receives the outcome once
+// the job has run, failed or been dropped. Full queues refuse the job with
+// errJobQueueFull.
+func (q *JobQueue) Submit(ctx context.Context, submitter string, job jobRequest) (<-chan jobOutcome, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil, errJobQueueClosed
+	}
+	if q.waiting >= *jobQueueDepth {
+		return nil, errJobQueueFull
+	}
+	item := &queuedJob{ctx: ctx, job: job, done: make(chan jobOutcome, 1)}
+	if len(q.queues[submitter]) == 0 {
+		q.turns = append(q.turns, submitter)
+	}
+	q.queues[submitter] = append(q.queues[submitter], item)
+	q.waiting++
+	jobsQueuedMetric.Set(int64(q.waiting))
+	q.ready.Signal()
+	return item.done, nil
+}
+
+// Wait for the next job, taking the submitters in turn. Reports false once
+// the queue is closed.
+func (q *JobQueue) next() (*queuedJob, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for q.waiting == 0 && !q.closed {
+		q.ready.Wait()
+	}
+	if q.closed {
+		return nil, false
+	}
+	submitter := q.turns[0]
+	q.turns = q.turns[1:]
+	pending := q.queues[submitter]
+	item := pending[0]
+	if len(pending) > 1 {
+		q.queues[submitter] = pending[1:]
+		q.turns = append(q.turns, submitter)
+	} else {
+		delete(q.queues, submitter)
+	}
+	q.waiting--
+	jobsQueuedMetric.Set(int64(q.waiting))
+	return item, true
+}
+
+// Stop handing out jobs and fail the ones still waiting.
+func (q *JobQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	queues := q.queues
+	q.queues, q.turns, q.waiting = make(map[string][]*queuedJob), nil, 0
+	jobsQueuedMetric.Set(0)
+	q.ready.Broadcast()
+	q.mu.Unlock()
+
+	for _, pending := range queues {
+		for _, item := range pending {
+			receipts.Fail(item.job.ID, errJobQueueClosed)
+			item.done <- jobOutcome{err: errJobQueueClosed}
+		}
+	}
+}
+
+// Job Worker Thread
+func runJobWorkers(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	context.AfterFunc(ctx, jobQueue.close)
+
+	var workers sync.WaitGroup
+	for i := 0; i < *jobWorkers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for {
+				item, ok := jobQueue.next()
+				if !ok {
+					return
+				}
+				tx, err := runQueuedJob(item)
+				item.done <- jobOutcome{tx: tx, err: err}
+			}
+		}()
+	}
+	workers.Wait()
+}
+
+// Run a job taken from the queue within -job-timeout.
+func runQueuedJob(item *queuedJob) (Transaction, error) {
+	if err := item.ctx.Err(); err != nil {
+		err = fmt.Errorf("job was abandoned while queued: %v", err)
+		receipts.Fail(item.job.ID, err)
+		return Transaction{}, err
+	}
+	jobsRunningMetric.Add(1)
+	defer jobsRunningMetric.Add(-1)
+
+	ctx, cancel := context.WithTimeout(item.ctx, *jobTimeout)
+	defer cancel()
+	tx, err := runJob(ctx, item.job)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) && item.ctx.Err() == nil {
+		err = &jobError{codeTimedOut, fmt.Errorf("job did not finish within %v: %v", *jobTimeout, err)}
+		receipts.Fail(item.job.ID, err)
+	}
+	return tx, err
+}
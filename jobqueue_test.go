@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestJobQueueTakesSubmittersInTurn(t *testing.T) {
+	q := NewJobQueue()
+	ctx := context.Background()
+	for _, submit := range []struct{ submitter, id string }{
+		{"a", "a1"}, {"a", "a2"}, {"a", "a3"}, {"b", "b1"}, {"c", "c1"}, {"b", "b2"},
+	} {
+		if _, err := q.Submit(ctx, submit.submitter, jobRequest{ID: submit.id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{"a1", "b1", "c1", "a2", "b2", "a3"}
+	for _, id := range want {
+		item, ok := q.next()
+		if !ok || item.job.ID != id {
+			t.Fatalf("next job is %q, want %q", item.job.ID, id)
+		}
+	}
+}
+
+func TestJobQueueIsBounded(t *testing.T) {
+	defer func(depth int) { *jobQueueDepth = depth }(*jobQueueDepth)
+	*jobQueueDepth = 2
+
+	q := NewJobQueue()
+	ctx := context.Background()
+	q.Submit(ctx, "a", jobRequest{})
+	q.Submit(ctx, "b", jobRequest{})
+	_, err := q.Submit(ctx, "c", jobRequest{})
+	if !errors.Is(err, errJobQueueFull) || jobErrorCode(err, "") != codeQueueFull {
+		t.Fatalf("submitting to a full queue returned %v", err)
+	}
+
+	q.next()
+	if _, err := q.Submit(ctx, "c", jobRequest{}); err != nil {
+		t.Fatalf("queue refused a job after one was taken: %v", err)
+	}
+}
+
+func TestClosedJobQueueFailsWaitingJobs(t *testing.T) {
+	q := NewJobQueue()
+	done, err := q.Submit(context.Background(), "a", jobRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.close()
+
+	if outcome := <-done; outcome.err != errJobQueueClosed {
+		t.Errorf("waiting job ended with %v", outcome.err)
+	}
+	if _, ok := q.next(); ok {
+		t.Error("closed queue handed out a job")
+	}
+	if _, err := q.Submit(context.Background(), "a", jobRequest{}); err != errJobQueueClosed {
+		t.Errorf("closed queue accepted a job: %v", err)
+	}
+}
+
+func TestQueuedJobTimesOut(t *testing.T) {
+	defer func(timeout time.Duration) { *jobTimeout = timeout }(*jobTimeout)
+	*jobTimeout = time.Nanosecond
+	defer func(ipfs ipfsClient) { ipfsShell = ipfs }(ipfsShell)
+	ipfsShell = newFakeIPFS()
+
+	job := jobRequest{ScriptCID: testScriptCID, DataCID: testDataCID}
+	job.ID = receipts.Open(job)
+	_, err := runQueuedJob(&queuedJob{ctx: context.Background(), job: job})
+	if jobErrorCode(err, "") != codeTimedOut {
+		t.Fatalf("job past its timeout failed with %v", err)
+	}
+	if receipt, _ := receipts.Get(job.ID); receipt.Status != jobFailed || receipt.Code != codeTimedOut {
+		t.Errorf("receipt of the timed out job is %+v", receipt)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Environment variable holding the keystore passphrase.
+const keystorePassphraseEnv = "KEYSTORE_PASSPHRASE"
+
+// scrypt cost parameters for newly written keystore files.
+const (
+	keystoreScryptN = 1 << 18
+	keystoreScryptR = 8
+	keystoreScryptP = 1
+)
+
+var (
+	keystorePassphraseFile = flag.String("keystore-passphrase-file", "", "file containing the keystore passphrase (default: $"+keystorePassphraseEnv+" or prompt)")
+
+	passphraseOnce sync.Once
+	passphrase     []byte
+	passphraseErr  error
+)
+
+// On-disk format of an encrypted private key. Only ciphertext is stored.
+type keystoreFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       string `json:"salt"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// Return the keystore passphrase, reading it once from the passphrase file,
+// the environment, or an interactive prompt, in that order.
+func keystorePassphrase() ([]byte, error) {
+	passphraseOnce.Do(func() {
+		switch {
+		case *keystorePassphraseFile != "":
+			data, err := os.ReadFile(*keystorePassphraseFile)
+			if err != nil {
+				passphraseErr = fmt.Errorf("failed to read passphrase file: %v", err)
+				return
+			}
+			passphrase = []byte(strings.TrimRight(string(data), "\r\n"))
+		case os.Getenv(keystorePassphraseEnv) != "":
+			passphrase = []byte(os.Getenv(keystorePassphraseEnv))
+		default:
+			fmt.Print("Keystore passphrase: ")
+			line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil && line == "" {
+				passphraseErr = fmt.Errorf("failed to read passphrase: %v", err)
+				return
+			}
+			passphrase = []byte(strings.TrimRight(line, "\r\n"))
+		}
+		if len(passphrase) == 0 {
+			passphraseErr = errors.New("keystore passphrase is empty")
+		}
+	})
+	return passphrase, passphraseErr
+}
+
+// Encrypt a private key with the passphrase and write it to path.
+func saveEncryptedKey(path string, key crypto.PrivateKey, pass []byte) error {
+	plaintext, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to encode private key: %v", err)
+	}
+
+	ks := keystoreFile{Version: 1, KDF: "scrypt", N: keystoreScryptN, R: keystoreScryptR, P: keystoreScryptP}
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %v", err)
+	}
+	ks.Salt = hex.EncodeToString(salt)
+
+	gcm, err := keystoreCipher(ks, pass)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	ks.Nonce = hex.EncodeToString(nonce)
+	ks.Ciphertext = hex.EncodeToString(gcm.Seal(nil, nonce, plaintext, keystoreAAD(ks)))
+
+	data, err := json.MarshalIndent(ks, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode keystore file: %v", err)
+	}
+	return writeFileAtomic(path, data, 0600)
+}
+
+// Read the keystore file at path and decrypt its private key.
+func loadEncryptedKey(path string, pass []byte) (crypto.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore file: %v", err)
+	}
+	var ks keystoreFile
+	if err := json.Unmarshal(data, &ks); err != nil {
+		return nil, fmt.Errorf("failed to decode keystore file: %v", err)
+	}
+	if ks.Version != 1 || ks.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported keystore version %d / kdf %q", ks.Version, ks.KDF)
+	}
+
+	gcm, err := keystoreCipher(ks, pass)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hex.DecodeString(ks.Nonce)
+	if err != nil || len(nonce) != gcm.NonceSize() {
+		return nil, errors.New("keystore file has an invalid nonce")
+	}
+	ciphertext, err := hex.DecodeString(ks.Ciphertext)
+	if err != nil {
+		return nil, errors.New("keystore file has invalid ciphertext")
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, keystoreAAD(ks))
+	if err != nil {
+		return nil, errors.New("wrong passphrase or corrupted keystore file")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %v", err)
+	}
+	return key, nil
+}
+
+// Derive the AES-256-GCM cipher for a keystore file from the passphrase.
+func keystoreCipher(ks keystoreFile, pass []byte) (cipher.AEAD, error) {
+	salt, err := hex.DecodeString(ks.Salt)
+	if err != nil || len(salt) == 0 {
+		return nil, errors.New("keystore file has an invalid salt")
+	}
+	derived, err := scrypt.Key(pass, salt, ks.N, ks.R, ks.P, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %v", err)
+	}
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Authenticate the KDF parameters so they cannot be swapped undetected.
+func keystoreAAD(ks keystoreFile) []byte {
+	return []byte(fmt.Sprintf("v%d:%s:%d:%d:%d:%s", ks.Version, ks.KDF, ks.N, ks.R, ks.P, ks.Salt))
+}
+
+// Write data to a temporary file next to path and rename it into place, so
+// a crash never leaves a half-written file behind.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set file permissions: %v", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temp file: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temp file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"expvar"
+	"flag"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	firstWavePeers = flag.Int("first-wave-peers", 8, "number of lowest-latency miners a new block is sent to first")
+
+	latencyMu   sync.Mutex
+	peerLatency = make(map[string]time.Duration) // Smoothed round-trip time by miner IP
+
+	peerLatencyMetric = expvar.NewMap("peer_latency_ms")
+)
+
+// Fold a round-trip sample into the miner's smoothed latency.
+func recordLatency(miner string, rtt time.Duration) {
+	latencyMu.Lock()
+	defer latencyMu.Unlock()
+	if prev, ok := peerLatency[miner]; ok {
+		rtt = (prev*7 + rtt) / 8
+	}
+	peerLatency[miner] = rtt
+
+	ms := new(expvar.Float)
+	ms.Set(float64(rtt) / float64(time.Millisecond))
+	peerLatencyMetric.Set(miner, ms)
+}
+
+// Order miners by latency, unmeasured miners last.
+func minersByLatency(miners []string) []string {
+	miners = append([]string{}, miners...)
+
+	latencyMu.Lock()
+	defer latencyMu.Unlock()
+	sort.SliceStable(miners, func(i, j int) bool {
+		a, aOK := peerLatency[miners[i]]
+		b, bOK := peerLatency[miners[j]]
+		if aOK != bOK {
+			return aOK
+		}
+		return a < b
+	})
+	return miners
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Reward credited to the miner of each block.
+const blockReward = 50
+
+// Coinbase marks a block's first transaction as the miner's reward.
+type Coinbase struct {
+	Address string // Wallet address credited
+	Amount  int64
+}
+
+var ledger = NewLedger() // Balances on the main chain
+
+// Ledger tracks account balances as of the main chain tip.
+type Ledger struct {
+	mu       sync.RWMutex
+	balances balanceSheet
+}
+
+// balanceSheet maps addresses to their non-zero balances.
+type balanceSheet map[string]int64
+
+func NewLedger() *Ledger {
+	return &Ledger{balances: make(balanceSheet)}
+}
+
+// Build the coinbase transaction for a block at the given height, paying
+// the block reward plus the fees of the block's other transactions.
+func coinbaseTransaction(height int, address string, fees int64) Transaction {
+	data := fmt.Sprintf("coinbase %d %s", height, address)
+	return Transaction{
+		ID:       generateTransactionID(data),
+		Data:     data,
+		Coinbase: &Coinbase{Address: address, Amount: blockReward + fees},
+	}
+}
+
+// Check a coinbase transaction of a block at the given height whose other
+// transactions pay the given fees.
+func checkCoinbase(tx Transaction, height int, fees int64) error {
+	expected := coinbaseTransaction(height, tx.Coinbase.Address, fees)
+	if tx.ID != expected.ID || tx.Data != expected.Data {
+		return fmt.Errorf("coinbase %s does not match block %d", tx.ID, height)
+	}
+	if tx.Coinbase.Amount != expected.Coinbase.Amount {
+		return fmt.Errorf("coinbase pays %d, reward plus fees is %d", tx.Coinbase.Amount, expected.Coinbase.Amount)
+	}
+	return nil
+}
+
+// Total fees paid by the non-coinbase transactions.
+func blockFees(transactions []Transaction) int64 {
+	var fees int64
+	for _, tx := range transactions {
+		if tx.Coinbase == nil {
+			fees += tx.Fee
+		}
+	}
+	return fees
+}
+
+// Check that every sender in a block can pay its fees from its balance
+// before the block. The block's own coinbase cannot fund them.
+func (l *Ledger) CheckFees(block Block) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.balances.checkFees(block)
+}
+
+// Apply a block appended to the main chain.
+func (l *Ledger) ApplyBlock(block Block) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.balances.apply(block)
+}
+
+// Move the balances across a reorg, checking the fees of each added block
+// against the balances before it. If a block cannot pay, the ledger is left
+// unchanged.
+func (l *Ledger) ApplyReorg(reorg chainReorg) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	balances := make(balanceSheet, len(l.balances))
+	for address, balance := range l.balances {
+		balances[address] = balance
+	}
+	for i := len(reorg.Removed) - 1; i >= 0; i-- {
+		balances.revert(reorg.Removed[i])
+	}
+	for _, block := range reorg.Added {
+		if err := balances.checkFees(block); err != nil {
+			return fmt.Errorf("block %d: %v", block.BlockNumber, err)
+		}
+		balances.apply(block)
+	}
+	l.balances = balances
+	return nil
+}
+
+func (b balanceSheet) checkFees(block Block) error {
+	spent := make(map[string]int64)
+	for _, tx := range block.Transactions {
+		if tx.Coinbase != nil || tx.Fee == 0 {
+			continue
+		}
+		sender, err := transactionSender(tx)
+		if err != nil {
+			return fmt.Errorf("transaction %s: %v", tx.ID, err)
+		}
+		if tx.Fee > b[sender]-spent[sender] {
+			return fmt.Errorf("sender %s cannot pay the fee of transaction %s", sender, tx.ID)
+		}
+		spent[sender] += tx.Fee
+	}
+	return nil
+}
+
+func (b balanceSheet) apply(block Block) {
+	for _, tx := range block.Transactions {
+		if tx.Coinbase != nil {
+			b.add(tx.Coinbase.Address, tx.Coinbase.Amount)
+		} else if tx.Fee > 0 {
+			// Checked by checkFees before the block was connected
+			sender, _ := transactionSender(tx)
+			b.add(sender, -tx.Fee)
+		}
+	}
+}
+
+func (b balanceSheet) revert(block Block) {
+	for _, tx := range block.Transactions {
+		if tx.Coinbase != nil {
+			b.add(tx.Coinbase.Address, -tx.Coinbase.Amount)
+		} else if tx.Fee > 0 {
+			sender, _ := transactionSender(tx)
+			b.add(sender, tx.Fee)
+		}
+	}
+}
+
+// Change a balance, forgetting addresses that drop to zero.
+func (b balanceSheet) add(address string, amount int64) {
+	b[address] += amount
+	if b[address] == 0 {
+		delete(b, address)
+	}
+}
+
+func (l *Ledger) Balance(address string) int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.balances[address]
+}
+
+// Balances of every address with a non-zero balance.
+func (l *Ledger) Snapshot() map[string]int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	balances := make(map[string]int64, len(l.balances))
+	for address, balance := range l.balances {
+		balances[address] = balance
+	}
+	return balances
+}
+
+// Rebuild the ledger from the stored chain, starting from the balances of
+// the checkpoint the chain starts from, if any.
+func (l *Ledger) Load(store *ChainStore) error {
+	start := 0
+	if checkpoint := store.Checkpoint(); checkpoint != nil {
+		l.mu.Lock()
+		for address, balance := range checkpoint.Balances {
+			l.balances.add(address, balance)
+		}
+		l.mu.Unlock()
+		start = checkpoint.Height + 1
+	}
+	for n := start; n < store.Height(); n++ {
+		block, err := store.GetBlockByNumber(n)
+		if err != nil {
+			return err
+		}
+		l.ApplyBlock(block)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+)
+
+var lightMode = flag.Bool("light", false, "run as a light node: sync and check block headers only, and verify transactions through Merkle proofs from full nodes")
+
+// Directory of the chain store. Light nodes keep their headers apart from
+// a full chain in the same data directory.
+func chainDataDir() string {
+	if *lightMode {
+		return filepath.Join(*dataDir, "headers")
+	}
+	return *dataDir
+}
+
+// The form a block is stored in: light nodes keep headers only.
+func storedForm(block Block) Block {
+	if *lightMode {
+		return block.Header()
+	}
+	return block
+}
+
+// Check a proof that a transaction was mined against the stored header of
+// the block it names, rather than the header the proof carries. Returns the
+// stored header.
+func verifyInclusion(proof merkleProof) (Block, error) {
+	header, err := chainStore.GetBlockByHash(proof.BlockHash)
+	if errors.Is(err, errBlockNotFound) {
+		return Block{}, fmt.Errorf("block %s is not on the main chain", proof.BlockHash)
+	}
+	if err != nil {
+		return Block{}, err
+	}
+	root, err := proof.Root()
+	if err != nil {
+		return Block{}, err
+	}
+	if root != header.MerkleRoot {
+		return Block{}, fmt.Errorf("proof does not lead to the Merkle root of block %s", proof.BlockHash)
+	}
+	return header, nil
+}
+
+// Ask the peers for a proof that a transaction was mined until one serves a
+// proof that holds against our headers. The returned proof carries our
+// header of the block. Returns errTransactionNotFound if no peer knows the
+// transaction.
+func fetchTransactionProof(txID string) (merkleProof, error) {
+	for _, addr := range peerManager.Peers() {
+		proof, err := transport.TransactionProof(addr, txID)
+		if errors.Is(err, errTransactionNotFound) {
+			continue
+		}
+		if err != nil {
+			p2pLog.Warn("Could not get transaction proof from peer", "peer", addr, "tx", txID, "err", err)
+			continue
+		}
+		if proof.TxID != txID {
+			penalizeIdentity(addr, scoreMalformedMessage, "proof for another transaction")
+			continue
+		}
+		header, err := verifyInclusion(proof)
+		if err != nil {
+			p2pLog.Warn("Rejected transaction proof", "peer", addr, "tx", txID, "err", err)
+			continue
+		}
+		proof.Header = &header
+		return proof, nil
+	}
+	return merkleProof{}, errTransactionNotFound
+}
+
+// Ask a miner for the Merkle proof of a transaction on its main chain.
+func requestProof(addr, txID string) (merkleProof, error) {
+	reply, err := requestFromPeer(addr, peerMessage{Type: msgGetProof, Hash: txID}, msgProof)
+	if err != nil {
+		return merkleProof{}, err
+	}
+	if reply.Proof == nil {
+		return merkleProof{}, errTransactionNotFound
+	}
+	return *reply.Proof, nil
+}
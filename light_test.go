@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestMerkleProofsLeadToRoot(t *testing.T) {
+	for count := 1; count <= 7; count++ {
+		block := Block{Hash: "block"}
+		for i := 0; i < count; i++ {
+			block.Transactions = append(block.Transactions, Transaction{ID: fmt.Sprintf("tx%d", i)})
+		}
+		want := computeMerkleRoot(block.Transactions)
+		for i := range block.Transactions {
+			proof := buildMerkleProof(block, i)
+			if root, err := proof.Root(); err != nil || root != want {
+				t.Errorf("proof of transaction %d of %d leads to %q, %v; want %q", i, count, root, err, want)
+			}
+			proof.TxID = "other"
+			if root, _ := proof.Root(); root == want {
+				t.Errorf("proof of transaction %d of %d holds for another transaction", i, count)
+			}
+		}
+	}
+
+	proof := buildMerkleProof(Block{Transactions: []Transaction{{ID: "a"}, {ID: "b"}}}, 0)
+	proof.Branch = append(proof.Branch, proof.Branch[0])
+	if _, err := proof.Root(); err == nil {
+		t.Error("proof with a branch too long was accepted")
+	}
+}
+
+func TestLightNodeSyncsHeaders(t *testing.T) {
+	network := newTestNetwork(t, 2)
+	miner := network.nodes[0]
+	var mined []Block
+	for i := 0; i < 3; i++ {
+		mined = append(mined, network.Mine(miner))
+		network.Settle()
+	}
+
+	light := network.addNode("198.51.100.10")
+	light.light = true
+	light.peers.Add(miner.ip)
+	network.Settle()
+	network.RequireConverged(4)
+
+	for _, block := range mined {
+		header, err := light.store.GetBlockByHash(block.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Transactions != nil || header.MerkleRoot != block.MerkleRoot {
+			t.Fatalf("light node stored %+v for block %d", header, block.BlockNumber)
+		}
+	}
+
+	// Inclusion is proven by the full node and checked against the headers
+	coinbase := mined[1].Transactions[0]
+	network.Run(light, func() {
+		proof, err := fetchTransactionProof(coinbase.ID)
+		if err != nil || proof.BlockHash != mined[1].Hash || proof.Header.BlockNumber != mined[1].BlockNumber {
+			t.Errorf("proof of a mined transaction is %+v, %v", proof, err)
+		}
+		if _, err := fetchTransactionProof("unknown"); !errors.Is(err, errTransactionNotFound) {
+			t.Errorf("proof of an unknown transaction returned %v", err)
+		}
+
+		// A proof for another block's root does not hold
+		forged := buildMerkleProof(mined[1], 0)
+		forged.BlockHash = mined[2].Hash
+		if _, err := verifyInclusion(forged); err == nil {
+			t.Error("proof against the wrong block was accepted")
+		}
+	})
+}
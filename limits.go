@@ -0,0 +1,407 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Misbehaviour points added to a peer's score; a peer reaching
+// peerScoreLimit is banned for -ban-duration, disconnected and refused on
+// both listeners. Bans are kept in <datadir>/bans.json (see bans.go).
+const (
+	scoreMalformedMessage   = 10
+	scoreOversizedMessage   = 50
+	scoreBandwidthExceeded  = 50
+	scoreInvalidBlock       = 20
+	scoreInvalidProofOfWork = 50
+	peerScoreLimit          = 100
+
+	// Scores drop by scoreDecay points a minute, so a peer that stops
+	// misbehaving is forgotten within the hour.
+	scoreDecay = 2
+
+	// Bandwidth and job budgets unused for this long are full again and
+	// are dropped; the peer gets a fresh one when it returns.
+	peerBudgetIdle = 10 * time.Minute
+)
+
+var (
+	maxMessageSize  = flag.Int("max-message-size", 1<<20, "maximum size in bytes of a single protocol message")
+	peerBandwidth   = flag.Int("peer-bandwidth", 1<<20, "per-peer read and write budget in bytes per second")
+	maxConnHandlers = flag.Int("max-handlers", 64, "maximum concurrent connection handlers across all listeners")
+	maxConnsPerIP   = flag.Int("max-conns-per-ip", 16, "maximum concurrent inbound connections from one IP")
+	peerIdleTimeout = flag.Duration("peer-idle-timeout", 2*time.Minute, "how long an inbound connection may wait for the peer to send something before it is closed (0 for no limit)")
+	jobRate         = flag.Int("job-rate", 30, "jobs accepted per minute from one IP (0 for no limit)")
+	banDuration     = flag.Duration("ban-duration", 24*time.Hour, "how long a misbehaving peer stays banned")
+
+	errBandwidthExceeded = errors.New("peer exceeded its bandwidth budget")
+	errJobRateExceeded   = errors.New("job rate limit exceeded, try again later")
+
+	handlerSlots     chan struct{} // Semaphore capping connection handler goroutines
+	handlerSlotsOnce sync.Once
+
+	peerLimitsMu sync.Mutex
+	peerScores   = make(map[string]int)                  // Misbehaviour score by peer IP or node ID
+	peerBans     = make(map[string]time.Time)            // Ban expiry by peer IP or node ID
+	peerBuckets  = make(map[string]*peerBandwidthBudget) // Bandwidth budgets by peer IP
+	peerJobs     = make(map[string]*tokenBucket)         // Job submission budgets by peer IP
+	peerConns    = make(map[string]int)                  // Live inbound connections by peer IP
+)
+
+// Read and write token buckets shared by all connections from one peer.
+type peerBandwidthBudget struct {
+	read, write tokenBucket
+}
+
+func (b *peerBandwidthBudget) idleSince(t time.Time) bool {
+	return b.read.idleSince(t) && b.write.idleSince(t)
+}
+
+type tokenBucket struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// Refill the bucket at rate tokens per second, holding at most burst.
+func (b *tokenBucket) refill(rate, burst float64) {
+	now := time.Now()
+	if b.last.IsZero() {
+		b.tokens = burst
+	} else {
+		b.tokens += now.Sub(b.last).Seconds() * rate
+		if b.tokens > burst {
+			b.tokens = burst
+		}
+	}
+	b.last = now
+}
+
+// Report whether the bucket has not been used since t.
+func (b *tokenBucket) idleSince(t time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.last.Before(t)
+}
+
+// Spend n bytes and report whether the budget was still positive afterwards.
+// The bucket holds two seconds' worth of bytes.
+func (b *tokenBucket) spend(n int, rate float64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(rate, 2*rate)
+	b.tokens -= float64(n)
+	return b.tokens >= 0
+}
+
+// Take one token if the bucket has one; unlike spend, a refused take costs
+// nothing, so a peer that keeps retrying is not locked out forever.
+func (b *tokenBucket) take(rate, burst float64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(rate, burst)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Spend n bytes, then block until the budget has recovered from them.
+// Writers sleeping on the same bucket queue up behind each other's debt
+// without holding the lock.
+func (b *tokenBucket) wait(n int, rate float64) {
+	b.mu.Lock()
+	b.refill(rate, 2*rate)
+	b.tokens -= float64(n)
+	delay := time.Duration(-b.tokens / rate * float64(time.Second))
+	b.mu.Unlock()
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// limitedConn enforces the peer's bandwidth budget: reads over budget fail
+// with errBandwidthExceeded, writes wait until budget is available. Reads
+// on accepted connections time out after -peer-idle-timeout so that idle
+// peers cannot hold on to handler slots.
+type limitedConn struct {
+	net.Conn
+	budget   *peerBandwidthBudget
+	release  sync.Once
+	peer     string       // Set on accepted connections, which hold a handler slot
+	deadline atomic.Int64 // Read deadline set by the conn's user, in Unix nanoseconds; 0 for none
+}
+
+func (c *limitedConn) Read(p []byte) (int, error) {
+	if c.peer != "" && *peerIdleTimeout > 0 {
+		deadline := time.Now().Add(*peerIdleTimeout)
+		if set := c.deadline.Load(); set != 0 && set < deadline.UnixNano() {
+			deadline = time.Unix(0, set)
+		}
+		c.Conn.SetReadDeadline(deadline)
+	}
+	n, err := c.Conn.Read(p)
+	if n > 0 && !c.budget.read.spend(n, float64(*peerBandwidth)) {
+		return n, errBandwidthExceeded
+	}
+	return n, err
+}
+
+func (c *limitedConn) Write(p []byte) (int, error) {
+	c.budget.write.wait(len(p), float64(*peerBandwidth))
+	return c.Conn.Write(p)
+}
+
+// Deadlines set explicitly, such as the handshake's, still apply when they
+// are sooner than the idle timeout.
+func (c *limitedConn) SetDeadline(t time.Time) error {
+	c.deadline.Store(unixNanoOrZero(t))
+	return c.Conn.SetDeadline(t)
+}
+
+func (c *limitedConn) SetReadDeadline(t time.Time) error {
+	c.deadline.Store(unixNanoOrZero(t))
+	return c.Conn.SetReadDeadline(t)
+}
+
+func unixNanoOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func (c *limitedConn) Close() error {
+	if c.peer != "" {
+		c.release.Do(func() {
+			releasePeerConn(c.peer)
+			<-handlerSlots
+		})
+	}
+	return c.Conn.Close()
+}
+
+// limitListener refuses banned peers, caps the number of live connections in
+// total and per IP, and wraps accepted connections with bandwidth budgets.
+type limitListener struct {
+	net.Listener
+}
+
+// Listen on addr with DoS limits applied to every accepted connection.
+func listenLimited(addr string) (net.Listener, error) {
+	handlerSlotsOnce.Do(func() {
+		handlerSlots = make(chan struct{}, *maxConnHandlers)
+	})
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return limitListener{ln}, nil
+}
+
+func (l limitListener) Accept() (net.Conn, error) {
+	for {
+		// Blocks while every handler slot is busy, so a flood of
+		// connections waits in the kernel backlog instead of spawning
+		// goroutines.
+		handlerSlots <- struct{}{}
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			<-handlerSlots
+			return nil, err
+		}
+
+		peer := peerHost(conn.RemoteAddr())
+		if peerBanned(peer) {
+			p2pLog.Warn("Refusing connection from banned peer", "peer", peer)
+			conn.Close()
+			<-handlerSlots
+			continue
+		}
+		if !acquirePeerConn(peer) {
+			p2pLog.Warn("Refusing connection over the per-IP limit", "peer", peer, "limit", *maxConnsPerIP)
+			conn.Close()
+			<-handlerSlots
+			continue
+		}
+		return &limitedConn{Conn: conn, budget: peerBudget(peer), peer: peer}, nil
+	}
+}
+
+// Dial addr with the peer's write budget applied.
+func dialLimited(addr string) (net.Conn, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &limitedConn{Conn: conn, budget: peerBudget(peerHost(conn.RemoteAddr()))}, nil
+}
+
+// Score the peer for a read error that ended its connection. id is the
+// peer's node ID if known.
+func penalizeFrameError(addr net.Addr, id string, err error) {
+	switch {
+	case errors.Is(err, errFrameTooLarge):
+		penalizeNode(addr, id, scoreOversizedMessage, "oversized message")
+	case errors.Is(err, errBandwidthExceeded):
+		penalizeNode(addr, id, scoreBandwidthExceeded, "bandwidth budget exceeded")
+	}
+}
+
+// Add misbehaviour points to the peer behind addr.
+func penalizePeer(addr net.Addr, points int, reason string) {
+	penalizeIdentity(peerHost(addr), points, reason)
+}
+
+// Add misbehaviour points to the peer behind addr and also to its node ID
+// if known, so the score follows the node when its address changes.
+func penalizeNode(addr net.Addr, id string, points int, reason string) {
+	host := peerHost(addr)
+	penalizeIdentity(host, points, reason)
+	if id != "" && id != host {
+		penalizeIdentity(id, points, reason)
+	}
+}
+
+// Add misbehaviour points to a peer known by IP, certificate name or node ID,
+// banning it once its score reaches the limit.
+func penalizeIdentity(peer string, points int, reason string) {
+	peerLimitsMu.Lock()
+	peerScores[peer] += points
+	score := peerScores[peer]
+	peerLimitsMu.Unlock()
+
+	p2pLog.Warn("Peer misbehaved", "peer", peer, "reason", reason, "score", score)
+	if score >= peerScoreLimit {
+		banPeer(peer, *banDuration)
+		p2pLog.Warn("Banned peer", "peer", peer, "duration", *banDuration)
+	}
+}
+
+// Report whether the peer is serving a ban. Expired bans are forgotten, so
+// the peer starts again from a clean score.
+func peerBanned(peer string) bool {
+	peerLimitsMu.Lock()
+	defer peerLimitsMu.Unlock()
+	until, ok := peerBans[peer]
+	if !ok {
+		return false
+	}
+	if time.Now().After(until) {
+		delete(peerBans, peer)
+		return false
+	}
+	return true
+}
+
+// Count a new inbound connection from peer unless it already has the
+// maximum number open.
+func acquirePeerConn(peer string) bool {
+	peerLimitsMu.Lock()
+	defer peerLimitsMu.Unlock()
+	if peerConns[peer] >= *maxConnsPerIP {
+		return false
+	}
+	peerConns[peer]++
+	return true
+}
+
+func releasePeerConn(peer string) {
+	peerLimitsMu.Lock()
+	defer peerLimitsMu.Unlock()
+	if peerConns[peer]--; peerConns[peer] <= 0 {
+		delete(peerConns, peer)
+	}
+}
+
+// Charge a job submission to the peer behind addr, failing once it has used
+// up its -job-rate jobs per minute.
+func chargeJob(addr net.Addr) error {
+	if *jobRate <= 0 {
+		return nil
+	}
+	peer := peerHost(addr)
+	peerLimitsMu.Lock()
+	bucket, ok := peerJobs[peer]
+	if !ok {
+		bucket = &tokenBucket{}
+		peerJobs[peer] = bucket
+	}
+	peerLimitsMu.Unlock()
+
+	if !bucket.take(float64(*jobRate)/60, float64(*jobRate)) {
+		txprocLog.Warn("Job rate limit exceeded", "peer", peer, "limit", *jobRate)
+		return errJobRateExceeded
+	}
+	return nil
+}
+
+func peerBudget(peer string) *peerBandwidthBudget {
+	peerLimitsMu.Lock()
+	defer peerLimitsMu.Unlock()
+	budget, ok := peerBuckets[peer]
+	if !ok {
+		budget = &peerBandwidthBudget{}
+		peerBuckets[peer] = budget
+	}
+	return budget
+}
+
+// Decay misbehaviour scores and drop expired bans and idle budgets every
+// minute, so peers that come and go do not pile up in memory.
+func maintainPeerLimits(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		sweepPeerLimits(time.Now())
+	}
+}
+
+func sweepPeerLimits(now time.Time) {
+	peerLimitsMu.Lock()
+	defer peerLimitsMu.Unlock()
+	for peer, score := range peerScores {
+		if score -= scoreDecay; score > 0 {
+			peerScores[peer] = score
+		} else {
+			delete(peerScores, peer)
+		}
+	}
+	for peer, until := range peerBans {
+		if now.After(until) {
+			delete(peerBans, peer)
+		}
+	}
+	idle := now.Add(-peerBudgetIdle)
+	for peer, budget := range peerBuckets {
+		// Connections still open share the budget they were given
+		if peerConns[peer] == 0 && budget.idleSince(idle) {
+			delete(peerBuckets, peer)
+		}
+	}
+	for peer, bucket := range peerJobs {
+		if bucket.idleSince(idle) {
+			delete(peerJobs, peer)
+		}
+	}
+}
+
+// Strip the port from a remote address.
+func peerHost(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
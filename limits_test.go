@@ -0,0 +1,241 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestPeerIsBannedAtScoreLimit(t *testing.T) {
+	peer := "203.0.113.7"
+	t.Cleanup(func() {
+		peerLimitsMu.Lock()
+		delete(peerBans, peer)
+		delete(peerScores, peer)
+		peerLimitsMu.Unlock()
+	})
+
+	penalizeIdentity(peer, peerScoreLimit-1, "test")
+	if peerBanned(peer) {
+		t.Fatal("peer banned below the score limit")
+	}
+	penalizeIdentity(peer, 1, "test")
+	if !peerBanned(peer) {
+		t.Fatal("peer not banned at the score limit")
+	}
+
+	// Once the ban runs out the peer starts over
+	peerLimitsMu.Lock()
+	peerBans[peer] = time.Now().Add(-time.Second)
+	peerLimitsMu.Unlock()
+	if peerBanned(peer) {
+		t.Fatal("peer still banned after the ban expired")
+	}
+	if score := peerScores[peer]; score != 0 {
+		t.Errorf("score after ban = %d, want 0", score)
+	}
+}
+
+func TestListenerLimitsConnectionsPerIP(t *testing.T) {
+	defer func(n int) { *maxConnsPerIP = n }(*maxConnsPerIP)
+	*maxConnsPerIP = 2
+
+	ln, err := listenLimited("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	accepted := make(chan net.Conn, 4)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	dial := func() net.Conn {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+	expectAccept := func(want bool) net.Conn {
+		select {
+		case conn := <-accepted:
+			if !want {
+				t.Fatal("connection over the per-IP limit was accepted")
+			}
+			return conn
+		case <-time.After(200 * time.Millisecond):
+			if want {
+				t.Fatal("connection under the per-IP limit was refused")
+			}
+			return nil
+		}
+	}
+
+	dial()
+	first := expectAccept(true)
+	dial()
+	expectAccept(true)
+	dial()
+	expectAccept(false)
+
+	// Closing a connection frees its place for the next one
+	first.Close()
+	dial()
+	expectAccept(true)
+}
+
+func TestJobRateLimit(t *testing.T) {
+	defer func(n int) { *jobRate = n }(*jobRate)
+	*jobRate = 2
+
+	addr := &net.TCPAddr{IP: net.ParseIP("198.51.100.4"), Port: 4000}
+	t.Cleanup(func() {
+		peerLimitsMu.Lock()
+		delete(peerJobs, peerHost(addr))
+		peerLimitsMu.Unlock()
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := chargeJob(addr); err != nil {
+			t.Fatalf("job %d: %v", i, err)
+		}
+	}
+	if err := chargeJob(addr); !errors.Is(err, errJobRateExceeded) {
+		t.Fatalf("third job: err = %v, want %v", err, errJobRateExceeded)
+	}
+
+	// Other peers have their own budget
+	other := &net.TCPAddr{IP: net.ParseIP("198.51.100.5"), Port: 4000}
+	t.Cleanup(func() {
+		peerLimitsMu.Lock()
+		delete(peerJobs, peerHost(other))
+		peerLimitsMu.Unlock()
+	})
+	if err := chargeJob(other); err != nil {
+		t.Fatalf("other peer: %v", err)
+	}
+}
+
+func TestWaitingWriterDoesNotHoldBucket(t *testing.T) {
+	var bucket tokenBucket
+	bucket.spend(0, 1000) // Fill the bucket with two seconds' worth
+
+	// Three seconds' worth of writes leaves the writer a second to wait
+	go bucket.wait(3000, 1000)
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	bucket.spend(1, 1000)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("spending waited %v on a sleeping writer", elapsed)
+	}
+}
+
+func TestIdleConnectionTimesOut(t *testing.T) {
+	defer func(d time.Duration) { *peerIdleTimeout = d }(*peerIdleTimeout)
+	*peerIdleTimeout = 100 * time.Millisecond
+
+	ln, err := listenLimited("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// The client sends nothing, so the read gives up and the slot can go
+	done := make(chan error, 1)
+	go func() {
+		_, err := conn.Read(make([]byte, 1))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			t.Errorf("idle read failed with %v, want a timeout", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("read from an idle peer did not time out")
+	}
+
+	// A sooner deadline set by the caller still applies
+	*peerIdleTimeout = time.Minute
+	conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	go func() {
+		_, err := conn.Read(make([]byte, 1))
+		done <- err
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("idle timeout overrode the caller's deadline")
+	}
+}
+
+func TestSweepForgetsIdlePeers(t *testing.T) {
+	defer func(n int) { *jobRate = n }(*jobRate)
+	*jobRate = 2
+	idle, busy := "198.51.100.8", "198.51.100.9"
+	t.Cleanup(func() {
+		peerLimitsMu.Lock()
+		for _, peer := range []string{idle, busy} {
+			delete(peerScores, peer)
+			delete(peerBuckets, peer)
+			delete(peerJobs, peer)
+			delete(peerConns, peer)
+		}
+		peerLimitsMu.Unlock()
+	})
+
+	penalizeIdentity(idle, scoreDecay+1, "test")
+	penalizeIdentity(busy, scoreMalformedMessage, "test")
+	for _, peer := range []string{idle, busy} {
+		peerBudget(peer).read.spend(1, 1)
+		chargeJob(&net.TCPAddr{IP: net.ParseIP(peer), Port: 4000})
+	}
+	acquirePeerConn(busy)
+
+	sweepPeerLimits(time.Now().Add(peerBudgetIdle + time.Second))
+	peerLimitsMu.Lock()
+	if score := peerScores[idle]; score != 1 {
+		t.Errorf("score after one sweep = %d, want 1", score)
+	}
+	if _, ok := peerJobs[idle]; ok {
+		t.Error("idle job budget was kept")
+	}
+	if _, ok := peerBuckets[idle]; ok {
+		t.Error("idle bandwidth budget was kept")
+	}
+	if _, ok := peerBuckets[busy]; !ok {
+		t.Error("bandwidth budget of a connected peer was dropped")
+	}
+
+	peerLimitsMu.Unlock()
+
+	sweepPeerLimits(time.Now())
+	peerLimitsMu.Lock()
+	_, ok := peerScores[idle]
+	peerLimitsMu.Unlock()
+	if ok {
+		t.Error("decayed score was kept")
+	}
+}
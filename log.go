@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+var (
+	logLevel  = flag.String("log-level", "info", "minimum log level: debug, info, warn or error")
+	logFile   = flag.String("log-file", "", "file logs are appended to (default: stderr)")
+	logFormat = flag.String("log-format", "text", "log format: text or json")
+
+	logLevelVar slog.LevelVar // Minimum level, changeable at runtime (see setLogLevel)
+
+	// Per-module loggers
+	miningLog = slog.With("module", "mining")
+	p2pLog    = slog.With("module", "p2p")
+	ipfsLog   = slog.With("module", "ipfs")
+	txprocLog = slog.With("module", "txproc")
+	chainLog  = slog.With("module", "chain")
+	apiLog    = slog.With("module", "api")
+	alertLog  = slog.With("module", "alerts")
+	nodeLog   = slog.With("module", "node")
+
+	moduleLoggers = map[string]**slog.Logger{
+		"mining": &miningLog,
+		"p2p":    &p2pLog,
+		"ipfs":   &ipfsLog,
+		"txproc": &txprocLog,
+		"chain":  &chainLog,
+		"api":    &apiLog,
+		"alerts": &alertLog,
+		"node":   &nodeLog,
+	}
+)
+
+// Configure the log level, format and output from flags.
+func setupLogging() error {
+	if err := setLogLevel(*logLevel); err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stderr
+	if *logFile != "" {
+		file, err := os.OpenFile(*logFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %v", err)
+		}
+		out = file
+	}
+
+	options := &slog.HandlerOptions{Level: &logLevelVar}
+	var handler slog.Handler
+	switch *logFormat {
+	case "text":
+		handler = slog.NewTextHandler(out, options)
+	case "json":
+		handler = slog.NewJSONHandler(out, options)
+	default:
+		return fmt.Errorf("invalid log format %q", *logFormat)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	for module, logger := range moduleLoggers {
+		*logger = slog.With("module", module)
+	}
+	return nil
+}
+
+// Change the minimum log level: debug, info, warn or error.
+func setLogLevel(name string) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return fmt.Errorf("invalid log level %q", name)
+	}
+	logLevelVar.Set(level)
+	return nil
+}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Transaction priorities. Higher priorities are mined first.
+const (
+	priorityNormal   = 0
+	priorityRequeued = 1 // Returned from blocks dropped by a reorg
+)
+
+var (
+	mempoolSize = flag.Int("mempool-size", 1000, "maximum number of pending transactions")
+	mempoolTTL  = flag.Duration("mempool-ttl", time.Hour, "how long a pending transaction is kept before it is evicted")
+
+	minBlockTxs   = flag.Int("min-block-txs", 3, "pending transactions needed to start mining a block")
+	maxBlockTxs   = flag.Int("max-block-txs", 100, "maximum transactions per block")
+	blockInterval = flag.Duration("block-interval", 0, "mine a block with whatever is pending after this long without one (0 waits for -min-block-txs)")
+
+	mempoolSaveInterval = flag.Duration("mempool-save-interval", time.Minute, "how often pending transactions are saved to <datadir>/mempool.json; they are also saved on shutdown")
+
+	mempool = NewMempool() // Transactions waiting to be mined
+
+	errAlreadyPending = errors.New("transaction is already pending")
+	errMempoolFull    = errors.New("mempool is full")
+	errCannotPayFee   = errors.New("sender cannot pay the fee")
+	errExpired        = errors.New("transaction has expired")
+	errAlreadyMined   = errors.New("transaction is already mined")
+	errCoinbase       = errors.New("coinbase transactions cannot be pending")
+)
+
+// Mempool holds pending transactions, one per ID, until they are included
+// in an accepted block or expire. When full, the lowest-priority,
+// lowest-fee, newest transaction is evicted to make room for a better one.
+type Mempool struct {
+	mu      sync.Mutex
+	entries map[string]*mempoolEntry
+	ready   chan struct{} // Signalled when a transaction is added
+}
+
+type mempoolEntry struct {
+	tx       Transaction
+	priority int
+	added    time.Time
+	sender   string // Pays the fee; only set for transactions with one
+}
+
+func NewMempool() *Mempool {
+	return &Mempool{entries: make(map[string]*mempoolEntry), ready: make(chan struct{}, 1)}
+}
+
+// Add a transaction. It fails if the transaction is a coinbase or already
+// pending, if its sender cannot pay its fee on top of the fees it already
+// has pending, or if the pool is full of transactions that rank at least
+// as high.
+func (m *Mempool) Add(tx Transaction, priority int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.expire(time.Now())
+	if err := m.add(tx, priority, time.Now()); err != nil {
+		return err
+	}
+	events.Publish(eventNewTransaction, tx)
+
+	select {
+	case m.ready <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Add a transaction that arrived at the given time. The caller holds m.mu.
+func (m *Mempool) add(tx Transaction, priority int, added time.Time) error {
+	if tx.Coinbase != nil {
+		return errCoinbase
+	}
+	if _, ok := m.entries[tx.ID]; ok {
+		return errAlreadyPending
+	}
+	if chainStore != nil {
+		if tx.expired(chainStore.Height()) {
+			return errExpired
+		}
+		if _, ok := chainStore.SignedAt(transactionDigest(tx)); ok {
+			return errAlreadyMined
+		}
+	}
+	entry := &mempoolEntry{tx: tx, priority: priority, added: added}
+	if tx.Fee > 0 {
+		sender, err := transactionSender(tx)
+		if err != nil {
+			return err
+		}
+		if tx.Fee > ledger.Balance(sender)-m.pendingFees(sender) {
+			return errCannotPayFee
+		}
+		entry.sender = sender
+	}
+	if len(m.entries) >= *mempoolSize {
+		victim := m.lowest()
+		if victim == nil || !outranks(entry, victim) {
+			return errMempoolFull
+		}
+		delete(m.entries, victim.tx.ID)
+		txprocLog.Info("Mempool full, evicted transaction", "tx", victim.tx.ID)
+	}
+	m.entries[tx.ID] = entry
+	return nil
+}
+
+// Remove transactions, typically those included in an accepted block.
+func (m *Mempool) Remove(txs []Transaction) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, tx := range txs {
+		delete(m.entries, tx.ID)
+	}
+}
+
+// Flush drops every pending transaction and reports how many there were.
+func (m *Mempool) Flush() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := len(m.entries)
+	clear(m.entries)
+	return n
+}
+
+// DropExpired drops the transactions that can no longer be mined at the
+// given height.
+func (m *Mempool) DropExpired(height int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, entry := range m.entries {
+		if entry.tx.expired(height) {
+			delete(m.entries, id)
+			txprocLog.Info("Dropped expired transaction", "tx", id, "expiryHeight", entry.tx.ExpiryHeight)
+		}
+	}
+}
+
+// Select up to limit transactions that are final in a block at the given
+// height and timestamp and whose senders can still pay their fees, in
+// mining order. They stay in the pool until removed.
+func (m *Mempool) Select(limit, height int, blockTime int64) []Transaction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.expire(time.Now())
+	var final []*mempoolEntry
+	for _, entry := range m.entries {
+		if entry.tx.isFinal(height, blockTime) && !entry.tx.expired(height) {
+			final = append(final, entry)
+		}
+	}
+
+	// Balances may have dropped since the fees were checked on Add
+	sortEntries(final)
+	var payable []*mempoolEntry
+	spent := make(map[string]int64)
+	for _, entry := range final {
+		if entry.sender != "" {
+			if entry.tx.Fee > ledger.Balance(entry.sender)-spent[entry.sender] {
+				continue
+			}
+			spent[entry.sender] += entry.tx.Fee
+		}
+		payable = append(payable, entry)
+	}
+	return sortedTransactions(payable, limit)
+}
+
+// Pending returns every pending transaction, including time-locked ones,
+// in mining order.
+func (m *Mempool) Pending() []Transaction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.expire(time.Now())
+	entries := make([]*mempoolEntry, 0, len(m.entries))
+	for _, entry := range m.entries {
+		entries = append(entries, entry)
+	}
+	return sortedTransactions(entries, len(entries))
+}
+
+// Get a pending transaction by ID.
+func (m *Mempool) Get(id string) (Transaction, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.entries[id]
+	if !ok {
+		return Transaction{}, false
+	}
+	return entry.tx, true
+}
+
+// Choose the transactions for the next block at the given height and
+// timestamp, or report false to keep waiting. Mining starts once
+// -min-block-txs final transactions are pending, or once -block-interval has
+// passed since the miner started waiting, and takes up to -max-block-txs of
+// them.
+func (m *Mempool) selectForBlock(height int, blockTime int64, waitingSince time.Time) ([]Transaction, bool) {
+	transactions := m.Select(*maxBlockTxs, height, blockTime)
+	if len(transactions) >= *minBlockTxs {
+		return transactions, true
+	}
+	if *blockInterval > 0 && time.Since(waitingSince) >= *blockInterval {
+		return transactions, true
+	}
+	return nil, false
+}
+
+// Ready is signalled whenever a transaction is added.
+func (m *Mempool) Ready() <-chan struct{} {
+	return m.ready
+}
+
+func (m *Mempool) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.entries)
+}
+
+// Drop transactions older than the TTL.
+func (m *Mempool) expire(now time.Time) {
+	for id, entry := range m.entries {
+		if now.Sub(entry.added) > *mempoolTTL {
+			delete(m.entries, id)
+			txprocLog.Info("Expired pending transaction", "tx", id)
+		}
+	}
+}
+
+// Order entries for mining and return the first limit of them.
+func sortedTransactions(entries []*mempoolEntry, limit int) []Transaction {
+	sortEntries(entries)
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	transactions := make([]Transaction, 0, len(entries))
+	for _, entry := range entries {
+		transactions = append(transactions, entry.tx)
+	}
+	return transactions
+}
+
+// Mining order: highest priority first, then highest fee, then oldest.
+func sortEntries(entries []*mempoolEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return outranks(entries[i], entries[j])
+	})
+}
+
+// Whether a is mined before b.
+func outranks(a, b *mempoolEntry) bool {
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	if a.tx.Fee != b.tx.Fee {
+		return a.tx.Fee > b.tx.Fee
+	}
+	return a.added.Before(b.added)
+}
+
+// The entry evicted first: the last in mining order.
+func (m *Mempool) lowest() *mempoolEntry {
+	var victim *mempoolEntry
+	for _, entry := range m.entries {
+		if victim == nil || outranks(victim, entry) {
+			victim = entry
+		}
+	}
+	return victim
+}
+
+// Fees a sender already has pending. The caller holds m.mu.
+func (m *Mempool) pendingFees(sender string) int64 {
+	var fees int64
+	for _, entry := range m.entries {
+		if entry.sender == sender {
+			fees += entry.tx.Fee
+		}
+	}
+	return fees
+}
+
+// A pending transaction as saved to disk.
+type savedEntry struct {
+	Tx       Transaction `json:"tx"`
+	Priority int         `json:"priority,omitempty"`
+	Added    int64       `json:"added"` // Unix milliseconds the transaction arrived
+}
+
+// Write the pending transactions to path, replacing the previous snapshot.
+func (m *Mempool) Save(path string) error {
+	m.mu.Lock()
+	saved := make([]savedEntry, 0, len(m.entries))
+	for _, entry := range m.entries {
+		saved = append(saved, savedEntry{Tx: entry.tx, Priority: entry.priority, Added: entry.added.UnixMilli()})
+	}
+	m.mu.Unlock()
+
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return fmt.Errorf("failed to encode mempool: %v", err)
+	}
+	return writeFileAtomic(path, data, 0600)
+}
+
+// Reload the transactions saved at path, keeping their arrival times so
+// the TTL still applies. Transactions the main chain already holds, and
+// those the ledger no longer lets their sender pay for, are dropped.
+// Returns the number restored; a missing snapshot restores none.
+func (m *Mempool) Load(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read mempool: %v", err)
+	}
+	var saved []savedEntry
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return 0, fmt.Errorf("failed to decode %s: %v", path, err)
+	}
+	if len(saved) == 0 {
+		return 0, nil
+	}
+
+	oldest := saved[0].Added
+	for _, entry := range saved {
+		oldest = min(oldest, entry.Added)
+	}
+	mined := minedSince(time.UnixMilli(oldest))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	restored := 0
+	for _, entry := range saved {
+		if mined[entry.Tx.ID] {
+			continue
+		}
+		if err := m.add(entry.Tx, entry.Priority, time.UnixMilli(entry.Added)); err != nil {
+			txprocLog.Debug("Saved transaction not restored", "tx", entry.Tx.ID, "err", err)
+			continue
+		}
+		restored++
+	}
+	m.expire(time.Now())
+	if len(m.entries) > 0 {
+		select {
+		case m.ready <- struct{}{}:
+		default:
+		}
+	}
+	return restored, nil
+}
+
+// IDs of the transactions in main chain blocks that may have been mined
+// since the given time, allowing for miners' clocks running ahead.
+func minedSince(since time.Time) map[string]bool {
+	mined := make(map[string]bool)
+	floor := since.Add(-maxFutureBlockTime).Unix()
+	for number := chainStore.Height() - 1; number >= chainStore.Base(); number-- {
+		block, err := chainStore.GetBlockByNumber(number)
+		if err != nil || block.Timestamp < floor {
+			break
+		}
+		for _, tx := range block.Transactions {
+			mined[tx.ID] = true
+		}
+	}
+	return mined
+}
+
+// Reload the mempool saved by the last run.
+func loadMempool() error {
+	restored, err := mempool.Load(filepath.Join(*dataDir, "mempool.json"))
+	if err != nil {
+		return err
+	}
+	if restored > 0 {
+		txprocLog.Info("Restored pending transactions", "count", restored)
+	}
+	return nil
+}
+
+// Save the mempool every -mempool-save-interval and once more on shutdown,
+// so pending transactions survive a restart.
+func persistMempool(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	path := filepath.Join(*dataDir, "mempool.json")
+	var tick <-chan time.Time
+	if *mempoolSaveInterval > 0 {
+		ticker := time.NewTicker(*mempoolSaveInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			if err := mempool.Save(path); err != nil {
+				txprocLog.Error("Error saving mempool", "err", err)
+			}
+			return
+		case <-tick:
+			if err := mempool.Save(path); err != nil {
+				txprocLog.Warn("Error saving mempool", "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMempoolSurvivesRestart(t *testing.T) {
+	setupTestMiner(t)
+	path := filepath.Join(t.TempDir(), "mempool.json")
+	mined, pending := signedTestTransaction(t, "mined"), signedTestTransaction(t, "pending")
+	for _, tx := range []Transaction{mined, pending} {
+		if err := mempool.Add(tx, priorityNormal); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mempool.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// One of them is mined while the node is down
+	block := blockTemplate(1, genesisBlock.Hash, genesisBlock.PrevCID, []Transaction{mined})
+	block.Timestamp = time.Now().Unix()
+	block.Hash = block.ComputeHash()
+	if err := acceptBlock(block); err != nil {
+		t.Fatal(err)
+	}
+
+	mempool = NewMempool()
+	restored, err := mempool.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mempool.Pending(); restored != 1 || len(got) != 1 || got[0].ID != pending.ID {
+		t.Errorf("restored %d transactions %v, want only %s", restored, got, pending.ID)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/hamayuna47/BlockChain-For-Algorithms-With-POW/merkle"
+)
+
+// Compute the Merkle root of the transaction IDs, hashed as package merkle
+// lays out the tree.
+func computeMerkleRoot(transactions []Transaction) string {
+	if len(transactions) == 0 {
+		empty := sha256.Sum256(nil)
+		return hex.EncodeToString(empty[:])
+	}
+
+	level := make([][]byte, len(transactions))
+	for i, tx := range transactions {
+		level[i] = merkle.Leaf(tx.ID)
+	}
+	for len(level) > 1 {
+		level = merkleLevel(level)
+	}
+	return hex.EncodeToString(level[0])
+}
+
+// Hash one level of the tree into the next.
+func merkleLevel(level [][]byte) [][]byte {
+	next := make([][]byte, 0, (len(level)+1)/2)
+	for i := 0; i < len(level); i += 2 {
+		if i+1 == len(level) {
+			next = append(next, level[i])
+			continue
+		}
+		next = append(next, merkle.Node(level[i], level[i+1]))
+	}
+	return next
+}
+
+// merkleProof shows that a transaction is in a block: the sibling hashes on
+// the path from the transaction's leaf up to the block's Merkle root. Its
+// JSON decodes as a merkle.Proof.
+type merkleProof struct {
+	TxID      string   `json:"txId"`
+	BlockHash string   `json:"blockHash"`
+	Index     int      `json:"index"`            // Position of the transaction in the block
+	Count     int      `json:"count"`            // Transactions in the block
+	Branch    []string `json:"branch"`           // Hex sibling hashes, leaf level first
+	Header    *Block   `json:"header,omitempty"` // Header of the block, to check the proof against
+}
+
+// Build the proof that the transaction at index is in the block.
+func buildMerkleProof(block Block, index int) merkleProof {
+	header := block.Header()
+	proof := merkleProof{
+		TxID:      block.Transactions[index].ID,
+		BlockHash: block.Hash,
+		Header:    &header,
+		Index:     index,
+		Count:     len(block.Transactions),
+		Branch:    []string{},
+	}
+	level := make([][]byte, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		level[i] = merkle.Leaf(tx.ID)
+	}
+	for position := index; len(level) > 1; position /= 2 {
+		// A node carried up unchanged has no sibling
+		if sibling := position ^ 1; sibling < len(level) {
+			proof.Branch = append(proof.Branch, hex.EncodeToString(level[sibling]))
+		}
+		level = merkleLevel(level)
+	}
+	return proof
+}
+
+// Root returns the Merkle root the proof leads to. The proof holds if it is
+// the root of the block it names.
+func (p merkleProof) Root() (string, error) {
+	return merkle.RootFromBranch(p.TxID, p.Index, p.Count, p.Branch)
+}
@@ -0,0 +1,96 @@
+// Package merkle holds the hashing of the chain's transaction Merkle trees,
+// and lets light clients check the inclusion proofs nodes serve without
+// running a node.
+//
+// Leaves and inner nodes are hashed with distinct prefixes, and an odd node
+// at the end of a level is carried up unchanged rather than paired with
+// itself, so no two different transaction lists share a root.
+package merkle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// Leaf returns the hash of a leaf holding data, such as a transaction ID.
+func Leaf(data string) []byte {
+	sum := sha256.Sum256(append([]byte{0x00}, data...))
+	return sum[:]
+}
+
+// Node returns the hash of an inner node over its two children.
+func Node(left, right []byte) []byte {
+	data := append([]byte{0x01}, left...)
+	sum := sha256.Sum256(append(data, right...))
+	return sum[:]
+}
+
+// Header is the part of a block header a proof is checked against. It
+// decodes from the block JSON nodes serve.
+type Header struct {
+	Hash        string
+	PrevHash    string
+	MerkleRoot  string
+	BlockNumber int
+	Timestamp   int64 `json:",omitempty"`
+}
+
+// Proof shows that a transaction is in a block: the sibling hashes on the
+// path from the transaction's leaf up to the Merkle root of the block's
+// header. It decodes from the proofs nodes serve.
+type Proof struct {
+	TxID   string   `json:"txId"`
+	Index  int      `json:"index"`  // Position of the transaction in the block
+	Count  int      `json:"count"`  // Transactions in the block
+	Branch []string `json:"branch"` // Hex sibling hashes, leaf level first
+	Header Header   `json:"header"`
+}
+
+// RootFromBranch returns the Merkle root a branch leads to from the
+// transaction at index of a block of count transactions.
+func RootFromBranch(txID string, index, count int, branch []string) (string, error) {
+	if index < 0 || index >= count {
+		return "", fmt.Errorf("transaction index %d is outside a block of %d", index, count)
+	}
+	hash := Leaf(txID)
+	for position, size := index, count; size > 1; position, size = position/2, (size+1)/2 {
+		// A node carried up unchanged has no sibling
+		if position == size-1 && size%2 == 1 {
+			continue
+		}
+		if len(branch) == 0 {
+			return "", errors.New("merkle branch is too short")
+		}
+		sibling, err := hex.DecodeString(branch[0])
+		if err != nil || len(sibling) != sha256.Size {
+			return "", fmt.Errorf("invalid merkle branch hash %q", branch[0])
+		}
+		branch = branch[1:]
+		if position%2 == 0 {
+			hash = Node(hash, sibling)
+		} else {
+			hash = Node(sibling, hash)
+		}
+	}
+	if len(branch) != 0 {
+		return "", errors.New("merkle branch is too long")
+	}
+	return hex.EncodeToString(hash), nil
+}
+
+// VerifyProof checks that the proof's branch leads from its transaction to
+// the Merkle root of its header. It does not check the header: the caller
+// must know the block is on the chain, for instance by finding the header's
+// hash among the headers a light node has verified.
+func VerifyProof(proof Proof) error {
+	root, err := RootFromBranch(proof.TxID, proof.Index, proof.Count, proof.Branch)
+	if err != nil {
+		return err
+	}
+	if root != proof.Header.MerkleRoot {
+		return fmt.Errorf("proof does not lead to the Merkle root of block %s", proof.Header.Hash)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package merkle
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestVerifyProof(t *testing.T) {
+	// Three transactions: c is carried up past the first level
+	a, b, c := Leaf("a"), Leaf("b"), Leaf("c")
+	ab := Node(a, b)
+	root := hex.EncodeToString(Node(ab, c))
+	header := Header{Hash: "block", MerkleRoot: root}
+
+	for _, proof := range []Proof{
+		{TxID: "a", Index: 0, Count: 3, Branch: []string{hex.EncodeToString(b), hex.EncodeToString(c)}, Header: header},
+		{TxID: "b", Index: 1, Count: 3, Branch: []string{hex.EncodeToString(a), hex.EncodeToString(c)}, Header: header},
+		{TxID: "c", Index: 2, Count: 3, Branch: []string{hex.EncodeToString(ab)}, Header: header},
+	} {
+		if err := VerifyProof(proof); err != nil {
+			t.Errorf("proof of %s: %v", proof.TxID, err)
+		}
+	}
+
+	for name, proof := range map[string]Proof{
+		"other transaction": {TxID: "d", Index: 2, Count: 3, Branch: []string{hex.EncodeToString(ab)}, Header: header},
+		"wrong position":    {TxID: "a", Index: 1, Count: 3, Branch: []string{hex.EncodeToString(b), hex.EncodeToString(c)}, Header: header},
+		"index past count":  {TxID: "c", Index: 3, Count: 3, Branch: []string{hex.EncodeToString(ab)}, Header: header},
+		"short branch":      {TxID: "a", Index: 0, Count: 3, Branch: []string{hex.EncodeToString(b)}, Header: header},
+		"bad hash":          {TxID: "c", Index: 2, Count: 3, Branch: []string{"zz"}, Header: header},
+	} {
+		if err := VerifyProof(proof); err == nil {
+			t.Errorf("%s: proof verified", name)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// Main chain blocks the miner statistics cover by default and at most.
+const (
+	defaultMinerWindow = 100
+	maxMinerWindow     = 10000
+)
+
+// minerStats is one miner's share of the recent main chain.
+type minerStats struct {
+	MinerID   string  `json:"minerId"`
+	Blocks    int     `json:"blocks"`    // Blocks it mined in the window
+	Share     float64 `json:"share"`     // Fraction of the window's blocks
+	LastBlock int     `json:"lastBlock"` // Height of its latest block in the window
+	Invalid   int     `json:"invalid"`   // Blocks it signed that this node rejected
+}
+
+// Statistics of the miners of the last window main chain blocks and of the
+// miners whose blocks were rejected, most blocks first.
+func collectMinerStats(window int) ([]minerStats, int, error) {
+	byMiner := make(map[string]*minerStats)
+	stats := func(miner string) *minerStats {
+		if byMiner[miner] == nil {
+			byMiner[miner] = &minerStats{MinerID: miner}
+		}
+		return byMiner[miner]
+	}
+
+	counted := 0
+	for n := chainStore.Height() - 1; n >= chainStore.Base() && n > 0 && counted < window; n-- {
+		block, err := chainStore.GetBlockByNumber(n)
+		if err != nil {
+			return nil, 0, err
+		}
+		miner := stats(block.MinerID)
+		miner.Blocks++
+		miner.LastBlock = max(miner.LastBlock, block.BlockNumber)
+		counted++
+	}
+	for miner, count := range nodeState.InvalidBlocks() {
+		stats(miner).Invalid = count
+	}
+
+	list := make([]minerStats, 0, len(byMiner))
+	for _, miner := range byMiner {
+		if counted > 0 {
+			miner.Share = float64(miner.Blocks) / float64(counted)
+		}
+		list = append(list, *miner)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Blocks != list[j].Blocks {
+			return list[i].Blocks > list[j].Blocks
+		}
+		return list[i].MinerID < list[j].MinerID
+	})
+	return list, counted, nil
+}
+
+// GET /miners?blocks=N: who mined the last N main chain blocks, and how
+// many invalid blocks each miner has sent this node.
+// GET /miners?id=M: the numbers of every main chain block miner M mined.
+func handleMiners(w http.ResponseWriter, r *http.Request) {
+	if miner := r.URL.Query().Get("id"); miner != "" {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"minerId": miner,
+			"blocks":  chainStore.BlocksMinedBy(miner),
+			"invalid": nodeState.InvalidBlocks()[miner],
+		})
+		return
+	}
+	window := defaultMinerWindow
+	if value := r.URL.Query().Get("blocks"); value != "" {
+		var err error
+		if window, err = strconv.Atoi(value); err != nil || window < 1 || window > maxMinerWindow {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("blocks must be between 1 and %d", maxMinerWindow))
+			return
+		}
+	}
+	miners, counted, err := collectMinerStats(window)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"blocks": counted, "miners": miners})
+}
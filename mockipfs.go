@@ -0,0 +1,164 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"flag"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// mockIPFS serves the part of the IPFS HTTP API that nodes and the client
+// subcommand call, keeping objects in memory. Devnets run it in place of an
+// IPFS daemon.
+type mockIPFS struct {
+	mu      sync.Mutex
+	objects map[string][]byte // By CID
+	pins    map[string]bool
+}
+
+func newMockIPFS() *mockIPFS {
+	return &mockIPFS{objects: make(map[string][]byte), pins: make(map[string]bool)}
+}
+
+// Run `devnet ipfs`: serve an in-memory IPFS API until killed.
+func runMockIPFS(args []string) int {
+	fs := flag.NewFlagSet("devnet ipfs", flag.ContinueOnError)
+	addr := fs.String("addr", ":5001", "address to serve the IPFS HTTP API on")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	fmt.Println("Serving mock IPFS API on", *addr)
+	if err := http.ListenAndServe(*addr, newMockIPFS().handler()); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+	return 0
+}
+
+func (m *mockIPFS) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v0/add", m.handleAdd)
+	mux.HandleFunc("/api/v0/cat", m.handleCat)
+	mux.HandleFunc("/api/v0/pin/add", m.handlePin)
+	mux.HandleFunc("/api/v0/pin/rm", m.handleUnpin)
+	mux.HandleFunc("/api/v0/pin/ls", m.handlePins)
+	mux.HandleFunc("/api/v0/object/stat", m.handleObjectStat)
+	mux.HandleFunc("/api/v0/version", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"Version": "mock", "Commit": ""})
+	})
+	return mux
+}
+
+// CIDv1 of raw data: the sha256 multihash of the data, in base32.
+func mockCID(data []byte) string {
+	sum := sha256.Sum256(data)
+	cid := append([]byte{0x01, 0x55, 0x12, 0x20}, sum[:]...)
+	return "b" + strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(cid))
+}
+
+// Errors in the form the IPFS API reports them.
+func writeIPFSError(w http.ResponseWriter, message string) {
+	writeJSON(w, http.StatusInternalServerError, map[string]any{"Message": message, "Code": 0, "Type": "error"})
+}
+
+// The CID an "arg" parameter names, as a bare CID or an /ipfs/ path.
+func mockArg(r *http.Request) string {
+	return strings.TrimPrefix(r.URL.Query().Get("arg"), "/ipfs/")
+}
+
+func (m *mockIPFS) handleAdd(w http.ResponseWriter, r *http.Request) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeIPFSError(w, err.Error())
+		return
+	}
+	// Take the first file of the form
+	var part *multipart.Part
+	for part == nil || part.FormName() != "file" {
+		if part, err = reader.NextPart(); err != nil {
+			writeIPFSError(w, "no file to add")
+			return
+		}
+	}
+	data, err := io.ReadAll(part)
+	if err != nil {
+		writeIPFSError(w, err.Error())
+		return
+	}
+
+	cid := mockCID(data)
+	m.mu.Lock()
+	m.objects[cid] = data
+	if r.URL.Query().Get("pin") != "false" {
+		m.pins[cid] = true
+	}
+	m.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]string{"Name": part.FileName(), "Hash": cid, "Size": strconv.Itoa(len(data))})
+}
+
+func (m *mockIPFS) handleCat(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	data, ok := m.objects[mockArg(r)]
+	m.mu.Unlock()
+	if !ok {
+		writeIPFSError(w, "block was not found locally")
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(data)
+}
+
+func (m *mockIPFS) handlePin(w http.ResponseWriter, r *http.Request) {
+	cid := mockArg(r)
+	m.mu.Lock()
+	_, ok := m.objects[cid]
+	if ok {
+		m.pins[cid] = true
+	}
+	m.mu.Unlock()
+	if !ok {
+		writeIPFSError(w, "block was not found locally")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]string{"Pins": {cid}})
+}
+
+func (m *mockIPFS) handleUnpin(w http.ResponseWriter, r *http.Request) {
+	cid := mockArg(r)
+	m.mu.Lock()
+	delete(m.pins, cid)
+	m.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string][]string{"Pins": {cid}})
+}
+
+func (m *mockIPFS) handlePins(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	keys := make(map[string]map[string]string, len(m.pins))
+	for cid := range m.pins {
+		keys[cid] = map[string]string{"Type": "recursive"}
+	}
+	m.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{"Keys": keys})
+}
+
+func (m *mockIPFS) handleObjectStat(w http.ResponseWriter, r *http.Request) {
+	cid := mockArg(r)
+	m.mu.Lock()
+	data, ok := m.objects[cid]
+	m.mu.Unlock()
+	if !ok {
+		writeIPFSError(w, "block was not found locally")
+		return
+	}
+	size := len(data)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"Hash": cid, "NumLinks": 0, "BlockSize": size, "LinksSize": 0, "DataSize": size, "CumulativeSize": size,
+	})
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAnnouncedBlockIsAccepted(t *testing.T) {
+	network := newTestNetwork(t, 2)
+	miner, peer := network.nodes[0], network.nodes[1]
+
+	block := network.Mine(miner)
+	network.Deliver()
+
+	network.RequireConverged(2)
+	if got, _ := peer.store.Tip(); got.Hash != block.Hash {
+		t.Fatalf("peer tip is %q, want mined block %q", got.Hash, block.Hash)
+	}
+}
+
+func TestBlocksAreAnnouncedByInventory(t *testing.T) {
+	network := newTestNetwork(t, 2)
+	miner, peer := network.nodes[0], network.nodes[1]
+
+	block := network.Mine(miner)
+	if len(network.queue) != 1 {
+		t.Fatalf("%d announcements queued, want 1", len(network.queue))
+	}
+	announcement := network.queue[0].announcement
+	if announcement.Block != nil || announcement.Inventory == nil || announcement.Inventory.Hash != block.Hash {
+		t.Fatalf("announcement = %+v, want the block's inventory only", announcement)
+	}
+
+	// A miner that cannot serve the block is bypassed through IPFS
+	network.queue[0].from = &testNode{ip: "198.51.100.99"}
+	network.Deliver()
+	if got, _ := peer.store.Tip(); got.Hash != block.Hash {
+		t.Fatalf("peer tip is %q, want block %q fetched from IPFS", got.Hash, block.Hash)
+	}
+}
+
+func TestNodesConverge(t *testing.T) {
+	network := newTestNetwork(t, 3)
+
+	for i := 0; i < 3; i++ {
+		network.Mine(network.nodes[i])
+		network.Settle()
+		network.RequireConverged(i + 2)
+	}
+
+	// A transaction submitted to one node through the API is mined there and
+	// reaches every chain
+	submitter := network.nodes[1]
+	var tx Transaction
+	network.Run(submitter, func() {
+		tx = Transaction{ID: generateTransactionID("result"), Data: "result", NetworkID: networkID}
+		if err := signTransaction(&tx); err != nil {
+			t.Fatal(err)
+		}
+		body, _ := json.Marshal(tx)
+		recorder := httptest.NewRecorder()
+		handleSubmitTransaction(recorder, httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body)))
+		if recorder.Code != http.StatusAccepted {
+			t.Fatalf("submitting transaction: %d %s", recorder.Code, recorder.Body)
+		}
+	})
+	block := network.Mine(submitter)
+	network.Settle()
+	network.RequireConverged(5)
+
+	if len(block.Transactions) != 2 || block.Transactions[1].ID != tx.ID {
+		t.Fatalf("mined block does not include the submitted transaction")
+	}
+	for _, node := range network.nodes {
+		stored, err := node.store.GetBlockByNumber(4)
+		if err != nil || stored.Hash != block.Hash {
+			t.Fatalf("node %s does not have the block with the transaction", node.ip)
+		}
+		if node.ledger.Balance(submitter.walletAddress) != 2*blockReward {
+			t.Errorf("node %s credits the submitter %d, want %d", node.ip, node.ledger.Balance(submitter.walletAddress), 2*blockReward)
+		}
+	}
+	if submitter.mempool.Len() != 0 {
+		t.Errorf("mined transaction is still pending")
+	}
+}
+
+func TestTransactionsGossipToEveryMempool(t *testing.T) {
+	network := newTestNetwork(t, 3)
+	first, middle, last := network.nodes[0], network.nodes[1], network.nodes[2]
+
+	// A line: the first and last nodes only reach each other through the
+	// middle one
+	first.peers.Remove(last.ip)
+	last.peers.Remove(first.ip)
+
+	var tx Transaction
+	network.Run(first, func() {
+		tx = Transaction{ID: generateTransactionID("gossip"), Data: "gossip", NetworkID: networkID}
+		if err := signTransaction(&tx); err != nil {
+			t.Fatal(err)
+		}
+		body, _ := json.Marshal(tx)
+		recorder := httptest.NewRecorder()
+		handleSubmitTransaction(recorder, httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body)))
+		if recorder.Code != http.StatusAccepted {
+			t.Fatalf("submitting transaction: %d %s", recorder.Code, recorder.Body)
+		}
+	})
+	network.Deliver()
+
+	for _, node := range []*testNode{first, middle, last} {
+		pending := node.mempool.Pending()
+		if len(pending) != 1 || pending[0].ID != tx.ID {
+			t.Errorf("node %s has pending %v, want the gossiped transaction", node.ip, pending)
+		}
+	}
+	if len(network.queue) != 0 {
+		t.Errorf("gossip did not stop: %d deliveries queued", len(network.queue))
+	}
+}
+
+func TestNodesReorganizeToLongerChain(t *testing.T) {
+	network := newTestNetwork(t, 2)
+	a, b := network.nodes[0], network.nodes[1]
+
+	// Both nodes mine before hearing from each other; b's branch is longer
+	network.Mine(a)
+	network.Mine(b)
+	longer := network.Mine(b)
+	network.Deliver()
+
+	network.RequireConverged(3)
+	if got, _ := a.store.Tip(); got.Hash != longer.Hash {
+		t.Fatalf("tip is %q, want the longer branch %q", got.Hash, longer.Hash)
+	}
+	if a.ledger.Balance(a.walletAddress) != 0 || a.ledger.Balance(b.walletAddress) != 2*blockReward {
+		t.Errorf("ledger was not rebuilt for the new branch")
+	}
+}
+
+func TestReorgDoesNotRequeueCoinbase(t *testing.T) {
+	network := newTestNetwork(t, 2)
+	a, b := network.nodes[0], network.nodes[1]
+
+	// a's block, coinbase and all, is dropped for b's longer branch
+	network.Mine(a)
+	network.Mine(b)
+	network.Mine(b)
+	network.Deliver()
+
+	network.RequireConverged(3)
+	for _, tx := range a.mempool.Pending() {
+		if tx.Coinbase != nil {
+			t.Fatalf("coinbase %s of the dropped block is pending", tx.ID)
+		}
+	}
+}
+
+func TestVoteMustComeFromHelloIdentity(t *testing.T) {
+	network := newTestNetwork(t, 3)
+	miner, peer := network.nodes[0], network.nodes[2]
+	block := network.Mine(miner)
+
+	// The miner signs its announcement to the peer with a throwaway key
+	key, err := generateSigningKey(sigAlgEd25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vote, err := signPayload(cryptoSigner{key: key}, sigDomainVote, []byte(block.ComputeHash()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	throwaway, err := nodeIDFromPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	network.queueMu.Lock()
+	var toPeer testDelivery
+	for _, delivery := range network.queue {
+		if delivery.to == peer {
+			toPeer = delivery
+		}
+	}
+	toPeer.announcement.Vote = &vote
+	network.queue = []testDelivery{toPeer}
+	network.queueMu.Unlock()
+	t.Cleanup(func() {
+		peerLimitsMu.Lock()
+		delete(peerScores, miner.ip)
+		peerLimitsMu.Unlock()
+	})
+	network.Deliver()
+
+	if peer.state.HasVote(block.Hash, throwaway) || peer.state.HasVote(block.Hash, miner.id) {
+		t.Error("vote signed by another key than the hello's was counted")
+	}
+	peerLimitsMu.Lock()
+	score := peerScores[miner.ip]
+	peerLimitsMu.Unlock()
+	if score == 0 {
+		t.Error("peer sending a vote signed by another node was not penalized")
+	}
+}
+
+func TestRepeatedAnnouncementCountsOnce(t *testing.T) {
+	network := newTestNetwork(t, 3)
+	miner, peer := network.nodes[0], network.nodes[2]
+	block := network.Mine(miner)
+
+	// The miner's announcement reaches the peer three times; its vote alone
+	// is not a majority of the peer's two peers
+	network.queueMu.Lock()
+	var toPeer testDelivery
+	for _, delivery := range network.queue {
+		if delivery.to == peer {
+			toPeer = delivery
+		}
+	}
+	network.queue = []testDelivery{toPeer, toPeer, toPeer}
+	network.queueMu.Unlock()
+	network.Deliver()
+
+	if _, err := peer.store.GetBlockByHash(block.Hash); err == nil {
+		t.Fatal("repeated votes from one miner made up a majority")
+	}
+	if votes, _ := peer.state.AddVote(block.Hash, block.BlockNumber, miner.id); votes != 1 {
+		t.Errorf("block has %d votes, want the miner's one", votes)
+	}
+}
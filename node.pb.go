@@ -0,0 +1,1962 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: node.proto
+
+package main
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type PBHello struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       int64                  `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	MinVersion    int64                  `protobuf:"varint,2,opt,name=min_version,json=minVersion,proto3" json:"min_version,omitempty"`
+	Agent         string                 `protobuf:"bytes,3,opt,name=agent,proto3" json:"agent,omitempty"`
+	NetworkId     string                 `protobuf:"bytes,4,opt,name=network_id,json=networkId,proto3" json:"network_id,omitempty"`
+	Height        int64                  `protobuf:"varint,5,opt,name=height,proto3" json:"height,omitempty"`
+	JobPort       int64                  `protobuf:"varint,6,opt,name=job_port,json=jobPort,proto3" json:"job_port,omitempty"`
+	BlockPort     int64                  `protobuf:"varint,7,opt,name=block_port,json=blockPort,proto3" json:"block_port,omitempty"`
+	Reject        string                 `protobuf:"bytes,8,opt,name=reject,proto3" json:"reject,omitempty"`
+	NodeId        string                 `protobuf:"bytes,9,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	Time          int64                  `protobuf:"varint,10,opt,name=time,proto3" json:"time,omitempty"`
+	Identity      *PBSignature           `protobuf:"bytes,11,opt,name=identity,proto3" json:"identity,omitempty"`
+	Nonce         string                 `protobuf:"bytes,12,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	Proof         *PBSignature           `protobuf:"bytes,13,opt,name=proof,proto3" json:"proof,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PBHello) Reset() {
+	*x = PBHello{}
+	mi := &file_node_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PBHello) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PBHello) ProtoMessage() {}
+
+func (x *PBHello) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PBHello.ProtoReflect.Descriptor instead.
+func (*PBHello) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *PBHello) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *PBHello) GetMinVersion() int64 {
+	if x != nil {
+		return x.MinVersion
+	}
+	return 0
+}
+
+func (x *PBHello) GetAgent() string {
+	if x != nil {
+		return x.Agent
+	}
+	return ""
+}
+
+func (x *PBHello) GetNetworkId() string {
+	if x != nil {
+		return x.NetworkId
+	}
+	return ""
+}
+
+func (x *PBHello) GetHeight() int64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *PBHello) GetJobPort() int64 {
+	if x != nil {
+		return x.JobPort
+	}
+	return 0
+}
+
+func (x *PBHello) GetBlockPort() int64 {
+	if x != nil {
+		return x.BlockPort
+	}
+	return 0
+}
+
+func (x *PBHello) GetReject() string {
+	if x != nil {
+		return x.Reject
+	}
+	return ""
+}
+
+func (x *PBHello) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *PBHello) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *PBHello) GetIdentity() *PBSignature {
+	if x != nil {
+		return x.Identity
+	}
+	return nil
+}
+
+func (x *PBHello) GetNonce() string {
+	if x != nil {
+		return x.Nonce
+	}
+	return ""
+}
+
+func (x *PBHello) GetProof() *PBSignature {
+	if x != nil {
+		return x.Proof
+	}
+	return nil
+}
+
+type PBExecutionProof struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	System        string                 `protobuf:"bytes,1,opt,name=system,proto3" json:"system,omitempty"`
+	Data          string                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PBExecutionProof) Reset() {
+	*x = PBExecutionProof{}
+	mi := &file_node_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PBExecutionProof) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PBExecutionProof) ProtoMessage() {}
+
+func (x *PBExecutionProof) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PBExecutionProof.ProtoReflect.Descriptor instead.
+func (*PBExecutionProof) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PBExecutionProof) GetSystem() string {
+	if x != nil {
+		return x.System
+	}
+	return ""
+}
+
+func (x *PBExecutionProof) GetData() string {
+	if x != nil {
+		return x.Data
+	}
+	return ""
+}
+
+type PBExecutionEnv struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Image         string                 `protobuf:"bytes,1,opt,name=image,proto3" json:"image,omitempty"`
+	Interpreter   string                 `protobuf:"bytes,2,opt,name=interpreter,proto3" json:"interpreter,omitempty"`
+	Libraries     []string               `protobuf:"bytes,3,rep,name=libraries,proto3" json:"libraries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PBExecutionEnv) Reset() {
+	*x = PBExecutionEnv{}
+	mi := &file_node_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PBExecutionEnv) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PBExecutionEnv) ProtoMessage() {}
+
+func (x *PBExecutionEnv) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PBExecutionEnv.ProtoReflect.Descriptor instead.
+func (*PBExecutionEnv) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *PBExecutionEnv) GetImage() string {
+	if x != nil {
+		return x.Image
+	}
+	return ""
+}
+
+func (x *PBExecutionEnv) GetInterpreter() string {
+	if x != nil {
+		return x.Interpreter
+	}
+	return ""
+}
+
+func (x *PBExecutionEnv) GetLibraries() []string {
+	if x != nil {
+		return x.Libraries
+	}
+	return nil
+}
+
+type PBScriptParams struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Args          []string               `protobuf:"bytes,1,rep,name=args,proto3" json:"args,omitempty"`
+	Vars          map[string]string      `protobuf:"bytes,2,rep,name=vars,proto3" json:"vars,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PBScriptParams) Reset() {
+	*x = PBScriptParams{}
+	mi := &file_node_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PBScriptParams) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PBScriptParams) ProtoMessage() {}
+
+func (x *PBScriptParams) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PBScriptParams.ProtoReflect.Descriptor instead.
+func (*PBScriptParams) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *PBScriptParams) GetArgs() []string {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+func (x *PBScriptParams) GetVars() map[string]string {
+	if x != nil {
+		return x.Vars
+	}
+	return nil
+}
+
+type PBOutputFile struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Cid           string                 `protobuf:"bytes,2,opt,name=cid,proto3" json:"cid,omitempty"`
+	Hash          string                 `protobuf:"bytes,3,opt,name=hash,proto3" json:"hash,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PBOutputFile) Reset() {
+	*x = PBOutputFile{}
+	mi := &file_node_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PBOutputFile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PBOutputFile) ProtoMessage() {}
+
+func (x *PBOutputFile) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PBOutputFile.ProtoReflect.Descriptor instead.
+func (*PBOutputFile) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *PBOutputFile) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *PBOutputFile) GetCid() string {
+	if x != nil {
+		return x.Cid
+	}
+	return ""
+}
+
+func (x *PBOutputFile) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+type PBSignature struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Algorithm     string                 `protobuf:"bytes,1,opt,name=algorithm,proto3" json:"algorithm,omitempty"`
+	PublicKey     string                 `protobuf:"bytes,2,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	Value         string                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PBSignature) Reset() {
+	*x = PBSignature{}
+	mi := &file_node_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PBSignature) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PBSignature) ProtoMessage() {}
+
+func (x *PBSignature) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PBSignature.ProtoReflect.Descriptor instead.
+func (*PBSignature) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *PBSignature) GetAlgorithm() string {
+	if x != nil {
+		return x.Algorithm
+	}
+	return ""
+}
+
+func (x *PBSignature) GetPublicKey() string {
+	if x != nil {
+		return x.PublicKey
+	}
+	return ""
+}
+
+func (x *PBSignature) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+type PBCoinbase struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Amount        int64                  `protobuf:"varint,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PBCoinbase) Reset() {
+	*x = PBCoinbase{}
+	mi := &file_node_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PBCoinbase) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PBCoinbase) ProtoMessage() {}
+
+func (x *PBCoinbase) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PBCoinbase.ProtoReflect.Descriptor instead.
+func (*PBCoinbase) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *PBCoinbase) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *PBCoinbase) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+type PBTransaction struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Data          string                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	Recipient     string                 `protobuf:"bytes,3,opt,name=recipient,proto3" json:"recipient,omitempty"`
+	ScriptCid     string                 `protobuf:"bytes,4,opt,name=script_cid,json=scriptCid,proto3" json:"script_cid,omitempty"`
+	DataCid       string                 `protobuf:"bytes,5,opt,name=data_cid,json=dataCid,proto3" json:"data_cid,omitempty"`
+	Proof         *PBExecutionProof      `protobuf:"bytes,6,opt,name=proof,proto3" json:"proof,omitempty"`
+	LockHeight    int64                  `protobuf:"varint,7,opt,name=lock_height,json=lockHeight,proto3" json:"lock_height,omitempty"`
+	LockTime      int64                  `protobuf:"varint,8,opt,name=lock_time,json=lockTime,proto3" json:"lock_time,omitempty"`
+	Signature     *PBSignature           `protobuf:"bytes,9,opt,name=signature,proto3" json:"signature,omitempty"`
+	Coinbase      *PBCoinbase            `protobuf:"bytes,10,opt,name=coinbase,proto3" json:"coinbase,omitempty"`
+	Fee           int64                  `protobuf:"varint,11,opt,name=fee,proto3" json:"fee,omitempty"`
+	NetworkId     string                 `protobuf:"bytes,12,opt,name=network_id,json=networkId,proto3" json:"network_id,omitempty"`
+	ResultHash    string                 `protobuf:"bytes,13,opt,name=result_hash,json=resultHash,proto3" json:"result_hash,omitempty"`
+	Env           *PBExecutionEnv        `protobuf:"bytes,14,opt,name=env,proto3" json:"env,omitempty"`
+	Runtime       string                 `protobuf:"bytes,15,opt,name=runtime,proto3" json:"runtime,omitempty"`
+	Params        *PBScriptParams        `protobuf:"bytes,16,opt,name=params,proto3" json:"params,omitempty"`
+	ResultCid     string                 `protobuf:"bytes,17,opt,name=result_cid,json=resultCid,proto3" json:"result_cid,omitempty"`
+	Outputs       []*PBOutputFile        `protobuf:"bytes,18,rep,name=outputs,proto3" json:"outputs,omitempty"`
+	Units         int64                  `protobuf:"varint,19,opt,name=units,proto3" json:"units,omitempty"`
+	ExpiryHeight  int64                  `protobuf:"varint,20,opt,name=expiry_height,json=expiryHeight,proto3" json:"expiry_height,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PBTransaction) Reset() {
+	*x = PBTransaction{}
+	mi := &file_node_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PBTransaction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PBTransaction) ProtoMessage() {}
+
+func (x *PBTransaction) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PBTransaction.ProtoReflect.Descriptor instead.
+func (*PBTransaction) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *PBTransaction) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *PBTransaction) GetData() string {
+	if x != nil {
+		return x.Data
+	}
+	return ""
+}
+
+func (x *PBTransaction) GetRecipient() string {
+	if x != nil {
+		return x.Recipient
+	}
+	return ""
+}
+
+func (x *PBTransaction) GetScriptCid() string {
+	if x != nil {
+		return x.ScriptCid
+	}
+	return ""
+}
+
+func (x *PBTransaction) GetDataCid() string {
+	if x != nil {
+		return x.DataCid
+	}
+	return ""
+}
+
+func (x *PBTransaction) GetProof() *PBExecutionProof {
+	if x != nil {
+		return x.Proof
+	}
+	return nil
+}
+
+func (x *PBTransaction) GetLockHeight() int64 {
+	if x != nil {
+		return x.LockHeight
+	}
+	return 0
+}
+
+func (x *PBTransaction) GetLockTime() int64 {
+	if x != nil {
+		return x.LockTime
+	}
+	return 0
+}
+
+func (x *PBTransaction) GetSignature() *PBSignature {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+func (x *PBTransaction) GetCoinbase() *PBCoinbase {
+	if x != nil {
+		return x.Coinbase
+	}
+	return nil
+}
+
+func (x *PBTransaction) GetFee() int64 {
+	if x != nil {
+		return x.Fee
+	}
+	return 0
+}
+
+func (x *PBTransaction) GetNetworkId() string {
+	if x != nil {
+		return x.NetworkId
+	}
+	return ""
+}
+
+func (x *PBTransaction) GetResultHash() string {
+	if x != nil {
+		return x.ResultHash
+	}
+	return ""
+}
+
+func (x *PBTransaction) GetEnv() *PBExecutionEnv {
+	if x != nil {
+		return x.Env
+	}
+	return nil
+}
+
+func (x *PBTransaction) GetRuntime() string {
+	if x != nil {
+		return x.Runtime
+	}
+	return ""
+}
+
+func (x *PBTransaction) GetParams() *PBScriptParams {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+func (x *PBTransaction) GetResultCid() string {
+	if x != nil {
+		return x.ResultCid
+	}
+	return ""
+}
+
+func (x *PBTransaction) GetOutputs() []*PBOutputFile {
+	if x != nil {
+		return x.Outputs
+	}
+	return nil
+}
+
+func (x *PBTransaction) GetUnits() int64 {
+	if x != nil {
+		return x.Units
+	}
+	return 0
+}
+
+func (x *PBTransaction) GetExpiryHeight() int64 {
+	if x != nil {
+		return x.ExpiryHeight
+	}
+	return 0
+}
+
+type PBBlock struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PrevHash      string                 `protobuf:"bytes,1,opt,name=prev_hash,json=prevHash,proto3" json:"prev_hash,omitempty"`
+	MerkleRoot    string                 `protobuf:"bytes,2,opt,name=merkle_root,json=merkleRoot,proto3" json:"merkle_root,omitempty"`
+	Transactions  []*PBTransaction       `protobuf:"bytes,3,rep,name=transactions,proto3" json:"transactions,omitempty"`
+	Nonce         int64                  `protobuf:"varint,4,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	Hash          string                 `protobuf:"bytes,5,opt,name=hash,proto3" json:"hash,omitempty"`
+	PrevCid       string                 `protobuf:"bytes,6,opt,name=prev_cid,json=prevCid,proto3" json:"prev_cid,omitempty"`
+	BlockNumber   int64                  `protobuf:"varint,7,opt,name=block_number,json=blockNumber,proto3" json:"block_number,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,8,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	NetworkId     string                 `protobuf:"bytes,9,opt,name=network_id,json=networkId,proto3" json:"network_id,omitempty"`
+	Target        string                 `protobuf:"bytes,10,opt,name=target,proto3" json:"target,omitempty"`
+	MinerId       string                 `protobuf:"bytes,11,opt,name=miner_id,json=minerId,proto3" json:"miner_id,omitempty"`
+	MinerSig      *PBSignature           `protobuf:"bytes,12,opt,name=miner_sig,json=minerSig,proto3" json:"miner_sig,omitempty"`
+	Version       int64                  `protobuf:"varint,13,opt,name=version,proto3" json:"version,omitempty"`
+	Units         int64                  `protobuf:"varint,14,opt,name=units,proto3" json:"units,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PBBlock) Reset() {
+	*x = PBBlock{}
+	mi := &file_node_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PBBlock) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PBBlock) ProtoMessage() {}
+
+func (x *PBBlock) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PBBlock.ProtoReflect.Descriptor instead.
+func (*PBBlock) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *PBBlock) GetPrevHash() string {
+	if x != nil {
+		return x.PrevHash
+	}
+	return ""
+}
+
+func (x *PBBlock) GetMerkleRoot() string {
+	if x != nil {
+		return x.MerkleRoot
+	}
+	return ""
+}
+
+func (x *PBBlock) GetTransactions() []*PBTransaction {
+	if x != nil {
+		return x.Transactions
+	}
+	return nil
+}
+
+func (x *PBBlock) GetNonce() int64 {
+	if x != nil {
+		return x.Nonce
+	}
+	return 0
+}
+
+func (x *PBBlock) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *PBBlock) GetPrevCid() string {
+	if x != nil {
+		return x.PrevCid
+	}
+	return ""
+}
+
+func (x *PBBlock) GetBlockNumber() int64 {
+	if x != nil {
+		return x.BlockNumber
+	}
+	return 0
+}
+
+func (x *PBBlock) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *PBBlock) GetNetworkId() string {
+	if x != nil {
+		return x.NetworkId
+	}
+	return ""
+}
+
+func (x *PBBlock) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *PBBlock) GetMinerId() string {
+	if x != nil {
+		return x.MinerId
+	}
+	return ""
+}
+
+func (x *PBBlock) GetMinerSig() *PBSignature {
+	if x != nil {
+		return x.MinerSig
+	}
+	return nil
+}
+
+func (x *PBBlock) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *PBBlock) GetUnits() int64 {
+	if x != nil {
+		return x.Units
+	}
+	return 0
+}
+
+type SubmitTransactionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ScriptCid     string                 `protobuf:"bytes,1,opt,name=script_cid,json=scriptCid,proto3" json:"script_cid,omitempty"`
+	DataCid       string                 `protobuf:"bytes,2,opt,name=data_cid,json=dataCid,proto3" json:"data_cid,omitempty"`
+	Recipient     string                 `protobuf:"bytes,3,opt,name=recipient,proto3" json:"recipient,omitempty"`
+	LockHeight    int64                  `protobuf:"varint,4,opt,name=lock_height,json=lockHeight,proto3" json:"lock_height,omitempty"`
+	LockTime      int64                  `protobuf:"varint,5,opt,name=lock_time,json=lockTime,proto3" json:"lock_time,omitempty"`
+	Fee           int64                  `protobuf:"varint,6,opt,name=fee,proto3" json:"fee,omitempty"`
+	Env           *PBExecutionEnv        `protobuf:"bytes,7,opt,name=env,proto3" json:"env,omitempty"`
+	Runtime       string                 `protobuf:"bytes,8,opt,name=runtime,proto3" json:"runtime,omitempty"`
+	Params        *PBScriptParams        `protobuf:"bytes,9,opt,name=params,proto3" json:"params,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitTransactionRequest) Reset() {
+	*x = SubmitTransactionRequest{}
+	mi := &file_node_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitTransactionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitTransactionRequest) ProtoMessage() {}
+
+func (x *SubmitTransactionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitTransactionRequest.ProtoReflect.Descriptor instead.
+func (*SubmitTransactionRequest) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *SubmitTransactionRequest) GetScriptCid() string {
+	if x != nil {
+		return x.ScriptCid
+	}
+	return ""
+}
+
+func (x *SubmitTransactionRequest) GetDataCid() string {
+	if x != nil {
+		return x.DataCid
+	}
+	return ""
+}
+
+func (x *SubmitTransactionRequest) GetRecipient() string {
+	if x != nil {
+		return x.Recipient
+	}
+	return ""
+}
+
+func (x *SubmitTransactionRequest) GetLockHeight() int64 {
+	if x != nil {
+		return x.LockHeight
+	}
+	return 0
+}
+
+func (x *SubmitTransactionRequest) GetLockTime() int64 {
+	if x != nil {
+		return x.LockTime
+	}
+	return 0
+}
+
+func (x *SubmitTransactionRequest) GetFee() int64 {
+	if x != nil {
+		return x.Fee
+	}
+	return 0
+}
+
+func (x *SubmitTransactionRequest) GetEnv() *PBExecutionEnv {
+	if x != nil {
+		return x.Env
+	}
+	return nil
+}
+
+func (x *SubmitTransactionRequest) GetRuntime() string {
+	if x != nil {
+		return x.Runtime
+	}
+	return ""
+}
+
+func (x *SubmitTransactionRequest) GetParams() *PBScriptParams {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+type PBBlockInventory struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hash          string                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	Height        int64                  `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
+	Cid           string                 `protobuf:"bytes,3,opt,name=cid,proto3" json:"cid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PBBlockInventory) Reset() {
+	*x = PBBlockInventory{}
+	mi := &file_node_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PBBlockInventory) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PBBlockInventory) ProtoMessage() {}
+
+func (x *PBBlockInventory) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PBBlockInventory.ProtoReflect.Descriptor instead.
+func (*PBBlockInventory) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *PBBlockInventory) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *PBBlockInventory) GetHeight() int64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *PBBlockInventory) GetCid() string {
+	if x != nil {
+		return x.Cid
+	}
+	return ""
+}
+
+type AnnounceBlockRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Block         *PBBlock               `protobuf:"bytes,1,opt,name=block,proto3" json:"block,omitempty"`
+	Vote          *PBSignature           `protobuf:"bytes,2,opt,name=vote,proto3" json:"vote,omitempty"`
+	Inventory     *PBBlockInventory      `protobuf:"bytes,3,opt,name=inventory,proto3" json:"inventory,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AnnounceBlockRequest) Reset() {
+	*x = AnnounceBlockRequest{}
+	mi := &file_node_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AnnounceBlockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnnounceBlockRequest) ProtoMessage() {}
+
+func (x *AnnounceBlockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnnounceBlockRequest.ProtoReflect.Descriptor instead.
+func (*AnnounceBlockRequest) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *AnnounceBlockRequest) GetBlock() *PBBlock {
+	if x != nil {
+		return x.Block
+	}
+	return nil
+}
+
+func (x *AnnounceBlockRequest) GetVote() *PBSignature {
+	if x != nil {
+		return x.Vote
+	}
+	return nil
+}
+
+func (x *AnnounceBlockRequest) GetInventory() *PBBlockInventory {
+	if x != nil {
+		return x.Inventory
+	}
+	return nil
+}
+
+type AnnounceBlockResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AnnounceBlockResponse) Reset() {
+	*x = AnnounceBlockResponse{}
+	mi := &file_node_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AnnounceBlockResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnnounceBlockResponse) ProtoMessage() {}
+
+func (x *AnnounceBlockResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnnounceBlockResponse.ProtoReflect.Descriptor instead.
+func (*AnnounceBlockResponse) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{12}
+}
+
+type GetBlocksRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	From          int64                  `protobuf:"varint,1,opt,name=from,proto3" json:"from,omitempty"`
+	To            int64                  `protobuf:"varint,2,opt,name=to,proto3" json:"to,omitempty"`
+	HeadersOnly   bool                   `protobuf:"varint,3,opt,name=headers_only,json=headersOnly,proto3" json:"headers_only,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBlocksRequest) Reset() {
+	*x = GetBlocksRequest{}
+	mi := &file_node_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBlocksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBlocksRequest) ProtoMessage() {}
+
+func (x *GetBlocksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBlocksRequest.ProtoReflect.Descriptor instead.
+func (*GetBlocksRequest) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *GetBlocksRequest) GetFrom() int64 {
+	if x != nil {
+		return x.From
+	}
+	return 0
+}
+
+func (x *GetBlocksRequest) GetTo() int64 {
+	if x != nil {
+		return x.To
+	}
+	return 0
+}
+
+func (x *GetBlocksRequest) GetHeadersOnly() bool {
+	if x != nil {
+		return x.HeadersOnly
+	}
+	return false
+}
+
+type GetBlocksResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Blocks        []*PBBlock             `protobuf:"bytes,1,rep,name=blocks,proto3" json:"blocks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBlocksResponse) Reset() {
+	*x = GetBlocksResponse{}
+	mi := &file_node_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBlocksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBlocksResponse) ProtoMessage() {}
+
+func (x *GetBlocksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBlocksResponse.ProtoReflect.Descriptor instead.
+func (*GetBlocksResponse) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GetBlocksResponse) GetBlocks() []*PBBlock {
+	if x != nil {
+		return x.Blocks
+	}
+	return nil
+}
+
+type GetBlockRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hash          string                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBlockRequest) Reset() {
+	*x = GetBlockRequest{}
+	mi := &file_node_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBlockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBlockRequest) ProtoMessage() {}
+
+func (x *GetBlockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBlockRequest.ProtoReflect.Descriptor instead.
+func (*GetBlockRequest) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *GetBlockRequest) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+type GetPeersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPeersRequest) Reset() {
+	*x = GetPeersRequest{}
+	mi := &file_node_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPeersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPeersRequest) ProtoMessage() {}
+
+func (x *GetPeersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPeersRequest.ProtoReflect.Descriptor instead.
+func (*GetPeersRequest) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{16}
+}
+
+type GetPeersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Peers         []string               `protobuf:"bytes,1,rep,name=peers,proto3" json:"peers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPeersResponse) Reset() {
+	*x = GetPeersResponse{}
+	mi := &file_node_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPeersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPeersResponse) ProtoMessage() {}
+
+func (x *GetPeersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPeersResponse.ProtoReflect.Descriptor instead.
+func (*GetPeersResponse) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *GetPeersResponse) GetPeers() []string {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+type PingRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NetworkId     string                 `protobuf:"bytes,1,opt,name=network_id,json=networkId,proto3" json:"network_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PingRequest) Reset() {
+	*x = PingRequest{}
+	mi := &file_node_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PingRequest) ProtoMessage() {}
+
+func (x *PingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PingRequest.ProtoReflect.Descriptor instead.
+func (*PingRequest) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *PingRequest) GetNetworkId() string {
+	if x != nil {
+		return x.NetworkId
+	}
+	return ""
+}
+
+type RelayTransactionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Transactions  []*PBTransaction       `protobuf:"bytes,1,rep,name=transactions,proto3" json:"transactions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RelayTransactionsRequest) Reset() {
+	*x = RelayTransactionsRequest{}
+	mi := &file_node_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RelayTransactionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RelayTransactionsRequest) ProtoMessage() {}
+
+func (x *RelayTransactionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RelayTransactionsRequest.ProtoReflect.Descriptor instead.
+func (*RelayTransactionsRequest) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *RelayTransactionsRequest) GetTransactions() []*PBTransaction {
+	if x != nil {
+		return x.Transactions
+	}
+	return nil
+}
+
+type RelayTransactionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RelayTransactionsResponse) Reset() {
+	*x = RelayTransactionsResponse{}
+	mi := &file_node_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RelayTransactionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RelayTransactionsResponse) ProtoMessage() {}
+
+func (x *RelayTransactionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RelayTransactionsResponse.ProtoReflect.Descriptor instead.
+func (*RelayTransactionsResponse) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{20}
+}
+
+type PBMerkleProof struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TxId          string                 `protobuf:"bytes,1,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
+	BlockHash     string                 `protobuf:"bytes,2,opt,name=block_hash,json=blockHash,proto3" json:"block_hash,omitempty"`
+	Index         int64                  `protobuf:"varint,3,opt,name=index,proto3" json:"index,omitempty"`
+	Count         int64                  `protobuf:"varint,4,opt,name=count,proto3" json:"count,omitempty"`
+	Branch        []string               `protobuf:"bytes,5,rep,name=branch,proto3" json:"branch,omitempty"`
+	Header        *PBBlock               `protobuf:"bytes,6,opt,name=header,proto3" json:"header,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PBMerkleProof) Reset() {
+	*x = PBMerkleProof{}
+	mi := &file_node_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PBMerkleProof) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PBMerkleProof) ProtoMessage() {}
+
+func (x *PBMerkleProof) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PBMerkleProof.ProtoReflect.Descriptor instead.
+func (*PBMerkleProof) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *PBMerkleProof) GetTxId() string {
+	if x != nil {
+		return x.TxId
+	}
+	return ""
+}
+
+func (x *PBMerkleProof) GetBlockHash() string {
+	if x != nil {
+		return x.BlockHash
+	}
+	return ""
+}
+
+func (x *PBMerkleProof) GetIndex() int64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *PBMerkleProof) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *PBMerkleProof) GetBranch() []string {
+	if x != nil {
+		return x.Branch
+	}
+	return nil
+}
+
+func (x *PBMerkleProof) GetHeader() *PBBlock {
+	if x != nil {
+		return x.Header
+	}
+	return nil
+}
+
+type GetTransactionProofRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TxId          string                 `protobuf:"bytes,1,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTransactionProofRequest) Reset() {
+	*x = GetTransactionProofRequest{}
+	mi := &file_node_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTransactionProofRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTransactionProofRequest) ProtoMessage() {}
+
+func (x *GetTransactionProofRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTransactionProofRequest.ProtoReflect.Descriptor instead.
+func (*GetTransactionProofRequest) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *GetTransactionProofRequest) GetTxId() string {
+	if x != nil {
+		return x.TxId
+	}
+	return ""
+}
+
+type GetTransactionProofResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Proof         *PBMerkleProof         `protobuf:"bytes,1,opt,name=proof,proto3" json:"proof,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTransactionProofResponse) Reset() {
+	*x = GetTransactionProofResponse{}
+	mi := &file_node_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTransactionProofResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTransactionProofResponse) ProtoMessage() {}
+
+func (x *GetTransactionProofResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTransactionProofResponse.ProtoReflect.Descriptor instead.
+func (*GetTransactionProofResponse) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *GetTransactionProofResponse) GetProof() *PBMerkleProof {
+	if x != nil {
+		return x.Proof
+	}
+	return nil
+}
+
+type PingResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Height        int64                  `protobuf:"varint,1,opt,name=height,proto3" json:"height,omitempty"`
+	Tip           string                 `protobuf:"bytes,2,opt,name=tip,proto3" json:"tip,omitempty"`
+	NetworkId     string                 `protobuf:"bytes,3,opt,name=network_id,json=networkId,proto3" json:"network_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PingResponse) Reset() {
+	*x = PingResponse{}
+	mi := &file_node_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PingResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PingResponse) ProtoMessage() {}
+
+func (x *PingResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_node_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PingResponse.ProtoReflect.Descriptor instead.
+func (*PingResponse) Descriptor() ([]byte, []int) {
+	return file_node_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *PingResponse) GetHeight() int64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *PingResponse) GetTip() string {
+	if x != nil {
+		return x.Tip
+	}
+	return ""
+}
+
+func (x *PingResponse) GetNetworkId() string {
+	if x != nil {
+		return x.NetworkId
+	}
+	return ""
+}
+
+var File_node_proto protoreflect.FileDescriptor
+
+const file_node_proto_rawDesc = "" +
+	"\n" +
+	"\n" +
+	"node.proto\x12\rblockchain.v1\"\x90\x03\n" +
+	"\aPBHello\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\x03R\aversion\x12\x1f\n" +
+	"\vmin_version\x18\x02 \x01(\x03R\n" +
+	"minVersion\x12\x14\n" +
+	"\x05agent\x18\x03 \x01(\tR\x05agent\x12\x1d\n" +
+	"\n" +
+	"network_id\x18\x04 \x01(\tR\tnetworkId\x12\x16\n" +
+	"\x06height\x18\x05 \x01(\x03R\x06height\x12\x19\n" +
+	"\bjob_port\x18\x06 \x01(\x03R\ajobPort\x12\x1d\n" +
+	"\n" +
+	"block_port\x18\a \x01(\x03R\tblockPort\x12\x16\n" +
+	"\x06reject\x18\b \x01(\tR\x06reject\x12\x17\n" +
+	"\anode_id\x18\t \x01(\tR\x06nodeId\x12\x12\n" +
+	"\x04time\x18\n" +
+	" \x01(\x03R\x04time\x126\n" +
+	"\bidentity\x18\v \x01(\v2\x1a.blockchain.v1.PBSignatureR\bidentity\x12\x14\n" +
+	"\x05nonce\x18\f \x01(\tR\x05nonce\x120\n" +
+	"\x05proof\x18\r \x01(\v2\x1a.blockchain.v1.PBSignatureR\x05proof\">\n" +
+	"\x10PBExecutionProof\x12\x16\n" +
+	"\x06system\x18\x01 \x01(\tR\x06system\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\tR\x04data\"f\n" +
+	"\x0ePBExecutionEnv\x12\x14\n" +
+	"\x05image\x18\x01 \x01(\tR\x05image\x12 \n" +
+	"\vinterpreter\x18\x02 \x01(\tR\vinterpreter\x12\x1c\n" +
+	"\tlibraries\x18\x03 \x03(\tR\tlibraries\"\x9a\x01\n" +
+	"\x0ePBScriptParams\x12\x12\n" +
+	"\x04args\x18\x01 \x03(\tR\x04args\x12;\n" +
+	"\x04vars\x18\x02 \x03(\v2'.blockchain.v1.PBScriptParams.VarsEntryR\x04vars\x1a7\n" +
+	"\tVarsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"H\n" +
+	"\fPBOutputFile\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x10\n" +
+	"\x03cid\x18\x02 \x01(\tR\x03cid\x12\x12\n" +
+	"\x04hash\x18\x03 \x01(\tR\x04hash\"`\n" +
+	"\vPBSignature\x12\x1c\n" +
+	"\talgorithm\x18\x01 \x01(\tR\talgorithm\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x02 \x01(\tR\tpublicKey\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\tR\x05value\">\n" +
+	"\n" +
+	"PBCoinbase\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\x12\x16\n" +
+	"\x06amount\x18\x02 \x01(\x03R\x06amount\"\xd6\x05\n" +
+	"\rPBTransaction\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\tR\x04data\x12\x1c\n" +
+	"\trecipient\x18\x03 \x01(\tR\trecipient\x12\x1d\n" +
+	"\n" +
+	"script_cid\x18\x04 \x01(\tR\tscriptCid\x12\x19\n" +
+	"\bdata_cid\x18\x05 \x01(\tR\adataCid\x125\n" +
+	"\x05proof\x18\x06 \x01(\v2\x1f.blockchain.v1.PBExecutionProofR\x05proof\x12\x1f\n" +
+	"\vlock_height\x18\a \x01(\x03R\n" +
+	"lockHeight\x12\x1b\n" +
+	"\tlock_time\x18\b \x01(\x03R\blockTime\x128\n" +
+	"\tsignature\x18\t \x01(\v2\x1a.blockchain.v1.PBSignatureR\tsignature\x125\n" +
+	"\bcoinbase\x18\n" +
+	" \x01(\v2\x19.blockchain.v1.PBCoinbaseR\bcoinbase\x12\x10\n" +
+	"\x03fee\x18\v \x01(\x03R\x03fee\x12\x1d\n" +
+	"\n" +
+	"network_id\x18\f \x01(\tR\tnetworkId\x12\x1f\n" +
+	"\vresult_hash\x18\r \x01(\tR\n" +
+	"resultHash\x12/\n" +
+	"\x03env\x18\x0e \x01(\v2\x1d.blockchain.v1.PBExecutionEnvR\x03env\x12\x18\n" +
+	"\aruntime\x18\x0f \x01(\tR\aruntime\x125\n" +
+	"\x06params\x18\x10 \x01(\v2\x1d.blockchain.v1.PBScriptParamsR\x06params\x12\x1d\n" +
+	"\n" +
+	"result_cid\x18\x11 \x01(\tR\tresultCid\x125\n" +
+	"\aoutputs\x18\x12 \x03(\v2\x1b.blockchain.v1.PBOutputFileR\aoutputs\x12\x14\n" +
+	"\x05units\x18\x13 \x01(\x03R\x05units\x12#\n" +
+	"\rexpiry_height\x18\x14 \x01(\x03R\fexpiryHeight\"\xca\x03\n" +
+	"\aPBBlock\x12\x1b\n" +
+	"\tprev_hash\x18\x01 \x01(\tR\bprevHash\x12\x1f\n" +
+	"\vmerkle_root\x18\x02 \x01(\tR\n" +
+	"merkleRoot\x12@\n" +
+	"\ftransactions\x18\x03 \x03(\v2\x1c.blockchain.v1.PBTransactionR\ftransactions\x12\x14\n" +
+	"\x05nonce\x18\x04 \x01(\x03R\x05nonce\x12\x12\n" +
+	"\x04hash\x18\x05 \x01(\tR\x04hash\x12\x19\n" +
+	"\bprev_cid\x18\x06 \x01(\tR\aprevCid\x12!\n" +
+	"\fblock_number\x18\a \x01(\x03R\vblockNumber\x12\x1c\n" +
+	"\ttimestamp\x18\b \x01(\x03R\ttimestamp\x12\x1d\n" +
+	"\n" +
+	"network_id\x18\t \x01(\tR\tnetworkId\x12\x16\n" +
+	"\x06target\x18\n" +
+	" \x01(\tR\x06target\x12\x19\n" +
+	"\bminer_id\x18\v \x01(\tR\aminerId\x127\n" +
+	"\tminer_sig\x18\f \x01(\v2\x1a.blockchain.v1.PBSignatureR\bminerSig\x12\x18\n" +
+	"\aversion\x18\r \x01(\x03R\aversion\x12\x14\n" +
+	"\x05units\x18\x0e \x01(\x03R\x05units\"\xc4\x02\n" +
+	"\x18SubmitTransactionRequest\x12\x1d\n" +
+	"\n" +
+	"script_cid\x18\x01 \x01(\tR\tscriptCid\x12\x19\n" +
+	"\bdata_cid\x18\x02 \x01(\tR\adataCid\x12\x1c\n" +
+	"\trecipient\x18\x03 \x01(\tR\trecipient\x12\x1f\n" +
+	"\vlock_height\x18\x04 \x01(\x03R\n" +
+	"lockHeight\x12\x1b\n" +
+	"\tlock_time\x18\x05 \x01(\x03R\blockTime\x12\x10\n" +
+	"\x03fee\x18\x06 \x01(\x03R\x03fee\x12/\n" +
+	"\x03env\x18\a \x01(\v2\x1d.blockchain.v1.PBExecutionEnvR\x03env\x12\x18\n" +
+	"\aruntime\x18\b \x01(\tR\aruntime\x125\n" +
+	"\x06params\x18\t \x01(\v2\x1d.blockchain.v1.PBScriptParamsR\x06params\"P\n" +
+	"\x10PBBlockInventory\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\tR\x04hash\x12\x16\n" +
+	"\x06height\x18\x02 \x01(\x03R\x06height\x12\x10\n" +
+	"\x03cid\x18\x03 \x01(\tR\x03cid\"\xb3\x01\n" +
+	"\x14AnnounceBlockRequest\x12,\n" +
+	"\x05block\x18\x01 \x01(\v2\x16.blockchain.v1.PBBlockR\x05block\x12.\n" +
+	"\x04vote\x18\x02 \x01(\v2\x1a.blockchain.v1.PBSignatureR\x04vote\x12=\n" +
+	"\tinventory\x18\x03 \x01(\v2\x1f.blockchain.v1.PBBlockInventoryR\tinventory\"\x17\n" +
+	"\x15AnnounceBlockResponse\"Y\n" +
+	"\x10GetBlocksRequest\x12\x12\n" +
+	"\x04from\x18\x01 \x01(\x03R\x04from\x12\x0e\n" +
+	"\x02to\x18\x02 \x01(\x03R\x02to\x12!\n" +
+	"\fheaders_only\x18\x03 \x01(\bR\vheadersOnly\"C\n" +
+	"\x11GetBlocksResponse\x12.\n" +
+	"\x06blocks\x18\x01 \x03(\v2\x16.blockchain.v1.PBBlockR\x06blocks\"%\n" +
+	"\x0fGetBlockRequest\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\tR\x04hash\"\x11\n" +
+	"\x0fGetPeersRequest\"(\n" +
+	"\x10GetPeersResponse\x12\x14\n" +
+	"\x05peers\x18\x01 \x03(\tR\x05peers\",\n" +
+	"\vPingRequest\x12\x1d\n" +
+	"\n" +
+	"network_id\x18\x01 \x01(\tR\tnetworkId\"\\\n" +
+	"\x18RelayTransactionsRequest\x12@\n" +
+	"\ftransactions\x18\x01 \x03(\v2\x1c.blockchain.v1.PBTransactionR\ftransactions\"\x1b\n" +
+	"\x19RelayTransactionsResponse\"\xb7\x01\n" +
+	"\rPBMerkleProof\x12\x13\n" +
+	"\x05tx_id\x18\x01 \x01(\tR\x04txId\x12\x1d\n" +
+	"\n" +
+	"block_hash\x18\x02 \x01(\tR\tblockHash\x12\x14\n" +
+	"\x05index\x18\x03 \x01(\x03R\x05index\x12\x14\n" +
+	"\x05count\x18\x04 \x01(\x03R\x05count\x12\x16\n" +
+	"\x06branch\x18\x05 \x03(\tR\x06branch\x12.\n" +
+	"\x06header\x18\x06 \x01(\v2\x16.blockchain.v1.PBBlockR\x06header\"1\n" +
+	"\x1aGetTransactionProofRequest\x12\x13\n" +
+	"\x05tx_id\x18\x01 \x01(\tR\x04txId\"Q\n" +
+	"\x1bGetTransactionProofResponse\x122\n" +
+	"\x05proof\x18\x01 \x01(\v2\x1c.blockchain.v1.PBMerkleProofR\x05proof\"W\n" +
+	"\fPingResponse\x12\x16\n" +
+	"\x06height\x18\x01 \x01(\x03R\x06height\x12\x10\n" +
+	"\x03tip\x18\x02 \x01(\tR\x03tip\x12\x1d\n" +
+	"\n" +
+	"network_id\x18\x03 \x01(\tR\tnetworkId2\xf9\x05\n" +
+	"\x04Node\x127\n" +
+	"\x05Hello\x12\x16.blockchain.v1.PBHello\x1a\x16.blockchain.v1.PBHello\x12Z\n" +
+	"\x11SubmitTransaction\x12'.blockchain.v1.SubmitTransactionRequest\x1a\x1c.blockchain.v1.PBTransaction\x12Z\n" +
+	"\rAnnounceBlock\x12#.blockchain.v1.AnnounceBlockRequest\x1a$.blockchain.v1.AnnounceBlockResponse\x12N\n" +
+	"\tGetBlocks\x12\x1f.blockchain.v1.GetBlocksRequest\x1a .blockchain.v1.GetBlocksResponse\x12L\n" +
+	"\bGetBlock\x12\x1e.blockchain.v1.GetBlockRequest\x1a .blockchain.v1.GetBlocksResponse\x12K\n" +
+	"\bGetPeers\x12\x1e.blockchain.v1.GetPeersRequest\x1a\x1f.blockchain.v1.GetPeersResponse\x12?\n" +
+	"\x04Ping\x12\x1a.blockchain.v1.PingRequest\x1a\x1b.blockchain.v1.PingResponse\x12f\n" +
+	"\x11RelayTransactions\x12'.blockchain.v1.RelayTransactionsRequest\x1a(.blockchain.v1.RelayTransactionsResponse\x12l\n" +
+	"\x13GetTransactionProof\x12).blockchain.v1.GetTransactionProofRequest\x1a*.blockchain.v1.GetTransactionProofResponseB?Z=github.com/hamayuna47/BlockChain-For-Algorithms-With-POW;mainb\x06proto3"
+
+var (
+	file_node_proto_rawDescOnce sync.Once
+	file_node_proto_rawDescData []byte
+)
+
+func file_node_proto_rawDescGZIP() []byte {
+	file_node_proto_rawDescOnce.Do(func() {
+		file_node_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_node_proto_rawDesc), len(file_node_proto_rawDesc)))
+	})
+	return file_node_proto_rawDescData
+}
+
+var file_node_proto_msgTypes = make([]protoimpl.MessageInfo, 26)
+var file_node_proto_goTypes = []any{
+	(*PBHello)(nil),                     // 0: blockchain.v1.PBHello
+	(*PBExecutionProof)(nil),            // 1: blockchain.v1.PBExecutionProof
+	(*PBExecutionEnv)(nil),              // 2: blockchain.v1.PBExecutionEnv
+	(*PBScriptParams)(nil),              // 3: blockchain.v1.PBScriptParams
+	(*PBOutputFile)(nil),                // 4: blockchain.v1.PBOutputFile
+	(*PBSignature)(nil),                 // 5: blockchain.v1.PBSignature
+	(*PBCoinbase)(nil),                  // 6: blockchain.v1.PBCoinbase
+	(*PBTransaction)(nil),               // 7: blockchain.v1.PBTransaction
+	(*PBBlock)(nil),                     // 8: blockchain.v1.PBBlock
+	(*SubmitTransactionRequest)(nil),    // 9: blockchain.v1.SubmitTransactionRequest
+	(*PBBlockInventory)(nil),            // 10: blockchain.v1.PBBlockInventory
+	(*AnnounceBlockRequest)(nil),        // 11: blockchain.v1.AnnounceBlockRequest
+	(*AnnounceBlockResponse)(nil),       // 12: blockchain.v1.AnnounceBlockResponse
+	(*GetBlocksRequest)(nil),            // 13: blockchain.v1.GetBlocksRequest
+	(*GetBlocksResponse)(nil),           // 14: blockchain.v1.GetBlocksResponse
+	(*GetBlockRequest)(nil),             // 15: blockchain.v1.GetBlockRequest
+	(*GetPeersRequest)(nil),             // 16: blockchain.v1.GetPeersRequest
+	(*GetPeersResponse)(nil),            // 17: blockchain.v1.GetPeersResponse
+	(*PingRequest)(nil),                 // 18: blockchain.v1.PingRequest
+	(*RelayTransactionsRequest)(nil),    // 19: blockchain.v1.RelayTransactionsRequest
+	(*RelayTransactionsResponse)(nil),   // 20: blockchain.v1.RelayTransactionsResponse
+	(*PBMerkleProof)(nil),               // 21: blockchain.v1.PBMerkleProof
+	(*GetTransactionProofRequest)(nil),  // 22: blockchain.v1.GetTransactionProofRequest
+	(*GetTransactionProofResponse)(nil), // 23: blockchain.v1.GetTransactionProofResponse
+	(*PingResponse)(nil),                // 24: blockchain.v1.PingResponse
+	nil,                                 // 25: blockchain.v1.PBScriptParams.VarsEntry
+}
+var file_node_proto_depIdxs = []int32{
+	5,  // 0: blockchain.v1.PBHello.identity:type_name -> blockchain.v1.PBSignature
+	5,  // 1: blockchain.v1.PBHello.proof:type_name -> blockchain.v1.PBSignature
+	25, // 2: blockchain.v1.PBScriptParams.vars:type_name -> blockchain.v1.PBScriptParams.VarsEntry
+	1,  // 3: blockchain.v1.PBTransaction.proof:type_name -> blockchain.v1.PBExecutionProof
+	5,  // 4: blockchain.v1.PBTransaction.signature:type_name -> blockchain.v1.PBSignature
+	6,  // 5: blockchain.v1.PBTransaction.coinbase:type_name -> blockchain.v1.PBCoinbase
+	2,  // 6: blockchain.v1.PBTransaction.env:type_name -> blockchain.v1.PBExecutionEnv
+	3,  // 7: blockchain.v1.PBTransaction.params:type_name -> blockchain.v1.PBScriptParams
+	4,  // 8: blockchain.v1.PBTransaction.outputs:type_name -> blockchain.v1.PBOutputFile
+	7,  // 9: blockchain.v1.PBBlock.transactions:type_name -> blockchain.v1.PBTransaction
+	5,  // 10: blockchain.v1.PBBlock.miner_sig:type_name -> blockchain.v1.PBSignature
+	2,  // 11: blockchain.v1.SubmitTransactionRequest.env:type_name -> blockchain.v1.PBExecutionEnv
+	3,  // 12: blockchain.v1.SubmitTransactionRequest.params:type_name -> blockchain.v1.PBScriptParams
+	8,  // 13: blockchain.v1.AnnounceBlockRequest.block:type_name -> blockchain.v1.PBBlock
+	5,  // 14: blockchain.v1.AnnounceBlockRequest.vote:type_name -> blockchain.v1.PBSignature
+	10, // 15: blockchain.v1.AnnounceBlockRequest.inventory:type_name -> blockchain.v1.PBBlockInventory
+	8,  // 16: blockchain.v1.GetBlocksResponse.blocks:type_name -> blockchain.v1.PBBlock
+	7,  // 17: blockchain.v1.RelayTransactionsRequest.transactions:type_name -> blockchain.v1.PBTransaction
+	8,  // 18: blockchain.v1.PBMerkleProof.header:type_name -> blockchain.v1.PBBlock
+	21, // 19: blockchain.v1.GetTransactionProofResponse.proof:type_name -> blockchain.v1.PBMerkleProof
+	0,  // 20: blockchain.v1.Node.Hello:input_type -> blockchain.v1.PBHello
+	9,  // 21: blockchain.v1.Node.SubmitTransaction:input_type -> blockchain.v1.SubmitTransactionRequest
+	11, // 22: blockchain.v1.Node.AnnounceBlock:input_type -> blockchain.v1.AnnounceBlockRequest
+	13, // 23: blockchain.v1.Node.GetBlocks:input_type -> blockchain.v1.GetBlocksRequest
+	15, // 24: blockchain.v1.Node.GetBlock:input_type -> blockchain.v1.GetBlockRequest
+	16, // 25: blockchain.v1.Node.GetPeers:input_type -> blockchain.v1.GetPeersRequest
+	18, // 26: blockchain.v1.Node.Ping:input_type -> blockchain.v1.PingRequest
+	19, // 27: blockchain.v1.Node.RelayTransactions:input_type -> blockchain.v1.RelayTransactionsRequest
+	22, // 28: blockchain.v1.Node.GetTransactionProof:input_type -> blockchain.v1.GetTransactionProofRequest
+	0,  // 29: blockchain.v1.Node.Hello:output_type -> blockchain.v1.PBHello
+	7,  // 30: blockchain.v1.Node.SubmitTransaction:output_type -> blockchain.v1.PBTransaction
+	12, // 31: blockchain.v1.Node.AnnounceBlock:output_type -> blockchain.v1.AnnounceBlockResponse
+	14, // 32: blockchain.v1.Node.GetBlocks:output_type -> blockchain.v1.GetBlocksResponse
+	14, // 33: blockchain.v1.Node.GetBlock:output_type -> blockchain.v1.GetBlocksResponse
+	17, // 34: blockchain.v1.Node.GetPeers:output_type -> blockchain.v1.GetPeersResponse
+	24, // 35: blockchain.v1.Node.Ping:output_type -> blockchain.v1.PingResponse
+	20, // 36: blockchain.v1.Node.RelayTransactions:output_type -> blockchain.v1.RelayTransactionsResponse
+	23, // 37: blockchain.v1.Node.GetTransactionProof:output_type -> blockchain.v1.GetTransactionProofResponse
+	29, // [29:38] is the sub-list for method output_type
+	20, // [20:29] is the sub-list for method input_type
+	20, // [20:20] is the sub-list for extension type_name
+	20, // [20:20] is the sub-list for extension extendee
+	0,  // [0:20] is the sub-list for field type_name
+}
+
+func init() { file_node_proto_init() }
+func file_node_proto_init() {
+	if File_node_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_node_proto_rawDesc), len(file_node_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   26,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_node_proto_goTypes,
+		DependencyIndexes: file_node_proto_depIdxs,
+		MessageInfos:      file_node_proto_msgTypes,
+	}.Build()
+	File_node_proto = out.File
+	file_node_proto_goTypes = nil
+	file_node_proto_depIdxs = nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	tlsIdentity = flag.Bool("tls-identity", false, "encrypt miner connections with a self-signed certificate for the node identity key, pinning peers to their node IDs")
+	tlsPeers    = flag.String("tls-peers", "", "comma-separated node IDs allowed to connect with -tls-identity (empty allows any)")
+
+	identityTLS *identityTransport // nil unless -tls-identity
+)
+
+// identityTransport secures miner connections without a CA. Each node
+// presents a self-signed certificate for its identity key and is known by
+// that key's node ID. The node ID first seen at a dialed address is pinned
+// to it, so a different key at that address is refused later on.
+type identityTransport struct {
+	cert    tls.Certificate
+	allowed map[string]bool // Node IDs allowed to connect; empty allows any
+	pinFile string
+
+	mu   sync.Mutex
+	pins map[string]string // Pinned node ID by miner host
+}
+
+// Set up -tls-identity with the loaded node identity key.
+func setupIdentityTLS() error {
+	if *tlsCertFile != "" || *tlsKeyFile != "" || *tlsCAFile != "" {
+		return errors.New("-tls-identity cannot be combined with -tls-cert, -tls-key and -tls-ca")
+	}
+	signer, ok := nodeSigner.(cryptoSigner)
+	if !ok {
+		return errors.New("node identity key cannot be used for TLS")
+	}
+
+	var allowed []string
+	for _, id := range strings.Split(*tlsPeers, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			allowed = append(allowed, id)
+		}
+	}
+	t, err := newIdentityTransport(signer.key, allowed, filepath.Join(*dataDir, "known_miners.json"))
+	if err != nil {
+		return err
+	}
+	identityTLS = t
+	nodeLog.Info("Encrypting miner connections with the node identity key", "nodeID", nodeID, "allowedPeers", len(allowed))
+	return nil
+}
+
+func newIdentityTransport(key crypto.Signer, allowed []string, pinFile string) (*identityTransport, error) {
+	id, err := nodeIDFromPublicKey(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate certificate serial: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: id},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(10, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create identity certificate: %v", err)
+	}
+
+	t := &identityTransport{
+		cert:    tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key},
+		allowed: make(map[string]bool),
+		pinFile: pinFile,
+		pins:    make(map[string]string),
+	}
+	for _, id := range allowed {
+		t.allowed[id] = true
+	}
+	data, err := os.ReadFile(pinFile)
+	if err == nil {
+		if err := json.Unmarshal(data, &t.pins); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %v", pinFile, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read pinned miners: %v", err)
+	}
+	return t, nil
+}
+
+// TLS settings for a connection to the miner at host, or for accepting
+// connections when host is empty.
+func (t *identityTransport) config(host string) *tls.Config {
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS13,
+		Certificates: []tls.Certificate{t.cert},
+		ClientAuth:   tls.RequireAnyClientCert,
+		// Certificates are self-signed; the handshake proves the peer holds
+		// the key, and VerifyConnection checks the key's node ID.
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			return t.verifyPeer(host, cs)
+		},
+	}
+}
+
+func (t *identityTransport) verifyPeer(host string, cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("peer presented no certificate")
+	}
+	id, err := nodeIDFromPublicKey(cs.PeerCertificates[0].PublicKey)
+	if err != nil {
+		return err
+	}
+	if len(t.allowed) > 0 && !t.allowed[id] {
+		return fmt.Errorf("node %s is not an allowed peer", id)
+	}
+	if host == "" {
+		return nil
+	}
+	return t.pin(host, id)
+}
+
+// Pin host to node ID id on first contact, or check it against the pin.
+func (t *identityTransport) pin(host, id string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if pinned, ok := t.pins[host]; ok {
+		if pinned != id {
+			return fmt.Errorf("miner at %s presented node ID %s but is pinned to %s; remove it from %s if its key changed", host, id, pinned, t.pinFile)
+		}
+		return nil
+	}
+
+	t.pins[host] = id
+	data, err := json.MarshalIndent(t.pins, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode pinned miners: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(t.pinFile), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", filepath.Dir(t.pinFile), err)
+	}
+	if err := writeFileAtomic(t.pinFile, data, 0600); err != nil {
+		return err
+	}
+	p2pLog.Info("Pinned miner node ID", "peer", host, "nodeID", id)
+	return nil
+}
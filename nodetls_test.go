@@ -0,0 +1,111 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestIdentityTransport(t *testing.T, allowed []string, pinFile string) (*identityTransport, string) {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport, err := newIdentityTransport(key, allowed, pinFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := nodeIDFromPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return transport, id
+}
+
+// Handshake a client dialing host with a server over loopback and return
+// the node ID the server saw and the client's error.
+func identityHandshake(t *testing.T, client, server *identityTransport, host string) (string, error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	seen := make(chan string, 1)
+	go func() {
+		raw, err := ln.Accept()
+		if err != nil {
+			seen <- ""
+			return
+		}
+		defer raw.Close()
+		conn := tls.Server(raw, server.config(""))
+		if err := conn.Handshake(); err != nil {
+			seen <- ""
+			return
+		}
+		seen <- certIdentity(conn.ConnectionState().PeerCertificates[0])
+	}()
+
+	raw, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+	err = tls.Client(raw, client.config(host)).Handshake()
+	if err != nil {
+		raw.Close()
+	}
+	// TLS 1.3 clients finish before the server has checked their
+	// certificate, so wait for the server's verdict too
+	return <-seen, err
+}
+
+func TestIdentityTLSPinsNodeID(t *testing.T) {
+	pinFile := filepath.Join(t.TempDir(), "known_miners.json")
+	client, clientID := newTestIdentityTransport(t, nil, pinFile)
+	server, _ := newTestIdentityTransport(t, nil, "")
+	defer func(t *identityTransport) { identityTLS = t }(identityTLS)
+	identityTLS = client
+
+	seen, err := identityHandshake(t, client, server, "10.0.0.5")
+	if err != nil {
+		t.Fatalf("first handshake: %v", err)
+	}
+	if seen != clientID {
+		t.Errorf("server saw node %q, want %q", seen, clientID)
+	}
+
+	// A different key at the pinned address is refused, also after a restart
+	impostor, _ := newTestIdentityTransport(t, nil, "")
+	restarted, err := newIdentityTransport(client.cert.PrivateKey.(ed25519.PrivateKey), nil, pinFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := identityHandshake(t, restarted, impostor, "10.0.0.5"); err == nil || !strings.Contains(err.Error(), "pinned") {
+		t.Fatalf("handshake with impostor: err = %v, want pin mismatch", err)
+	}
+	if _, err := identityHandshake(t, restarted, server, "10.0.0.5"); err != nil {
+		t.Fatalf("handshake with pinned miner after restart: %v", err)
+	}
+}
+
+func TestIdentityTLSAllowedPeers(t *testing.T) {
+	dir := t.TempDir()
+	client, clientID := newTestIdentityTransport(t, nil, filepath.Join(dir, "client.json"))
+	stranger, _ := newTestIdentityTransport(t, nil, filepath.Join(dir, "stranger.json"))
+	server, _ := newTestIdentityTransport(t, []string{clientID}, "")
+
+	if seen, err := identityHandshake(t, client, server, "10.0.0.6"); err != nil || seen == "" {
+		t.Fatalf("allowed peer refused: %v", err)
+	}
+	if seen, _ := identityHandshake(t, stranger, server, "10.0.0.6"); seen != "" {
+		t.Fatal("server accepted a peer that is not allowed")
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+)
+
+var (
+	maxOrphans = flag.Int("max-orphans", 100, "number of blocks with unknown parents kept while their parents are fetched")
+
+	orphans = NewOrphanPool() // Valid blocks waiting for their parents
+)
+
+// OrphanPool holds blocks whose parent is not yet known, so that blocks
+// arriving out of order are connected once their ancestry arrives.
+type OrphanPool struct {
+	mu     sync.Mutex
+	blocks map[string]Block // By hash
+	order  []string         // Hashes, oldest first
+}
+
+func NewOrphanPool() *OrphanPool {
+	return &OrphanPool{blocks: make(map[string]Block)}
+}
+
+// Add a block to the pool, forgetting the oldest orphan if it is full.
+// It reports whether the block was new.
+func (op *OrphanPool) Add(block Block) bool {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	if _, ok := op.blocks[block.Hash]; ok {
+		return false
+	}
+	op.blocks[block.Hash] = block
+	op.order = append(op.order, block.Hash)
+	for len(op.order) > *maxOrphans {
+		delete(op.blocks, op.order[0])
+		op.order = op.order[1:]
+	}
+	return true
+}
+
+// Remove and return the orphans whose parent is the given block.
+func (op *OrphanPool) TakeChildren(parent string) []Block {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	var children []Block
+	kept := op.order[:0]
+	for _, hash := range op.order {
+		block, ok := op.blocks[hash]
+		if ok && block.PrevHash == parent {
+			children = append(children, block)
+			delete(op.blocks, hash)
+			continue
+		}
+		if ok {
+			kept = append(kept, hash)
+		}
+	}
+	op.order = kept
+	return children
+}
+
+// Highest block number among the orphans sealed at or below target, or -1
+// if there are none. Orphans are only held to the easiest target, since
+// their parent is unknown.
+func (op *OrphanPool) MaxBlockNumber(target *big.Int) int {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	highest := -1
+	for _, block := range op.blocks {
+		if consensus.VerifySeal(block, target) == nil {
+			highest = max(highest, block.BlockNumber)
+		}
+	}
+	return highest
+}
+
+func (op *OrphanPool) Len() int {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	return len(op.blocks)
+}
+
+// Accept a validated block and report whether it was stored. A block whose
+// parent is unknown is kept as an orphan while its parent is fetched; a
+// block that is stored brings in the orphans waiting for it.
+func acceptOrOrphan(block Block, from net.Addr) (bool, error) {
+	err := acceptBlock(block)
+	if errors.Is(err, errUnknownParent) {
+		if orphans.Add(block) {
+			chainLog.Info("Holding orphan block", "hash", block.Hash, "height", block.BlockNumber, "parent", block.PrevHash)
+			fetchParent(block, from)
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	connectOrphans(block.Hash)
+	return true, nil
+}
+
+// Accept the orphans descending from a block that joined the chain.
+func connectOrphans(hash string) {
+	for queue := []string{hash}; len(queue) > 0; queue = queue[1:] {
+		for _, child := range orphans.TakeChildren(queue[0]) {
+			if err := acceptBlock(child); err != nil {
+				chainLog.Warn("Dropping orphan block", "hash", child.Hash, "err", err)
+				continue
+			}
+			chainLog.Info("Connected orphan block", "hash", child.Hash, "height", child.BlockNumber)
+			queue = append(queue, child.Hash)
+		}
+	}
+}
+
+// Fetch an orphan's parent, from IPFS through the orphan's PrevCID or else
+// from the miner that sent the orphan, and accept it.
+func fetchParent(orphan Block, from net.Addr) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	parent, err := fetchParentFromIPFS(ctx, orphan)
+	if err != nil && from != nil {
+		chainLog.Debug("Could not fetch parent from IPFS", "hash", orphan.PrevHash, "err", err)
+		parent, err = fetchParentFromPeer(peerHost(from), orphan)
+	}
+	if err != nil {
+		chainLog.Warn("Could not fetch parent of orphan block", "hash", orphan.Hash, "parent", orphan.PrevHash, "err", err)
+		return
+	}
+
+	data, err := json.Marshal(parent)
+	if err != nil {
+		return
+	}
+	if !validateBlock(string(data), "-1", maxTarget) {
+		if from != nil {
+			penalizePeer(from, scoreInvalidBlock, "invalid parent of orphan block")
+		}
+		return
+	}
+	if _, err := acceptOrOrphan(parent, from); err != nil {
+		chainLog.Warn("Error storing parent of orphan block", "hash", parent.Hash, "err", err)
+	}
+}
+
+func fetchParentFromIPFS(ctx context.Context, orphan Block) (Block, error) {
+	if orphan.PrevCID == "" || orphan.PrevCID == "-1" {
+		return Block{}, errors.New("orphan has no parent CID")
+	}
+	parent, err := downloadBlockFromIPFS(ctx, orphan.PrevCID)
+	if err != nil {
+		return Block{}, err
+	}
+	if parent.Hash != orphan.PrevHash {
+		return Block{}, fmt.Errorf("parent CID %s holds block %s", orphan.PrevCID, parent.Hash)
+	}
+	return parent, nil
+}
+
+// Ask a miner for the block at the parent's height on its main chain.
+func fetchParentFromPeer(addr string, orphan Block) (Block, error) {
+	blocks, err := transport.Blocks(addr, orphan.BlockNumber-1, orphan.BlockNumber-1)
+	if err != nil {
+		return Block{}, err
+	}
+	if len(blocks) != 1 || blocks[0].Hash != orphan.PrevHash {
+		return Block{}, errors.New("peer does not have the parent on its main chain")
+	}
+	return blocks[0], nil
+}
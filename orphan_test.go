@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+// Deliver the queued announcements in reverse order, so children arrive
+// before their parents.
+func deliverReversed(network *testNetwork) {
+	network.queueMu.Lock()
+	queue := network.queue
+	for i, j := 0, len(queue)-1; i < j; i, j = i+1, j-1 {
+		queue[i], queue[j] = queue[j], queue[i]
+	}
+	network.queueMu.Unlock()
+	network.Deliver()
+}
+
+func TestOrphanParentFetchedFromIPFS(t *testing.T) {
+	network := newTestNetwork(t, 2)
+	miner, peer := network.nodes[0], network.nodes[1]
+	network.Mine(miner)
+	network.Mine(miner)
+
+	deliverReversed(network)
+	network.RequireConverged(3)
+	if peer.orphans.Len() != 0 {
+		t.Errorf("%d orphans left after their parent arrived", peer.orphans.Len())
+	}
+}
+
+func TestOrphanParentFetchedFromPeer(t *testing.T) {
+	network := newTestNetwork(t, 2)
+	miner, peer := network.nodes[0], network.nodes[1]
+	network.Mine(miner)
+	child := network.Mine(miner)
+
+	// The parent is no longer in IPFS; the miner still has it
+	network.ipfs.mu.Lock()
+	delete(network.ipfs.objects, child.PrevCID)
+	network.ipfs.mu.Unlock()
+
+	deliverReversed(network)
+	network.RequireConverged(3)
+	if peer.orphans.Len() != 0 {
+		t.Errorf("%d orphans left after their parent arrived", peer.orphans.Len())
+	}
+}
+
+func TestOrphanPoolIsBounded(t *testing.T) {
+	defer func(limit int) { *maxOrphans = limit }(*maxOrphans)
+	*maxOrphans = 2
+
+	pool := NewOrphanPool()
+	for _, hash := range []string{"a", "b", "c"} {
+		pool.Add(Block{Hash: hash, PrevHash: "parent"})
+	}
+	children := pool.TakeChildren("parent")
+	if len(children) != 2 || children[0].Hash != "b" || children[1].Hash != "c" {
+		t.Errorf("pool kept %v, want the two newest orphans", children)
+	}
+	if pool.Len() != 0 {
+		t.Errorf("children were not removed from the pool")
+	}
+}
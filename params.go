@@ -0,0 +1,167 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Limits on script parameters every node enforces on transactions. Node
+// policy (-script-max-args, -script-vars) is applied to jobs on top.
+const (
+	maxScriptArgs       = 64
+	maxScriptVars       = 32
+	maxScriptParamBytes = 16 << 10 // Arguments, names and values together
+)
+
+var (
+	scriptMaxArgs = flag.Int("script-max-args", 16, "most command-line arguments a job may pass its script")
+	scriptVars    = flag.String("script-vars", "", "comma-separated environment variables jobs may set for their scripts (default none)")
+
+	varNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+	// Variables the sandboxes set themselves or that change how any
+	// program loads, refused whatever a node allows
+	reservedVars = []string{"PATH", "HOME", "TMPDIR", "IFS", "ENV", "BASH_ENV", "PYTHONPATH", "PYTHONSTARTUP", "NODE_OPTIONS", "NODE_PATH", outputDirVar}
+)
+
+// ScriptParams are the command-line arguments, after the data path, and the
+// environment variables a job's script runs with.
+type ScriptParams struct {
+	Args []string          `json:",omitempty"`
+	Vars map[string]string `json:",omitempty"`
+}
+
+// Check the parameters against the limits every node enforces.
+func (p ScriptParams) Validate() error {
+	if len(p.Args) == 0 && len(p.Vars) == 0 {
+		return errors.New("empty script parameters")
+	}
+	if len(p.Args) > maxScriptArgs {
+		return fmt.Errorf("%d script arguments exceed the limit of %d", len(p.Args), maxScriptArgs)
+	}
+	if len(p.Vars) > maxScriptVars {
+		return fmt.Errorf("%d environment variables exceed the limit of %d", len(p.Vars), maxScriptVars)
+	}
+	size := 0
+	for _, arg := range p.Args {
+		if strings.ContainsRune(arg, 0) {
+			return errors.New("script argument contains a NUL byte")
+		}
+		size += len(arg)
+	}
+	for name, value := range p.Vars {
+		if !varNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid environment variable name %q", name)
+		}
+		if isReservedVar(name) {
+			return fmt.Errorf("environment variable %s may not be set", name)
+		}
+		if strings.ContainsRune(value, 0) {
+			return fmt.Errorf("environment variable %s contains a NUL byte", name)
+		}
+		size += len(name) + len(value)
+	}
+	if size > maxScriptParamBytes {
+		return fmt.Errorf("script parameters of %d bytes exceed the limit of %d", size, maxScriptParamBytes)
+	}
+	return nil
+}
+
+func isReservedVar(name string) bool {
+	return slices.Contains(reservedVars, name) || strings.HasPrefix(name, "LD_") || strings.HasPrefix(name, "DYLD_")
+}
+
+// Check the parameters of a job against this node's policy.
+func (p ScriptParams) allowed() error {
+	if len(p.Args) > *scriptMaxArgs {
+		return fmt.Errorf("this node passes scripts at most %d arguments", *scriptMaxArgs)
+	}
+	allowed := strings.Split(*scriptVars, ",")
+	for name := range p.Vars {
+		if !slices.Contains(allowed, name) {
+			return fmt.Errorf("this node does not let jobs set %s", name)
+		}
+	}
+	return nil
+}
+
+// Arguments to append to the script's command line; none for nil.
+func (p *ScriptParams) args() []string {
+	if p == nil {
+		return nil
+	}
+	return p.Args
+}
+
+// Variables as NAME=VALUE, sorted; none for nil.
+func (p *ScriptParams) environ() []string {
+	if p == nil {
+		return nil
+	}
+	vars := make([]string, 0, len(p.Vars))
+	for name, value := range p.Vars {
+		vars = append(vars, name+"="+value)
+	}
+	sort.Strings(vars)
+	return vars
+}
+
+// Hex SHA-256 of the parameters' JSON, which sorts the variables; empty for
+// nil. Identifies the parameters a result was checked with.
+func (p *ScriptParams) hash() string {
+	if p == nil {
+		return ""
+	}
+	data, _ := json.Marshal(p)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Apply an arg=VALUE or var=NAME=VALUE option of a job message to params,
+// allocating it on first use. It reports false for other options.
+func parseParamOption(params **ScriptParams, key, value string) (bool, error) {
+	switch key {
+	case "arg", "var":
+	default:
+		return false, nil
+	}
+	if *params == nil {
+		*params = &ScriptParams{}
+	}
+	if key == "arg" {
+		(*params).Args = append((*params).Args, value)
+		return true, nil
+	}
+	name, value, ok := strings.Cut(value, "=")
+	if !ok {
+		return true, errors.New("expected NAME=VALUE")
+	}
+	if _, set := (*params).Vars[name]; set {
+		return true, fmt.Errorf("%s set twice", name)
+	}
+	if (*params).Vars == nil {
+		(*params).Vars = make(map[string]string)
+	}
+	(*params).Vars[name] = value
+	return true, nil
+}
+
+// Check a job's parameters, if it has any, against the chain's limits and
+// this node's policy.
+func checkJobParams(job jobRequest) error {
+	if job.Params == nil {
+		return nil
+	}
+	if err := job.Params.Validate(); err != nil {
+		return fmt.Errorf("invalid script parameters: %v", err)
+	}
+	return job.Params.allowed()
+}
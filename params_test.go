@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJobMessageParams(t *testing.T) {
+	defer func(vars string) { *scriptVars = vars }(*scriptVars)
+	*scriptVars = "GREETING,LANG"
+
+	job, err := parseJobMessage(testJobCIDs + " arg=--upper arg= var=GREETING=a=b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Params == nil || strings.Join(job.Params.Args, ",") != "--upper," || job.Params.Vars["GREETING"] != "a=b" {
+		t.Errorf("parsed parameters %+v", job.Params)
+	}
+
+	for name, message := range map[string]string{
+		"variable not allowed": testJobCIDs + " var=SECRET=1",
+		"reserved variable":    testJobCIDs + " var=LD_PRELOAD=x.so",
+		"variable set twice":   testJobCIDs + " var=LANG=C var=LANG=en",
+		"no variable value":    testJobCIDs + " var=LANG",
+		"too many arguments":   testJobCIDs + strings.Repeat(" arg=x", *scriptMaxArgs+1),
+	} {
+		if _, err := parseJobMessage(message); err == nil {
+			t.Errorf("%s: parsed without error", name)
+		}
+	}
+
+	var spec jobSpec
+	json.Unmarshal([]byte(`{"scriptCid": "`+testScriptCID+`", "dataCid": "`+testDataCID+`", "args": ["two words"], "vars": {"LANG": "C"}}`), &spec)
+	if job, err := spec.job(); err != nil || job.Params.Args[0] != "two words" || job.Params.Vars["LANG"] != "C" {
+		t.Errorf("batch job with parameters: %+v, %v", job.Params, err)
+	}
+}
+
+func TestReexecutionUsesParams(t *testing.T) {
+	setupTestMiner(t)
+	setupReexecution(t)
+	scriptCID, _ := ipfsShell.Add(strings.NewReader(`printf '%s %s' "$2" "$GREETING"`))
+	dataCID, _ := ipfsShell.Add(strings.NewReader("unused"))
+
+	for _, test := range []struct {
+		params *ScriptParams
+		valid  bool
+	}{
+		{&ScriptParams{Args: []string{"hello"}, Vars: map[string]string{"GREETING": "world"}}, true},
+		{&ScriptParams{Args: []string{"goodbye"}, Vars: map[string]string{"GREETING": "world"}}, false},
+		{nil, false},
+	} {
+		tx := Transaction{
+			ID:         generateTransactionID("hello world"),
+			Data:       "hello world",
+			ScriptCID:  scriptCID,
+			DataCID:    dataCID,
+			ResultHash: resultHash("hello world"),
+			Params:     test.params,
+			NetworkID:  networkID,
+		}
+		if err := signTransaction(&tx); err != nil {
+			t.Fatal(err)
+		}
+		block := mineTestBlock(t, tx)
+		data, _ := json.Marshal(block)
+		if got := validateBlock(string(data), genesisBlock.Hash, currentTarget()); got != test.valid {
+			t.Errorf("block run with %+v: validateBlock = %v, want %v", test.params, got, test.valid)
+		}
+	}
+}
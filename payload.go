@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// Prefix identifying a payload sealed with sealPayload.
+var sealedPayloadMagic = []byte("BCAENC1")
+
+var (
+	payloadKeyFile = flag.String("payload-key", "", "keystore file with this node's X25519 key for encrypted job inputs (created if missing)")
+
+	payloadKey *ecdh.PrivateKey // nil when encrypted job inputs are not accepted
+)
+
+// Load the node's payload decryption key, generating it on first start.
+func setupPayloadKey() error {
+	if *payloadKeyFile == "" {
+		return nil
+	}
+	pass, err := keystorePassphrase()
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(*payloadKeyFile); os.IsNotExist(err) {
+		key, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			return fmt.Errorf("failed to generate payload key: %v", err)
+		}
+		if err := saveEncryptedKey(*payloadKeyFile, key, pass); err != nil {
+			return err
+		}
+	}
+
+	key, err := loadEncryptedKey(*payloadKeyFile, pass)
+	if err != nil {
+		return err
+	}
+	ecdhKey, ok := key.(*ecdh.PrivateKey)
+	if !ok || ecdhKey.Curve() != ecdh.X25519() {
+		return fmt.Errorf("%s does not hold an X25519 key", *payloadKeyFile)
+	}
+	payloadKey = ecdhKey
+	nodeLog.Info("Loaded payload encryption key", "publicKey", hex.EncodeToString(payloadKey.PublicKey().Bytes()))
+	return nil
+}
+
+// Parse a hex X25519 public key.
+func parsePayloadPublicKey(s string) (*ecdh.PublicKey, error) {
+	raw, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("recipient key is not valid hex")
+	}
+	return ecdh.X25519().NewPublicKey(raw)
+}
+
+func isSealedPayload(data []byte) bool {
+	return bytes.HasPrefix(data, sealedPayloadMagic)
+}
+
+// Encrypt plaintext so only the holder of recipient's private key can read
+// it: magic | ephemeral public key | nonce | AES-256-GCM ciphertext.
+func sealPayload(recipient *ecdh.PublicKey, plaintext []byte) ([]byte, error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := payloadCipher(shared, ephemeral.PublicKey(), recipient)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	sealed := append([]byte{}, sealedPayloadMagic...)
+	sealed = append(sealed, ephemeral.PublicKey().Bytes()...)
+	sealed = append(sealed, nonce...)
+	return gcm.Seal(sealed, nonce, plaintext, sealedPayloadMagic), nil
+}
+
+// Decrypt a payload produced by sealPayload.
+func openPayload(key *ecdh.PrivateKey, sealed []byte) ([]byte, error) {
+	if !isSealedPayload(sealed) {
+		return nil, errors.New("payload is not sealed")
+	}
+	rest := sealed[len(sealedPayloadMagic):]
+	if len(rest) < 32+12 {
+		return nil, errors.New("sealed payload is truncated")
+	}
+	ephemeral, err := ecdh.X25519().NewPublicKey(rest[:32])
+	if err != nil {
+		return nil, err
+	}
+	shared, err := key.ECDH(ephemeral)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := payloadCipher(shared, ephemeral, key.PublicKey())
+	if err != nil {
+		return nil, err
+	}
+	nonce, ciphertext := rest[32:32+gcm.NonceSize()], rest[32+gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, sealedPayloadMagic)
+	if err != nil {
+		return nil, errors.New("payload was not sealed to this key or was modified")
+	}
+	return plaintext, nil
+}
+
+// Derive the AES key from the shared secret bound to both public keys.
+func payloadCipher(shared []byte, ephemeral, recipient *ecdh.PublicKey) (cipher.AEAD, error) {
+	h := sha256.New()
+	h.Write(shared)
+	h.Write(ephemeral.Bytes())
+	h.Write(recipient.Bytes())
+	block, err := aes.NewCipher(h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Whether a downloaded job input is sealed to some node's payload key.
+func isSealedFile(path string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && isSealedPayload(data)
+}
+
+// Decrypt a downloaded job input in place if it was sealed to this node.
+func openSealedFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	if !isSealedPayload(data) {
+		return nil
+	}
+	if payloadKey == nil {
+		return errors.New("job input is encrypted but this node has no payload key")
+	}
+	plaintext, err := openPayload(payloadKey, data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, plaintext, 0600)
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Peer protocol message types, sent as JSON lines on the block port.
+const (
+	msgPing      = "ping"
+	msgPong      = "pong"
+	msgGetPeers  = "getpeers"
+	msgPeers     = "peers"
+	msgGetHeight = "getheight"
+	msgHeight    = "height"
+	msgGetBlocks = "getblocks"
+	msgBlocks    = "blocks"
+	msgGetBlock  = "getblock"
+	msgGetProof  = "getproof"
+	msgProof     = "proof"
+)
+
+var (
+	bootstrapPeers   = flag.String("bootstrap", "", "comma-separated IPs of miners to connect to at startup")
+	peerPingInterval = flag.Duration("peer-ping-interval", 30*time.Second, "how often peers are pinged and asked for their peers")
+	peerMaxFailures  = flag.Int("peer-max-failures", 3, "consecutive failed pings after which a peer is dropped")
+	maxPeers         = flag.Int("max-peers", 64, "maximum number of known peers")
+
+	peerManager *PeerManager // Known miners
+)
+
+// Control message exchanged between miners. Block announcements have no
+// type and are handled by the block validation path.
+type peerMessage struct {
+	Type    string       `json:"type"`
+	Network string       `json:"network,omitempty"` // Sender's network ID, on pings, pongs and heights
+	Peers   []string     `json:"peers,omitempty"`
+	Height  int          `json:"height,omitempty"` // Chain height, or first block of a range
+	To      int          `json:"to,omitempty"`     // Last block of a range, inclusive
+	Tip     string       `json:"tip,omitempty"`
+	Hash    string       `json:"hash,omitempty"`    // Block requested by getblock, or transaction by getproof
+	Headers bool         `json:"headers,omitempty"` // Getblocks asks for block headers only
+	Blocks  []Block      `json:"blocks,omitempty"`
+	Proof   *merkleProof `json:"proof,omitempty"` // Absent if the transaction is not on the main chain
+}
+
+// PeerManager tracks the miners this node talks to. It starts from the
+// bootstrap list, learns more through peer exchange, and drops peers that
+// stop answering pings.
+type PeerManager struct {
+	mu        sync.RWMutex
+	peers     map[string]*peerState // By miner IP
+	bootstrap []string
+}
+
+type peerState struct {
+	lastSeen time.Time
+	failures int          // Consecutive failed pings
+	hello    helloMessage // Latest handshake, if any
+}
+
+func NewPeerManager(bootstrap []string) *PeerManager {
+	pm := &PeerManager{peers: make(map[string]*peerState)}
+	for _, addr := range bootstrap {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			pm.bootstrap = append(pm.bootstrap, addr)
+			pm.Add(addr)
+		}
+	}
+	return pm
+}
+
+// Add a miner by IP. It reports whether the peer was new; this node's own
+// addresses and peers beyond the limit are ignored.
+func (pm *PeerManager) Add(addr string) bool {
+	if net.ParseIP(addr) == nil || isLocalAddress(addr) {
+		return false
+	}
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	if _, ok := pm.peers[addr]; ok || len(pm.peers) >= *maxPeers {
+		return false
+	}
+	pm.peers[addr] = &peerState{lastSeen: time.Now()}
+	return true
+}
+
+// Remember the hello of a known peer, so it is dialed on the block port it
+// announced. A peer's first hello is published as PeerConnected.
+func (pm *PeerManager) recordHello(addr string, hello helloMessage) {
+	pm.mu.Lock()
+	state, ok := pm.peers[addr]
+	first := ok && state.hello.Version == 0
+	if ok {
+		state.hello = hello
+	}
+
+	// A node that shows up at a new address no longer lives at the old one
+	var moved string
+	if ok && hello.NodeID != "" {
+		for other, otherState := range pm.peers {
+			if other != addr && otherState.hello.NodeID == hello.NodeID {
+				moved = other
+				delete(pm.peers, other)
+				break
+			}
+		}
+	}
+	pm.mu.Unlock()
+
+	if moved != "" {
+		p2pLog.Info("Peer changed address", "node", hello.NodeID, "from", moved, "to", addr)
+	}
+	if first {
+		events.Publish(eventPeerConnected, map[string]any{
+			"addr":    addr,
+			"nodeId":  hello.NodeID,
+			"agent":   hello.Agent,
+			"version": hello.Version,
+			"height":  hello.Height,
+		})
+	}
+}
+
+// Report whether a peer takes block announcements by inventory. Peers whose
+// hello has not been seen yet are assumed to.
+func (pm *PeerManager) takesInventory(addr string) bool {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	state, ok := pm.peers[addr]
+	return !ok || state.hello.Version == 0 || state.hello.Version >= inventoryProtocolVersion
+}
+
+// Node ID a peer proved in its hello, or "" if unknown.
+func (pm *PeerManager) NodeID(addr string) string {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	if state, ok := pm.peers[addr]; ok {
+		return state.hello.NodeID
+	}
+	return ""
+}
+
+// Block port a peer announced in its hello, or 0 if unknown.
+func (pm *PeerManager) blockPort(addr string) int {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	if state, ok := pm.peers[addr]; ok {
+		return state.hello.BlockPort
+	}
+	return 0
+}
+
+func (pm *PeerManager) Remove(addr string) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	delete(pm.peers, addr)
+}
+
+// Peers returns the IPs of all known miners.
+func (pm *PeerManager) Peers() []string {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	peers := make([]string, 0, len(pm.peers))
+	for addr := range pm.peers {
+		peers = append(peers, addr)
+	}
+	return peers
+}
+
+func (pm *PeerManager) Count() int {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return len(pm.peers)
+}
+
+// Highest chain height known peers announced in their latest hello, or -1
+// if none has sent one.
+func (pm *PeerManager) BestHeight() int {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	best := -1
+	for _, state := range pm.peers {
+		if state.hello.Version != 0 {
+			best = max(best, state.hello.Height)
+		}
+	}
+	return best
+}
+
+// Broadcast a block to every known miner, lowest-latency miners first.
+func (pm *PeerManager) Broadcast(block Block) {
+	miners := minersByLatency(pm.Peers())
+	firstWave := miners
+	if len(firstWave) > *firstWavePeers {
+		firstWave = miners[:*firstWavePeers]
+	}
+
+	sendWave := func(wave []string) {
+		var wg sync.WaitGroup
+		for _, miner := range wave {
+			wg.Add(1)
+			go func(miner string) {
+				defer wg.Done()
+				sendBlockToMiner(miner, block)
+			}(miner)
+		}
+		wg.Wait()
+	}
+	sendWave(firstWave)
+	sendWave(miners[len(firstWave):])
+}
+
+// Peer Maintenance Thread
+func (pm *PeerManager) Run(ctx context.Context) {
+	ticker := time.NewTicker(*peerPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, addr := range pm.Peers() {
+			pm.exchange(addr)
+		}
+
+		// Fall back to the bootstrap list if every peer died
+		if pm.Count() == 0 {
+			for _, addr := range pm.bootstrap {
+				pm.Add(addr)
+			}
+		}
+	}
+}
+
+// Ping a peer and ask it for its peers. Peers that fail too many pings in a
+// row are removed.
+func (pm *PeerManager) exchange(addr string) {
+	peers, rtt, err := transport.Exchange(addr)
+
+	pm.mu.Lock()
+	state, ok := pm.peers[addr]
+	if !ok {
+		pm.mu.Unlock()
+		return
+	}
+	if err != nil {
+		state.failures++
+		dead := state.failures >= *peerMaxFailures
+		if dead {
+			delete(pm.peers, addr)
+		}
+		pm.mu.Unlock()
+		if dead {
+			p2pLog.Info("Dropping unresponsive peer", "peer", addr, "node", state.hello.NodeID, "err", err)
+		}
+		return
+	}
+	state.failures = 0
+	state.lastSeen = time.Now()
+	pm.mu.Unlock()
+
+	recordLatency(addr, rtt)
+
+	// Learn a random sample of the peer's peers
+	rand.Shuffle(len(peers), func(i, j int) { peers[i], peers[j] = peers[j], peers[i] })
+	for _, peer := range peers {
+		if pm.Add(peer) {
+			p2pLog.Info("Learned peer", "peer", peer, "from", addr)
+		}
+	}
+}
+
+// Ping a miner, then request its peer list. Returns the list and the ping
+// round-trip time.
+func pingPeer(addr string) ([]string, time.Duration, error) {
+	conn, reader, err := dialPeer(addr)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	start := time.Now()
+	if err := writePeerMessage(conn, peerMessage{Type: msgPing, Network: networkID}); err != nil {
+		return nil, 0, err
+	}
+	reply, err := readPeerMessage(reader)
+	if err != nil {
+		return nil, 0, err
+	}
+	if reply.Type != msgPong {
+		return nil, 0, fmt.Errorf("expected pong, got %q", reply.Type)
+	}
+	if err := checkNetworkID(reply.Network); err != nil {
+		return nil, 0, err
+	}
+	rtt := time.Since(start)
+
+	if err := writePeerMessage(conn, peerMessage{Type: msgGetPeers}); err != nil {
+		return nil, rtt, err
+	}
+	reply, err = readPeerMessage(reader)
+	if err != nil {
+		return nil, rtt, err
+	}
+	if reply.Type != msgPeers {
+		return nil, rtt, fmt.Errorf("expected peers, got %q", reply.Type)
+	}
+	return reply.Peers, rtt, nil
+}
+
+// Answer a peer control message received on the block port.
+func handlePeerMessage(conn net.Conn, hello helloMessage, msg peerMessage) error {
+	switch msg.Type {
+	case msgPing:
+		// Miners of other networks are not peers. They still get a pong so
+		// they can see why.
+		if err := checkNetworkID(msg.Network); err != nil {
+			writePeerMessage(conn, peerMessage{Type: msgPong, Network: networkID})
+			return err
+		}
+		// Whoever pings us is listening on the block port too
+		if peerManager.Add(peerHost(conn.RemoteAddr())) {
+			p2pLog.Info("Learned peer from ping", "peer", peerHost(conn.RemoteAddr()), "node", hello.NodeID, "agent", hello.Agent)
+		}
+		peerManager.recordHello(peerHost(conn.RemoteAddr()), hello)
+		return writePeerMessage(conn, peerMessage{Type: msgPong, Network: networkID})
+	case msgGetPeers:
+		return writePeerMessage(conn, peerMessage{Type: msgPeers, Peers: peerManager.Peers()})
+	case msgPeers:
+		for _, peer := range msg.Peers {
+			peerManager.Add(peer)
+		}
+		return nil
+	case msgGetHeight:
+		reply := peerMessage{Type: msgHeight, Network: networkID, Height: chainStore.Height()}
+		if tip, ok := chainStore.Tip(); ok {
+			reply.Tip = tip.Hash
+		}
+		return writePeerMessage(conn, reply)
+	case msgGetBlocks:
+		blocks, err := servedBlocks(msg.Height, msg.To, msg.Headers)
+		if err != nil {
+			return err
+		}
+		return writePeerMessage(conn, peerMessage{Type: msgBlocks, Blocks: blocks})
+	case msgGetBlock:
+		reply := peerMessage{Type: msgBlocks}
+		if block, ok := lookupBlock(msg.Hash); ok && !*lightMode {
+			reply.Blocks = []Block{block}
+		}
+		return writePeerMessage(conn, reply)
+	case msgGetProof:
+		reply := peerMessage{Type: msgProof}
+		if proof, err := chainStore.TransactionProof(msg.Hash); err == nil {
+			reply.Proof = &proof
+		}
+		return writePeerMessage(conn, reply)
+	default:
+		return fmt.Errorf("unknown message type %q", msg.Type)
+	}
+}
+
+func writePeerMessage(conn net.Conn, msg peerMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return writeFrame(conn, framePeer, data)
+}
+
+func readPeerMessage(reader *bufio.Reader) (peerMessage, error) {
+	payload, err := readFrameOfType(reader, framePeer)
+	if err != nil {
+		return peerMessage{}, err
+	}
+	var msg peerMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		return peerMessage{}, fmt.Errorf("malformed peer message: %v", err)
+	}
+	return msg, nil
+}
+
+// Report whether ip belongs to this machine.
+func isLocalAddress(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	if parsed.IsLoopback() || parsed.IsUnspecified() {
+		return true
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(parsed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"sync"
+	"time"
+)
+
+var (
+	pinRetention      = flag.Int("pin-retention", 0, "number of most recent blocks kept pinned in IPFS (0 keeps the whole chain)")
+	pinRepairInterval = flag.Duration("pin-repair-interval", 10*time.Minute, "how often block pins are checked and repaired from the local store (0 disables)")
+)
+
+// CID of a stored block. After upload a block's PrevCID field holds its own
+// CID (see startMining).
+func blockCID(block Block) string {
+	return block.PrevCID
+}
+
+// IPFS Pin Maintenance Thread
+func maintainPins(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	ticker := time.NewTicker(*pinRepairInterval)
+	defer ticker.Stop()
+	for {
+		repairPins(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Make the IPFS node hold exactly the retained blocks: re-add and pin any
+// it lost, including blocks received from peers, and unpin blocks that
+// fell out of the retention window.
+func repairPins(ctx context.Context) {
+	pins, err := ipfsShell.Pins()
+	if err != nil {
+		ipfsLog.Warn("Could not list IPFS pins", "err", err)
+		return
+	}
+
+	height := chainStore.Height()
+	floor := 0
+	if *pinRetention > 0 && height > *pinRetention {
+		floor = height - *pinRetention
+	}
+
+	repaired, unpinned := 0, 0
+	// The genesis block is built locally and never uploaded
+	for n := max(chainStore.Base(), 1); n < height && ctx.Err() == nil; n++ {
+		block, err := chainStore.GetBlockByNumber(n)
+		if err != nil {
+			ipfsLog.Error("Error reading block for pin repair", "height", n, "err", err)
+			return
+		}
+		cid := blockCID(block)
+		_, pinned := pins[cid]
+
+		if n < floor {
+			if pinned {
+				if err := ipfsShell.Unpin(cid); err != nil {
+					ipfsLog.Warn("Error unpinning block", "height", n, "cid", cid, "err", err)
+					continue
+				}
+				unpinned++
+			}
+			continue
+		}
+		if pinned {
+			continue
+		}
+
+		// Adding the stored copy restores the block if IPFS collected it
+		added, err := uploadBlockToIPFS(block)
+		if err != nil {
+			ipfsLog.Warn("Error repairing block pin", "height", n, "err", err)
+			continue
+		}
+		if added != cid {
+			ipfsLog.Warn("Repaired block has a different CID", "height", n, "cid", cid, "added", added)
+		}
+		repaired++
+	}
+	if repaired > 0 || unpinned > 0 {
+		ipfsLog.Info("Repaired IPFS pins", "repaired", repaired, "unpinned", unpinned, "retained", height-floor)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"math/big"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Nonces a mining worker claims at a time.
+const nonceBatch = 1 << 12
+
+var miningWorkers = flag.Int("mining-workers", 0, "goroutines searching for nonces in parallel (default: GOMAXPROCS)")
+
+// Search for a nonce that brings the candidate's hash below the target it
+// declares. Reports false if ctx is cancelled, abort is signalled or mining
+// is switched off first.
+func mineBlock(ctx context.Context, abort <-chan struct{}, candidate Block) (Block, bool) {
+	return mineBlockBelow(ctx, abort, candidate, blockTarget(candidate))
+}
+
+// Search for a nonce that brings the candidate's hash below hashTarget, which
+// may be easier than the one the candidate declares.
+//
+// The search is split across worker goroutines that claim batches of
+// nonces from a shared counter. All of them stop as soon as one finds a
+// nonce, so the nonce found is not necessarily the smallest.
+func mineBlockBelow(ctx context.Context, abort <-chan struct{}, candidate Block, hashTarget *big.Int) (Block, bool) {
+	prefix := candidate.hashPrefix()
+	var targetBytes [sha256.Size]byte
+	hashTarget.FillBytes(targetBytes[:]) // Targets never exceed 2^255
+	miningActive.Store(true)
+	defer miningActive.Store(false)
+
+	workers := *miningWorkers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	var (
+		next  atomic.Int64 // First nonce of the next unclaimed batch
+		stop  atomic.Bool  // Set once a nonce is found or mining must end
+		found sync.Once
+		block Block
+		ok    bool
+	)
+
+	// Stop the workers on cancellation or abort
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-abort:
+		case <-finished:
+		}
+		stop.Store(true)
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !stop.Load() {
+				if !miningEnabled.Load() {
+					stop.Store(true)
+					return
+				}
+				first := next.Add(nonceBatch) - nonceBatch
+				hashes := uint64(0)
+				for nonce := first; nonce < first+nonceBatch && !stop.Load(); nonce++ {
+					hash, met := tryNonce(prefix, int(nonce), &targetBytes)
+					hashes++
+					if met {
+						found.Do(func() {
+							block = candidate
+							block.Nonce = int(nonce)
+							block.Hash = hex.EncodeToString(hash[:])
+							ok = true
+						})
+						stop.Store(true)
+					}
+				}
+				hashCount.Add(hashes)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if !ok {
+		return Block{}, false
+	}
+	return block, true
+}
+
+// Hash a candidate's serialization prefix with a nonce and report whether
+// the hash meets the target.
+func tryNonce(prefix []byte, nonce int, target *[sha256.Size]byte) ([sha256.Size]byte, bool) {
+	hash := sha256.Sum256(appendNonce(prefix, nonce))
+	return hash, bytes.Compare(hash[:], target[:]) < 0
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"testing"
+)
+
+func TestMineBlockWithWorkers(t *testing.T) {
+	setupTestMiner(t)
+	defer func(workers int) { *miningWorkers = workers }(*miningWorkers)
+
+	for _, workers := range []int{1, 3, 8} {
+		*miningWorkers = workers
+		before := hashCount.Load()
+		block, found := mineBlock(context.Background(), nil, blockTemplate(1, genesisBlock.Hash, genesisBlock.PrevCID, nil))
+		if !found {
+			t.Fatalf("%d workers: mining was interrupted", workers)
+		}
+		hash, _ := new(big.Int).SetString(block.Hash, 16)
+		if block.Hash != block.ComputeHash() || hash.Cmp(currentTarget()) >= 0 {
+			t.Errorf("%d workers: block %s does not meet the target", workers, block.Hash)
+		}
+		if hashCount.Load() == before {
+			t.Errorf("%d workers: hashes were not counted", workers)
+		}
+	}
+}
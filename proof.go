@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	proofSystemName = flag.String("proof-system", "", "name of the proof system this node proves with (default: no proofs)")
+	proofProveCmd   = flag.String("proof-prove-cmd", "", "command that prints a proof for <script> <data> <result file>")
+	proofVerifyCmd  = flag.String("proof-verify-cmd", "", "command that exits 0 if <statement file> <proof file> verifies")
+	proofProveTime  = flag.Duration("proof-prove-timeout", 10*time.Minute, "wall-clock limit after which the prover command is killed")
+	proofVerifyTime = flag.Duration("proof-verify-timeout", 30*time.Second, "wall-clock limit after which the verifier command is killed and the proof rejected")
+
+	// Registered proof systems by name. Validators reject transactions whose
+	// proof system is not registered here.
+	proofSystems = map[string]ProofSystem{}
+)
+
+// ExecutionProof is a succinct proof, for example from a zkVM, that the
+// transaction's result is the output of its script on its data.
+type ExecutionProof struct {
+	System string // Proof system that produced and verifies the proof
+	Data   string // Base64 proof bytes
+}
+
+// ProofStatement is the claim an execution proof attests to.
+type ProofStatement struct {
+	ScriptCID  string `json:"scriptCid"`
+	DataCID    string `json:"dataCid"`
+	ResultHash string `json:"resultHash"`
+}
+
+// ProofSystem produces and checks execution proofs. Implementations can wrap
+// a zkVM library or, like externalProofSystem, an external tool.
+type ProofSystem interface {
+	Prove(statement ProofStatement, scriptPath, dataPath, result string) ([]byte, error)
+	Verify(statement ProofStatement, proof []byte) error
+}
+
+// Register the external proof system configured by flags.
+func setupProofSystem() error {
+	if *proofSystemName == "" {
+		return nil
+	}
+	if *proofProveCmd == "" || *proofVerifyCmd == "" {
+		return fmt.Errorf("proof system %q needs -proof-prove-cmd and -proof-verify-cmd", *proofSystemName)
+	}
+	proofSystems[*proofSystemName] = externalProofSystem{
+		prove:         strings.Fields(*proofProveCmd),
+		verify:        strings.Fields(*proofVerifyCmd),
+		proveTimeout:  *proofProveTime,
+		verifyTimeout: *proofVerifyTime,
+	}
+	return nil
+}
+
+// Statement a transaction's proof must attest to. Results only stored in
+// IPFS are known by their hash.
+func transactionStatement(tx Transaction) ProofStatement {
+	hash := resultHash(tx.Data)
+	if transactionIDSource(tx) != tx.Data {
+		hash = tx.ResultHash
+	}
+	return ProofStatement{ScriptCID: tx.ScriptCID, DataCID: tx.DataCID, ResultHash: hash}
+}
+
+// Attach a proof to the transaction using this node's proof system, if any.
+func attachExecutionProof(tx *Transaction, scriptPath, dataPath, result string) error {
+	system, ok := proofSystems[*proofSystemName]
+	if !ok {
+		return nil
+	}
+	proof, err := system.Prove(transactionStatement(*tx), scriptPath, dataPath, result)
+	if err != nil {
+		return fmt.Errorf("failed to prove execution: %v", err)
+	}
+	tx.Proof = &ExecutionProof{System: *proofSystemName, Data: base64.StdEncoding.EncodeToString(proof)}
+	return nil
+}
+
+// Verify the transaction's execution proof instead of re-executing it.
+func verifyExecutionProof(tx Transaction) error {
+	system, ok := proofSystems[tx.Proof.System]
+	if !ok {
+		return fmt.Errorf("unknown proof system %q", tx.Proof.System)
+	}
+	proof, err := base64.StdEncoding.DecodeString(tx.Proof.Data)
+	if err != nil {
+		return fmt.Errorf("proof is not valid base64: %v", err)
+	}
+	return system.Verify(transactionStatement(tx), proof)
+}
+
+// externalProofSystem runs external prover and verifier commands. Each is
+// killed after its timeout, so a hung verifier cannot stall block
+// validation.
+type externalProofSystem struct {
+	prove, verify               []string
+	proveTimeout, verifyTimeout time.Duration
+}
+
+func (e externalProofSystem) Prove(statement ProofStatement, scriptPath, dataPath, result string) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "proof")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	resultPath := filepath.Join(dir, "result")
+	if err := os.WriteFile(resultPath, []byte(result), 0600); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e.proveTimeout)
+	defer cancel()
+	args := append(append([]string{}, e.prove[1:]...), scriptPath, dataPath, resultPath)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.prove[0], args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	killProcessGroup(cmd)
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("prover killed after %s timeout", e.proveTimeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("prover failed: %v, output: %s", err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+func (e externalProofSystem) Verify(statement ProofStatement, proof []byte) error {
+	dir, err := os.MkdirTemp("", "proof")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	statementJSON, err := json.Marshal(statement)
+	if err != nil {
+		return err
+	}
+	statementPath := filepath.Join(dir, "statement.json")
+	proofPath := filepath.Join(dir, "proof")
+	if err := os.WriteFile(statementPath, statementJSON, 0600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(proofPath, proof, 0600); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.verifyTimeout)
+	defer cancel()
+	args := append(append([]string{}, e.verify[1:]...), statementPath, proofPath)
+	cmd := exec.CommandContext(ctx, e.verify[0], args...)
+	killProcessGroup(cmd)
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("proof rejected: verifier killed after %s timeout", e.verifyTimeout)
+	}
+	if err != nil {
+		return fmt.Errorf("proof rejected: %v, output: %s", err, string(output))
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHungVerifierIsKilled(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not installed")
+	}
+	system := externalProofSystem{
+		verify:        []string{"sh", "-c", "sleep 30", "verifier"},
+		verifyTimeout: 100 * time.Millisecond,
+	}
+	start := time.Now()
+	err := system.Verify(ProofStatement{}, []byte("proof"))
+	if err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Errorf("hung verifier returned %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("hung verifier held validation for %s", elapsed)
+	}
+}
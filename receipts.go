@@ -0,0 +1,205 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"net"
+	"sync"
+	"time"
+)
+
+// Stages of a job's lifecycle, in order. A failed job stops at jobFailed; a
+// mined job goes back to jobPending if its block leaves the main chain.
+const (
+	jobQueued      = "queued"
+	jobDownloading = "downloading"
+	jobExecuting   = "executing"
+	jobPending     = "pending"
+	jobMined       = "mined"
+	jobFailed      = "failed"
+)
+
+var (
+	maxReceipts = flag.Int("max-receipts", 10000, "number of job receipts kept; the oldest are forgotten first")
+
+	receipts = NewReceiptStore() // Receipts of the jobs this node ran
+)
+
+// Receipt reports what became of a submitted job.
+type Receipt struct {
+	JobID     string    `json:"jobId"`
+	Status    string    `json:"status"`
+	ScriptCID string    `json:"scriptCid,omitempty"`
+	DataCID   string    `json:"dataCid,omitempty"`
+	TxID      string    `json:"txId,omitempty"`      // Result transaction, once created
+	Block     int       `json:"block,omitempty"`     // Number of the block holding it, once mined
+	BlockHash string    `json:"blockHash,omitempty"` // Hash of that block
+	Error     string    `json:"error,omitempty"`     // Why the job failed
+	Code      string    `json:"code,omitempty"`      // Why the job failed, as one of the code* constants
+	Updated   time.Time `json:"updated"`
+}
+
+// ReceiptStore keeps the receipts of recent jobs in memory.
+type ReceiptStore struct {
+	mu    sync.Mutex
+	byID  map[string]*Receipt
+	byTx  map[string]string // Transaction ID -> job ID
+	order []string          // Job IDs, oldest first
+}
+
+func NewReceiptStore() *ReceiptStore {
+	return &ReceiptStore{byID: make(map[string]*Receipt), byTx: make(map[string]string)}
+}
+
+// Open a receipt for a job being accepted and return its job ID.
+func (rs *ReceiptStore) Open(job jobRequest) string {
+	var id [8]byte
+	rand.Read(id[:])
+	receipt := &Receipt{
+		JobID:     hex.EncodeToString(id[:]),
+		Status:    jobQueued,
+		ScriptCID: job.ScriptCID,
+		DataCID:   job.DataCID,
+		Updated:   time.Now(),
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.byID[receipt.JobID] = receipt
+	rs.order = append(rs.order, receipt.JobID)
+	for len(rs.order) > *maxReceipts {
+		if old, ok := rs.byID[rs.order[0]]; ok {
+			delete(rs.byTx, old.TxID)
+			delete(rs.byID, old.JobID)
+		}
+		rs.order = rs.order[1:]
+	}
+	return receipt.JobID
+}
+
+// Get a copy of a job's receipt.
+func (rs *ReceiptStore) Get(jobID string) (Receipt, bool) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	receipt, ok := rs.byID[jobID]
+	if !ok {
+		return Receipt{}, false
+	}
+	return *receipt, true
+}
+
+// Get a copy of the receipt of the job that created a transaction.
+func (rs *ReceiptStore) ByTransaction(txID string) (Receipt, bool) {
+	rs.mu.Lock()
+	jobID, ok := rs.byTx[txID]
+	rs.mu.Unlock()
+	if !ok {
+		return Receipt{}, false
+	}
+	return rs.Get(jobID)
+}
+
+// Apply a change to a job's receipt. Unknown and forgotten jobs are ignored.
+func (rs *ReceiptStore) update(jobID string, change func(*Receipt)) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.change(jobID, change)
+}
+
+// Like update, for callers that hold rs.mu.
+func (rs *ReceiptStore) change(jobID string, change func(*Receipt)) {
+	if receipt, ok := rs.byID[jobID]; ok {
+		change(receipt)
+		receipt.Updated = time.Now()
+	}
+}
+
+func (rs *ReceiptStore) Downloading(jobID string) {
+	rs.update(jobID, func(r *Receipt) { r.Status = jobDownloading })
+}
+
+func (rs *ReceiptStore) Executing(jobID string) {
+	rs.update(jobID, func(r *Receipt) { r.Status = jobExecuting })
+}
+
+// Record the job's result transaction, about to enter the mempool.
+func (rs *ReceiptStore) Pending(jobID, txID string) {
+	rs.update(jobID, func(r *Receipt) {
+		r.Status, r.TxID = jobPending, txID
+		rs.byTx[txID] = jobID
+	})
+}
+
+func (rs *ReceiptStore) Fail(jobID string, err error) {
+	rs.update(jobID, func(r *Receipt) { r.Status, r.Error, r.Code = jobFailed, err.Error(), jobErrorCode(err, codeJobFailed) })
+}
+
+// Mark the jobs whose transactions a block added to the main chain as mined.
+func (rs *ReceiptStore) BlockConnected(block Block) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.connect(block)
+}
+
+// Return the jobs mined in a block that left the main chain to pending.
+func (rs *ReceiptStore) BlockDisconnected(block Block) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.disconnect(block)
+}
+
+// Move the receipts across a reorg in one step, so readers never see a job
+// pending between its old and new block.
+func (rs *ReceiptStore) ApplyReorg(reorg chainReorg) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	for i := len(reorg.Removed) - 1; i >= 0; i-- {
+		rs.disconnect(reorg.Removed[i])
+	}
+	for _, block := range reorg.Added {
+		rs.connect(block)
+	}
+	return nil
+}
+
+func (rs *ReceiptStore) connect(block Block) {
+	for _, tx := range block.Transactions {
+		if jobID, ok := rs.byTx[tx.ID]; ok {
+			rs.change(jobID, func(r *Receipt) {
+				r.Status, r.Block, r.BlockHash = jobMined, block.BlockNumber, block.Hash
+			})
+		}
+	}
+}
+
+func (rs *ReceiptStore) disconnect(block Block) {
+	for _, tx := range block.Transactions {
+		if jobID, ok := rs.byTx[tx.ID]; ok {
+			rs.change(jobID, func(r *Receipt) {
+				if r.BlockHash == block.Hash {
+					r.Status, r.Block, r.BlockHash = jobPending, 0, ""
+				}
+			})
+		}
+	}
+}
+
+// Send a receipt to a job client.
+func writeReceipt(conn net.Conn, receipt Receipt) error {
+	data, err := json.Marshal(receipt)
+	if err != nil {
+		return err
+	}
+	return writeFrame(conn, frameReceipt, data)
+}
+
+// Send the receipts acknowledging a batch to a job client.
+func writeReceipts(conn net.Conn, acks []Receipt) error {
+	data, err := json.Marshal(acks)
+	if err != nil {
+		return err
+	}
+	return writeFrame(conn, frameReceipts, data)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReceiptLifecycle(t *testing.T) {
+	store := NewReceiptStore()
+	id := store.Open(jobRequest{ScriptCID: "script", DataCID: "data"})
+
+	block := Block{Hash: "b1", BlockNumber: 3, Transactions: []Transaction{{ID: "tx1"}}}
+
+	steps := []struct {
+		apply  func()
+		status string
+	}{
+		{func() {}, jobQueued},
+		{func() { store.Downloading(id) }, jobDownloading},
+		{func() { store.Executing(id) }, jobExecuting},
+		{func() { store.Pending(id, "tx1") }, jobPending},
+		{func() { store.BlockConnected(block) }, jobMined},
+		{func() { store.BlockDisconnected(block) }, jobPending},
+	}
+	for _, step := range steps {
+		step.apply()
+		if receipt, _ := store.Get(id); receipt.Status != step.status {
+			t.Fatalf("receipt status is %q, want %q", receipt.Status, step.status)
+		}
+	}
+	if receipt, ok := store.ByTransaction("tx1"); !ok || receipt.JobID != id {
+		t.Errorf("receipt not found by transaction ID")
+	}
+
+	failed := store.Open(jobRequest{})
+	store.Fail(failed, errors.New("script crashed"))
+	if receipt, _ := store.Get(failed); receipt.Status != jobFailed || receipt.Error != "script crashed" {
+		t.Errorf("failed job has receipt %+v", receipt)
+	}
+}
+
+func TestReceiptsAreBounded(t *testing.T) {
+	defer func(limit int) { *maxReceipts = limit }(*maxReceipts)
+	*maxReceipts = 2
+
+	store := NewReceiptStore()
+	first := store.Open(jobRequest{})
+	store.Pending(first, "tx1")
+	store.Open(jobRequest{})
+	store.Open(jobRequest{})
+	if _, ok := store.Get(first); ok {
+		t.Error("oldest receipt was kept")
+	}
+	if _, ok := store.ByTransaction("tx1"); ok {
+		t.Error("oldest receipt is still indexed by transaction")
+	}
+}
+
+func TestReceiptFollowsChain(t *testing.T) {
+	network := newTestNetwork(t, 2)
+	a, b := network.nodes[0], network.nodes[1]
+
+	var jobID string
+	network.Run(a, func() {
+		tx := signedTestTransaction(t, "result")
+		jobID = receipts.Open(jobRequest{})
+		receipts.Pending(jobID, tx.ID)
+		if err := mempool.Add(tx, priorityNormal); err != nil {
+			t.Fatal(err)
+		}
+	})
+	mined := network.Mine(a)
+	if receipt, _ := a.receipts.Get(jobID); receipt.Status != jobMined || receipt.BlockHash != mined.Hash {
+		t.Fatalf("receipt after mining is %+v", receipt)
+	}
+
+	// A longer branch without the transaction takes the block off the chain
+	network.Mine(b)
+	network.Mine(b)
+	network.Deliver()
+	network.RequireConverged(3)
+	if receipt, _ := a.receipts.Get(jobID); receipt.Status != jobPending || receipt.Block != 0 {
+		t.Errorf("receipt after reorg is %+v", receipt)
+	}
+}
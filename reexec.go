@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Statements confirmed by re-execution that are remembered, so a block is
+// not re-executed again for every vote on it.
+const maxVerifiedResults = 10000
+
+var (
+	verifyExecution = flag.Bool("verify-execution", false, "re-run the scripts of transactions in received blocks and reject blocks whose results differ (scripts must be deterministic)")
+
+	verifiedMu      sync.Mutex
+	verifiedResults = make(map[reexecution]bool) // Statements confirmed by re-execution
+	verifiedOrder   []reexecution                // Oldest first
+
+	// The transaction's inputs are sealed to another node, so its result
+	// cannot be checked here.
+	errUnverifiable = errors.New("inputs are encrypted to another node")
+)
+
+// A statement checked by re-execution, in the runtime and environment it
+// names.
+type reexecution struct {
+	ProofStatement
+	runtime            string
+	image, interpreter string
+	params             string // Hash of the script parameters
+	outputs            string // Hash of the output files' names and hashes
+	units              int64  // Execution units claimed, if any
+}
+
+// Hex SHA-256 of a job's plaintext result, as carried in Transaction.ResultHash.
+func resultHash(result string) string {
+	sum := sha256.Sum256([]byte(result))
+	return hex.EncodeToString(sum[:])
+}
+
+// Re-execute the transactions of a block that claim a result hash and carry
+// no execution proof, and check that each script reproduces its result.
+// Without -verify-execution only the transactions whose computation is
+// credited as work are re-executed; those must be verifiable.
+func verifyBlockExecution(block Block) error {
+	for _, tx := range block.Transactions {
+		credited := creditsComputation() && tx.Units > 0
+		if tx.Coinbase != nil || tx.ResultHash == "" {
+			continue
+		}
+		if !credited && (tx.Proof != nil || !*verifyExecution) {
+			continue
+		}
+		runtime, err := enabledRuntime(tx.Runtime)
+		if credited {
+			// Credited scripts are WASM, which every node runs in process
+			runtime, err = lookupRuntime(tx.Runtime)
+		}
+		if err != nil {
+			chainLog.Debug("Cannot re-execute transaction", "tx", tx.ID, "err", err)
+			continue
+		}
+		statement := reexecution{
+			ProofStatement: ProofStatement{ScriptCID: tx.ScriptCID, DataCID: tx.DataCID, ResultHash: tx.ResultHash},
+			runtime:        runtime.Name,
+			params:         tx.Params.hash(),
+			outputs:        outputsHash(tx.Outputs),
+			units:          tx.Units,
+		}
+		if tx.Env != nil {
+			statement.image, statement.interpreter = tx.Env.Image, tx.Env.Interpreter
+		}
+		verifiedMu.Lock()
+		verified := verifiedResults[statement]
+		verifiedMu.Unlock()
+		if verified {
+			continue
+		}
+
+		err = reexecuteTransaction(tx, runtime)
+		if errors.Is(err, errUnverifiable) && !credited {
+			chainLog.Debug("Cannot re-execute transaction", "tx", tx.ID, "err", err)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("transaction %s: %v", tx.ID, err)
+		}
+
+		verifiedMu.Lock()
+		verifiedResults[statement] = true
+		verifiedOrder = append(verifiedOrder, statement)
+		if len(verifiedOrder) > maxVerifiedResults {
+			delete(verifiedResults, verifiedOrder[0])
+			verifiedOrder = verifiedOrder[1:]
+		}
+		verifiedMu.Unlock()
+	}
+	return nil
+}
+
+// Run a transaction's script on its data with its parameters in a private
+// directory and compare the result, the output files and any execution
+// units claimed.
+func reexecuteTransaction(tx Transaction, runtime scriptRuntime) error {
+	ctx, cancel := context.WithTimeout(context.Background(), *scriptTimeout+time.Minute)
+	defer cancel()
+
+	dir, release, err := newJobDir("reexec")
+	if err != nil {
+		return err
+	}
+	defer release()
+	scriptPath, dataPath := filepath.Join(dir, "script"+runtime.Extensions[0]), filepath.Join(dir, "data.txt")
+	outputDir := filepath.Join(dir, "output")
+	if err := os.Mkdir(outputDir, 0o700); err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	if err := downloadFromIPFS(ctx, tx.DataCID, dataPath, *maxDataSize); err != nil {
+		return fmt.Errorf("failed to download data: %v", err)
+	}
+	if err := downloadFromIPFS(ctx, tx.ScriptCID, scriptPath, *maxScriptSize); err != nil {
+		return fmt.Errorf("failed to download script: %v", err)
+	}
+	if openSealedFile(dataPath) != nil || openSealedFile(scriptPath) != nil {
+		return errUnverifiable
+	}
+
+	result, units, err := executeScript(ctx, runtime, tx.Env, scriptPath, dataPath, outputDir, tx.Params)
+	if err != nil {
+		return fmt.Errorf("re-execution failed: %v", err)
+	}
+	if got := resultHash(result); got != tx.ResultHash {
+		return fmt.Errorf("re-execution produced result %s, transaction claims %s", got, tx.ResultHash)
+	}
+	if tx.Units != 0 && units != tx.Units {
+		return fmt.Errorf("re-execution metered %d units, transaction claims %d", units, tx.Units)
+	}
+	outputs, err := collectOutputs(outputDir)
+	if err != nil {
+		return fmt.Errorf("re-execution failed: %v", err)
+	}
+	return compareOutputs(tx.Outputs, outputs)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// shellSandbox runs job scripts with sh, so tests need no Python.
+type shellSandbox struct{}
+
+func (shellSandbox) Command(ctx context.Context, runtime scriptRuntime, scriptPath, dataPath, outputDir string, params *ScriptParams) (*exec.Cmd, error) {
+	cmd := exec.CommandContext(ctx, "sh", append([]string{scriptPath, dataPath}, params.args()...)...)
+	cmd.Env = append(params.environ(), outputEnv(outputDir)...)
+	return cmd, nil
+}
+
+// Use a fake IPFS and the shell sandbox, and switch on re-execution, for
+// the duration of a test.
+func setupReexecution(t *testing.T) {
+	t.Helper()
+	savedIPFS, savedSandbox, savedVerify := ipfsShell, scriptSandbox, *verifyExecution
+	t.Cleanup(func() { ipfsShell, scriptSandbox, *verifyExecution = savedIPFS, savedSandbox, savedVerify })
+	ipfsShell, scriptSandbox, *verifyExecution = newFakeIPFS(), shellSandbox{}, true
+}
+
+// A signed transaction claiming that the upper-casing script turns "hello"
+// into result.
+func reexecTransaction(t *testing.T, result string) Transaction {
+	t.Helper()
+	scriptCID, _ := ipfsShell.Add(strings.NewReader(`tr a-z A-Z < "$1"`))
+	dataCID, _ := ipfsShell.Add(strings.NewReader("hello"))
+	tx := Transaction{
+		ID:         generateTransactionID(result),
+		Data:       result,
+		ScriptCID:  scriptCID,
+		DataCID:    dataCID,
+		ResultHash: resultHash(result),
+		NetworkID:  networkID,
+	}
+	if err := signTransaction(&tx); err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func TestValidateBlockReexecutes(t *testing.T) {
+	setupTestMiner(t)
+	setupReexecution(t)
+
+	for _, test := range []struct {
+		result string
+		valid  bool
+	}{
+		{"HELLO", true},
+		{"GOODBYE", false},
+	} {
+		block := mineTestBlock(t, reexecTransaction(t, test.result))
+		data, _ := json.Marshal(block)
+		if got := validateBlock(string(data), genesisBlock.Hash, currentTarget()); got != test.valid {
+			t.Errorf("block claiming %q: validateBlock = %v, want %v", test.result, got, test.valid)
+		}
+	}
+}
+
+func TestResultHashMustMatchData(t *testing.T) {
+	setupTestMiner(t)
+	setupReexecution(t)
+
+	tx := reexecTransaction(t, "HELLO")
+	tx.ResultHash = resultHash("GOODBYE")
+	if err := signTransaction(&tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkTransaction(tx); err == nil {
+		t.Error("accepted a plaintext result whose hash does not match its data")
+	}
+}
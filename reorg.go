@@ -0,0 +1,45 @@
+package main
+
+// chainReorg is a switch of the main chain from one branch to another at a
+// fork point. It is published as eventReorgDetected once done.
+type chainReorg struct {
+	ForkHeight int     `json:"forkHeight"` // Height of the first block that differs
+	Removed    []Block `json:"removed"`    // Blocks rolled back, oldest first
+	Added      []Block `json:"added"`      // Blocks applied in their place, oldest first
+}
+
+// The reorg back to the branch this one left.
+func (r chainReorg) inverse() chainReorg {
+	return chainReorg{ForkHeight: r.ForkHeight, Removed: r.Added, Added: r.Removed}
+}
+
+// reorgHandler is state derived from the main chain that follows it across
+// reorgs.
+type reorgHandler interface {
+	// Roll back the removed blocks and apply the added ones as one change:
+	// on error the handler is left as it was.
+	ApplyReorg(reorg chainReorg) error
+}
+
+// The handlers a reorg is delivered to, in order. Looked up on each reorg
+// since the stores behind them can be replaced.
+func reorgHandlers() []reorgHandler {
+	return []reorgHandler{ledger, receipts}
+}
+
+// Deliver a reorg to every handler. If one refuses it, the handlers that
+// already applied it are switched back and its error is returned.
+func notifyReorg(reorg chainReorg) error {
+	handlers := reorgHandlers()
+	for i, handler := range handlers {
+		if err := handler.ApplyReorg(reorg); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				if err := handlers[j].ApplyReorg(reorg.inverse()); err != nil {
+					chainLog.Error("Error undoing reorg", "forkHeight", reorg.ForkHeight, "err", err)
+				}
+			}
+			return err
+		}
+	}
+	return nil
+}
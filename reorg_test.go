@@ -0,0 +1,67 @@
+package main
+
+import (
+	"maps"
+	"testing"
+)
+
+// A transaction from the test wallet paying fee.
+func feeTestTransaction(t *testing.T, data string, fee int64) Transaction {
+	t.Helper()
+	tx := Transaction{ID: generateTransactionID(data), Data: data, Fee: fee, NetworkID: networkID}
+	if err := signTransaction(&tx); err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func TestLedgerAppliesReorgAtomically(t *testing.T) {
+	setupTestMiner(t)
+	funding := Block{BlockNumber: 1, Hash: "funding", Transactions: []Transaction{coinbaseTransaction(1, walletAddress, 0)}}
+	l := NewLedger()
+	l.ApplyBlock(funding)
+	before := l.Snapshot()
+
+	// Without the funding block the wallet cannot pay the fee
+	spend := feeTestTransaction(t, "spend", 10)
+	unfunded := chainReorg{ForkHeight: 1, Removed: []Block{funding}, Added: []Block{
+		{BlockNumber: 1, Hash: "other", Transactions: []Transaction{coinbaseTransaction(1, "miner", 0)}},
+		{BlockNumber: 2, Hash: "spend", Transactions: []Transaction{coinbaseTransaction(2, "miner", 10), spend}},
+	}}
+	if err := l.ApplyReorg(unfunded); err == nil {
+		t.Fatal("reorg spending an unfunded balance was applied")
+	}
+	if !maps.Equal(l.Snapshot(), before) {
+		t.Fatalf("refused reorg changed the balances to %v", l.Snapshot())
+	}
+
+	funded := chainReorg{ForkHeight: 2, Added: []Block{
+		{BlockNumber: 2, Hash: "spend", Transactions: []Transaction{coinbaseTransaction(2, "miner", 10), spend}},
+	}}
+	if err := l.ApplyReorg(funded); err != nil {
+		t.Fatal(err)
+	}
+	if l.Balance(walletAddress) != blockReward-10 || l.Balance("miner") != blockReward+10 {
+		t.Fatalf("balances after reorg are %v", l.Snapshot())
+	}
+	if err := l.ApplyReorg(funded.inverse()); err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(l.Snapshot(), before) {
+		t.Errorf("undoing the reorg left balances %v", l.Snapshot())
+	}
+}
+
+func TestReceiptsMoveAcrossReorg(t *testing.T) {
+	store := NewReceiptStore()
+	id := store.Open(jobRequest{})
+	store.Pending(id, "tx1")
+	old := Block{BlockNumber: 2, Hash: "old", Transactions: []Transaction{{ID: "tx1"}}}
+	store.BlockConnected(old)
+
+	replacement := Block{BlockNumber: 3, Hash: "new", Transactions: []Transaction{{ID: "tx1"}}}
+	store.ApplyReorg(chainReorg{ForkHeight: 2, Removed: []Block{old}, Added: []Block{{BlockNumber: 2, Hash: "filler"}, replacement}})
+	if receipt, _ := store.Get(id); receipt.Status != jobMined || receipt.BlockHash != "new" || receipt.Block != 3 {
+		t.Errorf("receipt after reorg is %+v", receipt)
+	}
+}
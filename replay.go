@@ -0,0 +1,86 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+)
+
+var txLifetime = flag.Int("tx-lifetime", 1000, "blocks the transaction of a job stays minable before it expires (0 for no expiry)")
+
+// What identifies a signed transaction against replays: the hex SHA-256 of
+// its sender's address and the payload it signed. Re-signing cannot change
+// it, unlike the signature, and transactions with equal results but
+// different jobs, senders or expiry heights keep apart, unlike the ID.
+// Empty for unsigned transactions.
+func transactionDigest(tx Transaction) string {
+	sender, err := transactionSender(tx)
+	if err != nil {
+		return ""
+	}
+	payload, err := transactionSigningPayload(tx)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(append([]byte(sender+"\n"), payload...))
+	return hex.EncodeToString(sum[:])
+}
+
+// Expiry height of a job's transaction if it is created now.
+func jobExpiryHeight() int {
+	if *txLifetime <= 0 {
+		return 0
+	}
+	return chainStore.Height() + *txLifetime
+}
+
+// Check that no signed transaction of a block appears twice in it or in
+// the chain the block extends, which may be a side branch. Called with
+// chainMu held.
+func checkReplays(block Block) error {
+	if *lightMode {
+		return nil // Headers only
+	}
+	digests := make(map[string]string) // Transaction ID by digest
+	for _, tx := range block.Transactions {
+		digest := transactionDigest(tx)
+		if digest == "" {
+			continue
+		}
+		if _, ok := digests[digest]; ok {
+			return fmt.Errorf("transaction %s appears twice in block %s", tx.ID, block.Hash)
+		}
+		digests[digest] = tx.ID
+	}
+	if len(digests) == 0 {
+		return nil
+	}
+
+	// Side-branch ancestors down to the fork point
+	hash := block.PrevHash
+	for {
+		side, ok := sideBlocks[hash]
+		if !ok {
+			break
+		}
+		for _, tx := range side.block.Transactions {
+			if id, ok := digests[transactionDigest(tx)]; ok {
+				return fmt.Errorf("transaction %s was already mined in block %s", id, side.block.Hash)
+			}
+		}
+		hash = side.block.PrevHash
+	}
+
+	// Main chain blocks up to the fork point
+	fork, err := chainStore.GetBlockByHash(hash)
+	if err != nil {
+		return nil // Unknown parent, refused by the caller
+	}
+	for digest, id := range digests {
+		if number, ok := chainStore.SignedAt(digest); ok && number <= fork.BlockNumber {
+			return fmt.Errorf("transaction %s was already mined in block %d", id, number)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReplayedTransactionsAreRejected(t *testing.T) {
+	setupTestMiner(t)
+	// acceptBlock leaves proof of work to validation, so skip mining
+	build := func(parent Block, nonce int, transactions ...Transaction) Block {
+		block := blockTemplate(parent.BlockNumber+1, parent.Hash, parent.PrevCID, transactions)
+		block.Nonce = nonce
+		block.Hash = block.ComputeHash()
+		return block
+	}
+
+	tx := signedTestTransaction(t, "result")
+	first := build(genesisBlock, 0, tx)
+	if err := acceptBlock(first); err != nil {
+		t.Fatal(err)
+	}
+
+	// Signing again does not make the same transaction new
+	resigned := tx
+	if err := signTransaction(&resigned); err != nil {
+		t.Fatal(err)
+	}
+	for _, replay := range []Transaction{tx, resigned} {
+		if err := acceptBlock(build(first, 0, replay)); err == nil {
+			t.Error("replay on the main chain was accepted")
+		}
+		if err := mempool.Add(replay, priorityNormal); err != errAlreadyMined {
+			t.Errorf("replay into the mempool: %v", err)
+		}
+	}
+	if err := acceptBlock(build(genesisBlock, 1, tx, resigned)); err == nil {
+		t.Error("block holding a transaction twice was accepted")
+	}
+
+	// A side branch is checked against its own ancestors
+	side := signedTestTransaction(t, "side")
+	fork := build(genesisBlock, 2, side)
+	if err := acceptBlock(fork); err != nil {
+		t.Fatal(err)
+	}
+	if err := acceptBlock(build(fork, 0, side)); err == nil {
+		t.Error("replay on a side branch was accepted")
+	}
+	if err := acceptBlock(build(fork, 0, tx)); err != nil {
+		t.Errorf("transaction mined only on the other branch: %v", err)
+	}
+
+	// The same result under another expiry height is another transaction
+	again := Transaction{ID: tx.ID, Data: tx.Data, NetworkID: networkID, ExpiryHeight: 10}
+	if err := signTransaction(&again); err != nil {
+		t.Fatal(err)
+	}
+	if err := mempool.Add(again, priorityNormal); err != nil {
+		t.Errorf("new transaction with a mined ID: %v", err)
+	}
+}
+
+func TestExpiredTransactionsLeaveMempool(t *testing.T) {
+	setupTestMiner(t)
+	expiring := Transaction{ID: generateTransactionID("soon"), Data: "soon", NetworkID: networkID, ExpiryHeight: 2}
+	if err := signTransaction(&expiring); err != nil {
+		t.Fatal(err)
+	}
+	if err := mempool.Add(expiring, priorityNormal); err != nil {
+		t.Fatal(err)
+	}
+	if got := mempool.Select(10, 2, time.Now().Unix()); len(got) != 0 {
+		t.Errorf("expired transaction selected for block 2: %v", got)
+	}
+
+	block := blockTemplate(1, genesisBlock.Hash, genesisBlock.PrevCID, nil)
+	block.Hash = block.ComputeHash()
+	if err := acceptBlock(block); err != nil {
+		t.Fatal(err)
+	}
+	if mempool.Len() != 0 {
+		t.Error("transaction that can no longer be mined is still pending")
+	}
+	if err := mempool.Add(expiring, priorityNormal); err != errExpired {
+		t.Errorf("adding an expired transaction: %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Runtime of jobs that name none.
+const defaultRuntime = "python3"
+
+var (
+	enabledRuntimes = flag.String("runtimes", defaultRuntime, "comma-separated runtimes this node runs job scripts in: "+strings.Join(runtimeNames(), ", "))
+
+	// Runtimes a job script may be written for, by name
+	runtimes = map[string]scriptRuntime{
+		"python3": {Extensions: []string{".py"}, Command: []string{"python3", "{script}", "{data}"}, Image: "python:3-slim"},
+		"node":    {Extensions: []string{".js", ".mjs"}, Command: []string{"node", "{script}", "{data}"}, Image: "node:22-slim"},
+		"bash":    {Extensions: []string{".sh"}, Command: []string{"bash", "{script}", "{data}"}, Image: "bash:5"},
+		"wasm":    {Extensions: []string{".wasm"}, Run: executeWASM},
+	}
+
+	allowedRuntimes = map[string]bool{defaultRuntime: true} // Set by setupRuntimes
+)
+
+// scriptRuntime describes how job scripts of one language are run.
+type scriptRuntime struct {
+	Name       string
+	Extensions []string // Script file extensions, the first used inside containers
+	Command    []string // Command template; {script} and {data} are replaced by the file paths
+	Image      string   // Container image the docker sandbox runs it in
+
+	// Runs a script in process instead of in the sandbox, if set, and
+	// reports the execution units it metered
+	Run func(ctx context.Context, scriptPath, dataPath, outputDir string, params *ScriptParams) (string, int64, error)
+}
+
+// Command line that runs scriptPath on dataPath.
+func (r scriptRuntime) Args(scriptPath, dataPath string) []string {
+	args := make([]string, len(r.Command))
+	for i, arg := range r.Command {
+		args[i] = strings.NewReplacer("{script}", scriptPath, "{data}", dataPath).Replace(arg)
+	}
+	return args
+}
+
+func runtimeNames() []string {
+	names := make([]string, 0, len(runtimes))
+	for name := range runtimes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Look up a runtime by name; an empty name is the default runtime.
+func lookupRuntime(name string) (scriptRuntime, error) {
+	if name == "" {
+		name = defaultRuntime
+	}
+	runtime, ok := runtimes[name]
+	if !ok {
+		return scriptRuntime{}, fmt.Errorf("unknown runtime %q", name)
+	}
+	runtime.Name = name
+	return runtime, nil
+}
+
+// Look up a runtime this node is configured to run.
+func enabledRuntime(name string) (scriptRuntime, error) {
+	runtime, err := lookupRuntime(name)
+	if err != nil {
+		return scriptRuntime{}, err
+	}
+	if !allowedRuntimes[runtime.Name] {
+		return scriptRuntime{}, fmt.Errorf("runtime %s is not enabled on this node", runtime.Name)
+	}
+	return runtime, nil
+}
+
+// Name of the runtime whose extensions include the file's, or "" if none.
+func runtimeForFile(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, name := range runtimeNames() {
+		if slices.Contains(runtimes[name].Extensions, ext) {
+			return name
+		}
+	}
+	return ""
+}
+
+// Enable the runtimes configured by flags.
+func setupRuntimes() error {
+	allowed := make(map[string]bool)
+	for _, name := range strings.Split(*enabledRuntimes, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := runtimes[name]; !ok {
+			return fmt.Errorf("unknown runtime %q", name)
+		}
+		allowed[name] = true
+	}
+	if len(allowed) == 0 {
+		return fmt.Errorf("no runtimes enabled")
+	}
+	allowedRuntimes = allowed
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRuntimeArgs(t *testing.T) {
+	runtime, err := lookupRuntime("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.Name != defaultRuntime {
+		t.Errorf("default runtime is %s", runtime.Name)
+	}
+	if got := strings.Join(runtime.Args("s.py", "d.txt"), " "); got != "python3 s.py d.txt" {
+		t.Errorf("python3 runs %q", got)
+	}
+	if _, err := lookupRuntime("cobol"); err == nil {
+		t.Error("unknown runtime found")
+	}
+}
+
+func TestRuntimeForFile(t *testing.T) {
+	for path, want := range map[string]string{
+		"job.py":      "python3",
+		"dir/job.JS":  "node",
+		"job.mjs":     "node",
+		"job.sh":      "bash",
+		"job.wasm":    "wasm",
+		"job.unknown": "",
+	} {
+		if got := runtimeForFile(path); got != want {
+			t.Errorf("runtime for %s is %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestJobRuntimeOption(t *testing.T) {
+	job, err := parseJobMessage(jobMessage(testScriptCID, testDataCID, clientOptions{runtime: "node"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Runtime != "node" {
+		t.Errorf("parsed runtime %q", job.Runtime)
+	}
+	if _, err := parseJobMessage(testJobCIDs + " runtime=cobol"); err == nil {
+		t.Error("job with unknown runtime parsed without error")
+	}
+}
+
+func TestSetupRuntimes(t *testing.T) {
+	saved, savedAllowed := *enabledRuntimes, allowedRuntimes
+	t.Cleanup(func() { *enabledRuntimes, allowedRuntimes = saved, savedAllowed })
+
+	*enabledRuntimes = "python3, bash"
+	if err := setupRuntimes(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := enabledRuntime("bash"); err != nil {
+		t.Error(err)
+	}
+	if _, err := enabledRuntime("node"); err == nil {
+		t.Error("disabled runtime is usable")
+	}
+
+	for _, value := range []string{"python3,cobol", " , "} {
+		*enabledRuntimes = value
+		if err := setupRuntimes(); err == nil {
+			t.Errorf("runtimes %q accepted", value)
+		}
+	}
+}
+
+func TestExecuteScriptInRuntime(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+	saved := scriptSandbox
+	t.Cleanup(func() { scriptSandbox = saved })
+	scriptSandbox = plainSandbox{}
+
+	dir := t.TempDir()
+	scriptPath, dataPath := filepath.Join(dir, "job.sh"), filepath.Join(dir, "data.txt")
+	os.WriteFile(scriptPath, []byte(`echo "${BASH_VERSION:+bash} $(cat "$1")"`), 0o600)
+	os.WriteFile(dataPath, []byte("data"), 0o600)
+
+	runtime, _ := lookupRuntime("bash")
+	result, _, err := executeScript(context.Background(), runtime, nil, scriptPath, dataPath, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "bash data\n" {
+		t.Errorf("script printed %q", result)
+	}
+}
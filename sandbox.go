@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+var (
+	sandboxName   = flag.String("sandbox", "process", "how job scripts are isolated: process, docker or none")
+	scriptTimeout = flag.Duration("script-timeout", time.Minute, "wall-clock limit after which a job script is killed")
+	scriptCPU     = flag.Int("script-cpu", 30, "CPU seconds a job script may use")
+	scriptMemory  = flag.Int("script-memory", 512, "memory limit of a job script in MiB")
+	sandboxImage  = flag.String("sandbox-image", "python:3-slim", "container image the docker sandbox runs python3 jobs in")
+
+	sandboxes = map[string]func() (sandbox, error){
+		"none":    func() (sandbox, error) { return plainSandbox{}, nil },
+		"process": newProcessSandbox,
+		"docker":  func() (sandbox, error) { return dockerSandbox{}, nil },
+	}
+
+	scriptSandbox sandbox // Sandbox job scripts run in
+)
+
+// sandbox builds the command that runs a job script on its data, with the
+// job's parameters if any, in the script's runtime. The script may write
+// files to outputDir, if not empty, which it finds in $JOB_OUTPUT_DIR. The
+// command is killed when ctx is done.
+type sandbox interface {
+	Command(ctx context.Context, runtime scriptRuntime, scriptPath, dataPath, outputDir string, params *ScriptParams) (*exec.Cmd, error)
+}
+
+// Select the sandbox configured by flags.
+func setupSandbox() error {
+	newSandbox, ok := sandboxes[*sandboxName]
+	if !ok {
+		return fmt.Errorf("unknown sandbox %q", *sandboxName)
+	}
+	s, err := newSandbox()
+	if err != nil {
+		return err
+	}
+	scriptSandbox = s
+	return nil
+}
+
+// plainSandbox runs scripts with the node's privileges, limited only by the
+// timeout.
+type plainSandbox struct{}
+
+func (plainSandbox) Command(ctx context.Context, runtime scriptRuntime, scriptPath, dataPath, outputDir string, params *ScriptParams) (*exec.Cmd, error) {
+	args := append(runtime.Args(scriptPath, dataPath), params.args()...)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	if vars := append(params.environ(), outputEnv(outputDir)...); len(vars) > 0 {
+		cmd.Env = append(os.Environ(), vars...)
+	}
+	killProcessGroup(cmd)
+	return cmd, nil
+}
+
+// processSandbox runs scripts as a subprocess with CPU, memory, file size
+// and open file limits, an empty environment and a private working
+// directory. Where unprivileged user namespaces are available it also
+// gives the script a network namespace without interfaces.
+type processSandbox struct {
+	noNetwork bool
+}
+
+func newProcessSandbox() (sandbox, error) {
+	var s processSandbox
+	if exec.Command("unshare", "--map-root-user", "--net", "true").Run() == nil {
+		s.noNetwork = true
+	} else {
+		txprocLog.Warn("User namespaces unavailable, job scripts keep network access")
+	}
+	return s, nil
+}
+
+func (s processSandbox) Command(ctx context.Context, runtime scriptRuntime, scriptPath, dataPath, outputDir string, params *ScriptParams) (*exec.Cmd, error) {
+	scriptPath, err := filepath.Abs(scriptPath)
+	if err != nil {
+		return nil, err
+	}
+	dataPath, err = filepath.Abs(dataPath)
+	if err != nil {
+		return nil, err
+	}
+	if outputDir != "" {
+		if outputDir, err = filepath.Abs(outputDir); err != nil {
+			return nil, err
+		}
+	}
+	command := append(runtime.Args(scriptPath, dataPath), params.args()...)
+	// The script's environment has no PATH of the node's, so resolve it here
+	if command[0], err = exec.LookPath(command[0]); err != nil {
+		return nil, fmt.Errorf("runtime %s is not installed: %v", runtime.Name, err)
+	}
+	dir, err := os.MkdirTemp("", "job")
+	if err != nil {
+		return nil, err
+	}
+
+	limits := fmt.Sprintf("ulimit -t %d && ulimit -v %d && ulimit -f %d && ulimit -n 64 && exec \"$@\"",
+		*scriptCPU, *scriptMemory*1024, 100*1024)
+	args := append([]string{"-c", limits, "sh"}, command...)
+	name := "sh"
+	if s.noNetwork {
+		name, args = "unshare", append([]string{"--map-root-user", "--net", "sh"}, args...)
+	}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Env = append([]string{"PATH=/usr/bin:/bin", "HOME=" + dir, "TMPDIR=" + dir}, params.environ()...)
+	cmd.Env = append(cmd.Env, outputEnv(outputDir)...)
+	killProcessGroup(cmd)
+	return cmd, nil
+}
+
+// dockerSandbox runs scripts in a throwaway container with no network, a
+// read-only root filesystem and the inputs mounted read-only. The
+// container is the runtime's image unless the job pins an environment.
+type dockerSandbox struct {
+	env *ExecutionEnv // Optional
+}
+
+func (s dockerSandbox) Command(ctx context.Context, runtime scriptRuntime, scriptPath, dataPath, outputDir string, params *ScriptParams) (*exec.Cmd, error) {
+	scriptPath, err := filepath.Abs(scriptPath)
+	if err != nil {
+		return nil, err
+	}
+	dataPath, err = filepath.Abs(dataPath)
+	if err != nil {
+		return nil, err
+	}
+	suffix := make([]byte, 8)
+	rand.Read(suffix)
+	name := "job-" + hex.EncodeToString(suffix)
+
+	image, command := runtime.Image, runtime.Args("/job/script"+runtime.Extensions[0], "/job/data.txt")
+	if runtime.Name == defaultRuntime {
+		image = *sandboxImage
+	}
+	if s.env != nil {
+		image = s.env.Image
+		if s.env.Interpreter != "" {
+			command[0] = s.env.Interpreter
+		}
+	}
+
+	args := []string{"run", "--rm", "--name", name,
+		"--network", "none", "--read-only", "--tmpfs", "/tmp",
+		"--memory", fmt.Sprintf("%dm", *scriptMemory), "--ulimit", fmt.Sprintf("cpu=%d", *scriptCPU),
+		"--pids-limit", "64", "--cap-drop", "ALL", "--security-opt", "no-new-privileges",
+		"-v", scriptPath + ":/job/script" + runtime.Extensions[0] + ":ro", "-v", dataPath + ":/job/data.txt:ro"}
+	if outputDir != "" {
+		outputDir, err = filepath.Abs(outputDir)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "-v", outputDir+":/job/output")
+	}
+	vars := params.environ()
+	if outputDir != "" {
+		vars = append(vars, outputEnv("/job/output")...)
+	}
+	for _, v := range vars {
+		args = append(args, "-e", v)
+	}
+	args = append(append(args, image), command...)
+	cmd := exec.CommandContext(ctx, "docker", append(args, params.args()...)...)
+	// Killing the client leaves the container running, so kill it by name
+	cmd.Cancel = func() error {
+		exec.Command("docker", "kill", name).Run()
+		return cmd.Process.Kill()
+	}
+	return cmd, nil
+}
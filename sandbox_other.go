@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+import "os/exec"
+
+// Process groups are not available; only the script process is killed.
+func killProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net"
+	"strings"
+	"time"
+)
+
+var (
+	dnsSeeds        = flag.String("dns-seeds", "", "comma-separated DNS names whose A/AAAA and TXT records list bootstrap miners")
+	dnsSeedInterval = flag.Duration("dns-seed-interval", 30*time.Minute, "how often DNS seeds are re-resolved")
+)
+
+// Resolve the DNS seeds and add any new miners to the peer manager.
+func resolveDNSSeeds() {
+	for _, seed := range strings.Split(*dnsSeeds, ",") {
+		seed = strings.TrimSpace(seed)
+		if seed == "" {
+			continue
+		}
+
+		var found []string
+		addrs, err := net.LookupHost(seed)
+		if err != nil {
+			p2pLog.Warn("Error resolving DNS seed", "seed", seed, "err", err)
+		}
+		found = append(found, addrs...)
+
+		// TXT records hold comma- or space-separated miner IPs
+		records, _ := net.LookupTXT(seed)
+		for _, record := range records {
+			for _, field := range strings.FieldsFunc(record, func(r rune) bool { return r == ',' || r == ' ' }) {
+				if net.ParseIP(field) != nil {
+					found = append(found, field)
+				}
+			}
+		}
+
+		for _, miner := range found {
+			if peerManager.Add(miner) {
+				p2pLog.Info("Discovered miner from DNS seed", "peer", miner, "seed", seed)
+			}
+		}
+	}
+}
+
+// DNS Seed Thread
+func refreshDNSSeeds(ctx context.Context) {
+	ticker := time.NewTicker(*dnsSeedInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		resolveDNSSeeds()
+	}
+}
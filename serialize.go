@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Versions of the block hash preimage layout. Version 1 blocks are hashed
+// with their transactions. Version 2 blocks are hashed over the header
+// alone, which commits to the transactions through the Merkle root, so
+// light nodes can check their proof of work without the transactions.
+const (
+	blockHashVersion  = 1
+	headerHashVersion = 2
+)
+
+// Fields covered by the block hash, in their fixed order. Hash is excluded
+// because it cannot cover itself, and PrevCID because it is assigned after
+// mining. New block fields must be added here explicitly, with omitempty,
+// so that the hashes of existing blocks do not change. Transactions is nil
+// in the hash preimage of version 2 blocks.
+type blockHashPreimage struct {
+	Version      int            `json:"version"`
+	BlockNumber  int            `json:"blockNumber"`
+	PrevHash     string         `json:"prevHash"`
+	MerkleRoot   string         `json:"merkleRoot"`
+	Transactions *[]Transaction `json:"transactions,omitempty"`
+	Timestamp    int64          `json:"timestamp,omitempty"`
+	NetworkID    string         `json:"networkId,omitempty"`
+	Target       string         `json:"target,omitempty"`
+	MinerID      string         `json:"minerId,omitempty"`
+	MinerSig     *Signature     `json:"minerSig,omitempty"`
+	Units        int64          `json:"units,omitempty"`
+}
+
+// SerializeForHash returns the canonical encoding of the block that its hash
+// is computed over: the JSON object of blockHashPreimage followed by the
+// nonce. Every node hashes exactly these bytes.
+func (b Block) SerializeForHash() []byte {
+	return appendNonce(b.hashPrefix(), b.Nonce)
+}
+
+// Serialize returns the canonical encoding of the whole block, as stored in
+// IPFS: the hash preimage with the transactions, followed by the nonce. For
+// version 1 blocks it is SerializeForHash.
+func (b Block) Serialize() []byte {
+	return appendNonce(b.preimagePrefix(true), b.Nonce)
+}
+
+// Header returns the block without its transactions, as light nodes keep
+// it. Version 1 blocks cannot be checked without their transactions, so
+// they are returned whole.
+func (b Block) Header() Block {
+	if b.hashVersion() != blockHashVersion {
+		b.Transactions = nil
+	}
+	return b
+}
+
+// Layout version of the block's hash preimage. Blocks from before versions
+// were recorded have Version 0.
+func (b Block) hashVersion() int {
+	if b.Version == 0 {
+		return blockHashVersion
+	}
+	return b.Version
+}
+
+// ComputeHash returns the hex SHA-256 of the canonical serialization.
+func (b Block) ComputeHash() string {
+	sum := sha256.Sum256(b.SerializeForHash())
+	return hex.EncodeToString(sum[:])
+}
+
+// The canonical serialization up to the nonce, which is always last so the
+// miner can reuse the prefix for every nonce it tries.
+func (b Block) hashPrefix() []byte {
+	return b.preimagePrefix(b.hashVersion() == blockHashVersion)
+}
+
+// The blockHashPreimage object without its closing brace, with or without
+// the transactions.
+func (b Block) preimagePrefix(withTransactions bool) []byte {
+	preimage := blockHashPreimage{
+		Version:     b.hashVersion(),
+		BlockNumber: b.BlockNumber,
+		PrevHash:    b.PrevHash,
+		MerkleRoot:  b.MerkleRoot,
+		Timestamp:   b.Timestamp,
+		NetworkID:   b.NetworkID,
+		Target:      b.Target,
+		MinerID:     b.MinerID,
+		MinerSig:    b.MinerSig,
+		Units:       b.Units,
+	}
+	if withTransactions {
+		transactions := b.Transactions
+		if transactions == nil {
+			transactions = []Transaction{}
+		}
+		preimage.Transactions = &transactions
+	}
+	// Marshalling strings, ints and Transactions cannot fail
+	data, _ := json.Marshal(preimage)
+	return data[:len(data)-1] // Drop the closing brace
+}
+
+// The bytes a miner signs: the hashed header without the transactions,
+// which the Merkle root commits to, and without the signature itself. The
+// nonce is found after signing, so it is not covered, but the hash covers
+// the signature.
+func (b Block) headerSigningBytes() []byte {
+	b.Transactions, b.MinerSig = nil, nil
+	return b.hashPrefix()
+}
+
+// Decode a canonical serialization back into a block, as stored in IPFS.
+// Hash is recomputed from the data; PrevCID is not covered and stays empty.
+// Anything but the exact bytes Serialize produces is rejected, so a decoded
+// block always hashes to the hash of the data it came from.
+func parseBlockSerialization(data []byte) (Block, error) {
+	var decoded struct {
+		blockHashPreimage
+		Nonce *int `json:"nonce"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&decoded); err != nil {
+		return Block{}, fmt.Errorf("failed to decode block: %v", err)
+	}
+	if decoded.Version != blockHashVersion && decoded.Version != headerHashVersion {
+		return Block{}, fmt.Errorf("unsupported block serialization version %d", decoded.Version)
+	}
+	if decoded.Nonce == nil {
+		return Block{}, errors.New("block has no nonce")
+	}
+	if decoded.Transactions == nil {
+		return Block{}, errors.New("block has no transactions")
+	}
+
+	block := Block{
+		PrevHash:     decoded.PrevHash,
+		MerkleRoot:   decoded.MerkleRoot,
+		Transactions: *decoded.Transactions,
+		Nonce:        *decoded.Nonce,
+		BlockNumber:  decoded.BlockNumber,
+		Timestamp:    decoded.Timestamp,
+		NetworkID:    decoded.NetworkID,
+		Target:       decoded.Target,
+		MinerID:      decoded.MinerID,
+		MinerSig:     decoded.MinerSig,
+		Units:        decoded.Units,
+	}
+	if decoded.Version == headerHashVersion {
+		block.Version = headerHashVersion
+	}
+	if !bytes.Equal(block.Serialize(), data) {
+		return Block{}, errors.New("block is not in canonical form")
+	}
+	block.Hash = block.ComputeHash()
+	return block, nil
+}
+
+func appendNonce(prefix []byte, nonce int) []byte {
+	data := append(append(make([]byte, 0, len(prefix)+32), prefix...), `,"nonce":`...)
+	data = strconv.AppendInt(data, int64(nonce), 10)
+	return append(data, '}')
+}
@@ -0,0 +1,109 @@
+package main
+
+import "testing"
+
+func testBlock() Block {
+	return Block{
+		PrevHash:     "00ab",
+		MerkleRoot:   computeMerkleRoot([]Transaction{{ID: generateTransactionID("x"), Data: "x"}}),
+		Transactions: []Transaction{{ID: generateTransactionID("x"), Data: "x", Fee: 2}},
+		Nonce:        42,
+		BlockNumber:  7,
+		Timestamp:    1700000000,
+		NetworkID:    "test",
+	}
+}
+
+func TestComputeHashCoversHashedFields(t *testing.T) {
+	base := testBlock()
+	hash := base.ComputeHash()
+
+	for name, change := range map[string]func(*Block){
+		"nonce":        func(b *Block) { b.Nonce++ },
+		"prevHash":     func(b *Block) { b.PrevHash = "00ac" },
+		"merkleRoot":   func(b *Block) { b.MerkleRoot = "" },
+		"blockNumber":  func(b *Block) { b.BlockNumber++ },
+		"timestamp":    func(b *Block) { b.Timestamp++ },
+		"networkId":    func(b *Block) { b.NetworkID = "other" },
+		"transactions": func(b *Block) { b.Transactions = nil },
+	} {
+		block := testBlock()
+		change(&block)
+		if block.ComputeHash() == hash {
+			t.Errorf("changing %s does not change the hash", name)
+		}
+	}
+
+	for name, change := range map[string]func(*Block){
+		"hash":    func(b *Block) { b.Hash = "ff" },
+		"prevCID": func(b *Block) { b.PrevCID = "cid" },
+	} {
+		block := testBlock()
+		change(&block)
+		if block.ComputeHash() != hash {
+			t.Errorf("changing %s changes the hash", name)
+		}
+	}
+}
+
+func TestEmptyAndNilTransactionsHashAlike(t *testing.T) {
+	withNil, withEmpty := testBlock(), testBlock()
+	withNil.Transactions, withEmpty.Transactions = nil, []Transaction{}
+	if withNil.ComputeHash() != withEmpty.ComputeHash() {
+		t.Fatal("nil and empty transaction lists hash differently")
+	}
+}
+
+func TestParseBlockSerializationRoundTrips(t *testing.T) {
+	block := testBlock()
+	block.Hash = block.ComputeHash()
+
+	parsed, err := parseBlockSerialization(block.SerializeForHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Hash != block.Hash || parsed.Nonce != block.Nonce || parsed.Transactions[0].Fee != 2 {
+		t.Fatalf("parsed %+v, want %+v", parsed, block)
+	}
+}
+
+func TestParseBlockSerializationRejectsOtherEncodings(t *testing.T) {
+	for name, data := range map[string]string{
+		"not JSON":      "Block{}",
+		"no nonce":      `{"version":1,"blockNumber":0,"prevHash":"","merkleRoot":"","transactions":[]}`,
+		"wrong version": `{"version":3,"blockNumber":0,"prevHash":"","merkleRoot":"","transactions":[],"nonce":1}`,
+		"extra field":   `{"version":1,"blockNumber":0,"prevHash":"","merkleRoot":"","transactions":[],"hash":"","nonce":1}`,
+		"whitespace":    `{"version":1, "blockNumber":0,"prevHash":"","merkleRoot":"","transactions":[],"nonce":1}`,
+	} {
+		if _, err := parseBlockSerialization([]byte(data)); err == nil {
+			t.Errorf("%s: parsed without error", name)
+		}
+	}
+}
+
+func TestHeaderHashLeavesOutTransactions(t *testing.T) {
+	block := testBlock()
+	block.Version = headerHashVersion
+	hash := block.ComputeHash()
+
+	if header := block.Header(); header.Transactions != nil || header.ComputeHash() != hash {
+		t.Fatal("the header of a version 2 block does not hash like the block")
+	}
+	changed := block
+	changed.MerkleRoot = ""
+	if changed.ComputeHash() == hash {
+		t.Error("changing the Merkle root does not change the hash")
+	}
+	if legacy := testBlock(); legacy.Header().Transactions == nil {
+		t.Error("the header of a version 1 block dropped the transactions it is hashed with")
+	}
+
+	// IPFS holds the whole block, which still decodes to the same hash
+	parsed, err := parseBlockSerialization(block.Serialize())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Hash != hash || parsed.Version != headerHashVersion || len(parsed.Transactions) != 1 {
+		t.Fatalf("parsed %+v, want %+v", parsed, block)
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Signer signs payloads for a miner identity or reward address. Callers
+// only see the public key, so the private key can live in an HSM or a
+// hardware wallet instead of host memory.
+type Signer interface {
+	Public() crypto.PublicKey
+	Sign(payload []byte) ([]byte, error)
+}
+
+// Backends that open a hardware-held key from a backend-specific spec.
+// Each backend is registered by its own file, usually behind a build tag.
+var signerBackends = map[string]func(spec string) (crypto.Signer, error){}
+
+// cryptoSigner adapts any crypto.Signer (software key, PKCS#11 object,
+// hardware wallet) to the Signer interface.
+type cryptoSigner struct {
+	key crypto.Signer
+}
+
+// Open a signer from a spec of the form "file:<keystore path>" for an
+// encrypted software key or "<backend>:<backend spec>" for hardware keys.
+func openSigner(spec string) (Signer, error) {
+	kind, rest, ok := strings.Cut(spec, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid signer spec %q, expected <kind>:<spec>", spec)
+	}
+
+	if kind == "file" {
+		pass, err := keystorePassphrase()
+		if err != nil {
+			return nil, err
+		}
+		key, err := loadEncryptedKey(rest, pass)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("key in %s cannot sign", rest)
+		}
+		return cryptoSigner{key: signer}, nil
+	}
+
+	open, ok := signerBackends[kind]
+	if !ok {
+		return nil, fmt.Errorf("signer backend %q is not available in this build", kind)
+	}
+	key, err := open(rest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s signer: %v", kind, err)
+	}
+	return cryptoSigner{key: key}, nil
+}
+
+func (s cryptoSigner) Public() crypto.PublicKey {
+	return s.key.Public()
+}
+
+// Sign the payload. ECDSA keys sign its SHA-256 digest, Ed25519 keys sign
+// the payload itself.
+func (s cryptoSigner) Sign(payload []byte) ([]byte, error) {
+	alg, err := signatureAlgorithmFor(s.key.Public())
+	if err != nil {
+		return nil, err
+	}
+	if alg == sigAlgECDSAP256 {
+		digest := sha256.Sum256(payload)
+		return s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	return s.key.Sign(rand.Reader, payload, crypto.Hash(0))
+}
+
+// Signature algorithm identifiers. The identifier is covered by the
+// signature, so a signature can never be reinterpreted under another scheme.
+const (
+	sigAlgECDSAP256 = "ecdsa-p256-sha256"
+	sigAlgEd25519   = "ed25519"
+)
+
+// Signing domains keep a transaction signature from being replayed as a
+// vote or a block signature.
+const (
+	sigDomainTransaction = "tx"
+	sigDomainVote        = "vote"
+	sigDomainBlock       = "block"
+	sigDomainCheckpoint  = "checkpoint"
+	sigDomainHello       = "hello"
+	sigDomainHelloProof  = "hello-proof"
+)
+
+// A signature scheme. New schemes are added by registering another entry.
+type signatureAlgorithm struct {
+	matches func(pub crypto.PublicKey) bool
+	verify  func(pub crypto.PublicKey, payload, sig []byte) bool
+}
+
+var signatureAlgorithms = map[string]signatureAlgorithm{
+	sigAlgECDSAP256: {
+		matches: func(pub crypto.PublicKey) bool {
+			key, ok := pub.(*ecdsa.PublicKey)
+			return ok && key.Curve == elliptic.P256()
+		},
+		verify: func(pub crypto.PublicKey, payload, sig []byte) bool {
+			digest := sha256.Sum256(payload)
+			return ecdsa.VerifyASN1(pub.(*ecdsa.PublicKey), digest[:], sig)
+		},
+	},
+	sigAlgEd25519: {
+		matches: func(pub crypto.PublicKey) bool {
+			_, ok := pub.(ed25519.PublicKey)
+			return ok
+		},
+		verify: func(pub crypto.PublicKey, payload, sig []byte) bool {
+			return ed25519.Verify(pub.(ed25519.PublicKey), payload, sig)
+		},
+	},
+}
+
+// Signature is a self-describing signature carried by transactions, votes
+// and blocks.
+type Signature struct {
+	Algorithm string `json:"alg"`
+	PublicKey string `json:"pub"` // Hex PKIX DER
+	Value     string `json:"sig"` // Hex signature bytes
+}
+
+// Generate a new signing key for the named algorithm.
+func generateSigningKey(alg string) (crypto.Signer, error) {
+	switch alg {
+	case sigAlgECDSAP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case sigAlgEd25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return nil, fmt.Errorf("unknown signature algorithm %q", alg)
+	}
+}
+
+// Find the registered algorithm for a public key.
+func signatureAlgorithmFor(pub crypto.PublicKey) (string, error) {
+	for name, alg := range signatureAlgorithms {
+		if alg.matches(pub) {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported signing key type %T", pub)
+}
+
+// Bytes actually signed: the domain and algorithm identifier followed by the
+// payload.
+func signingPayload(domain, alg string, payload []byte) []byte {
+	prefix := domain + "\x00" + alg + "\x00"
+	return append([]byte(prefix), payload...)
+}
+
+// Sign payload in the given domain.
+func signPayload(s Signer, domain string, payload []byte) (Signature, error) {
+	alg, err := signatureAlgorithmFor(s.Public())
+	if err != nil {
+		return Signature{}, err
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(s.Public())
+	if err != nil {
+		return Signature{}, fmt.Errorf("failed to encode public key: %v", err)
+	}
+	sig, err := s.Sign(signingPayload(domain, alg, payload))
+	if err != nil {
+		return Signature{}, fmt.Errorf("signing failed: %v", err)
+	}
+	return Signature{
+		Algorithm: alg,
+		PublicKey: hex.EncodeToString(pubDER),
+		Value:     hex.EncodeToString(sig),
+	}, nil
+}
+
+// Verify the signature over payload in the given domain.
+func (sig Signature) Verify(domain string, payload []byte) error {
+	alg, ok := signatureAlgorithms[sig.Algorithm]
+	if !ok {
+		return fmt.Errorf("unknown signature algorithm %q", sig.Algorithm)
+	}
+	pub, err := sig.Public()
+	if err != nil {
+		return err
+	}
+	if !alg.matches(pub) {
+		return fmt.Errorf("public key does not match algorithm %q", sig.Algorithm)
+	}
+	value, err := hex.DecodeString(sig.Value)
+	if err != nil {
+		return errors.New("signature is not valid hex")
+	}
+	if !alg.verify(pub, signingPayload(domain, sig.Algorithm, payload), value) {
+		return errors.New("signature verification failed")
+	}
+	return nil
+}
+
+// Decode the signer's public key.
+func (sig Signature) Public() (crypto.PublicKey, error) {
+	der, err := hex.DecodeString(sig.PublicKey)
+	if err != nil {
+		return nil, errors.New("public key is not valid hex")
+	}
+	pub, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %v", err)
+	}
+	return pub, nil
+}
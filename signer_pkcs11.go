@@ -0,0 +1,46 @@
+//go:build pkcs11
+
+package main
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ThalesIgnite/crypto11"
+)
+
+// Environment variable holding the PKCS#11 user PIN.
+const pkcs11PinEnv = "PKCS11_PIN"
+
+func init() {
+	signerBackends["pkcs11"] = openPKCS11Signer
+}
+
+// Open a key held in an HSM. The spec is "<module path>:<token label>:<key label>".
+func openPKCS11Signer(spec string) (crypto.Signer, error) {
+	parts := strings.SplitN(spec, ":", 3)
+	if len(parts) != 3 {
+		return nil, errors.New("expected <module path>:<token label>:<key label>")
+	}
+
+	ctx, err := crypto11.Configure(&crypto11.Config{
+		Path:       parts[0],
+		TokenLabel: parts[1],
+		Pin:        os.Getenv(pkcs11PinEnv),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PKCS#11 module: %v", err)
+	}
+
+	signer, err := ctx.FindKeyPair(nil, []byte(parts[2]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find key %q: %v", parts[2], err)
+	}
+	if signer == nil {
+		return nil, fmt.Errorf("no key labelled %q on token %q", parts[2], parts[1])
+	}
+	return signer, nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Heights the stale rate is measured over, and stale blocks kept in memory
+// for the API.
+const (
+	staleWindow = 1000
+	staleRecent = 1000
+)
+
+var (
+	staleBlocks *StaleLog // Blocks this node saw but did not adopt
+
+	staleCountMetric = expvar.NewInt("stale_blocks")
+	staleRateMetric  = expvar.NewFloat("stale_rate")
+)
+
+// StaleBlock records a valid block that lost to the main chain.
+type StaleBlock struct {
+	Hash     string `json:"hash"`
+	Number   int    `json:"number"`
+	PrevHash string `json:"prevHash"`
+	Miner    string `json:"miner,omitempty"` // Coinbase address
+	Mined    int64  `json:"mined"`           // Block timestamp
+	Seen     int64  `json:"seen,omitempty"`  // Unix milliseconds the block reached this node; unknown for reorged blocks
+	Reorged  bool   `json:"reorged"`         // The block was on this node's main chain before a reorg
+	Recorded int64  `json:"recorded"`        // Unix time the block became stale
+}
+
+// StaleLog persists stale blocks in <datadir>/stale.log, one JSON record per
+// line. A side block becomes stale once the main chain is maxForkDepth
+// blocks past it, so it can no longer win a reorg.
+type StaleLog struct {
+	mu     sync.Mutex
+	file   *os.File
+	total  int
+	recent []StaleBlock // Oldest first
+}
+
+func OpenStaleLog(dir string) (*StaleLog, error) {
+	file, err := os.OpenFile(filepath.Join(dir, "stale.log"), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stale block log: %v", err)
+	}
+	l := &StaleLog{file: file}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record StaleBlock
+		if json.Unmarshal(scanner.Bytes(), &record) != nil {
+			continue // A record cut short by a crash
+		}
+		l.remember(record)
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read stale block log: %v", err)
+	}
+	staleCountMetric.Set(int64(l.total))
+	return l, nil
+}
+
+func (l *StaleLog) remember(record StaleBlock) {
+	l.total++
+	l.recent = append(l.recent, record)
+	if len(l.recent) > staleRecent {
+		l.recent = l.recent[1:]
+	}
+}
+
+// Record a side block that can no longer join the main chain.
+func (l *StaleLog) Record(side sideBlock) error {
+	record := StaleBlock{
+		Hash:     side.block.Hash,
+		Number:   side.block.BlockNumber,
+		PrevHash: side.block.PrevHash,
+		Mined:    side.block.Timestamp,
+		Reorged:  side.reorged,
+		Recorded: time.Now().Unix(),
+	}
+	if len(side.block.Transactions) > 0 && side.block.Transactions[0].Coinbase != nil {
+		record.Miner = side.block.Transactions[0].Coinbase.Address
+	}
+	if !side.seen.IsZero() {
+		record.Seen = side.seen.UnixMilli()
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write stale block: %v", err)
+	}
+	l.remember(record)
+	staleCountMetric.Set(int64(l.total))
+	return nil
+}
+
+// The last n stale blocks, newest first.
+func (l *StaleLog) Recent(n int) []StaleBlock {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	blocks := []StaleBlock{}
+	for i := len(l.recent) - 1; i >= 0 && len(blocks) < n; i-- {
+		blocks = append(blocks, l.recent[i])
+	}
+	return blocks
+}
+
+// Stale block statistics at a chain height.
+type StaleStats struct {
+	Total       int     `json:"total"`       // Stale blocks ever recorded
+	Window      int     `json:"window"`      // Heights the rate is measured over
+	WindowStale int     `json:"windowStale"` // Stale blocks in the window
+	Rate        float64 `json:"rate"`        // Share of the window's valid blocks that went stale
+	SideBlocks  int     `json:"sideBlocks"`  // Competing blocks that may still win a reorg
+}
+
+// Statistics over the heights below height that can no longer change:
+// maxForkDepth heights are still open to reorgs.
+func (l *StaleLog) Stats(height int) StaleStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	last := height - maxForkDepth
+	first := max(last-staleWindow, 1) // Nothing competes with the genesis block
+	stats := StaleStats{Total: l.total, Window: max(last-first, 0)}
+	for _, record := range l.recent {
+		if record.Number >= first && record.Number < last {
+			stats.WindowStale++
+		}
+	}
+	if stats.Window > 0 {
+		stats.Rate = float64(stats.WindowStale) / float64(stats.Window+stats.WindowStale)
+	}
+	return stats
+}
+
+func (l *StaleLog) Close() error {
+	return l.file.Close()
+}
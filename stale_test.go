@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStaleLogPersists(t *testing.T) {
+	dir := t.TempDir()
+	log, err := OpenStaleLog(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n := 1; n <= 3; n++ {
+		side := sideBlock{block: Block{Hash: string(rune('a' + n)), BlockNumber: n}, seen: time.Now()}
+		if err := log.Record(side); err != nil {
+			t.Fatal(err)
+		}
+	}
+	log.Close()
+
+	if log, err = OpenStaleLog(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	recent := log.Recent(2)
+	if len(recent) != 2 || recent[0].Number != 3 || recent[1].Number != 2 || recent[0].Seen == 0 {
+		t.Errorf("recent stale blocks %+v", recent)
+	}
+
+	// Heights 1 to 9 are settled at height 9+maxForkDepth; 3 of their 12
+	// valid blocks went stale
+	stats := log.Stats(10 + maxForkDepth)
+	if stats.Total != 3 || stats.Window != 9 || stats.WindowStale != 3 || stats.Rate != 0.25 {
+		t.Errorf("stats %+v", stats)
+	}
+}
+
+func TestLosingBlockBecomesStale(t *testing.T) {
+	setupTestMiner(t)
+	// acceptBlock leaves proof of work to validation, so skip mining
+	build := func(parent Block, nonce int) Block {
+		block := blockTemplate(parent.BlockNumber+1, parent.Hash, parent.PrevCID, nil)
+		block.Nonce = nonce
+		block.Hash = block.ComputeHash()
+		return block
+	}
+
+	winner, loser := build(genesisBlock, 0), build(genesisBlock, 1)
+	for _, block := range []Block{winner, loser} {
+		if err := acceptBlock(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tip := winner
+	for i := 0; i < maxForkDepth; i++ {
+		if len(staleBlocks.Recent(1)) > 0 {
+			t.Fatalf("block went stale at height %d, while it could still win", chainStore.Height())
+		}
+		tip = build(tip, 0)
+		if err := acceptBlock(tip); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recent := staleBlocks.Recent(10)
+	if len(recent) != 1 || recent[0].Hash != loser.Hash || recent[0].Reorged || recent[0].Seen == 0 {
+		t.Fatalf("stale blocks %+v, want the losing block", recent)
+	}
+	if _, ok := sideBlocks[loser.Hash]; ok {
+		t.Error("stale block is still kept as a side block")
+	}
+}
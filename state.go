@@ -0,0 +1,100 @@
+package main
+
+import "sync"
+
+var nodeState = NewNodeState() // Counters and vote tallies of this node
+
+// NodeState holds the node's mutable bookkeeping that is shared between the
+// miner, block handlers and the HTTP API.
+type NodeState struct {
+	mu            sync.Mutex
+	minedBlocks   int                   // Blocks mined by this node
+	votes         map[string]*voteTally // By block hash
+	invalidBlocks map[string]int        // Rejected blocks by the miner that signed them
+}
+
+// The distinct miners that validated a block not yet on the chain.
+type voteTally struct {
+	height int
+	voters map[string]bool // By miner identity
+}
+
+func NewNodeState() *NodeState {
+	return &NodeState{votes: make(map[string]*voteTally), invalidBlocks: make(map[string]int)}
+}
+
+// Count a block mined by this node.
+func (s *NodeState) RecordMined() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.minedBlocks++
+}
+
+func (s *NodeState) MinedBlocks() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.minedBlocks
+}
+
+// Count an invalid block against the miner that signed it.
+func (s *NodeState) RecordInvalidBlock(miner string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.invalidBlocks[miner]++
+}
+
+// Invalid blocks received by the miner that signed them.
+func (s *NodeState) InvalidBlocks() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := make(map[string]int, len(s.invalidBlocks))
+	for miner, count := range s.invalidBlocks {
+		counts[miner] = count
+	}
+	return counts
+}
+
+// Record a miner's vote that a block is valid. It returns the number of
+// distinct miners that have voted for the block and whether this miner's
+// vote is new; a miner's repeated votes count once.
+func (s *NodeState) AddVote(hash string, height int, voter string) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tally, ok := s.votes[hash]
+	if !ok {
+		tally = &voteTally{height: height, voters: make(map[string]bool)}
+		s.votes[hash] = tally
+	}
+	if tally.voters[voter] {
+		return len(tally.voters), false
+	}
+	tally.voters[voter] = true
+	return len(tally.voters), true
+}
+
+// Report whether a miner has already voted for a block.
+func (s *NodeState) HasVote(hash, voter string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tally, ok := s.votes[hash]
+	return ok && tally.voters[voter]
+}
+
+// Drop the tally of a block that no longer needs votes.
+func (s *NodeState) ForgetVotes(hash string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.votes, hash)
+}
+
+// Drop the tallies of blocks below a height, which can no longer be
+// accepted.
+func (s *NodeState) PruneVotes(floor int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for hash, tally := range s.votes {
+		if tally.height < floor {
+			delete(s.votes, hash)
+		}
+	}
+}
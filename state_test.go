@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// Run with -race: the miner, block handlers and API use NodeState at once.
+func TestNodeStateConcurrentUse(t *testing.T) {
+	state := NewNodeState()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				state.RecordMined()
+				state.AddVote(fmt.Sprintf("block%d", j%4), 1, fmt.Sprintf("miner%d", i))
+				state.MinedBlocks()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if got := state.MinedBlocks(); got != 800 {
+		t.Errorf("counted %d mined blocks, want 800", got)
+	}
+	if got, isNew := state.AddVote("block0", 1, "miner0"); got != 8 || isNew {
+		t.Errorf("block has %d distinct votes (new vote %v), want 8 and a repeated vote", got, isNew)
+	}
+}
+
+func TestPruneVotes(t *testing.T) {
+	state := NewNodeState()
+	state.AddVote("old", 1, "miner")
+	state.AddVote("new", 5, "miner")
+	state.PruneVotes(3)
+	if state.HasVote("old", "miner") || !state.HasVote("new", "miner") {
+		t.Error("votes were not pruned by height")
+	}
+}
@@ -0,0 +1,474 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var (
+	dataDir = flag.String("datadir", "data", "directory holding the chain store")
+
+	chainStore *ChainStore // Local copy of the main chain
+)
+
+var (
+	errBlockNotFound       = errors.New("block not found")
+	errTransactionNotFound = errors.New("transaction not found")
+)
+
+// ChainStore persists the main chain in <datadir>/chain.log, an append-only
+// file of JSON-encoded blocks, one per line. Blocks are read from disk on
+// demand; only their offsets and indexes by block hash, transaction ID and
+// miner are kept in memory. The indexes are rebuilt when the store opens.
+//
+// A chain fast-synced from a checkpoint starts at the checkpoint's tip
+// instead of the genesis block; the checkpoint is kept in
+// <datadir>/checkpoint.json and blocks below it are not stored.
+type ChainStore struct {
+	mu         sync.RWMutex
+	dir        string
+	file       *os.File
+	checkpoint *Checkpoint           // Checkpoint the chain starts from, if any
+	base       int                   // Number of the first stored block
+	offsets    []int64               // File offset of each block, by block number less base
+	lengths    []int                 // Encoded length of each block, by block number less base
+	work       []*big.Int            // Cumulative work up to and including each block
+	byHash     map[string]int        // Block number by block hash
+	byTx       map[string]txLocation // Where each transaction was mined
+	bySigned   map[string]int        // Number of the block each signed transaction was mined in, by digest (see replay.go)
+	byMiner    map[string][]int      // Numbers of the blocks each miner mined, ascending
+	tip        *Block
+}
+
+// txLocation is where a transaction sits on the main chain.
+type txLocation struct {
+	BlockHash   string `json:"blockHash"`
+	BlockNumber int    `json:"blockNumber"`
+	Index       int    `json:"index"` // Position in the block's transactions
+}
+
+// Open the chain store in dir, creating it if needed. A partially written
+// last block, left behind by a crash, is truncated away.
+func OpenChainStore(dir string) (*ChainStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %v", err)
+	}
+	file, err := os.OpenFile(filepath.Join(dir, "chain.log"), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open chain store: %v", err)
+	}
+
+	s := &ChainStore{dir: dir, file: file, byHash: make(map[string]int), byTx: make(map[string]txLocation), bySigned: make(map[string]int), byMiner: make(map[string][]int)}
+	if err := s.loadCheckpoint(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := s.load(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Rebuild the in-memory indexes from the log.
+func (s *ChainStore) load() error {
+	reader := bufio.NewReader(s.file)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				chainLog.Warn("Discarding partially written block", "offset", offset)
+				return s.file.Truncate(offset)
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read chain store: %v", err)
+		}
+
+		var block Block
+		if err := json.Unmarshal(line, &block); err != nil {
+			chainLog.Warn("Discarding corrupt block", "offset", offset, "err", err)
+			return s.file.Truncate(offset)
+		}
+		if err := s.checkLink(block); err != nil {
+			chainLog.Warn("Discarding unlinked blocks", "offset", offset, "err", err)
+			return s.file.Truncate(offset)
+		}
+		s.index(block, offset, len(line))
+		offset += int64(len(line))
+	}
+}
+
+// Read the checkpoint the chain starts from, if there is one.
+func (s *ChainStore) loadCheckpoint() error {
+	data, err := os.ReadFile(filepath.Join(s.dir, "checkpoint.json"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read checkpoint: %v", err)
+	}
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return fmt.Errorf("failed to parse checkpoint: %v", err)
+	}
+	s.checkpoint, s.base = &checkpoint, checkpoint.Height
+	return nil
+}
+
+// Start an empty store from a verified checkpoint: its tip becomes the
+// first stored block.
+func (s *ChainStore) StartFromCheckpoint(checkpoint Checkpoint) error {
+	s.mu.Lock()
+	if len(s.offsets) > 0 {
+		s.mu.Unlock()
+		return errors.New("chain store is not empty")
+	}
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("failed to encode checkpoint: %v", err)
+	}
+	path := filepath.Join(s.dir, "checkpoint.json")
+	if err := os.WriteFile(path+".tmp", data, 0600); err != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+	s.checkpoint, s.base = &checkpoint, checkpoint.Height
+	s.mu.Unlock()
+	return s.AppendBlock(checkpoint.Tip)
+}
+
+// Checkpoint the chain starts from, or nil if it starts from the genesis.
+func (s *ChainStore) Checkpoint() *Checkpoint {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.checkpoint
+}
+
+// Number of the first stored block: 0, or the height of the checkpoint the
+// chain starts from.
+func (s *ChainStore) Base() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.base
+}
+
+// Check that block extends the current tip.
+func (s *ChainStore) checkLink(block Block) error {
+	if next := s.base + len(s.offsets); block.BlockNumber != next {
+		return fmt.Errorf("block number %d, expected %d", block.BlockNumber, next)
+	}
+	if s.tip == nil && s.checkpoint != nil {
+		if block.Hash != s.checkpoint.TipHash {
+			return fmt.Errorf("block %s is not the checkpoint's tip %s", block.Hash, s.checkpoint.TipHash)
+		}
+		return nil
+	}
+	prevHash := "-1"
+	if s.tip != nil {
+		prevHash = s.tip.Hash
+	}
+	if block.PrevHash != prevHash {
+		return fmt.Errorf("block %s does not extend tip %s", block.Hash, prevHash)
+	}
+	return nil
+}
+
+func (s *ChainStore) index(block Block, offset int64, length int) {
+	s.offsets = append(s.offsets, offset)
+	s.lengths = append(s.lengths, length)
+	if len(s.work) == 0 && s.checkpoint != nil {
+		work, _ := new(big.Int).SetString(s.checkpoint.Work, 16) // Checked when the checkpoint was verified
+		s.work = append(s.work, work)
+	} else {
+		s.work = append(s.work, new(big.Int).Add(s.totalWork(), blockWork(block)))
+	}
+	s.byHash[block.Hash] = block.BlockNumber
+	for i, tx := range block.Transactions {
+		// A transaction mined twice is found where it was first mined
+		if _, ok := s.byTx[tx.ID]; !ok {
+			s.byTx[tx.ID] = txLocation{BlockHash: block.Hash, BlockNumber: block.BlockNumber, Index: i}
+		}
+		if digest := transactionDigest(tx); digest != "" {
+			if _, ok := s.bySigned[digest]; !ok {
+				s.bySigned[digest] = block.BlockNumber
+			}
+		}
+	}
+	if block.MinerID != "" {
+		s.byMiner[block.MinerID] = append(s.byMiner[block.MinerID], block.BlockNumber)
+	}
+	s.tip = &block
+}
+
+// Remove a block that is being truncated away from the indexes.
+func (s *ChainStore) unindex(block Block) {
+	delete(s.byHash, block.Hash)
+	for _, tx := range block.Transactions {
+		if location, ok := s.byTx[tx.ID]; ok && location.BlockHash == block.Hash {
+			delete(s.byTx, tx.ID)
+		}
+		if digest := transactionDigest(tx); digest != "" && s.bySigned[digest] == block.BlockNumber {
+			delete(s.bySigned, digest)
+		}
+	}
+	if mined := s.byMiner[block.MinerID]; len(mined) > 0 && mined[len(mined)-1] == block.BlockNumber {
+		if len(mined) == 1 {
+			delete(s.byMiner, block.MinerID)
+		} else {
+			s.byMiner[block.MinerID] = mined[:len(mined)-1]
+		}
+	}
+}
+
+// AppendBlock adds a block that extends the current tip and syncs it to disk.
+func (s *ChainStore) AppendBlock(block Block) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkLink(block); err != nil {
+		return err
+	}
+	data, err := json.Marshal(block)
+	if err != nil {
+		return fmt.Errorf("failed to encode block: %v", err)
+	}
+	data = append(data, '\n')
+
+	offset, err := s.file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("failed to seek chain store: %v", err)
+	}
+	if _, err := s.file.Write(data); err != nil {
+		return fmt.Errorf("failed to write block: %v", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync chain store: %v", err)
+	}
+	s.index(block, offset, len(data))
+	return nil
+}
+
+// GetBlockByNumber returns the block at the given height.
+func (s *ChainStore) GetBlockByNumber(number int) (Block, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := number - s.base
+	if i < 0 || i >= len(s.offsets) {
+		return Block{}, errBlockNotFound
+	}
+	data := make([]byte, s.lengths[i])
+	if _, err := s.file.ReadAt(data, s.offsets[i]); err != nil {
+		return Block{}, fmt.Errorf("failed to read block %d: %v", number, err)
+	}
+	var block Block
+	if err := json.Unmarshal(bytes.TrimSpace(data), &block); err != nil {
+		return Block{}, fmt.Errorf("failed to decode block %d: %v", number, err)
+	}
+	return block, nil
+}
+
+// GetBlockByHash returns the block with the given hash.
+func (s *ChainStore) GetBlockByHash(hash string) (Block, error) {
+	s.mu.RLock()
+	number, ok := s.byHash[hash]
+	s.mu.RUnlock()
+	if !ok {
+		return Block{}, errBlockNotFound
+	}
+	return s.GetBlockByNumber(number)
+}
+
+// GetTransaction returns a transaction mined on the main chain and where
+// it was mined.
+func (s *ChainStore) GetTransaction(id string) (Transaction, txLocation, error) {
+	s.mu.RLock()
+	location, ok := s.byTx[id]
+	s.mu.RUnlock()
+	if !ok {
+		return Transaction{}, txLocation{}, errTransactionNotFound
+	}
+	block, err := s.GetBlockByNumber(location.BlockNumber)
+	if err != nil {
+		return Transaction{}, txLocation{}, err
+	}
+	if location.Index >= len(block.Transactions) || block.Transactions[location.Index].ID != id {
+		return Transaction{}, txLocation{}, fmt.Errorf("transaction index is out of step with block %d", location.BlockNumber)
+	}
+	return block.Transactions[location.Index], location, nil
+}
+
+// TransactionProof returns the Merkle proof that a main chain transaction
+// is in its block.
+func (s *ChainStore) TransactionProof(id string) (merkleProof, error) {
+	_, location, err := s.GetTransaction(id)
+	if err != nil {
+		return merkleProof{}, err
+	}
+	block, err := s.GetBlockByNumber(location.BlockNumber)
+	if err != nil {
+		return merkleProof{}, err
+	}
+	return buildMerkleProof(block, location.Index), nil
+}
+
+// BlocksMinedBy returns the numbers of the main chain blocks a miner mined,
+// oldest first.
+func (s *ChainStore) BlocksMinedBy(miner string) []int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]int(nil), s.byMiner[miner]...)
+}
+
+// SignedAt returns the number of the block a signed transaction was mined
+// in, by its digest, or false if it is not on the chain.
+func (s *ChainStore) SignedAt(digest string) (int, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	number, ok := s.bySigned[digest]
+	return number, ok
+}
+
+// Tip returns the last block of the chain, or false if the chain is empty.
+func (s *ChainStore) Tip() (Block, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.tip == nil {
+		return Block{}, false
+	}
+	return *s.tip, true
+}
+
+// Height returns the number of blocks in the chain, which is also the
+// number of the next block.
+func (s *ChainStore) Height() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.base + len(s.offsets)
+}
+
+// DiskUsage returns the bytes the store's files take on disk.
+func (s *ChainStore) DiskUsage() (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var size int64
+	for _, name := range []string{"chain.log", "checkpoint.json"} {
+		info, err := os.Stat(filepath.Join(s.dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		size += info.Size()
+	}
+	return size, nil
+}
+
+// TotalWork returns the cumulative work of the whole chain.
+func (s *ChainStore) TotalWork() *big.Int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return new(big.Int).Set(s.totalWork())
+}
+
+// WorkAt returns the cumulative work up to and including the given block.
+func (s *ChainStore) WorkAt(number int) (*big.Int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i := number - s.base
+	if i < 0 || i >= len(s.work) {
+		return nil, errBlockNotFound
+	}
+	return new(big.Int).Set(s.work[i]), nil
+}
+
+func (s *ChainStore) totalWork() *big.Int {
+	if len(s.work) == 0 {
+		return new(big.Int)
+	}
+	return s.work[len(s.work)-1]
+}
+
+// BlocksFrom returns every block from the given height to the tip, oldest
+// first.
+func (s *ChainStore) BlocksFrom(height int) ([]Block, error) {
+	var blocks []Block
+	for n := height; n < s.Height(); n++ {
+		block, err := s.GetBlockByNumber(n)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// Truncate removes every block from the given height on and returns them,
+// oldest first. It is used to roll the chain back to a fork point.
+func (s *ChainStore) Truncate(height int) ([]Block, error) {
+	removed, err := s.BlocksFrom(height)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// The block a checkpoint starts the chain from is final
+	keep := height - s.base
+	if keep < 0 || s.checkpoint != nil && keep < 1 || keep > len(s.offsets) {
+		return nil, fmt.Errorf("cannot truncate chain of height %d to %d", s.base+len(s.offsets), height)
+	}
+	if keep == len(s.offsets) {
+		return nil, nil
+	}
+
+	if err := s.file.Truncate(s.offsets[keep]); err != nil {
+		return nil, fmt.Errorf("failed to truncate chain store: %v", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return nil, fmt.Errorf("failed to sync chain store: %v", err)
+	}
+	// Newest first, so each miner's list shrinks from its end
+	for i := len(removed) - 1; i >= 0; i-- {
+		s.unindex(removed[i])
+	}
+	s.offsets = s.offsets[:keep]
+	s.lengths = s.lengths[:keep]
+	s.work = s.work[:keep]
+
+	s.tip = nil
+	if keep > 0 {
+		data := make([]byte, s.lengths[keep-1])
+		if _, err := s.file.ReadAt(data, s.offsets[keep-1]); err != nil {
+			return nil, fmt.Errorf("failed to read block %d: %v", height-1, err)
+		}
+		var tip Block
+		if err := json.Unmarshal(bytes.TrimSpace(data), &tip); err != nil {
+			return nil, fmt.Errorf("failed to decode block %d: %v", height-1, err)
+		}
+		s.tip = &tip
+	}
+	return removed, nil
+}
+
+func (s *ChainStore) Close() error {
+	return s.file.Close()
+}
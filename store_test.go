@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestStoreIndexes(t *testing.T) {
+	setupTestMiner(t)
+	buildTestChain(t, 3)
+	block2, err := chainStore.GetBlockByNumber(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coinbase := block2.Transactions[0]
+
+	tx, location, err := chainStore.GetTransaction(coinbase.ID)
+	if err != nil || tx.ID != coinbase.ID || location != (txLocation{BlockHash: block2.Hash, BlockNumber: 2, Index: 0}) {
+		t.Fatalf("transaction %s found at %+v, %v; want block 2", coinbase.ID, location, err)
+	}
+	if mined := chainStore.BlocksMinedBy(nodeID); !slices.Equal(mined, []int{1, 2, 3}) {
+		t.Fatalf("blocks mined by this node are %v", mined)
+	}
+
+	if _, err := chainStore.Truncate(2); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := chainStore.GetTransaction(coinbase.ID); !errors.Is(err, errTransactionNotFound) {
+		t.Errorf("transaction of a truncated block is still indexed: %v", err)
+	}
+	if mined := chainStore.BlocksMinedBy(nodeID); !slices.Equal(mined, []int{1}) {
+		t.Errorf("blocks mined by this node after truncation are %v", mined)
+	}
+
+	// The indexes are rebuilt from the log
+	reopened, err := OpenChainStore(chainStore.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	block1, _ := chainStore.GetBlockByNumber(1)
+	if _, location, err := reopened.GetTransaction(block1.Transactions[0].ID); err != nil || location.BlockHash != block1.Hash {
+		t.Errorf("reopened store finds block 1's coinbase at %+v, %v", location, err)
+	}
+	if mined := reopened.BlocksMinedBy(nodeID); !slices.Equal(mined, []int{1}) {
+		t.Errorf("reopened store has blocks mined by this node %v", mined)
+	}
+}
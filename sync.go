@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+)
+
+var syncBatchSize = flag.Int("sync-batch", 16, "blocks requested per message during chain sync")
+
+var errInvalidBlock = errors.New("block failed validation")
+
+// Return stored blocks from..to inclusive, at most limit of them. The range
+// stops short at the tip.
+func blockRange(from, to, limit int) ([]Block, error) {
+	if from < 0 || to < from {
+		return nil, fmt.Errorf("invalid block range %d-%d", from, to)
+	}
+	if to-from+1 > limit {
+		to = from + limit - 1
+	}
+	var blocks []Block
+	for n := from; n <= to; n++ {
+		block, err := chainStore.GetBlockByNumber(n)
+		if errors.Is(err, errBlockNotFound) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// Blocks served to a peer syncing from..to, or their headers only. Light
+// nodes have no full blocks to serve.
+func servedBlocks(from, to int, headers bool) ([]Block, error) {
+	if *lightMode && !headers {
+		return nil, nil
+	}
+	blocks, err := blockRange(from, to, *syncBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	if headers {
+		for i := range blocks {
+			blocks[i] = blocks[i].Header()
+		}
+	}
+	return blocks, nil
+}
+
+// Bring the local chain up to the best height among peers before mining.
+// Peers are tried from highest to lowest until one serves a valid chain.
+func syncChain(ctx context.Context) {
+	heights := make(map[string]int)
+	for _, addr := range peerManager.Peers() {
+		height, err := transport.Height(addr)
+		if err != nil {
+			chainLog.Warn("Could not get height from peer", "peer", addr, "err", err)
+			continue
+		}
+		heights[addr] = height
+	}
+
+	for len(heights) > 0 && ctx.Err() == nil {
+		best, bestHeight := "", -1
+		for addr, height := range heights {
+			if height > bestHeight {
+				best, bestHeight = addr, height
+			}
+		}
+		delete(heights, best)
+		if bestHeight <= chainStore.Height() {
+			break
+		}
+
+		chainLog.Info("Syncing blocks", "from", chainStore.Height(), "to", bestHeight-1, "peer", best)
+		if err := syncFromPeer(ctx, best, bestHeight); err != nil {
+			chainLog.Warn("Sync failed", "peer", best, "err", err)
+			continue
+		}
+	}
+	chainLog.Info("Chain synced", "height", chainStore.Height())
+}
+
+// Download, validate and store blocks from a peer up to its height. Light
+// nodes download the headers only.
+func syncFromPeer(ctx context.Context, addr string, height int) error {
+	for chainStore.Height() < height {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		from := chainStore.Height()
+		var blocks []Block
+		var err error
+		if *lightMode {
+			blocks, err = transport.Headers(addr, from, height-1)
+		} else {
+			blocks, err = transport.Blocks(addr, from, height-1)
+		}
+		if err != nil {
+			return err
+		}
+		if len(blocks) == 0 {
+			return errors.New("peer returned no blocks")
+		}
+
+		for _, block := range blocks {
+			if err := appendValidBlock(block); err != nil {
+				if errors.Is(err, errInvalidBlock) {
+					penalizeIdentity(addr, scoreInvalidBlock, "invalid block during sync")
+					return fmt.Errorf("block %d failed validation", block.BlockNumber)
+				}
+				return err
+			}
+		}
+		last := blocks[len(blocks)-1]
+		setChainHead(last)
+	}
+	return nil
+}
+
+// Validate a block that extends the stored tip and append it to the main
+// chain. The caller updates the chain head once a batch is appended.
+func appendValidBlock(block Block) error {
+	block = storedForm(block)
+	prevHash := "-1"
+	if tip, ok := chainStore.Tip(); ok {
+		prevHash = tip.Hash
+	}
+	blockData, err := json.Marshal(block)
+	if err != nil {
+		return err
+	}
+	// The seal is checked against the target the block declares, and that
+	// target against the one its parent implies
+	if !validateBlock(string(blockData), prevHash, maxTarget) {
+		return errInvalidBlock
+	}
+	chainMu.Lock()
+	defer chainMu.Unlock()
+	if err := checkMedianTime(block); err != nil {
+		return err
+	}
+	if err := checkBlockTarget(block); err != nil {
+		return err
+	}
+	return connectBlock(block)
+}
+
+func requestHeight(addr string) (int, error) {
+	reply, err := requestFromPeer(addr, peerMessage{Type: msgGetHeight}, msgHeight)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkNetworkID(reply.Network); err != nil {
+		return 0, err
+	}
+	return reply.Height, nil
+}
+
+func requestBlocks(addr string, from, to int, headers bool) ([]Block, error) {
+	reply, err := requestFromPeer(addr, peerMessage{Type: msgGetBlocks, Height: from, To: to, Headers: headers}, msgBlocks)
+	return reply.Blocks, err
+}
+
+// Send one request to a peer and read its reply of the expected type.
+func requestFromPeer(addr string, request peerMessage, replyType string) (peerMessage, error) {
+	conn, reader, err := dialPeer(addr)
+	if err != nil {
+		return peerMessage{}, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	if err := writePeerMessage(conn, request); err != nil {
+		return peerMessage{}, err
+	}
+	reply, err := readPeerMessage(reader)
+	if err != nil {
+		return peerMessage{}, err
+	}
+	if reply.Type != replyType {
+		return peerMessage{}, fmt.Errorf("expected %s, got %q", replyType, reply.Type)
+	}
+	return reply, nil
+}
@@ -0,0 +1,4 @@
+;; Asks for 100 pages (6.25 MiB) of memory. Assembled into bigmem.wasm.
+(module
+  (memory (export "memory") 100)
+  (func (export "_start")))
@@ -0,0 +1,6 @@
+;; Calls a function forever. Assembled into loop.wasm.
+(module
+  (memory (export "memory") 1)
+  (func $f)
+  (func (export "_start")
+    (loop (call $f) (br 0))))
@@ -0,0 +1,14 @@
+package main
+
+// Report whether a time-locked transaction may be included in a block at
+// the given height with the given timestamp. Judging by the block's own
+// timestamp rather than the local clock keeps every node's verdict on a
+// block the same.
+func (tx Transaction) isFinal(height int, blockTime int64) bool {
+	return height >= tx.LockHeight && blockTime >= tx.LockTime
+}
+
+// Report whether a transaction has expired by the given block height.
+func (tx Transaction) expired(height int) bool {
+	return tx.ExpiryHeight != 0 && height >= tx.ExpiryHeight
+}
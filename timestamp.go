@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// Blocks may be timestamped at most this far ahead of the local clock.
+	maxFutureBlockTime = 2 * time.Hour
+
+	// A block's timestamp must exceed the median of this many ancestors.
+	medianTimeBlocks = 11
+)
+
+// Median timestamp of up to medianTimeBlocks blocks ending at hash, on the
+// main chain or a side branch. Zero if there are none. The caller holds
+// chainMu.
+func medianTimePast(hash string) int64 {
+	var times []int64
+	for len(times) < medianTimeBlocks && hash != "-1" {
+		var block Block
+		if side, ok := sideBlocks[hash]; ok {
+			block = side.block
+		} else if stored, err := chainStore.GetBlockByHash(hash); err == nil {
+			block = stored
+		} else {
+			break
+		}
+		times = append(times, block.Timestamp)
+		hash = block.PrevHash
+	}
+	if len(times) == 0 {
+		return 0
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	return times[len(times)/2]
+}
+
+// Check the block's timestamp against its ancestors. The caller holds
+// chainMu.
+func checkMedianTime(block Block) error {
+	if median := medianTimePast(block.PrevHash); block.Timestamp <= median && median > 0 {
+		return fmt.Errorf("block timestamp %d is not after the median %d of its ancestors", block.Timestamp, median)
+	}
+	return nil
+}
+
+// Timestamp for a block mined on top of prevHash: now, or just after the
+// median of its ancestors if the clock is behind it.
+func nextBlockTimestamp(prevHash string) int64 {
+	chainMu.Lock()
+	median := medianTimePast(prevHash)
+	chainMu.Unlock()
+	if now := time.Now().Unix(); now > median {
+		return now
+	}
+	return median + 1
+}
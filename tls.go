@@ -0,0 +1,210 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	tlsCertFile = flag.String("tls-cert", "", "PEM certificate presented to other miners (enables mTLS)")
+	tlsKeyFile  = flag.String("tls-key", "", "PEM private key matching -tls-cert")
+	tlsCAFile   = flag.String("tls-ca", "", "PEM bundle of CAs allowed to sign miner certificates")
+
+	minerTLS *certReloader // nil when inter-miner connections are plaintext
+)
+
+// certReloader serves the miner certificate and trusted CA pool from disk,
+// re-reading the files whenever they change so certificates can be rotated
+// without restarting the node.
+type certReloader struct {
+	certFile, keyFile, caFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	pool    *x509.CertPool
+	modTime time.Time
+}
+
+// Set up mTLS for inter-miner connections if the TLS flags are given.
+func setupMinerTLS() error {
+	if *tlsIdentity {
+		return setupIdentityTLS()
+	}
+	if *tlsCertFile == "" && *tlsKeyFile == "" && *tlsCAFile == "" {
+		return nil
+	}
+	if *tlsCertFile == "" || *tlsKeyFile == "" || *tlsCAFile == "" {
+		return errors.New("mTLS requires -tls-cert, -tls-key and -tls-ca together")
+	}
+
+	r := &certReloader{certFile: *tlsCertFile, keyFile: *tlsKeyFile, caFile: *tlsCAFile}
+	if _, _, err := r.current(); err != nil {
+		return err
+	}
+	minerTLS = r
+	return nil
+}
+
+// Return the certificate and CA pool, reloading them if any file changed.
+func (r *certReloader) current() (*tls.Certificate, *x509.CertPool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var latest time.Time
+	for _, path := range []string{r.certFile, r.keyFile, r.caFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to stat TLS file: %v", err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	if r.cert != nil && !latest.After(r.modTime) {
+		return r.cert, r.pool, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load miner certificate: %v", err)
+	}
+	caPEM, err := os.ReadFile(r.caFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read CA bundle: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, nil, errors.New("no certificates found in CA bundle")
+	}
+
+	if r.cert != nil {
+		nodeLog.Info("Reloaded miner TLS certificate and CA bundle")
+	}
+	r.cert, r.pool, r.modTime = &cert, pool, latest
+	return r.cert, r.pool, nil
+}
+
+// Verify the peer's certificate chain against the current CA pool. Miners
+// are dialed by IP, so only the chain is checked, not the host name.
+func (r *certReloader) verifyPeer(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("peer presented no certificate")
+	}
+	_, pool, err := r.current()
+	if err != nil {
+		return err
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err = cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err
+}
+
+func (r *certReloader) config() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: tls.RequireAnyClientCert,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert, _, err := r.current()
+			return cert, err
+		},
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _, err := r.current()
+			return cert, err
+		},
+		// Chain verification happens in VerifyConnection against the
+		// reloadable pool instead of a pool fixed at startup.
+		InsecureSkipVerify: true,
+		VerifyConnection:   r.verifyPeer,
+	}
+}
+
+// TLS settings for a connection to the miner at host, or for accepting
+// connections when host is empty; nil when miner connections are plaintext.
+func minerTLSConfig(host string) *tls.Config {
+	switch {
+	case minerTLS != nil:
+		return minerTLS.config()
+	case identityTLS != nil:
+		return identityTLS.config(host)
+	}
+	return nil
+}
+
+// Listen for connections from other miners, with mTLS when configured.
+func listenMiners(addr string) (net.Listener, error) {
+	ln, err := listenLimited(addr)
+	config := minerTLSConfig("")
+	if err != nil || config == nil {
+		return ln, err
+	}
+	return tls.NewListener(ln, config), nil
+}
+
+// Dial another miner, with mTLS when configured.
+func dialMiner(addr string) (net.Conn, error) {
+	conn, err := dialLimited(addr)
+	if err != nil {
+		return nil, err
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	config := minerTLSConfig(host)
+	if config == nil {
+		return conn, nil
+	}
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("TLS handshake failed: %v", err)
+	}
+	return tlsConn, nil
+}
+
+// Identify the miner on the other end of a connection. With mTLS this is
+// the certificate's common name (or fingerprint), with -tls-identity the
+// node ID of its key, otherwise the remote IP.
+func minerIdentity(conn net.Conn) (string, error) {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return peerHost(conn.RemoteAddr()), nil
+	}
+
+	if err := tlsConn.Handshake(); err != nil {
+		return "", fmt.Errorf("TLS handshake failed: %v", err)
+	}
+	return certIdentity(tlsConn.ConnectionState().PeerCertificates[0]), nil
+}
+
+// A miner certificate's common name, or its fingerprint if it has none.
+// Self-signed identity certificates vouch only for their key, so with
+// -tls-identity the name is ignored in favour of the key's node ID.
+func certIdentity(cert *x509.Certificate) string {
+	if identityTLS != nil {
+		if id, err := nodeIDFromPublicKey(cert.PublicKey); err == nil {
+			return id
+		}
+	}
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName
+	}
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
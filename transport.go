@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"time"
+)
+
+var (
+	transportName = flag.String("transport", "tcp", "protocol spoken between miners on the block port: tcp or grpc")
+
+	transport peerTransport = tcpTransport{} // Carries requests to other miners
+)
+
+// peerTransport is the client side of the miner-to-miner protocol. Every
+// node on a network must use the same transport, since both are served on
+// the block port.
+type peerTransport interface {
+	// Send a block announcement to a miner.
+	SendBlock(addr string, announcement blockAnnouncement) error
+	// Ping a miner, then request its peer list. Returns the list and the
+	// ping round-trip time.
+	Exchange(addr string) ([]string, time.Duration, error)
+	// Request a miner's chain height.
+	Height(addr string) (int, error)
+	// Request main chain blocks from..to inclusive.
+	Blocks(addr string, from, to int) ([]Block, error)
+	// Request the headers of main chain blocks from..to inclusive.
+	Headers(addr string, from, to int) ([]Block, error)
+	// Request a block on the miner's main chain or side branches by hash.
+	// Returns errBlockNotFound if the miner does not have it.
+	Block(addr, hash string) (Block, error)
+	// Relay pending transactions to a miner.
+	SendTransactions(addr string, txs []Transaction) error
+	// Request the Merkle proof that a transaction is on the miner's main
+	// chain. Returns errTransactionNotFound if it is not.
+	TransactionProof(addr, txID string) (merkleProof, error)
+}
+
+// Select the transport named by -transport.
+func setupTransport() error {
+	switch *transportName {
+	case "tcp":
+		transport = tcpTransport{}
+	case "grpc":
+		transport = newGRPCTransport()
+	default:
+		return fmt.Errorf("unknown transport %q", *transportName)
+	}
+	return nil
+}
+
+// tcpTransport speaks the framed JSON protocol of codec.go.
+type tcpTransport struct{}
+
+func (tcpTransport) SendBlock(addr string, announcement blockAnnouncement) error {
+	message, err := json.Marshal(announcement)
+	if err != nil {
+		return err
+	}
+	conn, _, err := dialPeer(addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return writeFrame(conn, frameBlock, message)
+}
+
+func (tcpTransport) Block(addr, hash string) (Block, error) {
+	return requestBlock(addr, hash)
+}
+
+func (tcpTransport) SendTransactions(addr string, txs []Transaction) error {
+	message, err := json.Marshal(txs)
+	if err != nil {
+		return err
+	}
+	conn, _, err := dialPeer(addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return writeFrame(conn, frameTransactions, message)
+}
+
+func (tcpTransport) Exchange(addr string) ([]string, time.Duration, error) {
+	return pingPeer(addr)
+}
+
+func (tcpTransport) Height(addr string) (int, error) {
+	return requestHeight(addr)
+}
+
+func (tcpTransport) Blocks(addr string, from, to int) ([]Block, error) {
+	return requestBlocks(addr, from, to, false)
+}
+
+func (tcpTransport) Headers(addr string, from, to int) ([]Block, error) {
+	return requestBlocks(addr, from, to, true)
+}
+
+func (tcpTransport) TransactionProof(addr, txID string) (merkleProof, error) {
+	return requestProof(addr, txID)
+}
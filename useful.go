@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const (
+	// Execution units a block credits for each step of relief on its
+	// proof of work
+	usefulUnitsPerStep = 1_000_000
+
+	// Most a block's computation may ease its proof of work, as a multiple
+	// of the declared target. Hashing stays the tiebreaker between blocks.
+	maxUsefulRelief = 16
+
+	// Most execution units one transaction may claim, so a block's total
+	// cannot overflow
+	maxTransactionUnits = 1 << 40
+)
+
+// usefulWork is proof of useful work, a research mode. Transactions run in
+// the wasm runtime meter their function calls (see wasm.go) and every
+// validator re-executes them to check the count. A block's work is the
+// verified computation it includes, with the proof of work over its hash
+// deciding between blocks with the same computation, and its computation
+// eases the target its hash must meet, so miners grind less. Nodes of the
+// network must share -wasm-fuel, or scripts stopped early on one node are
+// rejected by others.
+//
+// Credit goes to any verified computation, useful or not: a miner that
+// pays for its own jobs can pad its blocks. This mode is for studying the
+// trade-off, not for networks with adversarial miners.
+type usefulWork struct{}
+
+func (usefulWork) PrepareBlock(block *Block) {
+	block.Target = formatTarget(templateTarget(block.PrevHash))
+	block.Units = creditedUnits(block.Transactions)
+}
+
+func (usefulWork) Seal(ctx context.Context, abort <-chan struct{}, block Block) (Block, bool) {
+	return mineBlockBelow(ctx, abort, block, usefulTarget(blockTarget(block), block.Units))
+}
+
+func (usefulWork) VerifySeal(block Block, limit *big.Int) error {
+	declared, err := checkDeclaredTarget(block, limit)
+	if err != nil {
+		return err
+	}
+	if block.Units < 0 {
+		return errors.New("negative execution units")
+	}
+	hash, ok := new(big.Int).SetString(block.Hash, 16)
+	if !ok || hash.Cmp(usefulTarget(declared, block.Units)) != -1 {
+		return errors.New("insufficient proof of work for its computation")
+	}
+	return nil
+}
+
+func (usefulWork) CalcDifficulty(parent Block, find func(hash string) (Block, bool)) (*big.Int, error) {
+	return expectedTarget(parent, find)
+}
+
+// Computation outweighs any amount of hashing, which only adds at most
+// 2^256 per block.
+func (usefulWork) Work(block Block) *big.Int {
+	work := new(big.Int).Lsh(big.NewInt(block.Units), 256)
+	return work.Add(work, hashWork(blockTarget(block)))
+}
+
+// Whether the consensus engine credits computation as work.
+func creditsComputation() bool {
+	_, ok := consensus.(usefulWork)
+	return ok
+}
+
+// The target a block's hash must meet given the declared target and the
+// execution units it credits: one step easier per usefulUnitsPerStep, up
+// to maxUsefulRelief times the declared target.
+func usefulTarget(declared *big.Int, units int64) *big.Int {
+	relief := min(1+units/usefulUnitsPerStep, maxUsefulRelief)
+	relaxed := new(big.Int).Mul(declared, big.NewInt(relief))
+	if relaxed.Cmp(maxTarget) > 0 {
+		relaxed.Set(maxTarget)
+	}
+	return relaxed
+}
+
+// Execution units a block's header must claim: the sum over its
+// transactions under a consensus that credits computation, otherwise none.
+func creditedUnits(transactions []Transaction) int64 {
+	if !creditsComputation() {
+		return 0
+	}
+	var units int64
+	for _, tx := range transactions {
+		units += tx.Units
+	}
+	return units
+}
+
+// Check the execution units a transaction claims. Only the wasm runtime
+// meters them, and they are checked against the result by re-execution.
+func checkUnits(tx Transaction) error {
+	if tx.Units == 0 {
+		return nil
+	}
+	if tx.Units < 0 || tx.Units > maxTransactionUnits {
+		return fmt.Errorf("invalid execution units %d", tx.Units)
+	}
+	if tx.Runtime != "wasm" {
+		return errors.New("only wasm scripts meter execution units")
+	}
+	if tx.ResultHash == "" {
+		return errors.New("execution units claimed without a result hash to check them by")
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUsefulTarget(t *testing.T) {
+	declared := big.NewInt(1000)
+	for _, tc := range []struct {
+		units int64
+		want  int64
+	}{
+		{0, 1000},
+		{usefulUnitsPerStep - 1, 1000},
+		{usefulUnitsPerStep, 2000},
+		{3 * usefulUnitsPerStep, 4000},
+		{1000 * usefulUnitsPerStep, maxUsefulRelief * 1000},
+	} {
+		if got := usefulTarget(declared, tc.units); got.Cmp(big.NewInt(tc.want)) != 0 {
+			t.Errorf("target for %d units is %v, want %d", tc.units, got, tc.want)
+		}
+	}
+	if got := usefulTarget(maxTarget, 10*usefulUnitsPerStep); got.Cmp(maxTarget) != 0 {
+		t.Errorf("relaxed target %x exceeds the easiest target", got)
+	}
+}
+
+// A signed transaction claiming the result and units of echo.wasm on data,
+// with both stored in the fake IPFS.
+func usefulTransaction(t *testing.T, data string, units int64) Transaction {
+	t.Helper()
+	module, err := os.ReadFile(filepath.Join("testdata", "echo.wasm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scriptCID, _ := ipfsShell.Add(strings.NewReader(string(module)))
+	dataCID, _ := ipfsShell.Add(strings.NewReader(data))
+	tx := Transaction{
+		ID:         generateTransactionID(data),
+		Data:       data,
+		ScriptCID:  scriptCID,
+		DataCID:    dataCID,
+		ResultHash: resultHash(data),
+		Runtime:    "wasm",
+		Units:      units,
+		NetworkID:  networkID,
+	}
+	if err := signTransaction(&tx); err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func TestUsefulWork(t *testing.T) {
+	setupTestMiner(t)
+	setupReexecution(t)
+	*verifyExecution = false // Credited computation is checked regardless
+	defer func(c Consensus) { consensus = c }(consensus)
+	consensus = usefulWork{}
+
+	_, units, err := runTestModule(t, "echo.wasm", "useful")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seal := func(tx Transaction) (Block, bool) {
+		t.Helper()
+		block, found := consensus.Seal(context.Background(), nil, signedTestTemplate(t, 1, genesisBlock, []Transaction{tx}))
+		if !found {
+			t.Fatal("sealing was interrupted")
+		}
+		data, err := json.Marshal(block)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return block, validateBlock(string(data), genesisBlock.Hash, currentTarget())
+	}
+
+	block, valid := seal(usefulTransaction(t, "useful", units))
+	if block.Units != units {
+		t.Errorf("block credits %d units, transaction metered %d", block.Units, units)
+	}
+	if !valid {
+		t.Fatal("block crediting verified computation is invalid")
+	}
+	plain := mineTestBlock(t, signedTestTransaction(t, "plain"))
+	if consensus.Work(block).Cmp(consensus.Work(plain)) <= 0 {
+		t.Error("block with computation does not outweigh one without")
+	}
+
+	if _, valid := seal(usefulTransaction(t, "padded", units+1)); valid {
+		t.Error("block overstating its computation is valid")
+	}
+}
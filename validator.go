@@ -0,0 +1,30 @@
+package main
+
+import (
+	"flag"
+	"sync/atomic"
+)
+
+var (
+	noMine = flag.Bool("no-mine", false, "run as a validator: process jobs, validate, store and relay blocks without mining (toggled at runtime with POST /mining)")
+
+	miningEnabled atomic.Bool              // Whether the mining thread should search for blocks
+	miningToggled = make(chan struct{}, 1) // Signalled when mining is switched on or off
+)
+
+// Switch mining on or off. A block being mined is abandoned when mining is
+// switched off; its transactions stay pending.
+func setMiningEnabled(enabled bool) {
+	if miningEnabled.Swap(enabled) == enabled {
+		return
+	}
+	if enabled {
+		miningLog.Info("Mining enabled")
+	} else {
+		miningLog.Info("Mining disabled, running as a validator")
+	}
+	select {
+	case miningToggled <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var (
+	walletSpec = flag.String("wallet", "file:wallet.json", "signer for transactions this node creates: file:<keystore> (created on first start) or <backend>:<spec>")
+
+	walletSigner  Signer // Signs transactions created by this node
+	walletAddress string // Address derived from the wallet public key
+)
+
+// Open the wallet key, generating a keystore file on first start.
+func setupWallet() error {
+	if path, ok := strings.CutPrefix(*walletSpec, "file:"); ok {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			pass, err := keystorePassphrase()
+			if err != nil {
+				return err
+			}
+			key, err := generateSigningKey(sigAlgECDSAP256)
+			if err != nil {
+				return fmt.Errorf("failed to generate wallet key: %v", err)
+			}
+			if err := saveEncryptedKey(path, key, pass); err != nil {
+				return err
+			}
+			nodeLog.Info("Generated new wallet key", "file", path)
+		}
+	}
+
+	signer, err := openSigner(*walletSpec)
+	if err != nil {
+		return fmt.Errorf("failed to open wallet: %v", err)
+	}
+	address, err := nodeIDFromPublicKey(signer.Public())
+	if err != nil {
+		return err
+	}
+	walletSigner, walletAddress = signer, address
+	nodeLog.Info("Loaded wallet", "address", address)
+	return nil
+}
+
+// Bytes a transaction signature covers: the transaction without its
+// signature.
+func transactionSigningPayload(tx Transaction) ([]byte, error) {
+	tx.Signature = nil
+	return json.Marshal(tx)
+}
+
+// Sign a transaction with the wallet key.
+func signTransaction(tx *Transaction) error {
+	payload, err := transactionSigningPayload(*tx)
+	if err != nil {
+		return err
+	}
+	sig, err := signPayload(walletSigner, sigDomainTransaction, payload)
+	if err != nil {
+		return fmt.Errorf("failed to sign transaction: %v", err)
+	}
+	tx.Signature = &sig
+	return nil
+}
+
+// Address of a transaction's sender, taken from its signature's public key
+// without verifying the signature.
+func transactionSender(tx Transaction) (string, error) {
+	if tx.Signature == nil {
+		return "", errors.New("transaction is not signed")
+	}
+	pub, err := tx.Signature.Public()
+	if err != nil {
+		return "", err
+	}
+	return nodeIDFromPublicKey(pub)
+}
+
+// Verify a transaction's signature and return the sender's address.
+func verifyTransactionSignature(tx Transaction) (string, error) {
+	if tx.Signature == nil {
+		return "", errors.New("transaction is not signed")
+	}
+	payload, err := transactionSigningPayload(tx)
+	if err != nil {
+		return "", err
+	}
+	if err := tx.Signature.Verify(sigDomainTransaction, payload); err != nil {
+		return "", err
+	}
+	pub, err := tx.Signature.Public()
+	if err != nil {
+		return "", err
+	}
+	return nodeIDFromPublicKey(pub)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+var (
+	wasmFuel = flag.Int64("wasm-fuel", 100_000_000, "function calls a WASM job may make before it is stopped")
+
+	errOutOfFuel = errors.New("out of fuel")
+)
+
+// Run a WASI command module in process. The module reads its data on stdin
+// and writes its result to stdout. It sees no files, clocks or randomness
+// of the node, so every validator gets the same bytes out of it; memory is
+// capped at -script-memory and every function call burns one unit of
+// -wasm-fuel. Returns the units burnt along with the output.
+func executeWASM(ctx context.Context, scriptPath, dataPath, outputDir string, params *ScriptParams) (string, int64, error) {
+	module, err := os.ReadFile(scriptPath)
+	if err != nil {
+		return "", 0, err
+	}
+	data, err := os.Open(dataPath)
+	if err != nil {
+		return "", 0, err
+	}
+	defer data.Close()
+
+	// The interpreter behaves the same on every platform
+	config := wazero.NewRuntimeConfigInterpreter().
+		WithMemoryLimitPages(uint32(*scriptMemory) * 16). // 64 KiB pages
+		WithCloseOnContextDone(true)
+	meter := &fuelMeter{remaining: *wasmFuel}
+	ctx = experimental.WithFunctionListenerFactory(ctx, meter)
+	runtime := wazero.NewRuntimeWithConfig(ctx, config)
+	defer runtime.Close(ctx)
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		return "", 0, fmt.Errorf("failed to set up WASI: %v", err)
+	}
+
+	var output bytes.Buffer
+	moduleConfig := wazero.NewModuleConfig().
+		WithName("job").
+		WithArgs(append([]string{"job"}, params.args()...)...).
+		WithStdin(data).
+		WithStdout(&output).
+		WithStderr(&output)
+	if params != nil {
+		for name, value := range params.Vars {
+			moduleConfig = moduleConfig.WithEnv(name, value)
+		}
+	}
+	if outputDir != "" {
+		moduleConfig = moduleConfig.
+			WithFSConfig(wazero.NewFSConfig().WithDirMount(outputDir, "/output")).
+			WithEnv(outputDirVar, "/output")
+	}
+	_, err = runtime.InstantiateWithConfig(ctx, module, moduleConfig)
+	var exit *sys.ExitError
+	if errors.As(err, &exit) && exit.ExitCode() == 0 {
+		err = nil
+	}
+	if errors.Is(err, errOutOfFuel) {
+		err = fmt.Errorf("out of fuel after %d function calls", *wasmFuel)
+	}
+	return output.String(), meter.used(), err
+}
+
+// fuelMeter stops a module once it has made its allowance of function
+// calls. Counting calls rather than instructions keeps the interpreter
+// fast; a loop without calls is stopped by the script timeout instead.
+type fuelMeter struct {
+	remaining int64
+}
+
+// Units burnt so far, up to the whole allowance.
+func (m *fuelMeter) used() int64 {
+	return *wasmFuel - max(m.remaining, 0)
+}
+
+func (m *fuelMeter) NewFunctionListener(api.FunctionDefinition) experimental.FunctionListener {
+	return m
+}
+
+func (m *fuelMeter) Before(context.Context, api.Module, api.FunctionDefinition, []uint64, experimental.StackIterator) {
+	m.remaining--
+	if m.remaining < 0 {
+		panic(errOutOfFuel)
+	}
+}
+
+func (m *fuelMeter) After(context.Context, api.Module, api.FunctionDefinition, []uint64) {}
+
+func (m *fuelMeter) Abort(context.Context, api.Module, api.FunctionDefinition, error) {}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Run a module from testdata on data in the wasm runtime.
+func runTestModule(t *testing.T, module, data string) (string, int64, error) {
+	t.Helper()
+	dataPath := filepath.Join(t.TempDir(), "data.txt")
+	if err := os.WriteFile(dataPath, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	runtime, err := lookupRuntime("wasm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return executeScript(context.Background(), runtime, nil, filepath.Join("testdata", module), dataPath, "", nil)
+}
+
+func TestExecuteWASM(t *testing.T) {
+	first, units, err := runTestModule(t, "echo.wasm", "input data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != "input data" {
+		t.Errorf("module printed %q", first)
+	}
+	if units <= 0 {
+		t.Errorf("module metered %d units", units)
+	}
+	if again, againUnits, _ := runTestModule(t, "echo.wasm", "input data"); again != first || againUnits != units {
+		t.Errorf("second run printed %q in %d units, first %q in %d", again, againUnits, first, units)
+	}
+}
+
+func TestWASMLimits(t *testing.T) {
+	savedFuel, savedMemory := *wasmFuel, *scriptMemory
+	t.Cleanup(func() { *wasmFuel, *scriptMemory = savedFuel, savedMemory })
+	*wasmFuel, *scriptMemory = 1000, 1
+
+	if _, _, err := runTestModule(t, "loop.wasm", ""); err == nil || !strings.Contains(err.Error(), "out of fuel") {
+		t.Errorf("endless module stopped with %v", err)
+	}
+	if _, _, err := runTestModule(t, "bigmem.wasm", ""); err == nil {
+		t.Error("module over the memory limit ran")
+	}
+	if _, _, err := runTestModule(t, "echo.wasm", "x"); err != nil {
+		t.Errorf("module within limits failed: %v", err)
+	}
+}
+
+func TestWASMTakesNoEnvironment(t *testing.T) {
+	if _, err := parseJobMessage(testJobCIDs + " runtime=wasm image=" + testImage); err == nil {
+		t.Error("wasm job with a container image parsed without error")
+	}
+}
@@ -0,0 +1,105 @@
+"use strict";
+
+const gateway = document.body.dataset.gateway;
+
+function element(tag, text, className) {
+  const el = document.createElement(tag);
+  if (text !== undefined) el.textContent = text;
+  if (className) el.className = className;
+  return el;
+}
+
+// A link to a CID on the IPFS gateway, or a dash for transactions without one.
+function cidLink(cid) {
+  if (!cid || cid === "-1") return element("span", "–");
+  const link = element("a", cid.slice(0, 12) + "…");
+  link.href = gateway + cid;
+  link.title = cid;
+  link.target = "_blank";
+  link.rel = "noopener";
+  return link;
+}
+
+function transactionRow(tx) {
+  const row = element("tr");
+  row.append(element("td", tx.ID.slice(0, 16) + "…"));
+  if (tx.Coinbase) {
+    row.append(element("td", `coinbase ${tx.Coinbase.Amount} to ${tx.Coinbase.Address.slice(0, 16)}…`));
+    row.append(element("td"), element("td"), element("td"));
+    return row;
+  }
+  row.append(element("td", tx.Recipient ? "sealed" : tx.Data.slice(0, 40)));
+  const script = element("td"), data = element("td");
+  script.append(cidLink(tx.ScriptCID));
+  data.append(cidLink(tx.DataCID));
+  row.append(script, data, element("td", tx.Fee || 0));
+  return row;
+}
+
+function blockView(block) {
+  const transactions = block.Transactions || [];
+  const view = element("details", undefined, "block");
+  const summary = element("summary");
+  const time = new Date(block.Timestamp * 1000).toLocaleString();
+  summary.append(element("strong", `#${block.BlockNumber}`), ` ${time} · ${transactions.length} transactions · `, element("code", block.Hash));
+  view.append(summary);
+
+  const previous = element("p");
+  previous.append("Previous block on IPFS: ", cidLink(block.PrevCID));
+  view.append(previous);
+
+  const table = element("table");
+  const head = element("tr");
+  for (const title of ["Transaction", "Result", "Script", "Data", "Fee"]) head.append(element("th", title));
+  table.append(head);
+  for (const tx of transactions) table.append(transactionRow(tx));
+  view.append(table);
+  return view;
+}
+
+async function getJSON(path) {
+  const response = await fetch(path);
+  if (!response.ok) throw new Error(`${path}: ${response.status}`);
+  return response.json();
+}
+
+async function refreshBlocks() {
+  const blocks = await getJSON("/blocks?count=20");
+  document.getElementById("blocks").replaceChildren(...blocks.map(blockView));
+}
+
+async function refreshPeers() {
+  const peers = await getJSON("/peers");
+  const list = document.getElementById("peers");
+  list.replaceChildren(...peers.map((peer) => element("li", peer)));
+  if (peers.length === 0) list.append(element("li", "none"));
+}
+
+// Follow the miner over the websocket, reloading blocks when the tip moves.
+function followMining() {
+  const scheme = location.protocol === "https:" ? "wss:" : "ws:";
+  const socket = new WebSocket(`${scheme}//${location.host}/mining/live`);
+  let tip = null;
+  socket.onmessage = (event) => {
+    const status = JSON.parse(event.data);
+    document.getElementById("height").textContent = status.height;
+    document.getElementById("tip").textContent = status.tip || "–";
+    const mining = document.getElementById("mining");
+    mining.textContent = !status.enabled ? "disabled" : status.active ? "mining" : "idle";
+    mining.className = status.enabled ? "on" : "off";
+    document.getElementById("hashrate").textContent = `${Math.round(status.hashrate)} H/s`;
+    document.getElementById("mined").textContent = status.minedBlocks;
+    if (status.tip !== tip) {
+      tip = status.tip;
+      refreshBlocks().catch(console.error);
+    }
+  };
+  socket.onclose = () => {
+    document.getElementById("mining").textContent = "disconnected";
+    setTimeout(followMining, 5000);
+  };
+}
+
+refreshPeers().catch(console.error);
+setInterval(() => refreshPeers().catch(console.error), 30000);
+followMining();
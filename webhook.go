@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Environment variable holding the webhook HMAC secret.
+const webhookSecretEnv = "WEBHOOK_SECRET"
+
+// Webhook event names.
+const (
+	eventBlockAccepted = "block.accepted"
+	eventChainReorg    = "chain.reorg"
+	eventJobCompleted  = "job.completed"
+)
+
+var (
+	webhookURLs    = flag.String("webhooks", "", "comma-separated URLs notified of chain events")
+	webhookRetries = flag.Int("webhook-retries", 5, "delivery attempts per webhook event")
+
+	webhookSecret []byte              // HMAC key from $WEBHOOK_SECRET
+	webhookQueues []chan webhookEvent // Events waiting for delivery, one queue per URL
+	webhookTo     []string            // URL each queue delivers to
+)
+
+// Body posted to webhook URLs. The X-Signature header carries
+// "sha256=<hex HMAC-SHA256 of the body>" keyed with $WEBHOOK_SECRET.
+type webhookEvent struct {
+	Event string `json:"event"`
+	Time  int64  `json:"time"`
+	Data  any    `json:"data"`
+}
+
+// Load the webhook secret and make a delivery queue for each URL. Without a
+// secret anyone could forge the signatures, so webhooks require one.
+func setupWebhooks() error {
+	if *webhookURLs == "" {
+		return nil
+	}
+	if webhookSecret = []byte(os.Getenv(webhookSecretEnv)); len(webhookSecret) == 0 {
+		return fmt.Errorf("-webhooks requires $%s to sign the events", webhookSecretEnv)
+	}
+	webhookQueues, webhookTo = nil, nil
+	for _, url := range strings.Split(*webhookURLs, ",") {
+		webhookQueues = append(webhookQueues, make(chan webhookEvent, 256))
+		webhookTo = append(webhookTo, strings.TrimSpace(url))
+	}
+	return nil
+}
+
+// Queue an event for delivery to every configured webhook.
+func fireWebhook(event string, data any) {
+	for i, queue := range webhookQueues {
+		select {
+		case queue <- webhookEvent{Event: event, Time: time.Now().Unix(), Data: data}:
+		default:
+			alertLog.Warn("Webhook queue full, dropping event", "url", webhookTo[i], "event", event)
+		}
+	}
+}
+
+// Webhook Delivery Threads, one per URL so that a dead endpoint only holds
+// up its own events
+func deliverWebhooks(ctx context.Context) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	var wg sync.WaitGroup
+	for i, queue := range webhookQueues {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			deliverWebhook(ctx, client, webhookTo[i], queue)
+		}()
+	}
+	wg.Wait()
+}
+
+func deliverWebhook(ctx context.Context, client *http.Client, url string, queue <-chan webhookEvent) {
+	for {
+		var event webhookEvent
+		select {
+		case <-ctx.Done():
+			return
+		case event = <-queue:
+		}
+
+		body, err := json.Marshal(event)
+		if err != nil {
+			alertLog.Error("Error encoding webhook event", "err", err)
+			continue
+		}
+		mac := hmac.New(sha256.New, webhookSecret)
+		mac.Write(body)
+		signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+		if err := postWebhook(ctx, client, url, event.Event, signature, body); err != nil {
+			alertLog.Warn("Giving up on webhook", "url", url, "event", event.Event, "err", err)
+		}
+	}
+}
+
+// Post one event, retrying with exponential backoff on failure until ctx
+// is cancelled.
+func postWebhook(ctx context.Context, client *http.Client, url, event, signature string, body []byte) error {
+	backoff := time.Second
+	var lastErr error
+	for attempt := 0; attempt < *webhookRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Event", event)
+		req.Header.Set("X-Signature", signature)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode/100 == 2 {
+			return nil
+		}
+		lastErr = fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return lastErr
+}
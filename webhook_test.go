@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhooksRequireSecret(t *testing.T) {
+	defer func(urls string) { *webhookURLs = urls; webhookQueues, webhookTo = nil, nil }(*webhookURLs)
+	*webhookURLs = "http://127.0.0.1:1/hook"
+	t.Setenv(webhookSecretEnv, "")
+	if err := setupWebhooks(); err == nil {
+		t.Error("webhooks configured without a secret")
+	}
+}
+
+func TestDeadWebhookDoesNotDelayOthers(t *testing.T) {
+	defer func(urls string, retries int) {
+		*webhookURLs, *webhookRetries = urls, retries
+		webhookQueues, webhookTo = nil, nil
+	}(*webhookURLs, *webhookRetries)
+
+	dead := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer dead.Close()
+	delivered := make(chan string, 1)
+	live := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered <- r.Header.Get("X-Event")
+	}))
+	defer live.Close()
+
+	*webhookURLs, *webhookRetries = dead.URL+","+live.URL, 5
+	t.Setenv(webhookSecretEnv, "secret")
+	if err := setupWebhooks(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		deliverWebhooks(ctx)
+		close(stopped)
+	}()
+	defer func() { cancel(); <-stopped }()
+
+	// The dead endpoint retries for seconds; the live one hears at once
+	fireWebhook(eventBlockAccepted, nil)
+	fireWebhook(eventChainReorg, nil)
+	for _, want := range []string{eventBlockAccepted, eventChainReorg} {
+		select {
+		case got := <-delivered:
+			if got != want {
+				t.Errorf("delivered %q, want %q", got, want)
+			}
+		case <-time.After(500 * time.Millisecond):
+			t.Fatalf("%s was held up behind the dead webhook", want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	jobDirFlag   = flag.String("job-dir", "", "directory jobs get their private working directories in (default <datadir>/jobs)")
+	jobRetention = flag.Duration("job-retention", 0, "how long a job's working directory is kept after the job, for debugging; 0 removes it at once")
+
+	jobDirBase = "" // Set by setupJobDirs; the system temp directory until then
+)
+
+// Create the directory job working directories go in, and remove the ones
+// a previous run left behind past their retention.
+func setupJobDirs() error {
+	base := *jobDirFlag
+	if base == "" {
+		base = filepath.Join(*dataDir, "jobs")
+	}
+	if err := os.MkdirAll(base, 0o700); err != nil {
+		return fmt.Errorf("failed to create job directory: %v", err)
+	}
+	entries, err := os.ReadDir(base)
+	if err != nil {
+		return fmt.Errorf("failed to read job directory: %v", err)
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !entry.IsDir() || !strings.HasPrefix(entry.Name(), "job") {
+			continue
+		}
+		if time.Since(info.ModTime()) >= *jobRetention {
+			os.RemoveAll(filepath.Join(base, entry.Name()))
+		}
+	}
+	jobDirBase = base
+	return nil
+}
+
+// Create a private working directory for one job, so concurrent jobs never
+// share files. The returned function removes it, at once or after
+// -job-retention.
+func newJobDir(name string) (string, func(), error) {
+	dir, err := os.MkdirTemp(jobDirBase, "job-"+name+"-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create job directory: %v", err)
+	}
+	release := func() {
+		if *jobRetention <= 0 {
+			os.RemoveAll(dir)
+			return
+		}
+		txprocLog.Debug("Keeping job directory", "dir", dir, "for", *jobRetention)
+		time.AfterFunc(*jobRetention, func() { os.RemoveAll(dir) })
+	}
+	return dir, release, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Create job directories in a temporary directory for the duration of a
+// test.
+func setupTestJobDirs(t *testing.T, retention time.Duration) string {
+	t.Helper()
+	savedFlag, savedRetention, savedBase := *jobDirFlag, *jobRetention, jobDirBase
+	t.Cleanup(func() { *jobDirFlag, *jobRetention, jobDirBase = savedFlag, savedRetention, savedBase })
+	*jobDirFlag, *jobRetention = t.TempDir(), retention
+	if err := setupJobDirs(); err != nil {
+		t.Fatal(err)
+	}
+	return *jobDirFlag
+}
+
+func TestJobDirsArePrivate(t *testing.T) {
+	base := setupTestJobDirs(t, 0)
+	first, releaseFirst, err := newJobDir("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, releaseSecond, err := newJobDir("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second || filepath.Dir(first) != base {
+		t.Fatalf("job directories %s and %s", first, second)
+	}
+
+	releaseFirst()
+	if _, err := os.Stat(first); !os.IsNotExist(err) {
+		t.Error("released job directory still exists")
+	}
+	if _, err := os.Stat(second); err != nil {
+		t.Errorf("other job's directory is gone: %v", err)
+	}
+	releaseSecond()
+}
+
+func TestJobDirRetention(t *testing.T) {
+	setupTestJobDirs(t, 50*time.Millisecond)
+	dir, release, err := newJobDir("kept")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if _, err := os.Stat(dir); err != nil {
+		t.Fatalf("job directory removed before its retention: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("job directory kept past its retention")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSetupJobDirsRemovesExpired(t *testing.T) {
+	base := setupTestJobDirs(t, time.Hour)
+	expired, fresh := filepath.Join(base, "job-old-1"), filepath.Join(base, "job-new-1")
+	os.Mkdir(expired, 0o700)
+	os.Mkdir(fresh, 0o700)
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(expired, old, old)
+
+	if err := setupJobDirs(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(expired); !os.IsNotExist(err) {
+		t.Error("expired job directory was kept")
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Errorf("job directory within its retention was removed: %v", err)
+	}
+}